@@ -0,0 +1,389 @@
+package geoutil
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/golang/geo/s2"
+)
+
+// OverlayOperation identifies a boolean operation between geometries.
+type OverlayOperation int
+
+const (
+	// OverlayIntersection keeps the points in both geometries.
+	OverlayIntersection OverlayOperation = iota
+
+	// OverlayUnion keeps the points in either geometry.
+	OverlayUnion
+
+	// OverlayDifference keeps the points in the first geometry and not in
+	// the second.
+	OverlayDifference
+
+	// OverlaySymmetricDifference keeps the points in exactly one of the
+	// geometries.
+	OverlaySymmetricDifference
+)
+
+var overlayOperationNames = [...]string{
+	OverlayIntersection:        "intersection",
+	OverlayUnion:               "union",
+	OverlayDifference:          "difference",
+	OverlaySymmetricDifference: "symmetric difference",
+}
+
+func (o OverlayOperation) String() string {
+	if o < 0 || int(o) >= len(overlayOperationNames) {
+		return fmt.Sprintf("OverlayOperation(%d)", int(o))
+	}
+
+	return overlayOperationNames[o]
+}
+
+func (o OverlayOperation) selected(a, b bool) bool {
+	switch o {
+	case OverlayIntersection:
+		return a && b
+	case OverlayUnion:
+		return a || b
+	case OverlayDifference:
+		return a && !b
+	default:
+		return a != b
+	}
+}
+
+// overlayPolygons returns the result of an operation between two valid
+// polygons, with vertices closer than the snap radius merged.
+func overlayPolygons(a, b *s2.Polygon, operation OverlayOperation) *s2.Polygon {
+	inputs := []overlayInput{polygonInput(a), polygonInput(b)}
+	return overlay(inputs, defaultSnapRadius, func(inside []bool) bool {
+		return operation.selected(inside[0], inside[1])
+	})
+}
+
+// Intersection returns the polygon of the points in both of two polygons.
+func Intersection(a, b *s2.Polygon) *s2.Polygon {
+	return overlayPolygons(a, b, OverlayIntersection)
+}
+
+// Union returns the polygon of the points in any of polygons.
+func Union(polygons ...*s2.Polygon) *s2.Polygon {
+	inputs := make([]overlayInput, len(polygons))
+	for i, polygon := range polygons {
+		inputs[i] = polygonInput(polygon)
+	}
+
+	return unionInputs(inputs, defaultSnapRadius)
+}
+
+// Difference returns the polygon of the points in a polygon and not in
+// another.
+func Difference(a, b *s2.Polygon) *s2.Polygon {
+	return overlayPolygons(a, b, OverlayDifference)
+}
+
+// SymmetricDifference returns the polygon of the points in exactly one of two
+// polygons.
+func SymmetricDifference(a, b *s2.Polygon) *s2.Polygon {
+	return overlayPolygons(a, b, OverlaySymmetricDifference)
+}
+
+// covers reports whether a point is inside the region of an input or within
+// the snap radius of one of edges.
+func covers(input overlayInput, edges []s2.Edge, point s2.Point) bool {
+	for _, edge := range edges {
+		if s2.DistanceFromSegment(point, edge.V0, edge.V1) < defaultSnapRadius {
+			return true
+		}
+	}
+
+	return input.contains(point)
+}
+
+// inputEdges returns the edges of the rings of an input.
+func inputEdges(input overlayInput) []s2.Edge {
+	edges := []s2.Edge{}
+	for _, ring := range input.rings {
+		for i, point := range ring {
+			edges = append(edges, s2.Edge{V0: point, V1: ring[(i+1)%len(ring)]})
+		}
+	}
+
+	return edges
+}
+
+// boundaryIndex returns an index of the edges of the rings of an input, and
+// the offsets of the rings in the edges returned by inputEdges.
+func boundaryIndex(input overlayInput) (*edgeIndex, []int) {
+	boundary := newEdgeIndex(defaultSnapRadius)
+	offsets := make([]int, len(input.rings))
+	for i, ring := range input.rings {
+		if i > 0 {
+			offsets[i] = offsets[i-1] + len(input.rings[i-1])
+		}
+
+		boundary.add(i, ring, true)
+	}
+
+	return boundary, offsets
+}
+
+// clipPolylines returns the parts of polylines inside the region of an
+// input, including the parts along its boundary, or the parts outside of it.
+func clipPolylines(polylines []*s2.Polyline, input overlayInput, inside bool) []*s2.Polyline {
+	edges := inputEdges(input)
+	boundary, offsets := boundaryIndex(input)
+
+	lines := newEdgeIndex(defaultSnapRadius)
+	lineEdges := [][2]int{}
+	firstEdges := make([]int, len(polylines))
+	for i, polyline := range polylines {
+		firstEdges[i] = len(lineEdges)
+		if len(*polyline) < 2 {
+			continue
+		}
+
+		lines.add(i, *polyline, false)
+		for j := 1; j < len(*polyline); j++ {
+			lineEdges = append(lineEdges, [2]int{i, j})
+		}
+	}
+
+	// The candidates of each polyline edge are the boundary edges near it,
+	// which cross it, are near one of its vertices, or have a vertex near
+	// it.
+	candidates := make([][]int, len(lineEdges))
+	for k, lineEdge := range lineEdges {
+		polyline := *polylines[lineEdge[0]]
+		a, b := polyline[lineEdge[1]-1], polyline[lineEdge[1]]
+		near := boundary.crossings(a, b, s2.CrossingTypeAll)
+		near = append(near, boundary.near(a)...)
+		near = append(near, boundary.near(b)...)
+		for _, edge := range near {
+			candidates[k] = append(candidates[k], offsets[edge[0]]+edge[1])
+		}
+	}
+
+	for i, ring := range input.rings {
+		for j, point := range ring {
+			previous := (j + len(ring) - 1) % len(ring)
+			for _, lineEdge := range lines.near(point) {
+				k := firstEdges[lineEdge[0]] + lineEdge[1]
+				candidates[k] = append(candidates[k], offsets[i]+previous, offsets[i]+j)
+			}
+		}
+	}
+
+	clipped := []*s2.Polyline{}
+	var current s2.Polyline
+	flush := func() {
+		if len(current) >= 2 {
+			polyline := current
+			clipped = append(clipped, &polyline)
+		}
+
+		current = nil
+	}
+
+	for k, lineEdge := range lineEdges {
+		polyline := *polylines[lineEdge[0]]
+		a, b := polyline[lineEdge[1]-1], polyline[lineEdge[1]]
+		if lineEdge[1] == 1 {
+			flush()
+		}
+
+		// The edge is split where it crosses the boundary and near the
+		// vertices of the boundary.
+		sort.Ints(candidates[k])
+		nearby := []s2.Edge{}
+		points := []s2.Point{a, b}
+		for i, e := range candidates[k] {
+			if i > 0 && e == candidates[k][i-1] {
+				continue
+			}
+
+			edge := edges[e]
+			nearby = append(nearby, edge)
+			if s2.CrossingSign(a, b, edge.V0, edge.V1) == s2.Cross {
+				points = append(points, s2.Intersection(a, b, edge.V0, edge.V1))
+			}
+
+			for _, v := range []s2.Point{edge.V0, edge.V1} {
+				if s2.DistanceFromSegment(v, a, b) < defaultSnapRadius {
+					points = append(points, s2.Project(v, a, b))
+				}
+			}
+		}
+
+		sort.SliceStable(points[2:], func(i, j int) bool {
+			return a.Distance(points[2+i]) < a.Distance(points[2+j])
+		})
+
+		// Split points within the snap radius of the previous one, or of
+		// the end of the edge, are merged into it.
+		splits := []s2.Point{a}
+		for _, point := range append(points[2:], b) {
+			if point.Distance(splits[len(splits)-1]) >= defaultSnapRadius {
+				splits = append(splits, point)
+			} else if point == b && len(splits) > 1 {
+				splits[len(splits)-1] = b
+			}
+		}
+
+		for i := 1; i < len(splits); i++ {
+			p, q := splits[i-1], splits[i]
+
+			middle := s2.Point{Vector: p.Add(q.Vector).Normalize()}
+			if covers(input, nearby, middle) != inside {
+				flush()
+				continue
+			}
+
+			if len(current) == 0 || current[len(current)-1] != p {
+				flush()
+				current = append(current, p)
+			}
+
+			current = append(current, q)
+		}
+	}
+
+	flush()
+	return clipped
+}
+
+// ClipPolylines returns the parts of polylines inside a polygon, including
+// the parts along its boundary.
+func ClipPolylines(polylines []*s2.Polyline, polygon *s2.Polygon) []*s2.Polyline {
+	return clipPolylines(polylines, polygonInput(polygon), true)
+}
+
+// DifferencePolylines returns the parts of polylines outside of a polygon.
+func DifferencePolylines(polylines []*s2.Polyline, polygon *s2.Polygon) []*s2.Polyline {
+	return clipPolylines(polylines, polygonInput(polygon), false)
+}
+
+// lineInput returns an input of no region whose boundary is the edges of
+// polylines, each traversed there and back by a ring, so that clipping
+// polylines with it keeps or removes their parts along the polylines.
+func lineInput(polylines []*s2.Polyline) overlayInput {
+	input := overlayInput{
+		contains: func(s2.Point) bool { return false },
+	}
+
+	for _, polyline := range polylines {
+		if len(*polyline) < 2 {
+			continue
+		}
+
+		ring := append([]s2.Point(nil), *polyline...)
+		for i := len(*polyline) - 2; i > 0; i-- {
+			ring = append(ring, (*polyline)[i])
+		}
+
+		input.rings = append(input.rings, ring)
+	}
+
+	return input
+}
+
+// overlayPolylines returns the result of an operation between polylines,
+// whose parts along each other are kept once.
+func overlayPolylines(a, b []*s2.Polyline, operation OverlayOperation) []*s2.Polyline {
+	switch operation {
+	case OverlayIntersection:
+		return clipPolylines(a, lineInput(b), true)
+	case OverlayUnion:
+		return append(append([]*s2.Polyline{}, a...), clipPolylines(b, lineInput(a), false)...)
+	case OverlayDifference:
+		return clipPolylines(a, lineInput(b), false)
+	default:
+		return append(clipPolylines(a, lineInput(b), false), clipPolylines(b, lineInput(a), false)...)
+	}
+}
+
+// clipPoints returns the points inside the region of an input, including the
+// points on its boundary, or the points outside of it.
+func clipPoints(points []s2.Point, input overlayInput, inside bool) []s2.Point {
+	edges := inputEdges(input)
+	boundary, offsets := boundaryIndex(input)
+	clipped := []s2.Point{}
+	for _, point := range points {
+		nearby := []s2.Edge{}
+		for _, edge := range boundary.near(point) {
+			nearby = append(nearby, edges[offsets[edge[0]]+edge[1]])
+		}
+
+		if covers(input, nearby, point) == inside {
+			clipped = append(clipped, point)
+		}
+	}
+
+	return clipped
+}
+
+// Overlay returns the result of an operation between geometries of the
+// types decoded by the encoding packages. Operations between two polygons
+// return a *s2.Polygon, and operations between polylines return
+// []*s2.Polyline. The intersection of polylines or points and a polygon, in
+// either order, and their difference with a polygon return []*s2.Polyline or
+// []s2.Point, and the difference of a polygon with polylines or points is the
+// polygon. The union and symmetric difference of a polygon with polylines or
+// points have parts of different dimensions, which no single type holds, and
+// return an error, as do operations between polylines and points.
+func Overlay(a, b interface{}, operation OverlayOperation) (interface{}, error) {
+	asPolylines := func(geometry interface{}) ([]*s2.Polyline, bool) {
+		switch geometry := geometry.(type) {
+		case *s2.Polyline:
+			return []*s2.Polyline{geometry}, true
+		case []*s2.Polyline:
+			return geometry, true
+		}
+
+		return nil, false
+	}
+
+	asPoints := func(geometry interface{}) ([]s2.Point, bool) {
+		switch geometry := geometry.(type) {
+		case s2.Point:
+			return []s2.Point{geometry}, true
+		case []s2.Point:
+			return geometry, true
+		}
+
+		return nil, false
+	}
+
+	polygonA, aIsPolygon := a.(*s2.Polygon)
+	polygonB, bIsPolygon := b.(*s2.Polygon)
+	polylinesA, aIsPolylines := asPolylines(a)
+	polylinesB, bIsPolylines := asPolylines(b)
+	pointsA, aIsPoints := asPoints(a)
+	pointsB, bIsPoints := asPoints(b)
+	if operation < 0 || int(operation) >= len(overlayOperationNames) {
+		return nil, fmt.Errorf("geoutil: unknown overlay operation %v", operation)
+	}
+
+	inside := operation == OverlayIntersection
+	switch {
+	case aIsPolygon && bIsPolygon:
+		return overlayPolygons(polygonA, polygonB, operation), nil
+	case aIsPolylines && bIsPolylines:
+		return overlayPolylines(polylinesA, polylinesB, operation), nil
+	case operation != OverlayIntersection && operation != OverlayDifference:
+	case aIsPolylines && bIsPolygon:
+		return clipPolylines(polylinesA, polygonInput(polygonB), inside), nil
+	case aIsPoints && bIsPolygon:
+		return clipPoints(pointsA, polygonInput(polygonB), inside), nil
+	case aIsPolygon && (bIsPolylines || bIsPoints) && operation == OverlayDifference:
+		return polygonA, nil
+	case aIsPolygon && bIsPolylines:
+		return clipPolylines(polylinesB, polygonInput(polygonA), inside), nil
+	case aIsPolygon && bIsPoints:
+		return clipPoints(pointsB, polygonInput(polygonA), inside), nil
+	}
+
+	return nil, fmt.Errorf("geoutil: %v of %T and %T is not supported", operation, a, b)
+}
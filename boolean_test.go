@@ -0,0 +1,313 @@
+package geoutil
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/golang/geo/s2"
+)
+
+// checkPolylines checks that polylines have about the given lengths in
+// degrees, the edges of polylines and polygons being geodesics rather than
+// parallels.
+func checkPolylines(t *testing.T, name string, polylines []*s2.Polyline, lengths ...float64) {
+	t.Helper()
+	if len(polylines) != len(lengths) {
+		t.Errorf("%s: %d polylines, want %d", name, len(polylines), len(lengths))
+		return
+	}
+
+	for i, polyline := range polylines {
+		if length := polyline.Length().Degrees(); math.Abs(length-lengths[i]) > 0.02*lengths[i] {
+			t.Errorf("%s: polyline %d of length %v, want %v", name, i, length, lengths[i])
+		}
+	}
+}
+
+func TestClipPolylines(t *testing.T) {
+	polygon, err := PolygonFromPolygonCoordinates([][][]float64{square, squareHole})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	polylines := polylinesFromDegrees(
+
+		// Along the equator, the southern edge of the square.
+		[]float64{0, -5, 0, 15},
+
+		// Across the square and its hole.
+		[]float64{5, -5, 5, 15},
+
+		// Within a fraction of the snap radius of the western edge, which
+		// it neither crosses nor touches.
+		[]float64{2, -1e-12, 8, -1e-12},
+
+		// Entirely outside of the square.
+		[]float64{20, 0, 20, 10},
+	)
+
+	clipped := ClipPolylines(polylines, polygon)
+	checkPolylines(t, "clipped", clipped, 10, 4, 4, 6)
+
+	difference := DifferencePolylines(polylines, polygon)
+	checkPolylines(t, "difference", difference, 5, 5, 5, 2, 5, 9.4)
+}
+
+func TestOverlayPolygons(t *testing.T) {
+
+	// The second polygon overlaps the northeastern quarter of the first,
+	// and has a second shell away from it.
+	a, err := PolygonFromPolygonCoordinates([][][]float64{square})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := PolygonFromMultiPolygonCoordinates([][][][]float64{
+		{{{5, 5}, {15, 5}, {15, 15}, {5, 15}, {5, 5}}},
+		{{{20, 0}, {25, 0}, {25, 5}, {20, 5}, {20, 0}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	intersection := Intersection(a, b)
+	tests := []struct {
+		operation OverlayOperation
+		polygon   *s2.Polygon
+		area      float64
+		inside    []s2.Point
+		outside   []s2.Point
+	}{
+		{
+			operation: OverlayIntersection,
+			polygon:   intersection,
+			area:      intersection.Area(),
+			inside:    []s2.Point{pointFromDegrees(7, 7)},
+			outside:   []s2.Point{pointFromDegrees(2, 2), pointFromDegrees(12, 12), pointFromDegrees(2, 22)},
+		},
+		{
+			operation: OverlayUnion,
+			polygon:   Union(a, b),
+			area:      a.Area() + b.Area() - intersection.Area(),
+			inside:    []s2.Point{pointFromDegrees(2, 2), pointFromDegrees(7, 7), pointFromDegrees(12, 12), pointFromDegrees(2, 22)},
+			outside:   []s2.Point{pointFromDegrees(12, 2), pointFromDegrees(2, 17)},
+		},
+		{
+			operation: OverlayDifference,
+			polygon:   Difference(a, b),
+			area:      a.Area() - intersection.Area(),
+			inside:    []s2.Point{pointFromDegrees(2, 2)},
+			outside:   []s2.Point{pointFromDegrees(7, 7), pointFromDegrees(12, 12), pointFromDegrees(2, 22)},
+		},
+		{
+			operation: OverlaySymmetricDifference,
+			polygon:   SymmetricDifference(a, b),
+			area:      a.Area() + b.Area() - 2*intersection.Area(),
+			inside:    []s2.Point{pointFromDegrees(2, 2), pointFromDegrees(12, 12), pointFromDegrees(2, 22)},
+			outside:   []s2.Point{pointFromDegrees(7, 7), pointFromDegrees(12, 2)},
+		},
+	}
+
+	for _, test := range tests {
+		result, err := Overlay(a, b, test.operation)
+		if err != nil {
+			t.Errorf("%v: %v", test.operation, err)
+			continue
+		}
+
+		// The results of Overlay and of the functions of each operation
+		// are the same.
+		polygon, ok := result.(*s2.Polygon)
+		if !ok || polygon.NumLoops() != test.polygon.NumLoops() || polygon.Area() != test.polygon.Area() {
+			t.Errorf("%v: result %v, want %v", test.operation, result, test.polygon)
+			continue
+		}
+
+		if problems, err := Validate(polygon); err != nil || len(problems) != 0 {
+			t.Errorf("%v: invalid result %v, %v", test.operation, problems, err)
+		}
+
+		if math.Abs(polygon.Area()-test.area) > 1e-12 {
+			t.Errorf("%v: area %v, want %v", test.operation, polygon.Area(), test.area)
+		}
+
+		for _, point := range test.inside {
+			if !polygon.ContainsPoint(point) {
+				t.Errorf("%v: point %v outside of the result", test.operation, s2.LatLngFromPoint(point))
+			}
+		}
+
+		for _, point := range test.outside {
+			if polygon.ContainsPoint(point) {
+				t.Errorf("%v: point %v inside of the result", test.operation, s2.LatLngFromPoint(point))
+			}
+		}
+	}
+
+	// The union of many polygons is the union of their halves.
+	squares := []*s2.Polygon{}
+	for i := 0; i < 40; i++ {
+		lng := float64(i)
+		polygon, err := PolygonFromPolygonCoordinates([][][]float64{{{lng, 0}, {lng + 1.5, 0}, {lng + 1.5, 1}, {lng, 1}, {lng, 0}}})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		squares = append(squares, polygon)
+	}
+
+	union := Union(squares...)
+	if problems, err := Validate(union); err != nil || len(problems) != 0 || union.NumLoops() != 1 {
+		t.Errorf("union of many polygons: %d loops, problems %v, %v", union.NumLoops(), problems, err)
+	}
+
+	if union := Union(); !union.IsEmpty() {
+		t.Errorf("union of no polygons: %v", union)
+	}
+}
+
+func TestOverlayPolylines(t *testing.T) {
+
+	// The second polylines overlap the eastern half of the first polyline,
+	// and cross it.
+	a := polylinesFromDegrees([]float64{0, 0, 0, 10})
+	b := polylinesFromDegrees([]float64{0, 5, 0, 15}, []float64{-5, 7, 5, 7})
+	tests := []struct {
+		operation OverlayOperation
+		lengths   []float64
+	}{
+		{OverlayIntersection, []float64{5}},
+		{OverlayUnion, []float64{10, 5, 10}},
+		{OverlayDifference, []float64{5}},
+		{OverlaySymmetricDifference, []float64{5, 5, 10}},
+	}
+
+	for _, test := range tests {
+		result, err := Overlay(a, b, test.operation)
+		if err != nil {
+			t.Errorf("%v: %v", test.operation, err)
+			continue
+		}
+
+		polylines, ok := result.([]*s2.Polyline)
+		if !ok {
+			t.Errorf("%v: result of type %T", test.operation, result)
+			continue
+		}
+
+		checkPolylines(t, test.operation.String(), polylines, test.lengths...)
+	}
+}
+
+func TestOverlay(t *testing.T) {
+	polygon, err := PolygonFromPolygonCoordinates([][][]float64{square, squareHole})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	polylines := polylinesFromDegrees([]float64{5, -5, 5, 15})
+	points := []s2.Point{pointFromDegrees(2, 2), pointFromDegrees(5, 5), pointFromDegrees(20, 20)}
+
+	// Polylines and points are clipped by polygons in either order.
+	for _, operands := range [][2]interface{}{{polylines, polygon}, {polygon, polylines}} {
+		result, err := Overlay(operands[0], operands[1], OverlayIntersection)
+		if polylines, ok := result.([]*s2.Polyline); err != nil || !ok {
+			t.Errorf("intersection of %T and %T: result %v, %v", operands[0], operands[1], result, err)
+		} else {
+			checkPolylines(t, "intersection", polylines, 4, 4)
+		}
+	}
+
+	result, err := Overlay(polylines, polygon, OverlayDifference)
+	if polylines, ok := result.([]*s2.Polyline); err != nil || !ok {
+		t.Errorf("difference of polylines: result %v, %v", result, err)
+	} else {
+		checkPolylines(t, "difference", polylines, 5, 2, 5)
+	}
+
+	result, err = Overlay(points, polygon, OverlayIntersection)
+	if clipped, ok := result.([]s2.Point); err != nil || !ok || len(clipped) != 1 || clipped[0] != points[0] {
+		t.Errorf("intersection of points: result %v, %v", result, err)
+	}
+
+	result, err = Overlay(polygon, points, OverlayDifference)
+	if result != polygon || err != nil {
+		t.Errorf("difference of a polygon and points: result %v, %v", result, err)
+	}
+
+	// Results of different dimensions are not supported.
+	tests := []struct {
+		a, b      interface{}
+		operation OverlayOperation
+	}{
+		{polygon, polylines, OverlayUnion},
+		{polylines, polygon, OverlaySymmetricDifference},
+		{points, polygon, OverlayUnion},
+		{polylines, points, OverlayIntersection},
+		{polygon, polygon, OverlayOperation(4)},
+		{polygon, polygon, OverlayOperation(-1)},
+	}
+
+	for _, test := range tests {
+		if result, err := Overlay(test.a, test.b, test.operation); err == nil {
+			t.Errorf("%v of %T and %T: result %v, no error", test.operation, test.a, test.b, result)
+		}
+	}
+}
+
+func TestClipManyPoints(t *testing.T) {
+	polygon, err := PolygonFromPolygonCoordinates([][][]float64{circleCoords(2000, 10)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The vertices are on the boundary, and so inside of the polygon. The
+	// other points are away from it.
+	points := []s2.Point{}
+	for _, coord := range circleCoords(2000, 10)[:2000] {
+		points = append(points, pointFromDegrees(coord[1], coord[0]))
+	}
+
+	want := len(points)
+	for lat := -14.9375; lat < 15; lat += 0.125 {
+		for lng := -14.9375; lng < 15; lng += 0.125 {
+			point := pointFromDegrees(lat, lng)
+			points = append(points, point)
+			if polygon.ContainsPoint(point) {
+				want++
+			}
+		}
+	}
+
+	start := time.Now()
+	result, err := Overlay(points, polygon, OverlayIntersection)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("points clipped in %v", elapsed)
+	}
+
+	if clipped := result.([]s2.Point); len(clipped) != want {
+		t.Errorf("%d points inside of the polygon, want %d", len(clipped), want)
+	}
+}
+
+func TestOverlayOperationString(t *testing.T) {
+	tests := []struct {
+		operation OverlayOperation
+		want      string
+	}{
+		{OverlayIntersection, "intersection"},
+		{OverlaySymmetricDifference, "symmetric difference"},
+		{OverlayOperation(4), "OverlayOperation(4)"},
+	}
+
+	for _, test := range tests {
+		if s := test.operation.String(); s != test.want {
+			t.Errorf("%d: %q, want %q", int(test.operation), s, test.want)
+		}
+	}
+}
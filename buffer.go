@@ -0,0 +1,228 @@
+package geoutil
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/golang/geo/r3"
+	"github.com/golang/geo/s1"
+	"github.com/golang/geo/s2"
+)
+
+var errBufferTooLarge = errors.New("geoutil: buffer distance must be less than a quarter of the circumference of the Earth")
+
+const (
+	// defaultBufferSegments is the number of segments approximating a
+	// quarter of a circle when no option is given, as in PostGIS.
+	defaultBufferSegments = 8
+
+	// maxBufferSegments bounds the number of segments approximating a
+	// quarter of a circle, which are within a millionth of the distance of
+	// the circle.
+	maxBufferSegments = 1024
+)
+
+// BufferOptions are the options of buffering. Circles are approximated by
+// Segments segments per quarter of a circle or, if Segments is zero, by as
+// many as needed for the approximation to be within Tolerance of the circle,
+// up to 1024 segments. Use AngleFromMeters for a tolerance in meters.
+type BufferOptions struct {
+	Segments  int
+	Tolerance s1.Angle
+}
+
+// buffer approximates the circles and the strips along edges of a buffer.
+type buffer struct {
+	radius s1.Angle
+
+	// step is the angle between the vertices of circles, and maxEdge the
+	// longest edge along which a strip is not split.
+	step    float64
+	maxEdge float64
+}
+
+func newBuffer(radius s1.Angle, options BufferOptions) *buffer {
+	b := &buffer{radius: radius}
+	r := radius.Radians()
+	segments := float64(defaultBufferSegments)
+	if options.Segments > 0 {
+		segments = float64(options.Segments)
+	} else if options.Tolerance > 0 {
+		tolerance := math.Min(options.Tolerance.Radians(), r)
+		segments = math.Ceil(math.Pi / 2 / (2 * math.Acos(1-tolerance/r)))
+	}
+
+	b.step = math.Pi / 2 / math.Min(segments, maxBufferSegments)
+
+	// The sides of a strip bulge away from the edge. They are split so that
+	// they bulge no more than the edges of the circles fall short of them.
+	tolerance := r * (1 - math.Cos(b.step/2))
+	b.maxEdge = math.Pi
+	if r+tolerance < math.Pi/2 {
+		b.maxEdge = 2 * math.Acos(math.Tan(r)/math.Tan(r+tolerance))
+	}
+
+	return b
+}
+
+// offset returns the point at the buffer distance from a point in a
+// direction tangent to the sphere at the point.
+func (b *buffer) offset(point s2.Point, direction r3.Vector) s2.Point {
+	r := b.radius.Radians()
+	return s2.Point{Vector: point.Mul(math.Cos(r)).Add(direction.Mul(math.Sin(r))).Normalize()}
+}
+
+// circle returns the ring of the circle around a point.
+func (b *buffer) circle(center s2.Point) []s2.Point {
+	u := center.Ortho()
+	v := center.Cross(u)
+	n := int(math.Round(2 * math.Pi / b.step))
+	points := make([]s2.Point, n)
+	for i := range points {
+		angle := float64(i) * b.step
+		points[i] = b.offset(center, u.Mul(math.Cos(angle)).Add(v.Mul(math.Sin(angle))))
+	}
+
+	return points
+}
+
+// strip returns the ring of the points within the buffer distance of an
+// edge and closer to its interior than to its endpoints, or nil if the edge
+// is degenerate.
+func (b *buffer) strip(a, c s2.Point) []s2.Point {
+	length := a.Distance(c).Radians()
+	normal := a.PointCross(c).Normalize()
+	if length == 0 || normal.Norm() == 0 {
+		return nil
+	}
+
+	n := int(math.Ceil(length / b.maxEdge))
+	right := make([]s2.Point, 0, n+1)
+	left := make([]s2.Point, 0, n+1)
+	for i := 0; i <= n; i++ {
+		point := s2.Interpolate(float64(i)/float64(n), a, c)
+		right = append(right, b.offset(point, normal.Mul(-1)))
+		left = append(left, b.offset(point, normal))
+	}
+
+	for i, j := 0, len(left)-1; i < j; i, j = i+1, j-1 {
+		left[i], left[j] = left[j], left[i]
+	}
+
+	return append(right, left...)
+}
+
+// pieces returns the inputs of the circles around the vertices of chains
+// and the strips along their edges, whose union is the buffer of the chains.
+// The pieces are in the order of the chains, so that the pieces next to each
+// other are near each other.
+func (b *buffer) pieces(chains []s2.Polyline) []overlayInput {
+	inputs := []overlayInput{}
+	for _, chain := range chains {
+		for i, point := range chain {
+			if i == 0 || point != chain[0] {
+				inputs = append(inputs, ringInput(b.circle(point)))
+			}
+
+			if i == 0 {
+				continue
+			}
+
+			if ring := b.strip(chain[i-1], point); ring != nil {
+				inputs = append(inputs, ringInput(ring))
+			}
+		}
+	}
+
+	return inputs
+}
+
+// Buffer returns the polygon of the points within a distance in meters of a
+// geometry on the sphere of the mean radius of the Earth. A negative
+// distance shrinks polygons by the points within the distance of their
+// boundary, and gives an empty polygon for points and polylines.
+func Buffer(geometry interface{}, meters float64, options BufferOptions) (*s2.Polygon, error) {
+	if math.IsNaN(meters) || math.IsInf(meters, 0) {
+		return nil, fmt.Errorf("geoutil: invalid buffer distance %v", meters)
+	}
+
+	radius := AngleFromMeters(math.Abs(meters))
+	if radius >= math.Pi/2 {
+		return nil, errBufferTooLarge
+	}
+
+	// The points, polylines and boundaries of loops are buffered as chains
+	// of points, loops being closed by their first vertex.
+	chains := []s2.Polyline{}
+	regions := []overlayInput{}
+	addLoop := func(loop *s2.Loop) {
+		if loop.IsEmpty() || loop.IsFull() {
+			return
+		}
+
+		chains = append(chains, append(loop.Vertices(), loop.Vertex(0)))
+	}
+
+	switch geometry := geometry.(type) {
+	case s2.LatLng:
+		chains = append(chains, s2.Polyline{s2.PointFromLatLng(geometry)})
+	case s2.Point:
+		chains = append(chains, s2.Polyline{geometry})
+	case []s2.Point:
+		for _, point := range geometry {
+			chains = append(chains, s2.Polyline{point})
+		}
+
+	case *s2.Polyline:
+		chains = append(chains, *geometry)
+	case []*s2.Polyline:
+		for _, polyline := range geometry {
+			chains = append(chains, *polyline)
+		}
+
+	case *s2.Loop:
+		regions = append(regions, polygonInput(s2.PolygonFromLoops([]*s2.Loop{geometry})))
+		addLoop(geometry)
+	case *s2.Polygon:
+		regions = append(regions, polygonInput(geometry))
+		for _, loop := range geometry.Loops() {
+			addLoop(loop)
+		}
+
+	case s2.CellUnion:
+		for _, id := range geometry {
+			loop := s2.LoopFromCell(s2.CellFromCellID(id))
+			regions = append(regions, polygonInput(s2.PolygonFromLoops([]*s2.Loop{loop})))
+			addLoop(loop)
+		}
+
+	default:
+		return nil, fmt.Errorf("geoutil: unknown geometry type %T", geometry)
+	}
+
+	if meters < 0 && len(regions) == 0 {
+		return s2.PolygonFromLoops(nil), nil
+	}
+
+	// The pieces are united by halves, as a single overlay of all
+	// of them would test every piece in every face.
+	region := unionInputs(regions, defaultSnapRadius)
+	if radius == 0 {
+		return region, nil
+	}
+
+	pieces := unionInputs(newBuffer(radius, options).pieces(chains), defaultSnapRadius)
+	if len(regions) == 0 {
+		return pieces, nil
+	}
+
+	inputs := []overlayInput{polygonInput(region), polygonInput(pieces)}
+	if meters < 0 {
+		return overlay(inputs, defaultSnapRadius, func(inside []bool) bool {
+			return inside[0] && !inside[1]
+		}), nil
+	}
+
+	return overlay(inputs, defaultSnapRadius, anyInside), nil
+}
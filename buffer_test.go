@@ -0,0 +1,186 @@
+package geoutil
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/golang/geo/s2"
+)
+
+// checkBuffer checks that a buffer is valid, that its area is within 1% of
+// an area in square radians, that it contains the points inside and does
+// not contain the points outside.
+func checkBuffer(t *testing.T, name string, polygon *s2.Polygon, area float64, inside, outside []s2.Point) {
+	t.Helper()
+	if problems, err := Validate(polygon); err != nil || len(problems) != 0 {
+		t.Errorf("%s: invalid buffer %v, %v", name, problems, err)
+	}
+
+	if math.Abs(polygon.Area()-area) > 0.01*area {
+		t.Errorf("%s: area %v, want %v", name, polygon.Area(), area)
+	}
+
+	for _, point := range inside {
+		if !polygon.ContainsPoint(point) {
+			t.Errorf("%s: point %v outside of the buffer", name, s2.LatLngFromPoint(point))
+		}
+	}
+
+	for _, point := range outside {
+		if polygon.ContainsPoint(point) {
+			t.Errorf("%s: point %v inside of the buffer", name, s2.LatLngFromPoint(point))
+		}
+	}
+}
+
+func TestBuffer(t *testing.T) {
+	r := AngleFromMeters(1000).Radians()
+	d := AngleFromMeters(1000).Degrees()
+	square, err := PolygonFromPolygonCoordinates([][][]float64{{{0, 0}, {1, 0}, {1, 1}, {0, 1}, {0, 0}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	length := math.Pi / 180
+	perimeter := 0.0
+	for i := 0; i < square.Loop(0).NumEdges(); i++ {
+		edge := square.Loop(0).Edge(i)
+		perimeter += edge.V0.Distance(edge.V1).Radians()
+	}
+
+	tests := []struct {
+		name            string
+		geometry        interface{}
+		meters          float64
+		area            float64
+		inside, outside []s2.Point
+	}{
+		{
+			name:     "point",
+			geometry: pointFromDegrees(0, 0),
+			meters:   1000,
+			area:     math.Pi * r * r,
+			inside:   []s2.Point{pointFromDegrees(0, 0), pointFromDegrees(0.99*d, 0), pointFromDegrees(0, -0.99*d)},
+			outside:  []s2.Point{pointFromDegrees(1.01*d, 0), pointFromDegrees(0, -1.01*d)},
+		},
+		{
+			name:     "polyline",
+			geometry: polylinesFromDegrees([]float64{0, 0, 0, 1})[0],
+			meters:   1000,
+			area:     2*r*length + math.Pi*r*r,
+			inside:   []s2.Point{pointFromDegrees(0.99*d, 0.5), pointFromDegrees(-0.99*d, 0.5), pointFromDegrees(0, 1+0.99*d)},
+			outside:  []s2.Point{pointFromDegrees(1.01*d, 0.5), pointFromDegrees(-1.01*d, 0.5), pointFromDegrees(0, 1+1.01*d)},
+		},
+		{
+			name:     "polygon",
+			geometry: square,
+			meters:   1000,
+			area:     square.Area() + perimeter*r + math.Pi*r*r,
+			inside:   []s2.Point{pointFromDegrees(0.5, 0.5), pointFromDegrees(0.5, -0.99*d), pointFromDegrees(1+0.99*d, 0.5)},
+			outside:  []s2.Point{pointFromDegrees(0.5, -1.01*d), pointFromDegrees(1+1.01*d, 0.5)},
+		},
+
+		// A negative distance shrinks polygons by the distance, leaving
+		// square corners.
+		{
+			name:     "negative polygon",
+			geometry: square,
+			meters:   -1000,
+			area:     square.Area() - perimeter*r + 4*r*r,
+			inside:   []s2.Point{pointFromDegrees(0.5, 0.5), pointFromDegrees(0.5, 1.01*d), pointFromDegrees(1.01*d, 1.01*d)},
+			outside:  []s2.Point{pointFromDegrees(0.5, 0.99*d), pointFromDegrees(0.99*d, 0.5), pointFromDegrees(0.5, 1.5)},
+		},
+		{
+			name:     "zero distance",
+			geometry: square.Loop(0),
+			meters:   0,
+			area:     square.Area(),
+			inside:   []s2.Point{pointFromDegrees(0.5, 0.5)},
+			outside:  []s2.Point{pointFromDegrees(0.5, -0.01)},
+		},
+	}
+
+	for _, test := range tests {
+		polygon, err := Buffer(test.geometry, test.meters, BufferOptions{Segments: 32})
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+
+		checkBuffer(t, test.name, polygon, test.area, test.inside, test.outside)
+	}
+
+	polygon, err := Buffer(polylinesFromDegrees([]float64{0, 0, 0, 1}), -1000, BufferOptions{})
+	if err != nil || !polygon.IsEmpty() {
+		t.Errorf("negative polyline: buffer %v, %v", polygon, err)
+	}
+
+	if _, err := Buffer(pointFromDegrees(0, 0), 2e7, BufferOptions{}); err != errBufferTooLarge {
+		t.Errorf("large distance: error %v, want %v", err, errBufferTooLarge)
+	}
+
+	for _, meters := range []float64{math.NaN(), math.Inf(1), math.Inf(-1)} {
+		if _, err := Buffer(pointFromDegrees(0, 0), meters, BufferOptions{}); err == nil {
+			t.Errorf("distance %v: no error", meters)
+		}
+	}
+
+	// Tolerances too small for the number of segments are bounded.
+	polygon, err = Buffer(pointFromDegrees(0, 0), 1000, BufferOptions{Tolerance: 1e-16})
+	if err != nil {
+		t.Errorf("small tolerance: %v", err)
+	} else if polygon.NumLoops() != 1 || polygon.NumEdges() != 4*maxBufferSegments {
+		t.Errorf("small tolerance: %d loops of %d edges, want 1 of %d", polygon.NumLoops(), polygon.NumEdges(), 4*maxBufferSegments)
+	}
+
+	if _, err := Buffer(1, 1000, BufferOptions{}); err == nil {
+		t.Errorf("unknown geometry: no error")
+	}
+}
+
+func TestBufferManyPieces(t *testing.T) {
+
+	// A zigzag whose pieces overlap their neighbors, and a ring of points
+	// whose circles overlap the circles of the next points.
+	zigzag := make(s2.Polyline, 500)
+	for i := range zigzag {
+		zigzag[i] = pointFromDegrees(float64(i%2)*0.01, float64(i)*0.01)
+	}
+
+	points := make([]s2.Point, 200)
+	for i := range points {
+		angle := 2 * math.Pi * float64(i) / float64(len(points))
+		points[i] = pointFromDegrees(0.01*math.Sin(angle), 0.01*math.Cos(angle))
+	}
+
+	tests := []struct {
+		name     string
+		geometry interface{}
+		loops    int
+	}{
+		{"zigzag", &zigzag, 1},
+		{"ring of points", points, 2},
+	}
+
+	for _, test := range tests {
+		start := time.Now()
+		polygon, err := Buffer(test.geometry, 500, BufferOptions{})
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+
+		if elapsed := time.Since(start); elapsed > 10*time.Second {
+			t.Errorf("%s: buffer found in %v", test.name, elapsed)
+		}
+
+		if problems, err := Validate(polygon); err != nil || len(problems) != 0 {
+			t.Errorf("%s: invalid buffer %v, %v", test.name, problems, err)
+		}
+
+		if polygon.NumLoops() != test.loops {
+			t.Errorf("%s: %d loops, want %d", test.name, polygon.NumLoops(), test.loops)
+		}
+	}
+}
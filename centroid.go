@@ -0,0 +1,319 @@
+package geoutil
+
+import (
+	"container/heap"
+	"errors"
+	"fmt"
+
+	"github.com/golang/geo/s1"
+	"github.com/golang/geo/s2"
+)
+
+var errEmptyGeometry = errors.New("geoutil: empty geometry has no representative point")
+
+// centroidSum returns the sum of the centroids of the parts of a geometry,
+// each scaled by its area, length or count, and the dimension of the
+// parts. Parts of lower dimension are ignored when a geometry has parts of a
+// higher dimension, as their weight is zero.
+func centroidSum(geometry interface{}) (s2.Point, error) {
+	switch geometry := geometry.(type) {
+	case s2.LatLng:
+		return s2.PointFromLatLng(geometry), nil
+	case s2.Point:
+		return geometry, nil
+	case []s2.Point:
+		sum := s2.Point{}
+		for _, point := range geometry {
+			sum.Vector = sum.Add(point.Vector)
+		}
+
+		return sum, nil
+	case *s2.Polyline:
+		return centroidSum([]*s2.Polyline{geometry})
+	case []*s2.Polyline:
+		sum := s2.Point{}
+		for _, polyline := range geometry {
+			sum.Vector = sum.Add(polyline.Centroid().Vector)
+		}
+
+		// Polylines of no length are weighted by their vertices.
+		if sum.Norm() == 0 {
+			for _, polyline := range geometry {
+				sum.Vector = sum.Add(polylineVertexSum(polyline).Vector)
+			}
+		}
+
+		return sum, nil
+	case *s2.Loop:
+		return geometry.Centroid(), nil
+	case *s2.Polygon:
+		sum := s2.Point{}
+		for _, loop := range geometry.Loops() {
+			if loop.IsHole() {
+				sum.Vector = sum.Sub(loop.Centroid().Vector)
+			} else {
+				sum.Vector = sum.Add(loop.Centroid().Vector)
+			}
+		}
+
+		return sum, nil
+	case s2.CellUnion:
+		sum := s2.Point{}
+		for _, id := range geometry {
+			sum.Vector = sum.Add(s2.LoopFromCell(s2.CellFromCellID(id)).Centroid().Vector)
+		}
+
+		return sum, nil
+	default:
+		return s2.Point{}, fmt.Errorf("geoutil: unknown geometry type %T", geometry)
+	}
+}
+
+func polylineVertexSum(polyline *s2.Polyline) s2.Point {
+	sum := s2.Point{}
+	for _, point := range *polyline {
+		sum.Vector = sum.Add(point.Vector)
+	}
+
+	return sum
+}
+
+// Centroid returns the centroid of a geometry on the sphere: the mean of
+// points, the centroid of lines weighted by length, or the centroid of areas
+// weighted by area. The centroid of a concave polygon may lie outside of it.
+func Centroid(geometry interface{}) (s2.Point, error) {
+	sum, err := centroidSum(geometry)
+	if err != nil {
+		return s2.Point{}, err
+	}
+
+	if sum.Norm() == 0 {
+		return s2.Point{}, errEmptyGeometry
+	}
+
+	return s2.Point{Vector: sum.Normalize()}, nil
+}
+
+// nearestPoint returns the point nearest to a target.
+func nearestPoint(points []s2.Point, target s2.Point) s2.Point {
+	nearest := points[0]
+	for _, point := range points[1:] {
+		if point.Distance(target) < nearest.Distance(target) {
+			nearest = point
+		}
+	}
+
+	return nearest
+}
+
+// coveringLevel returns the finest level of the cells covering a polygon,
+// whose cells are no smaller than a thousandth of the polygon so that the
+// cells along thin polygons are not subdivided down to leaf cells.
+func coveringLevel(polygon *s2.Polygon) int {
+	return s2.MinWidthMetric.MaxLevel(polygon.CapBound().Radius().Radians() / 1000)
+}
+
+// polygonPointOnSurface returns a point inside a polygon: its centroid if
+// it is inside, else the center of the largest cell inside the polygon, else
+// a point just left of the middle of one of its edges.
+func polygonPointOnSurface(polygon *s2.Polygon) (s2.Point, error) {
+	if polygon.IsFull() {
+		return s2.OriginPoint(), nil
+	}
+
+	if centroid, err := Centroid(polygon); err == nil && polygon.ContainsPoint(centroid) {
+		return centroid, nil
+	}
+
+	coverer := &s2.RegionCoverer{MaxLevel: coveringLevel(polygon), MaxCells: 8}
+	if cells := coverer.InteriorCovering(polygon); len(cells) > 0 {
+		largest := cells[0]
+		for _, id := range cells[1:] {
+			if id.Level() < largest.Level() {
+				largest = id
+			}
+		}
+
+		return largest.Point(), nil
+	}
+
+	for _, loop := range polygon.Loops() {
+		for i := 0; i < loop.NumVertices(); i++ {
+			a, b := loop.OrientedVertex(i), loop.OrientedVertex(i+1)
+			middle := a.Add(b.Vector).Normalize()
+			left := a.Cross(b.Vector).Normalize()
+			for offset := a.Distance(b).Radians() / 4; offset > 1e-15; offset /= 16 {
+				point := s2.Point{Vector: middle.Add(left.Mul(offset)).Normalize()}
+				if polygon.ContainsPoint(point) {
+					return point, nil
+				}
+			}
+		}
+	}
+
+	return s2.Point{}, errEmptyGeometry
+}
+
+// PointOnSurface returns a point guaranteed to lie on a geometry: the point
+// nearest to the centroid of points, the vertex nearest to the centroid of
+// lines, and a point inside polygons, preferably their centroid.
+func PointOnSurface(geometry interface{}) (s2.Point, error) {
+	switch geometry := geometry.(type) {
+	case s2.LatLng:
+		return s2.PointFromLatLng(geometry), nil
+	case s2.Point:
+		return geometry, nil
+	case []s2.Point:
+		centroid, err := Centroid(geometry)
+		if err != nil {
+			return s2.Point{}, err
+		}
+
+		return nearestPoint(geometry, centroid), nil
+	case *s2.Polyline:
+		return PointOnSurface([]*s2.Polyline{geometry})
+	case []*s2.Polyline:
+		centroid, err := Centroid(geometry)
+		if err != nil {
+			return s2.Point{}, err
+		}
+
+		vertices := []s2.Point{}
+		for _, polyline := range geometry {
+			vertices = append(vertices, *polyline...)
+		}
+
+		return nearestPoint(vertices, centroid), nil
+	case *s2.Loop:
+		return polygonPointOnSurface(s2.PolygonFromLoops([]*s2.Loop{geometry}))
+	case *s2.Polygon:
+		return polygonPointOnSurface(geometry)
+	case s2.CellUnion:
+		centroid, err := Centroid(geometry)
+		if err != nil {
+			return s2.Point{}, err
+		}
+
+		centers := make([]s2.Point, len(geometry))
+		for i, id := range geometry {
+			centers[i] = id.Point()
+		}
+
+		return nearestPoint(centers, centroid), nil
+	default:
+		return s2.Point{}, fmt.Errorf("geoutil: unknown geometry type %T", geometry)
+	}
+}
+
+// labelCell is a cell searched for the pole of inaccessibility, with the
+// signed distance from its center to the boundary of the polygon and the
+// largest distance of any of its points.
+type labelCell struct {
+	cell     s2.Cell
+	center   s2.Point
+	distance s1.Angle
+	max      s1.Angle
+}
+
+type labelCellHeap []*labelCell
+
+func (h labelCellHeap) Len() int            { return len(h) }
+func (h labelCellHeap) Less(i, j int) bool  { return h[i].max > h[j].max }
+func (h labelCellHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *labelCellHeap) Push(x interface{}) { *h = append(*h, x.(*labelCell)) }
+func (h *labelCellHeap) Pop() interface{} {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
+
+// PoleOfInaccessibility returns the point inside a polygon farthest from its
+// boundary, the best place for a label, and its distance to the boundary.
+// The point is found by subdividing cells like the polylabel algorithm, to
+// within precision of the best distance.
+func PoleOfInaccessibility(polygon *s2.Polygon, precision s1.Angle) (s2.Point, s1.Angle, error) {
+	if polygon.IsEmpty() || polygon.IsFull() {
+		return s2.Point{}, 0, errEmptyGeometry
+	}
+
+	edges := []s2.Edge{}
+	for _, loop := range polygon.Loops() {
+		for i := 0; i < loop.NumEdges(); i++ {
+			edges = append(edges, loop.Edge(i))
+		}
+	}
+
+	// The distance is negative outside of the polygon.
+	distance := func(point s2.Point) s1.Angle {
+		d := s1.InfAngle()
+		for _, edge := range edges {
+			if e := s2.DistanceFromSegment(point, edge.V0, edge.V1); e < d {
+				d = e
+			}
+		}
+
+		if !polygon.ContainsPoint(point) {
+			return -d
+		}
+
+		return d
+	}
+
+	newCell := func(cell s2.Cell) *labelCell {
+		c := &labelCell{
+			cell:   cell,
+			center: cell.Center(),
+		}
+
+		c.distance = distance(c.center)
+		radius := s1.Angle(0)
+		for k := 0; k < 4; k++ {
+			if r := c.center.Distance(cell.Vertex(k)); r > radius {
+				radius = r
+			}
+		}
+
+		c.max = c.distance + radius
+		return c
+	}
+
+	point, err := polygonPointOnSurface(polygon)
+	if err != nil {
+		return s2.Point{}, 0, err
+	}
+
+	best := &labelCell{center: point, distance: distance(point)}
+	h := &labelCellHeap{}
+	coverer := &s2.RegionCoverer{MaxLevel: coveringLevel(polygon), MaxCells: 16}
+	for _, id := range coverer.Covering(polygon) {
+		*h = append(*h, newCell(s2.CellFromCellID(id)))
+	}
+
+	heap.Init(h)
+	for h.Len() > 0 {
+		c := heap.Pop(h).(*labelCell)
+		if c.distance > best.distance {
+			best = c
+		}
+
+		// No remaining cell can improve the best distance by more than the
+		// precision.
+		if c.max-best.distance <= precision {
+			break
+		}
+
+		if c.cell.IsLeaf() {
+			continue
+		}
+
+		children, _ := c.cell.Children()
+		for _, child := range children {
+			if child := newCell(child); child.max-best.distance > precision {
+				heap.Push(h, child)
+			}
+		}
+	}
+
+	return best.center, best.distance, nil
+}
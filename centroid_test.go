@@ -0,0 +1,180 @@
+package geoutil
+
+import (
+	"math"
+	"testing"
+
+	"github.com/golang/geo/s1"
+	"github.com/golang/geo/s2"
+)
+
+var (
+	// centeredSquare is centered on the intersection of the equator and the
+	// prime meridian.
+	centeredSquare = [][]float64{{-10, -10}, {10, -10}, {10, 10}, {-10, 10}, {-10, -10}}
+
+	// hook is a concave polygon whose centroid lies outside of it.
+	hook = [][]float64{{0, 0}, {10, 0}, {10, 2}, {2, 2}, {2, 8}, {10, 8}, {10, 10}, {0, 10}, {0, 0}}
+)
+
+func TestCentroid(t *testing.T) {
+	polygon, err := PolygonFromPolygonCoordinates([][][]float64{centeredSquare})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The hole is east of the center, which moves the centroid west.
+	holed, err := PolygonFromPolygonCoordinates([][][]float64{centeredSquare, {{2, -2}, {6, -2}, {6, 2}, {2, 2}, {2, -2}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		geometry interface{}
+		lat, lng float64
+	}{
+		{"latlng", s2.LatLngFromDegrees(12, 34), 12, 34},
+		{"point", pointFromDegrees(12, 34), 12, 34},
+		{"points", []s2.Point{pointFromDegrees(0, 0), pointFromDegrees(0, 90)}, 0, 45},
+		{"polyline", polylinesFromDegrees([]float64{0, 0, 0, 10, 0, 20})[0], 0, 10},
+
+		// Lines of the same length weigh the same, whatever their number
+		// of vertices.
+		{"polylines", polylinesFromDegrees([]float64{0, -20, 0, -15, 0, -10}, []float64{0, 5, 0, 15}), 0, -2.5},
+
+		// Lines of no length are weighted by their vertices.
+		{"points as polylines", polylinesFromDegrees([]float64{0, 0, 0, 0}, []float64{0, 90}), 0, math.Atan(0.5) * 180 / math.Pi},
+		{"polygon", polygon, 0, 0},
+		{"loop", polygon.Loop(0), 0, 0},
+		{"cell union", s2.CellUnion{s2.CellIDFromFace(0)}, 0, 0},
+	}
+
+	for _, test := range tests {
+		centroid, err := Centroid(test.geometry)
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+
+		if d := centroid.Distance(pointFromDegrees(test.lat, test.lng)).Degrees(); d > 1e-9 {
+			t.Errorf("%s: centroid %v, want %v, %v", test.name, s2.LatLngFromPoint(centroid), test.lat, test.lng)
+		}
+	}
+
+	centroid, err := Centroid(holed)
+	if latLng := s2.LatLngFromPoint(centroid); err != nil || math.Abs(latLng.Lat.Degrees()) > 1e-9 || latLng.Lng.Degrees() >= -0.1 {
+		t.Errorf("polygon with a hole: centroid %v, %v", latLng, err)
+	}
+
+	for _, geometry := range []interface{}{[]s2.Point{}, []*s2.Polyline{}, s2.EmptyLoop(), s2.CellUnion{}} {
+		if _, err := Centroid(geometry); err != errEmptyGeometry {
+			t.Errorf("%T: error %v, want %v", geometry, err, errEmptyGeometry)
+		}
+	}
+
+	if _, err := Centroid(1); err == nil {
+		t.Errorf("unknown geometry: no error")
+	}
+}
+
+func TestPointOnSurface(t *testing.T) {
+	polygon, err := PolygonFromPolygonCoordinates([][][]float64{hook})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if centroid, err := Centroid(polygon); err != nil || polygon.ContainsPoint(centroid) {
+		t.Fatalf("centroid %v of the hook is inside of it, %v", s2.LatLngFromPoint(centroid), err)
+	}
+
+	point, err := PointOnSurface(polygon)
+	if err != nil || !polygon.ContainsPoint(point) {
+		t.Errorf("polygon: point %v outside of the polygon, %v", s2.LatLngFromPoint(point), err)
+	}
+
+	point, err = PointOnSurface(polygon.Loop(0))
+	if err != nil || !polygon.ContainsPoint(point) {
+		t.Errorf("loop: point %v outside of the loop, %v", s2.LatLngFromPoint(point), err)
+	}
+
+	// A polygon too thin for any cell of its interior covering has a point
+	// next to one of its edges, found without covering its whole length
+	// with leaf cells.
+	thin, err := PolygonFromPolygonCoordinates([][][]float64{{{0, 0}, {10, 0}, {10, 1e-9}, {0, 0}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	point, err = PointOnSurface(thin)
+	if err != nil || !thin.ContainsPoint(point) {
+		t.Errorf("thin polygon: point %v outside of the polygon, %v", s2.LatLngFromPoint(point), err)
+	}
+
+	// Points and lines are represented by their point and vertex nearest
+	// to their centroid.
+	tests := []struct {
+		name     string
+		geometry interface{}
+		want     s2.Point
+	}{
+		{"points", []s2.Point{pointFromDegrees(0, 0), pointFromDegrees(0, 4), pointFromDegrees(0, 10)}, pointFromDegrees(0, 4)},
+		{"polyline", polylinesFromDegrees([]float64{0, 0, 0, 3, 0, 10})[0], pointFromDegrees(0, 3)},
+		{"cell union", s2.CellUnion{s2.CellIDFromFace(0).ChildBeginAtLevel(2)}, s2.CellIDFromFace(0).ChildBeginAtLevel(2).Point()},
+	}
+
+	for _, test := range tests {
+		point, err := PointOnSurface(test.geometry)
+		if err != nil || point != test.want {
+			t.Errorf("%s: point %v, want %v, %v", test.name, s2.LatLngFromPoint(point), s2.LatLngFromPoint(test.want), err)
+		}
+	}
+
+	if _, err := PointOnSurface([]s2.Point{}); err != errEmptyGeometry {
+		t.Errorf("no points: error %v, want %v", err, errEmptyGeometry)
+	}
+}
+
+func TestPoleOfInaccessibility(t *testing.T) {
+	polygon, err := PolygonFromPolygonCoordinates([][][]float64{centeredSquare})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The center of the square is 10 degrees away from its meridian edges,
+	// and farther away from the others, which bulge towards the poles.
+	precision := s1.Angle(1e-6)
+	point, distance, err := PoleOfInaccessibility(polygon, precision)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if d := point.Distance(pointFromDegrees(0, 0)).Degrees(); d > 0.5 {
+		t.Errorf("square: pole %v, %v degrees from the center", s2.LatLngFromPoint(point), d)
+	}
+
+	if want := 10 * s1.Degree; distance > want || distance < want-precision {
+		t.Errorf("square: distance %v, want %v", distance.Degrees(), want.Degrees())
+	}
+
+	// The pole of the hook is in one of its western corners, as far from
+	// its two outer edges as from its inner corner, about 2√2 / (1 + √2)
+	// degrees away.
+	hookPolygon, err := PolygonFromPolygonCoordinates([][][]float64{hook})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	point, distance, err = PoleOfInaccessibility(hookPolygon, precision)
+	if err != nil || !hookPolygon.ContainsPoint(point) {
+		t.Fatalf("hook: pole %v outside of the polygon, %v", s2.LatLngFromPoint(point), err)
+	}
+
+	if want := 2 * math.Sqrt2 / (1 + math.Sqrt2); math.Abs(distance.Degrees()-want) > 0.01*want {
+		t.Errorf("hook: distance %v, want %v", distance.Degrees(), want)
+	}
+
+	if _, _, err := PoleOfInaccessibility(s2.PolygonFromLoops(nil), precision); err != errEmptyGeometry {
+		t.Errorf("empty polygon: error %v, want %v", err, errEmptyGeometry)
+	}
+}
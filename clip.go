@@ -0,0 +1,180 @@
+package geoutil
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/golang/geo/s1"
+	"github.com/golang/geo/s2"
+)
+
+// rectParallelTolerance is the largest distance between the parallels
+// bounding a rectangle and the geodesic edges approximating them, about 6cm
+// on the Earth.
+const rectParallelTolerance = 1e-8
+
+// rectMaxStep is the longest edge approximating a parallel or a meridian.
+const rectMaxStep = 10 * s1.Degree
+
+// parallel returns the points of a parallel from a longitude eastward for a
+// length, ending at the last point, spaced so that the geodesic edges
+// between them stay within rectParallelTolerance of the parallel.
+func parallel(lat, lng, length s1.Angle) []s2.Point {
+	step := rectMaxStep.Radians()
+	phi := math.Abs(lat.Radians())
+	if phi > 0 && phi+rectParallelTolerance < math.Pi/2 {
+		step = math.Min(step, 2*math.Acos(math.Tan(phi)/math.Tan(phi+rectParallelTolerance)))
+	}
+
+	n := int(math.Ceil(length.Radians() / step))
+	if n < 1 {
+		n = 1
+	}
+
+	points := make([]s2.Point, n+1)
+	for i := range points {
+		l := lng + length*s1.Angle(i)/s1.Angle(n)
+		points[i] = s2.PointFromLatLng(s2.LatLng{Lat: lat, Lng: l}.Normalized())
+	}
+
+	return points
+}
+
+// meridian returns the points of a meridian from a latitude to another,
+// excluding the last point, no farther apart than rectMaxStep.
+func meridian(lng, from, to s1.Angle) []s2.Point {
+	n := int(math.Ceil(math.Abs((to - from).Radians()) / rectMaxStep.Radians()))
+	points := make([]s2.Point, 0, n)
+	for i := 0; i < n; i++ {
+		lat := from + (to-from)*s1.Angle(i)/s1.Angle(n)
+		points = append(points, s2.PointFromLatLng(s2.LatLng{Lat: lat, Lng: lng}))
+	}
+
+	return points
+}
+
+// rectInput returns the input of the region of a rectangle that is neither
+// empty nor full, with its interior on the left of its rings.
+func rectInput(rect s2.Rect) overlayInput {
+	south, north := s1.Angle(rect.Lat.Lo), s1.Angle(rect.Lat.Hi)
+	west, east := s1.Angle(rect.Lng.Lo), s1.Angle(rect.Lng.Hi)
+	rings := [][]s2.Point{}
+	if rect.Lng.IsFull() {
+		// The rectangle is a band between parallels, or a cap.
+		if south > -math.Pi/2 {
+			ring := parallel(south, -math.Pi, 2*math.Pi)
+			rings = append(rings, ring[:len(ring)-1])
+		}
+
+		if north < math.Pi/2 {
+			ring := parallel(north, -math.Pi, 2*math.Pi)
+			ring = ring[:len(ring)-1]
+			for i, j := 0, len(ring)-1; i < j; i, j = i+1, j-1 {
+				ring[i], ring[j] = ring[j], ring[i]
+			}
+
+			rings = append(rings, ring)
+		}
+	} else {
+		// The boundary runs east along the southern parallel, north along
+		// the eastern meridian, west along the northern parallel and south
+		// along the western meridian. Parallels at the poles are points.
+		width := s1.Angle(rect.Lng.Length())
+		ring := []s2.Point{}
+		if south > -math.Pi/2 {
+			southern := parallel(south, west, width)
+			ring = append(ring, southern[:len(southern)-1]...)
+		}
+
+		ring = append(ring, meridian(east, south, north)...)
+		if north < math.Pi/2 {
+			northern := parallel(north, west, width)
+			for i := len(northern) - 1; i > 0; i-- {
+				ring = append(ring, northern[i])
+			}
+		}
+
+		ring = append(ring, meridian(west, north, south)...)
+		rings = append(rings, ring)
+	}
+
+	loops := make([]*s2.Loop, len(rings))
+	input := overlayInput{rings: rings}
+	for i, ring := range rings {
+		loops[i] = s2.LoopFromPoints(ring)
+	}
+
+	input.contains = func(point s2.Point) bool {
+		for _, loop := range loops {
+			if !loop.ContainsPoint(point) {
+				return false
+			}
+		}
+
+		return true
+	}
+
+	return input
+}
+
+// ClipToRect returns the parts of a geometry inside a rectangle, which may
+// cross the antimeridian. Points are returned as []s2.Point, polylines as
+// []*s2.Polyline and polygons as *s2.Polygon, keeping their holes and
+// parts. Parallels bounding the rectangle are approximated by geodesic
+// edges to within about 6cm.
+func ClipToRect(geometry interface{}, rect s2.Rect) (interface{}, error) {
+	switch geometry := geometry.(type) {
+	case s2.LatLng:
+		return ClipToRect(s2.PointFromLatLng(geometry), rect)
+	case s2.Point:
+		return ClipToRect([]s2.Point{geometry}, rect)
+	case []s2.Point:
+		clipped := []s2.Point{}
+		for _, point := range geometry {
+			if rect.ContainsPoint(point) {
+				clipped = append(clipped, point)
+			}
+		}
+
+		return clipped, nil
+	case *s2.Polyline:
+		return ClipToRect([]*s2.Polyline{geometry}, rect)
+	case []*s2.Polyline:
+		// Polylines inside the rectangle are kept as they are, and the
+		// input of the rectangle is built for the first polyline crossing
+		// its boundary.
+		var input *overlayInput
+		clipped := []*s2.Polyline{}
+		for _, polyline := range geometry {
+			bound := polyline.RectBound()
+			switch {
+			case rect.Contains(bound):
+				clipped = append(clipped, polyline)
+			case rect.Intersects(bound):
+				if input == nil {
+					r := rectInput(rect)
+					input = &r
+				}
+
+				clipped = append(clipped, clipPolylines([]*s2.Polyline{polyline}, *input, true)...)
+			}
+		}
+
+		return clipped, nil
+	case *s2.Polygon:
+		bound := geometry.RectBound()
+		switch {
+		case rect.Contains(bound):
+			return geometry, nil
+		case !rect.Intersects(bound):
+			return s2.PolygonFromLoops(nil), nil
+		}
+
+		inputs := []overlayInput{polygonInput(geometry), rectInput(rect)}
+		return overlay(inputs, defaultSnapRadius, func(inside []bool) bool {
+			return inside[0] && inside[1]
+		}), nil
+	default:
+		return nil, fmt.Errorf("geoutil: unknown geometry type %T", geometry)
+	}
+}
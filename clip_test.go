@@ -0,0 +1,193 @@
+package geoutil
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/golang/geo/s2"
+)
+
+// rectFromDegrees returns the rectangle between latitudes and from a western
+// to an eastern longitude, which may cross the antimeridian.
+func rectFromDegrees(south, west, north, east float64) s2.Rect {
+	rect := s2.RectFromLatLng(s2.LatLngFromDegrees(south, west))
+	rect.Lat.Hi = s2.LatLngFromDegrees(north, 0).Lat.Radians()
+	rect.Lng.Hi = s2.LatLngFromDegrees(0, east).Lng.Radians()
+	return rect
+}
+
+func TestClipPointsToRect(t *testing.T) {
+	points := []s2.Point{pointFromDegrees(5, 5), pointFromDegrees(5, 15), pointFromDegrees(5, 175), pointFromDegrees(5, -175)}
+	tests := []struct {
+		name     string
+		geometry interface{}
+		rect     s2.Rect
+		want     []s2.Point
+	}{
+		{"points", points, rectFromDegrees(0, 0, 10, 10), points[:1]},
+		{"across the antimeridian", points, rectFromDegrees(0, 170, 10, -170), points[2:]},
+		{"point", points[1], rectFromDegrees(0, 0, 10, 10), []s2.Point{}},
+		{"latlng", s2.LatLngFromDegrees(5, 5), rectFromDegrees(0, 0, 10, 10), points[:1]},
+	}
+
+	for _, test := range tests {
+		clipped, err := ClipToRect(test.geometry, test.rect)
+		if err != nil || !reflect.DeepEqual(clipped, test.want) {
+			t.Errorf("%s: clipped %v, want %v, %v", test.name, clipped, test.want, err)
+		}
+	}
+}
+
+func TestClipPolylinesToRect(t *testing.T) {
+	inside := polylinesFromDegrees([]float64{2, 2, 8, 8})[0]
+	tests := []struct {
+		name      string
+		polylines []*s2.Polyline
+		rect      s2.Rect
+		lengths   []float64
+	}{
+		{
+			name:      "across the rectangle",
+			polylines: polylinesFromDegrees([]float64{5, -10, 5, 20}),
+			rect:      rectFromDegrees(0, 0, 10, 10),
+			lengths:   []float64{10},
+		},
+		{
+			name:      "in and out of the rectangle",
+			polylines: polylinesFromDegrees([]float64{5, -5, 5, 5, 15, 5, 15, 8, 5, 8, 5, 15}),
+			rect:      rectFromDegrees(0, 0, 10, 10),
+			lengths:   []float64{10, 7},
+		},
+		{
+			name:      "across the antimeridian",
+			polylines: polylinesFromDegrees([]float64{0, 160, 0, -160}),
+			rect:      rectFromDegrees(-10, 170, 10, -170),
+			lengths:   []float64{20},
+		},
+		{
+			name:      "into a polar cap",
+			polylines: polylinesFromDegrees([]float64{70, 0, 89, 0}),
+			rect:      rectFromDegrees(80, -180, 90, 180),
+			lengths:   []float64{9},
+		},
+		{
+			name:      "outside of the rectangle",
+			polylines: polylinesFromDegrees([]float64{20, 0, 20, 10}),
+			rect:      rectFromDegrees(0, 0, 10, 10),
+			lengths:   []float64{},
+		},
+	}
+
+	for _, test := range tests {
+		clipped, err := ClipToRect(test.polylines, test.rect)
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+
+		checkPolylines(t, test.name, clipped.([]*s2.Polyline), test.lengths...)
+	}
+
+	// Polylines inside of the rectangle are kept as they are.
+	clipped, err := ClipToRect(inside, rectFromDegrees(0, 0, 10, 10))
+	if polylines, ok := clipped.([]*s2.Polyline); err != nil || !ok || len(polylines) != 1 || polylines[0] != inside {
+		t.Errorf("inside: clipped %v, %v", clipped, err)
+	}
+}
+
+func TestClipPolygonsToRect(t *testing.T) {
+	polygon, err := PolygonFromPolygonCoordinates([][][]float64{square, squareHole})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The area of a rectangle is the area between its parallels, bounded by
+	// geodesics in the clipped polygons and so only about equal to it.
+	rectArea := func(rect s2.Rect) float64 {
+		return rect.Lng.Length() * (math.Sin(rect.Lat.Hi) - math.Sin(rect.Lat.Lo))
+	}
+
+	tests := []struct {
+		name    string
+		rect    s2.Rect
+		area    float64
+		loops   int
+		inside  []s2.Point
+		outside []s2.Point
+	}{
+		{
+			name:    "corner",
+			rect:    rectFromDegrees(-5, -5, 3, 3),
+			area:    rectArea(rectFromDegrees(0, 0, 3, 3)),
+			loops:   1,
+			inside:  []s2.Point{pointFromDegrees(2, 2)},
+			outside: []s2.Point{pointFromDegrees(-2, 2), pointFromDegrees(4, 2)},
+		},
+
+		// The hole is kept inside of the rectangle.
+		{
+			name:    "band around the hole",
+			rect:    rectFromDegrees(3, -5, 7, 15),
+			area:    rectArea(rectFromDegrees(3, 0, 7, 10)) - rectArea(rectFromDegrees(4, 4, 6, 6)),
+			loops:   2,
+			inside:  []s2.Point{pointFromDegrees(5, 2), pointFromDegrees(5, 8)},
+			outside: []s2.Point{pointFromDegrees(5, 5), pointFromDegrees(2, 2), pointFromDegrees(8, 8)},
+		},
+		{
+			name:    "band of all longitudes",
+			rect:    rectFromDegrees(-5, -180, 2, 180),
+			area:    rectArea(rectFromDegrees(0, 0, 2, 10)),
+			loops:   1,
+			inside:  []s2.Point{pointFromDegrees(1, 5)},
+			outside: []s2.Point{pointFromDegrees(3, 5)},
+		},
+	}
+
+	for _, test := range tests {
+		clipped, err := ClipToRect(polygon, test.rect)
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+
+		result := clipped.(*s2.Polygon)
+		if problems, err := Validate(result); err != nil || len(problems) != 0 {
+			t.Errorf("%s: invalid result %v, %v", test.name, problems, err)
+		}
+
+		if result.NumLoops() != test.loops {
+			t.Errorf("%s: %d loops, want %d", test.name, result.NumLoops(), test.loops)
+		}
+
+		if math.Abs(result.Area()-test.area) > 0.01*test.area {
+			t.Errorf("%s: area %v, want %v", test.name, result.Area(), test.area)
+		}
+
+		for _, point := range test.inside {
+			if !result.ContainsPoint(point) {
+				t.Errorf("%s: point %v outside of the result", test.name, s2.LatLngFromPoint(point))
+			}
+		}
+
+		for _, point := range test.outside {
+			if result.ContainsPoint(point) {
+				t.Errorf("%s: point %v inside of the result", test.name, s2.LatLngFromPoint(point))
+			}
+		}
+	}
+
+	clipped, err := ClipToRect(polygon, rectFromDegrees(-20, -20, 20, 20))
+	if clipped != polygon || err != nil {
+		t.Errorf("polygon inside of the rectangle: clipped %v, %v", clipped, err)
+	}
+
+	clipped, err = ClipToRect(polygon, rectFromDegrees(20, 20, 30, 30))
+	if result, ok := clipped.(*s2.Polygon); err != nil || !ok || !result.IsEmpty() {
+		t.Errorf("polygon outside of the rectangle: clipped %v, %v", clipped, err)
+	}
+
+	if _, err := ClipToRect(1, rectFromDegrees(0, 0, 10, 10)); err == nil {
+		t.Errorf("unknown geometry: no error")
+	}
+}
@@ -0,0 +1,166 @@
+package geoutil
+
+import (
+	"fmt"
+
+	"github.com/golang/geo/s2"
+)
+
+// regionUnion is the union of the regions of the parts of a multi-geometry.
+// Cells are contained by the union only if one of the parts contains them.
+type regionUnion []s2.Region
+
+func (ru regionUnion) CapBound() s2.Cap {
+	capBound := s2.EmptyCap()
+	for _, region := range ru {
+		capBound = capBound.AddCap(region.CapBound())
+	}
+
+	return capBound
+}
+
+func (ru regionUnion) RectBound() s2.Rect {
+	rect := s2.EmptyRect()
+	for _, region := range ru {
+		rect = rect.Union(region.RectBound())
+	}
+
+	return rect
+}
+
+func (ru regionUnion) ContainsCell(cell s2.Cell) bool {
+	for _, region := range ru {
+		if region.ContainsCell(cell) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (ru regionUnion) IntersectsCell(cell s2.Cell) bool {
+	for _, region := range ru {
+		if region.IntersectsCell(cell) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (ru regionUnion) ContainsPoint(point s2.Point) bool {
+	for _, region := range ru {
+		if region.ContainsPoint(point) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (ru regionUnion) CellUnionBound() []s2.CellID {
+	cellIDs := []s2.CellID{}
+	for _, region := range ru {
+		cellIDs = append(cellIDs, region.CellUnionBound()...)
+	}
+
+	return cellIDs
+}
+
+// geometryRegion returns the region of a geometry as decoded by the encoding
+// packages. Any other s2.Region, such as an s2.Rect or s2.Cap, is accepted as
+// is.
+func geometryRegion(geometry interface{}) (s2.Region, error) {
+	switch geometry := geometry.(type) {
+	case s2.LatLng:
+		return s2.PointFromLatLng(geometry), nil
+	case []s2.Point:
+		ru := make(regionUnion, len(geometry))
+		for i, point := range geometry {
+			ru[i] = point
+		}
+
+		return ru, nil
+	case []*s2.Polyline:
+		ru := make(regionUnion, len(geometry))
+		for i, polyline := range geometry {
+			ru[i] = polyline
+		}
+
+		return ru, nil
+	case s2.Region:
+		return geometry, nil
+	default:
+		return nil, fmt.Errorf("geoutil: unknown geometry type %T", geometry)
+	}
+}
+
+// Covering returns the cells of a covering of a geometry, subject to the
+// MinLevel, MaxLevel, LevelMod and MaxCells restrictions of the coverer.
+func Covering(geometry interface{}, coverer *s2.RegionCoverer) (s2.CellUnion, error) {
+	region, err := geometryRegion(geometry)
+	if err != nil {
+		return nil, err
+	}
+
+	return coverer.Covering(region), nil
+}
+
+// InteriorCovering returns the cells of an interior covering of a geometry,
+// subject to the restrictions of the coverer. Points and polylines have no
+// interior, and so have empty interior coverings.
+func InteriorCovering(geometry interface{}, coverer *s2.RegionCoverer) (s2.CellUnion, error) {
+	region, err := geometryRegion(geometry)
+	if err != nil {
+		return nil, err
+	}
+
+	return coverer.InteriorCovering(region), nil
+}
+
+// CoveringTokens returns the tokens of the cells of a covering of a geometry.
+func CoveringTokens(geometry interface{}, coverer *s2.RegionCoverer) ([]string, error) {
+	cellUnion, err := Covering(geometry, coverer)
+	if err != nil {
+		return nil, err
+	}
+
+	return CellUnionTokens(cellUnion), nil
+}
+
+// InteriorCoveringTokens returns the tokens of the cells of an interior
+// covering of a geometry.
+func InteriorCoveringTokens(geometry interface{}, coverer *s2.RegionCoverer) ([]string, error) {
+	cellUnion, err := InteriorCovering(geometry, coverer)
+	if err != nil {
+		return nil, err
+	}
+
+	return CellUnionTokens(cellUnion), nil
+}
+
+// CellUnionTokens returns the tokens of the cells of a cell union.
+func CellUnionTokens(cellUnion s2.CellUnion) []string {
+	tokens := make([]string, len(cellUnion))
+	for i, cellID := range cellUnion {
+		tokens[i] = cellID.ToToken()
+	}
+
+	return tokens
+}
+
+// CellUnionFromTokens returns the cell union of the cells identified by
+// tokens. The cell union is not normalized.
+func CellUnionFromTokens(tokens []string) (s2.CellUnion, error) {
+	cellUnion := make(s2.CellUnion, len(tokens))
+	for i, token := range tokens {
+		cellID := s2.CellIDFromToken(token)
+		if !cellID.IsValid() {
+			return nil, fmt.Errorf("geoutil: invalid cell token %q", token)
+		}
+
+		cellUnion[i] = cellID
+	}
+
+	return cellUnion, nil
+}
@@ -0,0 +1,164 @@
+package geoutil
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/golang/geo/s2"
+)
+
+func pointFromDegrees(lat, lng float64) s2.Point {
+	return s2.PointFromLatLng(s2.LatLngFromDegrees(lat, lng))
+}
+
+// polylinesFromDegrees returns polylines of latitude and longitude pairs.
+func polylinesFromDegrees(coords ...[]float64) []*s2.Polyline {
+	polylines := []*s2.Polyline{}
+	for _, lineCoords := range coords {
+		polyline := s2.Polyline{}
+		for i := 0; i < len(lineCoords); i += 2 {
+			polyline = append(polyline, pointFromDegrees(lineCoords[i], lineCoords[i+1]))
+		}
+
+		polylines = append(polylines, &polyline)
+	}
+
+	return polylines
+}
+
+func TestCovering(t *testing.T) {
+	polygon, err := PolygonFromPolygonCoordinates([][][]float64{
+		{{2.25, 48.8}, {2.42, 48.8}, {2.42, 48.9}, {2.25, 48.9}, {2.25, 48.8}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	coverer := &s2.RegionCoverer{MinLevel: 4, MaxLevel: 16, MaxCells: 12}
+	covering, err := Covering(polygon, coverer)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(covering) == 0 || len(covering) > coverer.MaxCells {
+		t.Errorf("covering of %d cells", len(covering))
+	}
+
+	for lat := 48.8; lat <= 48.9; lat += 0.01 {
+		for lng := 2.25; lng <= 2.42; lng += 0.01 {
+			if point := pointFromDegrees(lat, lng); !covering.ContainsPoint(point) {
+				t.Errorf("covering misses %v", s2.LatLngFromPoint(point))
+			}
+		}
+	}
+
+	interior, err := InteriorCovering(polygon, coverer)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(interior) == 0 {
+		t.Errorf("empty interior covering")
+	}
+
+	for _, cellID := range interior {
+		if !polygon.ContainsCell(s2.CellFromCellID(cellID)) {
+			t.Errorf("interior covering cell %s is not inside the polygon", cellID.ToToken())
+		}
+	}
+}
+
+func TestCoveringGeometries(t *testing.T) {
+	points := []s2.Point{pointFromDegrees(1, 1), pointFromDegrees(10, 10), pointFromDegrees(1, 1.0001)}
+	polyline := s2.Polyline{pointFromDegrees(0, 0), pointFromDegrees(0, 1)}
+	coverer := &s2.RegionCoverer{MinLevel: 10, MaxLevel: 10, MaxCells: 8}
+
+	// Each point is covered by the cell containing it at the single level;
+	// the points are away from cell boundaries, where they would also be
+	// covered by the neighboring cells.
+	covering, err := Covering(points, coverer)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := s2.CellUnion{}
+	for _, point := range points {
+		want = append(want, s2.CellFromPoint(point).ID().Parent(10))
+	}
+
+	want.Normalize()
+	if !reflect.DeepEqual(covering, want) {
+		t.Errorf("covered points with %v, want %v", CellUnionTokens(covering), CellUnionTokens(want))
+	}
+
+	geometries := []interface{}{
+		points[1],
+		s2.LatLngFromDegrees(10, 10),
+		&polyline,
+		[]*s2.Polyline{&polyline},
+		s2.RectFromLatLng(s2.LatLngFromDegrees(0, 0)).AddPoint(s2.LatLngFromDegrees(1, 1)),
+	}
+
+	for _, geometry := range geometries {
+		covering, err := Covering(geometry, coverer)
+		if err != nil || len(covering) == 0 {
+			t.Errorf("%T: covered with %v, %v", geometry, covering, err)
+		}
+	}
+
+	// Points and polylines have no interior.
+	for _, geometry := range []interface{}{points, []*s2.Polyline{&polyline}} {
+		interior, err := InteriorCovering(geometry, coverer)
+		if err != nil || len(interior) != 0 {
+			t.Errorf("%T: interior covering %v, %v", geometry, interior, err)
+		}
+	}
+
+	if _, err := Covering(1, coverer); err == nil {
+		t.Errorf("covering an unknown geometry returned no error")
+	}
+
+	if _, err := InteriorCoveringTokens("POINT (0 0)", coverer); err == nil {
+		t.Errorf("covering an unknown geometry returned no error")
+	}
+}
+
+func TestCellUnionTokens(t *testing.T) {
+	cellUnion := s2.CellUnion{s2.CellIDFromFace(0), s2.CellIDFromFace(5), s2.CellIDFromFace(2).ChildBeginAtLevel(3)}
+	tokens := CellUnionTokens(cellUnion)
+	if want := []string{"1", "b", "404"}; !reflect.DeepEqual(tokens, want) {
+		t.Errorf("tokens %v, want %v", tokens, want)
+	}
+
+	decoded, err := CellUnionFromTokens(tokens)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(decoded, cellUnion) {
+		t.Errorf("decoded %v, want %v", decoded, cellUnion)
+	}
+
+	for _, token := range []string{"", "X", "0", "zz"} {
+		if _, err := CellUnionFromTokens([]string{"1", token}); err == nil {
+			t.Errorf("%q: no error", token)
+		}
+	}
+
+	polygon, _ := PolygonFromPolygonCoordinates([][][]float64{{{0, 0}, {1, 0}, {1, 1}, {0, 1}, {0, 0}}})
+	coverer := &s2.RegionCoverer{MaxLevel: 12, MaxCells: 4}
+	tokens, err = CoveringTokens(polygon, coverer)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	covering, _ := Covering(polygon, coverer)
+	if !reflect.DeepEqual(tokens, CellUnionTokens(covering)) {
+		t.Errorf("covering tokens %v, want %v", tokens, CellUnionTokens(covering))
+	}
+
+	tokens, err = InteriorCoveringTokens(polygon, coverer)
+	if err != nil || len(tokens) == 0 {
+		t.Errorf("interior covering tokens %v, %v", tokens, err)
+	}
+}
@@ -0,0 +1,189 @@
+package geoutil
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/golang/geo/s1"
+	"github.com/golang/geo/s2"
+)
+
+// maxDensifyDepth bounds the number of times an edge is halved when
+// densifying, so that an edge gains at most 2^maxDensifyDepth-1 vertices.
+const maxDensifyDepth = 20
+
+// planarMidpoint returns the middle of the straight line between two
+// coordinates in longitude and latitude, which are not normalized so that a
+// line may span more than half of the longitudes.
+func planarMidpoint(a, b s2.LatLng) s2.LatLng {
+	return s2.LatLng{Lat: (a.Lat + b.Lat) / 2, Lng: (a.Lng + b.Lng) / 2}
+}
+
+// densifyPlanarEdge appends the coordinates splitting the straight line
+// between two coordinates in longitude and latitude, so that the geodesic
+// edges between them are within tolerance of the line. Lines spanning more
+// than a quarter of the longitudes are always split, as their geodesic may
+// run anywhere.
+func densifyPlanarEdge(latLngs []s2.LatLng, a, b s2.LatLng, tolerance s1.Angle, depth int) []s2.LatLng {
+	if depth >= maxDensifyDepth {
+		return latLngs
+	}
+
+	middle := planarMidpoint(a, b)
+	if math.Abs((b.Lng-a.Lng).Radians()) <= math.Pi/2 &&
+		s2.DistanceFromSegment(s2.PointFromLatLng(middle), s2.PointFromLatLng(a), s2.PointFromLatLng(b)) <= tolerance {
+		return latLngs
+	}
+
+	latLngs = densifyPlanarEdge(latLngs, a, middle, tolerance, depth+1)
+	latLngs = append(latLngs, middle)
+	return densifyPlanarEdge(latLngs, middle, b, tolerance, depth+1)
+}
+
+// densifyGeodesicEdge appends the points splitting a geodesic edge, so that
+// the straight lines in longitude and latitude between them are within
+// tolerance of the edge. Edges crossing the antimeridian are not split, as
+// planar lines between their ends cannot follow them.
+func densifyGeodesicEdge(points []s2.Point, a, b s2.Point, tolerance s1.Angle, depth int) []s2.Point {
+	p, q := s2.LatLngFromPoint(a), s2.LatLngFromPoint(b)
+	if depth >= maxDensifyDepth || math.Abs((q.Lng-p.Lng).Radians()) > math.Pi {
+		return points
+	}
+
+	if s2.DistanceFromSegment(s2.PointFromLatLng(planarMidpoint(p, q)), a, b) <= tolerance {
+		return points
+	}
+
+	middle := s2.Point{Vector: a.Add(b.Vector).Normalize()}
+	points = densifyGeodesicEdge(points, a, middle, tolerance, depth+1)
+	points = append(points, middle)
+	return densifyGeodesicEdge(points, middle, b, tolerance, depth+1)
+}
+
+// DensifyPlanarCoordinates returns the coordinates of a LineString or of a
+// ring with coordinates inserted along the straight lines between them in
+// longitude and latitude, so that the geodesic edges of the geometry built
+// from them stay within tolerance of the lines drawn by planar viewers and
+// used by PostGIS geometry.
+func DensifyPlanarCoordinates(coords [][]float64, tolerance s1.Angle) [][]float64 {
+	if tolerance <= 0 || len(coords) < 2 {
+		return coords
+	}
+
+	densified := make([][]float64, 0, len(coords))
+	for i, coord := range coords {
+		if i > 0 && len(coords[i-1]) >= 2 && len(coord) >= 2 {
+			a := s2.LatLngFromDegrees(coords[i-1][1], coords[i-1][0])
+			b := s2.LatLngFromDegrees(coord[1], coord[0])
+			for _, latLng := range densifyPlanarEdge(nil, a, b, tolerance, 0) {
+				latLng = latLng.Normalized()
+				densified = append(densified, []float64{latLng.Lng.Degrees(), latLng.Lat.Degrees()})
+			}
+		}
+
+		densified = append(densified, coord)
+	}
+
+	return densified
+}
+
+// densifyPath returns the points of a path with the points splitting each of
+// its edges, including the closing edge of a ring.
+func densifyPath(points []s2.Point, ring bool, split func([]s2.Point, s2.Point, s2.Point) []s2.Point) []s2.Point {
+	if len(points) == 0 {
+		return points
+	}
+
+	densified := make([]s2.Point, 0, len(points))
+	for i, point := range points {
+		if i > 0 {
+			densified = split(densified, points[i-1], point)
+		}
+
+		densified = append(densified, point)
+	}
+
+	if ring {
+		densified = split(densified, points[len(points)-1], points[0])
+	}
+
+	return densified
+}
+
+// densify returns a geometry with the points splitting each of its edges.
+func densify(geometry interface{}, split func([]s2.Point, s2.Point, s2.Point) []s2.Point) (interface{}, error) {
+	switch geometry := geometry.(type) {
+	case s2.LatLng, s2.Point, []s2.Point:
+		return geometry, nil
+	case *s2.Polyline:
+		polyline := s2.Polyline(densifyPath(*geometry, false, split))
+		return &polyline, nil
+	case []*s2.Polyline:
+		polylines := make([]*s2.Polyline, len(geometry))
+		for i, polyline := range geometry {
+			densified := s2.Polyline(densifyPath(*polyline, false, split))
+			polylines[i] = &densified
+		}
+
+		return polylines, nil
+	case *s2.Loop:
+		if geometry.IsEmpty() || geometry.IsFull() {
+			return geometry, nil
+		}
+
+		return s2.LoopFromPoints(densifyPath(geometry.Vertices(), true, split)), nil
+	case *s2.Polygon:
+		if geometry.IsEmpty() || geometry.IsFull() {
+			return geometry, nil
+		}
+
+		// The loops are rebuilt from their vertices in the order keeping
+		// the interior of the polygon on their left.
+		loops := make([]*s2.Loop, 0, geometry.NumLoops())
+		for _, loop := range geometry.Loops() {
+			points := make([]s2.Point, loop.NumVertices())
+			for i := range points {
+				points[i] = loop.OrientedVertex(i)
+			}
+
+			loops = append(loops, s2.LoopFromPoints(densifyPath(points, true, split)))
+		}
+
+		return s2.PolygonFromOrientedLoops(loops), nil
+	default:
+		return nil, fmt.Errorf("geoutil: unknown geometry type %T", geometry)
+	}
+}
+
+// DensifyPlanar returns a geometry with vertices inserted along the straight
+// lines in longitude and latitude between its vertices, so that its geodesic
+// edges stay within tolerance of the lines drawn by planar viewers. It is
+// meant for geometries decoded from formats with planar semantics; use
+// DensifyPlanarCoordinates to densify coordinates before building them.
+func DensifyPlanar(geometry interface{}, tolerance s1.Angle) (interface{}, error) {
+	if tolerance <= 0 {
+		return geometry, nil
+	}
+
+	return densify(geometry, func(points []s2.Point, a, b s2.Point) []s2.Point {
+		for _, latLng := range densifyPlanarEdge(nil, s2.LatLngFromPoint(a), s2.LatLngFromPoint(b), tolerance, 0) {
+			points = append(points, s2.PointFromLatLng(latLng))
+		}
+
+		return points
+	})
+}
+
+// DensifyGeodesic returns a geometry with vertices inserted along its
+// geodesic edges, so that planar viewers drawing straight lines in longitude
+// and latitude between its vertices draw them within tolerance of its edges.
+// Edges crossing the antimeridian are left as they are.
+func DensifyGeodesic(geometry interface{}, tolerance s1.Angle) (interface{}, error) {
+	if tolerance <= 0 {
+		return geometry, nil
+	}
+
+	return densify(geometry, func(points []s2.Point, a, b s2.Point) []s2.Point {
+		return densifyGeodesicEdge(points, a, b, tolerance, 0)
+	})
+}
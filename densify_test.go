@@ -0,0 +1,216 @@
+package geoutil
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/golang/geo/s1"
+	"github.com/golang/geo/s2"
+)
+
+// checkDensified checks that the geodesic edges between points are within
+// tolerance of the straight lines in longitude and latitude between them.
+func checkDensified(t *testing.T, name string, points []s2.Point, tolerance s1.Angle) {
+	t.Helper()
+	for i := 1; i < len(points); i++ {
+		a, b := points[i-1], points[i]
+		middle := planarMidpoint(s2.LatLngFromPoint(a), s2.LatLngFromPoint(b))
+		if d := s2.DistanceFromSegment(s2.PointFromLatLng(middle), a, b); d > tolerance {
+			t.Errorf("%s: edge %d %v from the line, want at most %v", name, i-1, d, tolerance)
+			return
+		}
+	}
+}
+
+func TestDensifyPlanarCoordinates(t *testing.T) {
+	tolerance := AngleFromMeters(100)
+	tests := []struct {
+		name     string
+		coords   [][]float64
+		densify  bool
+		minCoord float64
+		maxCoord float64
+	}{
+		// The geodesic between two points of a parallel bulges towards the
+		// pole.
+		{"parallel", [][]float64{{0, 60}, {90, 60}}, true, 0, 90},
+
+		// The equator and meridians are geodesics.
+		{"equator", [][]float64{{0, 0}, {80, 0}}, false, 0, 80},
+		{"meridian", [][]float64{{10, -80}, {10, 80}}, false, 10, 10},
+
+		// A line spanning more than a quarter of the longitudes is split,
+		// even along the equator.
+		{"long equator", [][]float64{{0, 0}, {170, 0}}, true, 0, 170},
+
+		// A planar line from 170 to -170 runs westward through the prime
+		// meridian.
+		{"westward", [][]float64{{170, 0}, {-170, 0}}, true, -170, 170},
+	}
+
+	for _, test := range tests {
+		densified := DensifyPlanarCoordinates(test.coords, tolerance)
+		if !test.densify {
+			if len(densified) != len(test.coords) {
+				t.Errorf("%s: %d coordinates, want %d", test.name, len(densified), len(test.coords))
+			}
+
+			continue
+		}
+
+		if len(densified) <= len(test.coords) {
+			t.Errorf("%s: no coordinates inserted", test.name)
+			continue
+		}
+
+		first, last := densified[0], densified[len(densified)-1]
+		if first[0] != test.coords[0][0] || last[0] != test.coords[len(test.coords)-1][0] {
+			t.Errorf("%s: ends %v and %v, want %v and %v", test.name, first, last, test.coords[0], test.coords[len(test.coords)-1])
+		}
+
+		points := make([]s2.Point, len(densified))
+		for i, coord := range densified {
+			if coord[0] < test.minCoord || coord[0] > test.maxCoord {
+				t.Errorf("%s: longitude %v outside of [%v, %v]", test.name, coord[0], test.minCoord, test.maxCoord)
+			}
+
+			points[i] = pointFromDegrees(coord[1], coord[0])
+		}
+
+		// Inserted coordinates stay on the line, so the latitudes of a
+		// parallel are unchanged.
+		for _, coord := range densified {
+			if math.Abs(coord[1]-test.coords[0][1]) > 1e-9 {
+				t.Errorf("%s: latitude %v off the line", test.name, coord[1])
+				break
+			}
+		}
+
+		checkDensified(t, test.name, points, tolerance)
+	}
+
+	coords := [][]float64{{0, 60}, {90, 60}}
+	if densified := DensifyPlanarCoordinates(coords, 0); len(densified) != 2 {
+		t.Errorf("zero tolerance: %d coordinates, want 2", len(densified))
+	}
+}
+
+func TestDensifyPlanar(t *testing.T) {
+	tolerance := AngleFromMeters(100)
+	polygon, err := PolygonFromPolygonCoordinates([][][]float64{{{0, 50}, {40, 50}, {40, 60}, {0, 60}, {0, 50}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	densified, err := DensifyPlanar(polygon, tolerance)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The geodesic edges of the polygon bulge north of its parallels, by
+	// about 1.7 degrees along the southern edge and 1.5 degrees along the
+	// northern one. The densified edges follow the parallels.
+	result := densified.(*s2.Polygon)
+	if problems, err := Validate(result); err != nil || len(problems) != 0 {
+		t.Errorf("invalid polygon %v, %v", problems, err)
+	}
+
+	loop := result.Loop(0)
+	points := append(append([]s2.Point(nil), loop.Vertices()...), loop.Vertex(0))
+	checkDensified(t, "polygon", points, tolerance)
+	if south := pointFromDegrees(51, 20); !result.ContainsPoint(south) || polygon.ContainsPoint(south) {
+		t.Errorf("southern parallel not followed")
+	}
+
+	if north := pointFromDegrees(61, 20); result.ContainsPoint(north) || !polygon.ContainsPoint(north) {
+		t.Errorf("northern parallel not followed")
+	}
+
+	polylines := polylinesFromDegrees([]float64{60, 0, 60, 90}, []float64{0, 0, 0, 10})
+	densified, err = DensifyPlanar(polylines, tolerance)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	densifiedPolylines := densified.([]*s2.Polyline)
+	checkDensified(t, "polyline", *densifiedPolylines[0], tolerance)
+	if n := len(*densifiedPolylines[1]); n != 2 {
+		t.Errorf("equator: %d vertices, want 2", n)
+	}
+}
+
+func TestDensifyGeodesic(t *testing.T) {
+	tolerance := AngleFromMeters(100)
+	polygon, err := PolygonFromPolygonCoordinates([][][]float64{{{0, 50}, {40, 50}, {40, 60}, {0, 60}, {0, 50}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	densified, err := DensifyGeodesic(polygon, tolerance)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The inserted vertices lie on the edges, which leaves the region as it
+	// is.
+	result := densified.(*s2.Polygon)
+	if problems, err := Validate(result); err != nil || len(problems) != 0 {
+		t.Errorf("invalid polygon %v, %v", problems, err)
+	}
+
+	if math.Abs(result.Area()-polygon.Area()) > 1e-8*polygon.Area() {
+		t.Errorf("area %v, want %v", result.Area(), polygon.Area())
+	}
+
+	loop := result.Loop(0)
+	points := append(append([]s2.Point(nil), loop.Vertices()...), loop.Vertex(0))
+	if len(points) <= 5 {
+		t.Errorf("polygon: no vertices inserted")
+	}
+
+	checkDensified(t, "polygon", points, tolerance)
+
+	// Edges along the equator and meridians, and edges crossing the
+	// antimeridian, are left as they are.
+	tests := []struct {
+		name     string
+		polyline *s2.Polyline
+		vertices int
+	}{
+		{"parallel", polylinesFromDegrees([]float64{60, 0, 60, 90})[0], -1},
+		{"equator", polylinesFromDegrees([]float64{0, 0, 0, 80})[0], 2},
+		{"meridian", polylinesFromDegrees([]float64{-80, 10, 80, 10})[0], 2},
+		{"antimeridian", polylinesFromDegrees([]float64{60, 170, 60, -170})[0], 2},
+	}
+
+	for _, test := range tests {
+		densified, err := DensifyGeodesic(test.polyline, tolerance)
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+
+		polyline := *densified.(*s2.Polyline)
+		if test.vertices < 0 {
+			checkDensified(t, test.name, polyline, tolerance)
+		} else if len(polyline) != test.vertices {
+			t.Errorf("%s: %d vertices, want %d", test.name, len(polyline), test.vertices)
+		}
+	}
+
+	for _, geometry := range []interface{}{pointFromDegrees(0, 0), []s2.Point{}, s2.FullLoop(), s2.PolygonFromLoops(nil)} {
+		densified, err := DensifyGeodesic(geometry, tolerance)
+		if err != nil || !reflect.DeepEqual(densified, geometry) {
+			t.Errorf("%T: densified %v, %v", geometry, densified, err)
+		}
+	}
+
+	if densified, err := DensifyGeodesic(polygon, 0); densified != polygon || err != nil {
+		t.Errorf("zero tolerance: densified %v, %v", densified, err)
+	}
+
+	if _, err := DensifyGeodesic(1, tolerance); err == nil {
+		t.Errorf("unknown geometry: no error")
+	}
+}
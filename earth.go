@@ -0,0 +1,20 @@
+package geoutil
+
+import (
+	"github.com/golang/geo/s1"
+)
+
+// EarthRadius is the mean radius of the Earth in meters.
+const EarthRadius = 6371008.8
+
+// AngleFromMeters returns the angle subtended by a distance in meters on the
+// surface of the Earth.
+func AngleFromMeters(meters float64) s1.Angle {
+	return s1.Angle(meters / EarthRadius)
+}
+
+// MetersFromAngle returns the distance in meters on the surface of the Earth
+// subtended by an angle.
+func MetersFromAngle(angle s1.Angle) float64 {
+	return angle.Radians() * EarthRadius
+}
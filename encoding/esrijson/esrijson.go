@@ -0,0 +1,344 @@
+package esrijson
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+
+	"github.com/golang/geo/s2"
+
+	"github.com/topos-ai/geoutil"
+)
+
+const (
+	GeometryTypePoint      = "esriGeometryPoint"
+	GeometryTypeMultipoint = "esriGeometryMultipoint"
+	GeometryTypePolyline   = "esriGeometryPolyline"
+	GeometryTypePolygon    = "esriGeometryPolygon"
+)
+
+// earthRadius is the radius of the sphere used by the Web Mercator spatial
+// references.
+const earthRadius = 6378137
+
+// SpatialReference represents an Esri JSON spatial reference.
+type SpatialReference struct {
+	WKID       int    `json:"wkid,omitempty"`
+	LatestWKID int    `json:"latestWkid,omitempty"`
+	WKT        string `json:"wkt,omitempty"`
+}
+
+var wgs84 = &SpatialReference{
+	WKID: 4326,
+}
+
+// unprojectFunc returns the function converting coordinates of the spatial
+// reference to degrees. Geometries without a spatial reference are assumed to
+// be in WGS84.
+func unprojectFunc(sr *SpatialReference) (func([]float64) ([]float64, error), error) {
+	identity := func(coords []float64) ([]float64, error) {
+		if d := len(coords); d < 2 {
+			return nil, fmt.Errorf("esrijson: cannot process coordinates with dimension %d", d)
+		}
+
+		return coords[:2], nil
+	}
+
+	if sr == nil {
+		return identity, nil
+	}
+
+	wkid := sr.LatestWKID
+	if wkid == 0 {
+		wkid = sr.WKID
+	}
+
+	switch wkid {
+	case 0:
+		if sr.WKT != "" {
+			return nil, fmt.Errorf("esrijson: unsupported spatial reference %s", sr.WKT)
+		}
+
+		return identity, nil
+	case 4326:
+		return identity, nil
+	case 3857, 102100, 102113, 900913:
+		return func(coords []float64) ([]float64, error) {
+			coords, err := identity(coords)
+			if err != nil {
+				return nil, err
+			}
+
+			return []float64{
+				coords[0] / earthRadius * 180 / math.Pi,
+				(2*math.Atan(math.Exp(coords[1]/earthRadius)) - math.Pi/2) * 180 / math.Pi,
+			}, nil
+		}, nil
+	default:
+		return nil, fmt.Errorf("esrijson: unsupported spatial reference %d", wkid)
+	}
+}
+
+type rawGeometry struct {
+	X                *float64          `json:"x,omitempty"`
+	Y                *float64          `json:"y,omitempty"`
+	Points           [][]float64       `json:"points,omitempty"`
+	Paths            [][][]float64     `json:"paths,omitempty"`
+	Rings            [][][]float64     `json:"rings,omitempty"`
+	SpatialReference *SpatialReference `json:"spatialReference,omitempty"`
+}
+
+// ringArea returns twice the signed planar area of a closed ring, positive
+// for counter-clockwise rings.
+func ringArea(ring [][]float64) float64 {
+	area := 0.0
+	for i := 0; i+1 < len(ring); i++ {
+		area += ring[i][0]*ring[i+1][1] - ring[i+1][0]*ring[i][1]
+	}
+
+	return area
+}
+
+func reverseRing(ring [][]float64) {
+	for i, j := 0, len(ring)-1; i < j; i, j = i+1, j-1 {
+		ring[i], ring[j] = ring[j], ring[i]
+	}
+}
+
+// ringContains reports whether a closed ring contains a position, using the
+// even-odd rule in the plane.
+func ringContains(ring [][]float64, p []float64) bool {
+	contains := false
+	for i := 0; i+1 < len(ring); i++ {
+		a, b := ring[i], ring[i+1]
+		if (a[1] > p[1]) != (b[1] > p[1]) && p[0] < a[0]+(p[1]-a[1])*(b[0]-a[0])/(b[1]-a[1]) {
+			contains = !contains
+		}
+	}
+
+	return contains
+}
+
+// groupRings groups Esri rings into polygons. Clockwise rings are exterior
+// rings; counter-clockwise rings are holes of the exterior ring that contains
+// them. Holes outside every exterior ring are kept as exterior rings.
+func groupRings(rings [][][]float64) [][][][]float64 {
+	polygons := [][][][]float64{}
+	holes := [][][]float64{}
+	for _, ring := range rings {
+		if len(ring) == 0 {
+			continue
+		}
+
+		if ringArea(ring) <= 0 {
+			polygons = append(polygons, [][][]float64{ring})
+		} else {
+			holes = append(holes, ring)
+		}
+	}
+
+	for _, hole := range holes {
+		found := false
+		for i, polygon := range polygons {
+			if ringContains(polygon[0], hole[0]) {
+				polygons[i] = append(polygon, hole)
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			polygons = append(polygons, [][][]float64{hole})
+		}
+	}
+
+	return polygons
+}
+
+func decodeGeometry(rg *rawGeometry) (interface{}, error) {
+	unproject, err := unprojectFunc(rg.SpatialReference)
+	if err != nil {
+		return nil, err
+	}
+
+	unprojectAll := func(coords [][]float64) ([][]float64, error) {
+		positions := make([][]float64, len(coords))
+		for i := range coords {
+			position, err := unproject(coords[i])
+			if err != nil {
+				return nil, err
+			}
+
+			positions[i] = position
+		}
+
+		return positions, nil
+	}
+
+	switch {
+	case rg.Rings != nil:
+		rings := make([][][]float64, len(rg.Rings))
+		for i := range rg.Rings {
+			ring, err := unprojectAll(rg.Rings[i])
+			if err != nil {
+				return nil, err
+			}
+
+			rings[i] = ring
+		}
+
+		return geoutil.PolygonFromMultiPolygonCoordinates(groupRings(rings))
+
+	case rg.Paths != nil:
+		paths := make([][][]float64, len(rg.Paths))
+		for i := range rg.Paths {
+			path, err := unprojectAll(rg.Paths[i])
+			if err != nil {
+				return nil, err
+			}
+
+			paths[i] = path
+		}
+
+		if len(paths) == 1 {
+			return geoutil.PolylineFromLineStringCoordinates(paths[0])
+		}
+
+		return geoutil.PolylinesFromMultiLineStringCoordinates(paths)
+
+	case rg.Points != nil:
+		points, err := unprojectAll(rg.Points)
+		if err != nil {
+			return nil, err
+		}
+
+		return geoutil.PointsFromMultiPointCoordinates(points)
+
+	case rg.X != nil && rg.Y != nil:
+		position, err := unproject([]float64{*rg.X, *rg.Y})
+		if err != nil {
+			return nil, err
+		}
+
+		return geoutil.PointFromPointCoordinates(position)
+
+	default:
+
+		// Empty points have null coordinates.
+		return nil, nil
+	}
+}
+
+func encodeGeometry(v interface{}, precision int) (*rawGeometry, string, error) {
+	rg := &rawGeometry{
+		SpatialReference: wgs84,
+	}
+
+	switch geometry := v.(type) {
+	case s2.LatLng:
+		return encodeGeometry(s2.PointFromLatLng(geometry), precision)
+
+	case s2.Point:
+		coords, err := geoutil.PointCoordinates(geometry, precision)
+		if err != nil {
+			return nil, "", err
+		}
+
+		rg.X, rg.Y = &coords[0], &coords[1]
+		return rg, GeometryTypePoint, nil
+
+	case []s2.Point:
+		rg.Points = make([][]float64, len(geometry))
+		for i, point := range geometry {
+			coords, err := geoutil.PointCoordinates(point, precision)
+			if err != nil {
+				return nil, "", err
+			}
+
+			rg.Points[i] = coords
+		}
+
+		return rg, GeometryTypeMultipoint, nil
+
+	case *s2.Polyline:
+		return encodeGeometry([]*s2.Polyline{geometry}, precision)
+
+	case []*s2.Polyline:
+		rg.Paths = make([][][]float64, len(geometry))
+		for i, polyline := range geometry {
+			coords, err := geoutil.PolylineCoordinates(polyline, precision)
+			if err != nil {
+				return nil, "", err
+			}
+
+			rg.Paths[i] = coords
+		}
+
+		return rg, GeometryTypePolyline, nil
+
+	case *s2.Polygon:
+		polygonCoordinates, err := geoutil.PolygonCoordinates(geometry, precision)
+		if err != nil {
+			return nil, "", err
+		}
+
+		// Esri expects clockwise exterior rings and counter-clockwise holes.
+		rg.Rings = [][][]float64{}
+		for _, polygonCoords := range polygonCoordinates {
+			for i, ring := range polygonCoords {
+				if area := ringArea(ring); (i == 0) == (area > 0) {
+					reverseRing(ring)
+				}
+
+				rg.Rings = append(rg.Rings, ring)
+			}
+		}
+
+		return rg, GeometryTypePolygon, nil
+
+	default:
+		return nil, "", fmt.Errorf("esrijson: unknown geometry type %T", v)
+	}
+}
+
+// Geometry represents an Esri JSON geometry object. Geometry holds one of the
+// s2 types used by geoutil: s2.Point, []s2.Point, *s2.Polyline,
+// []*s2.Polyline or *s2.Polygon. Geometries are always encoded in WGS84;
+// WGS84 and Web Mercator geometries can be decoded.
+type Geometry struct {
+	Geometry  interface{}
+	Precision int
+}
+
+func (g *Geometry) UnmarshalJSON(data []byte) error {
+	rg := &rawGeometry{}
+	if err := json.Unmarshal(data, rg); err != nil {
+		return err
+	}
+
+	geometry, err := decodeGeometry(rg)
+	if err != nil {
+		return err
+	}
+
+	g.Geometry = geometry
+	return nil
+}
+
+func (g *Geometry) MarshalJSON() ([]byte, error) {
+	if g.Geometry == nil {
+		return []byte("null"), nil
+	}
+
+	rg, _, err := encodeGeometry(g.Geometry, g.Precision)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(rg)
+}
+
+func isNull(data json.RawMessage) bool {
+	return data == nil || bytes.Equal(data, []byte("null"))
+}
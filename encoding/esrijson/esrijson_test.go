@@ -0,0 +1,274 @@
+package esrijson
+
+import (
+	"encoding/json"
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/golang/geo/s2"
+
+	"github.com/topos-ai/geoutil"
+	"github.com/topos-ai/geoutil/encoding/geojson"
+)
+
+func decode(t *testing.T, data string) interface{} {
+	t.Helper()
+	g := &Geometry{}
+	if err := json.Unmarshal([]byte(data), g); err != nil {
+		t.Fatal(err)
+	}
+
+	return g.Geometry
+}
+
+func pointCoordinates(t *testing.T, point s2.Point) []float64 {
+	t.Helper()
+	coords, err := geoutil.PointCoordinates(point, geoutil.PrecisionE6)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return coords
+}
+
+func TestDecodePoint(t *testing.T) {
+	tests := []struct {
+		data string
+		want []float64
+	}{
+		{`{"x": -118.15, "y": 33.8, "spatialReference": {"wkid": 4326}}`, []float64{-118.15, 33.8}},
+		{`{"x": -118.15, "y": 33.8}`, []float64{-118.15, 33.8}},
+		{`{"x": 1113194.9079327357, "y": 5621521.486192066, "spatialReference": {"wkid": 102100, "latestWkid": 3857}}`, []float64{10, 45}},
+		{`{"x": 0, "y": 0, "spatialReference": {"wkid": 900913}}`, []float64{0, 0}},
+	}
+
+	for _, test := range tests {
+		point, ok := decode(t, test.data).(s2.Point)
+		if !ok {
+			t.Errorf("%s: decoded no point", test.data)
+			continue
+		}
+
+		if got := pointCoordinates(t, point); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: decoded %v, want %v", test.data, got, test.want)
+		}
+	}
+
+	// Empty points have null coordinates.
+	if g := decode(t, `{"x": null, "y": null}`); g != nil {
+		t.Errorf("decoded empty point as %v", g)
+	}
+}
+
+func TestDecodeMultipointAndPaths(t *testing.T) {
+	points, ok := decode(t, `{"points": [[-97.06, 32.83], [-97.06, 32.84]]}`).([]s2.Point)
+	if !ok || len(points) != 2 || !reflect.DeepEqual(pointCoordinates(t, points[1]), []float64{-97.06, 32.84}) {
+		t.Errorf("decoded multipoint %v", points)
+	}
+
+	polyline, ok := decode(t, `{"paths": [[[-97.06, 32.83], [-97.07, 32.84], [-97.08, 32.83]]]}`).(*s2.Polyline)
+	if !ok {
+		t.Fatalf("decoded no polyline")
+	}
+
+	coords, _ := geoutil.PolylineCoordinates(polyline, geoutil.PrecisionE6)
+	if want := [][]float64{{-97.06, 32.83}, {-97.07, 32.84}, {-97.08, 32.83}}; !reflect.DeepEqual(coords, want) {
+		t.Errorf("decoded polyline %v, want %v", coords, want)
+	}
+
+	polylines, ok := decode(t, `{"paths": [[[0, 0], [1, 1]], [[2, 2], [3, 3], [4, 2]]]}`).([]*s2.Polyline)
+	if !ok || len(polylines) != 2 || len(*polylines[1]) != 3 {
+		t.Errorf("decoded polylines %v", polylines)
+	}
+}
+
+func TestDecodeRings(t *testing.T) {
+	polygon, ok := decode(t, `{
+		"rings": [
+			[[0, 0], [0, 10], [10, 10], [10, 0], [0, 0]],
+			[[2, 2], [4, 2], [4, 4], [2, 4], [2, 2]],
+			[[20, 0], [20, 5], [25, 5], [25, 0], [20, 0]],
+			[[40, 0], [45, 0], [45, 5], [40, 5], [40, 0]]
+		]
+	}`).(*s2.Polygon)
+	if !ok {
+		t.Fatalf("decoded no polygon")
+	}
+
+	polygonCoordinates, err := geoutil.PolygonCoordinates(polygon, geoutil.PrecisionE6)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The clockwise rings are shells; the counter-clockwise ring inside the
+	// first is its hole, and the one outside every shell is kept as a shell.
+	rings := []int{}
+	for _, polygonCoords := range polygonCoordinates {
+		rings = append(rings, len(polygonCoords))
+	}
+
+	if !reflect.DeepEqual(rings, []int{2, 1, 1}) {
+		t.Errorf("decoded polygons with %v rings, want [2 1 1]", rings)
+	}
+
+	if !polygon.ContainsPoint(s2.PointFromLatLng(s2.LatLngFromDegrees(5, 5))) ||
+		polygon.ContainsPoint(s2.PointFromLatLng(s2.LatLngFromDegrees(3, 3))) ||
+		!polygon.ContainsPoint(s2.PointFromLatLng(s2.LatLngFromDegrees(2, 22))) ||
+		!polygon.ContainsPoint(s2.PointFromLatLng(s2.LatLngFromDegrees(2, 42))) {
+		t.Errorf("decoded polygon contains the wrong points")
+	}
+}
+
+func TestEncode(t *testing.T) {
+	polygon, err := geoutil.PolygonFromPolygonCoordinates([][][]float64{
+		{{0, 0}, {10, 0}, {10, 10}, {0, 10}, {0, 0}},
+		{{2, 2}, {2, 4}, {4, 4}, {4, 2}, {2, 2}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := json.Marshal(&Geometry{Geometry: polygon, Precision: geoutil.PrecisionE6})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rg := &rawGeometry{}
+	if err := json.Unmarshal(data, rg); err != nil {
+		t.Fatal(err)
+	}
+
+	if rg.SpatialReference == nil || rg.SpatialReference.WKID != 4326 {
+		t.Errorf("encoded spatial reference %+v, want WKID 4326", rg.SpatialReference)
+	}
+
+	// Exterior rings are clockwise and holes counter-clockwise.
+	if len(rg.Rings) != 2 || ringArea(rg.Rings[0]) >= 0 || ringArea(rg.Rings[1]) <= 0 {
+		t.Errorf("encoded rings %v", rg.Rings)
+	}
+
+	decoded := decode(t, string(data)).(*s2.Polygon)
+	if math.Abs(decoded.Area()-polygon.Area()) > 1e-9*polygon.Area() {
+		t.Errorf("decoded area %v, want %v", decoded.Area(), polygon.Area())
+	}
+
+	point := s2.PointFromLatLng(s2.LatLngFromDegrees(33.8, -118.15))
+	data, err = json.Marshal(&Geometry{Geometry: point, Precision: geoutil.PrecisionE6})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(data) != `{"x":-118.15,"y":33.8,"spatialReference":{"wkid":4326}}` {
+		t.Errorf("encoded point %s", data)
+	}
+
+	data, err = json.Marshal(&Geometry{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(data) != "null" {
+		t.Errorf("encoded empty geometry %s", data)
+	}
+
+	if _, err := json.Marshal(&Geometry{Geometry: 1}); err == nil {
+		t.Errorf("encoding an unknown geometry returned no error")
+	}
+}
+
+func TestFeatureSet(t *testing.T) {
+	data := `{
+		"objectIdFieldName": "OBJECTID",
+		"geometryType": "esriGeometryPoint",
+		"spatialReference": {"wkid": 102100},
+		"features": [
+			{"attributes": {"OBJECTID": 1, "name": "a"}, "geometry": {"x": 1113194.9079327357, "y": 5621521.486192066}},
+			{"attributes": {"OBJECTID": 2, "name": "b"}, "geometry": {"x": 10, "y": 45, "spatialReference": {"wkid": 4326}}},
+			{"attributes": {"OBJECTID": 3, "name": "c"}, "geometry": null},
+			{"attributes": {"name": "d"}}
+		]
+	}`
+
+	fs := &FeatureSet{}
+	if err := json.Unmarshal([]byte(data), fs); err != nil {
+		t.Fatal(err)
+	}
+
+	if fs.ObjectIDFieldName != "OBJECTID" || len(fs.Features) != 4 {
+		t.Fatalf("decoded feature set %+v", fs)
+	}
+
+	ids := []interface{}{}
+	for _, f := range fs.Features {
+		ids = append(ids, f.ID)
+	}
+
+	if !reflect.DeepEqual(ids, []interface{}{1.0, 2.0, 3.0, nil}) {
+		t.Errorf("decoded IDs %v", ids)
+	}
+
+	// The first geometry inherits the Web Mercator spatial reference of the
+	// set; the second has its own.
+	for _, f := range fs.Features[:2] {
+		if got := pointCoordinates(t, f.Geometry.(s2.Point)); !reflect.DeepEqual(got, []float64{10, 45}) {
+			t.Errorf("feature %v decoded at %v, want [10 45]", f.ID, got)
+		}
+	}
+
+	if fs.Features[2].Geometry != nil || fs.Features[3].Geometry != nil {
+		t.Errorf("decoded null geometries as %v and %v", fs.Features[2].Geometry, fs.Features[3].Geometry)
+	}
+
+	fc := fs.FeatureCollection()
+	fc.Features[0].Precision = geoutil.PrecisionE6
+	encoded, err := json.Marshal(NewFeatureSet(fc, "FID"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rfs := &rawFeatureSet{}
+	if err := json.Unmarshal(encoded, rfs); err != nil {
+		t.Fatal(err)
+	}
+
+	if rfs.GeometryType != GeometryTypePoint || rfs.SpatialReference == nil || rfs.SpatialReference.WKID != 4326 {
+		t.Errorf("encoded geometry type %s and spatial reference %+v", rfs.GeometryType, rfs.SpatialReference)
+	}
+
+	if rfs.Features[0].Attributes["FID"] != 1.0 || rfs.Features[3].Attributes["FID"] != nil {
+		t.Errorf("encoded attributes %v and %v", rfs.Features[0].Attributes, rfs.Features[3].Attributes)
+	}
+
+	if string(rfs.Features[0].Geometry) != `{"x":10,"y":45}` {
+		t.Errorf("encoded geometry %s", rfs.Features[0].Geometry)
+	}
+}
+
+func TestFeatureSetErrors(t *testing.T) {
+	decodeTests := map[string]string{
+		"null feature":      `{"features": [null]}`,
+		"spatial reference": `{"spatialReference": {"wkid": 27700}, "features": [{"attributes": {}, "geometry": {"x": 1, "y": 2}}]}`,
+		"wkt":               `{"features": [{"attributes": {}, "geometry": {"x": 1, "y": 2, "spatialReference": {"wkt": "PROJCS[]"}}}]}`,
+		"dimension":         `{"features": [{"attributes": {}, "geometry": {"points": [[1]]}}]}`,
+	}
+
+	for name, data := range decodeTests {
+		if err := json.Unmarshal([]byte(data), &FeatureSet{}); err == nil {
+			t.Errorf("%s: no error", name)
+		}
+	}
+
+	point := s2.PointFromLatLng(s2.LatLngFromDegrees(1, 2))
+	polyline, _ := geoutil.PolylineFromLineStringCoordinates([][]float64{{0, 0}, {1, 1}})
+	encodeTests := map[string][]*geojson.Feature{
+		"mixed types": {{Geometry: point}, {Geometry: polyline}},
+		"id":          {{ID: 1, Geometry: point}},
+	}
+
+	for name, features := range encodeTests {
+		if _, err := json.Marshal(&FeatureSet{Features: features}); err == nil {
+			t.Errorf("%s: no error", name)
+		}
+	}
+}
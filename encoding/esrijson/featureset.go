@@ -0,0 +1,150 @@
+package esrijson
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/topos-ai/geoutil/encoding/geojson"
+)
+
+// FeatureSet represents an Esri JSON feature set, as returned by the query
+// operation of ArcGIS Feature Services. When ObjectIDFieldName is set, feature
+// IDs are read from and written to the attribute of that name.
+type FeatureSet struct {
+	ObjectIDFieldName string
+	Features          []*geojson.Feature
+}
+
+type rawFeature struct {
+	Attributes map[string]interface{} `json:"attributes"`
+	Geometry   json.RawMessage        `json:"geometry,omitempty"`
+}
+
+type rawFeatureSet struct {
+	ObjectIDFieldName string            `json:"objectIdFieldName,omitempty"`
+	GeometryType      string            `json:"geometryType,omitempty"`
+	SpatialReference  *SpatialReference `json:"spatialReference,omitempty"`
+	Features          []*rawFeature     `json:"features"`
+}
+
+// NewFeatureSet returns a feature set holding the features of a
+// FeatureCollection.
+func NewFeatureSet(fc *geojson.FeatureCollection, objectIDFieldName string) *FeatureSet {
+	return &FeatureSet{
+		ObjectIDFieldName: objectIDFieldName,
+		Features:          fc.Features,
+	}
+}
+
+// FeatureCollection returns a FeatureCollection holding the features of the
+// feature set.
+func (fs *FeatureSet) FeatureCollection() *geojson.FeatureCollection {
+	return &geojson.FeatureCollection{
+		Features: fs.Features,
+	}
+}
+
+func (fs *FeatureSet) UnmarshalJSON(data []byte) error {
+	rfs := &rawFeatureSet{}
+	if err := json.Unmarshal(data, rfs); err != nil {
+		return err
+	}
+
+	features := make([]*geojson.Feature, len(rfs.Features))
+	for i, rf := range rfs.Features {
+		if rf == nil {
+			return fmt.Errorf("esrijson: null feature")
+		}
+
+		f := &geojson.Feature{
+			Properties: rf.Attributes,
+		}
+
+		if !isNull(rf.Geometry) {
+			rg := &rawGeometry{}
+			if err := json.Unmarshal(rf.Geometry, rg); err != nil {
+				return err
+			}
+
+			// Feature geometries inherit the spatial reference of the set.
+			if rg.SpatialReference == nil {
+				rg.SpatialReference = rfs.SpatialReference
+			}
+
+			geometry, err := decodeGeometry(rg)
+			if err != nil {
+				return err
+			}
+
+			f.Geometry = geometry
+		}
+
+		if rfs.ObjectIDFieldName != "" {
+			switch id := rf.Attributes[rfs.ObjectIDFieldName].(type) {
+			case string, float64:
+				f.ID = id
+			}
+		}
+
+		features[i] = f
+	}
+
+	fs.ObjectIDFieldName = rfs.ObjectIDFieldName
+	fs.Features = features
+	return nil
+}
+
+func (fs *FeatureSet) MarshalJSON() ([]byte, error) {
+	rfs := &rawFeatureSet{
+		ObjectIDFieldName: fs.ObjectIDFieldName,
+		Features:          make([]*rawFeature, len(fs.Features)),
+	}
+
+	for i, f := range fs.Features {
+		switch f.ID.(type) {
+		case string, float64, nil:
+		default:
+			return nil, fmt.Errorf("esrijson: invalid Feature ID type %T", f.ID)
+		}
+
+		attributes := make(map[string]interface{}, len(f.Properties)+1)
+		for name, v := range f.Properties {
+			attributes[name] = v
+		}
+
+		if fs.ObjectIDFieldName != "" && f.ID != nil {
+			attributes[fs.ObjectIDFieldName] = f.ID
+		}
+
+		rf := &rawFeature{
+			Attributes: attributes,
+		}
+
+		if f.Geometry != nil {
+			rg, geometryType, err := encodeGeometry(f.Geometry, f.Precision)
+			if err != nil {
+				return nil, err
+			}
+
+			// Feature sets hold a single geometry type.
+			if rfs.GeometryType == "" {
+				rfs.GeometryType = geometryType
+				rfs.SpatialReference = rg.SpatialReference
+			} else if rfs.GeometryType != geometryType {
+				return nil, fmt.Errorf("esrijson: mixed geometry types %s and %s", rfs.GeometryType, geometryType)
+			}
+
+			rg.SpatialReference = nil
+			data, err := json.Marshal(rg)
+			if err != nil {
+				return nil, err
+			}
+
+			rf.Geometry = data
+		}
+
+		rfs.Features[i] = rf
+	}
+
+	return json.Marshal(rfs)
+}
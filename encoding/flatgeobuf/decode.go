@@ -0,0 +1,638 @@
+package flatgeobuf
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+
+	"github.com/golang/geo/s1"
+	"github.com/golang/geo/s2"
+
+	"github.com/topos-ai/geoutil"
+	"github.com/topos-ai/geoutil/encoding/geojson"
+)
+
+// maxBufferSize bounds the size prefixes accepted for the header and features.
+const maxBufferSize = 1 << 30
+
+// readChunkSize is the size of the chunks in which buffers are read, so that
+// a corrupt size prefix allocates no more than the data actually present.
+const readChunkSize = 1 << 20
+
+// seekThreshold is the distance below which skipping forward reads through
+// the buffer instead of seeking.
+const seekThreshold = 64 << 10
+
+var errFilterAfterRead = errors.New("flatgeobuf: Filter called after Read")
+
+func decodeHeader(buf []byte) (header *Header, err error) {
+	defer fbRecover(&err)
+
+	t := fbRoot(buf)
+	header = &Header{
+		Name:          t.string(headerName),
+		Envelope:      t.float64s(headerEnvelope),
+		GeometryType:  GeometryType(t.uint8(headerGeometryType, 0)),
+		HasZ:          t.bool(headerHasZ),
+		HasM:          t.bool(headerHasM),
+		HasT:          t.bool(headerHasT),
+		HasTM:         t.bool(headerHasTM),
+		FeaturesCount: t.uint64(headerFeaturesCount, 0),
+		IndexNodeSize: t.uint16(headerIndexNodeSize, DefaultIndexNodeSize),
+		Title:         t.string(headerTitle),
+		Description:   t.string(headerDescription),
+		Metadata:      t.string(headerMetadata),
+	}
+
+	for _, c := range t.tables(headerColumns) {
+		header.Columns = append(header.Columns, Column{
+			Name:        c.string(columnName),
+			Type:        ColumnType(c.uint8(columnType, 0)),
+			Title:       c.string(columnTitle),
+			Description: c.string(columnDescription),
+		})
+	}
+
+	if c, ok := t.table(headerCRS); ok {
+		header.CRS = &CRS{
+			Org:         c.string(crsOrg),
+			Code:        c.int32(crsCode, 0),
+			Name:        c.string(crsName),
+			Description: c.string(crsDescription),
+			WKT:         c.string(crsWKT),
+			CodeString:  c.string(crsCodeString),
+		}
+	}
+
+	return header, nil
+}
+
+// checkXY returns an error if a flat xy vector holds a value that is not a
+// finite number.
+func checkXY(xy []float64) error {
+	for _, v := range xy {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return fmt.Errorf("flatgeobuf: invalid coordinate %v", v)
+		}
+	}
+
+	return nil
+}
+
+// splitXY splits a flat xy vector into coordinate sequences ending at ends.
+// Rings are closed sequences of at least 4 positions.
+func splitXY(xy []float64, ends []uint32, rings bool) ([][][]float64, error) {
+	if len(xy)%2 != 0 {
+		return nil, fmt.Errorf("flatgeobuf: odd number of xy values %d", len(xy))
+	}
+
+	if err := checkXY(xy); err != nil {
+		return nil, err
+	}
+
+	n := uint32(len(xy) / 2)
+	if len(ends) == 0 {
+		if n == 0 {
+			return [][][]float64{}, nil
+		}
+
+		ends = []uint32{n}
+	}
+
+	lines := make([][][]float64, len(ends))
+	start := uint32(0)
+	for i, end := range ends {
+		if end < start || end > n {
+			return nil, fmt.Errorf("flatgeobuf: invalid end index %d", end)
+		}
+
+		if rings && end-start < 4 {
+			return nil, fmt.Errorf("flatgeobuf: ring of %d positions", end-start)
+		}
+
+		line := make([][]float64, end-start)
+		for j := range line {
+			k := 2 * (start + uint32(j))
+			line[j] = xy[k : k+2]
+		}
+
+		lines[i] = line
+		start = end
+	}
+
+	return lines, nil
+}
+
+// decodeGeometry decodes a Geometry table. The geometry type of the header
+// takes precedence over that of the table unless it is unknown.
+func decodeGeometry(t fbTableReader, headerType GeometryType, bounds *nodeItem) (interface{}, error) {
+	kind := headerType
+	if kind == GeometryTypeUnknown {
+		kind = GeometryType(t.uint8(geometryType, 0))
+	}
+
+	xy := t.float64s(geometryXY)
+	for i := 0; i+1 < len(xy); i += 2 {
+		bounds.expandXY(xy[i], xy[i+1])
+	}
+
+	switch kind {
+	case GeometryTypePoint:
+		if len(xy) < 2 {
+			return nil, fmt.Errorf("flatgeobuf: empty Point")
+		}
+
+		if err := checkXY(xy[:2]); err != nil {
+			return nil, err
+		}
+
+		return geoutil.PointFromPointCoordinates(xy[:2])
+
+	case GeometryTypeLineString:
+		lines, err := splitXY(xy, nil, false)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(lines) == 0 {
+			return &s2.Polyline{}, nil
+		}
+
+		return geoutil.PolylineFromLineStringCoordinates(lines[0])
+
+	case GeometryTypePolygon:
+		rings, err := splitXY(xy, t.uint32s(geometryEnds), true)
+		if err != nil {
+			return nil, err
+		}
+
+		return geoutil.PolygonFromPolygonCoordinates(rings)
+
+	case GeometryTypeMultiPoint:
+		lines, err := splitXY(xy, nil, false)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(lines) == 0 {
+			return []s2.Point{}, nil
+		}
+
+		return geoutil.PointsFromMultiPointCoordinates(lines[0])
+
+	case GeometryTypeMultiLineString:
+		lines, err := splitXY(xy, t.uint32s(geometryEnds), false)
+		if err != nil {
+			return nil, err
+		}
+
+		return geoutil.PolylinesFromMultiLineStringCoordinates(lines)
+
+	case GeometryTypeMultiPolygon:
+		parts := t.tables(geometryParts)
+		polygons := make([][][][]float64, len(parts))
+		for i, part := range parts {
+			partXY := part.float64s(geometryXY)
+			for j := 0; j+1 < len(partXY); j += 2 {
+				bounds.expandXY(partXY[j], partXY[j+1])
+			}
+
+			rings, err := splitXY(partXY, part.uint32s(geometryEnds), true)
+			if err != nil {
+				return nil, err
+			}
+
+			polygons[i] = rings
+		}
+
+		return geoutil.PolygonFromMultiPolygonCoordinates(polygons)
+
+	default:
+		return nil, fmt.Errorf("flatgeobuf: unsupported geometry type %d", kind)
+	}
+}
+
+func decodeProperties(data []byte, columns []Column) (map[string]interface{}, error) {
+	properties := map[string]interface{}{}
+	for len(data) > 0 {
+		if len(data) < 2 {
+			return nil, io.ErrUnexpectedEOF
+		}
+
+		i := int(binary.LittleEndian.Uint16(data))
+		data = data[2:]
+		if i >= len(columns) {
+			return nil, fmt.Errorf("flatgeobuf: invalid column index %d", i)
+		}
+
+		column := columns[i]
+
+		size := 0
+		switch column.Type {
+		case ColumnTypeBool, ColumnTypeByte, ColumnTypeUByte:
+			size = 1
+		case ColumnTypeShort, ColumnTypeUShort:
+			size = 2
+		case ColumnTypeInt, ColumnTypeUInt, ColumnTypeFloat:
+			size = 4
+		case ColumnTypeLong, ColumnTypeULong, ColumnTypeDouble:
+			size = 8
+		case ColumnTypeString, ColumnTypeJSON, ColumnTypeDateTime, ColumnTypeBinary:
+			if len(data) < 4 {
+				return nil, io.ErrUnexpectedEOF
+			}
+
+			n := binary.LittleEndian.Uint32(data)
+			data = data[4:]
+			if uint64(n) > uint64(len(data)) {
+				return nil, io.ErrUnexpectedEOF
+			}
+
+			size = int(n)
+		default:
+			return nil, fmt.Errorf("flatgeobuf: unknown column type %d", column.Type)
+		}
+
+		if len(data) < size {
+			return nil, io.ErrUnexpectedEOF
+		}
+
+		value := data[:size]
+		data = data[size:]
+
+		var v interface{}
+		switch column.Type {
+		case ColumnTypeBool:
+			v = value[0] != 0
+		case ColumnTypeByte:
+			v = int8(value[0])
+		case ColumnTypeUByte:
+			v = value[0]
+		case ColumnTypeShort:
+			v = int16(binary.LittleEndian.Uint16(value))
+		case ColumnTypeUShort:
+			v = binary.LittleEndian.Uint16(value)
+		case ColumnTypeInt:
+			v = int32(binary.LittleEndian.Uint32(value))
+		case ColumnTypeUInt:
+			v = binary.LittleEndian.Uint32(value)
+		case ColumnTypeLong:
+			v = int64(binary.LittleEndian.Uint64(value))
+		case ColumnTypeULong:
+			v = binary.LittleEndian.Uint64(value)
+		case ColumnTypeFloat:
+			v = math.Float32frombits(binary.LittleEndian.Uint32(value))
+		case ColumnTypeDouble:
+			v = math.Float64frombits(binary.LittleEndian.Uint64(value))
+		case ColumnTypeString, ColumnTypeDateTime:
+			v = string(value)
+		case ColumnTypeBinary:
+			v = append([]byte{}, value...)
+		case ColumnTypeJSON:
+			if err := json.Unmarshal(value, &v); err != nil {
+				return nil, err
+			}
+		}
+
+		properties[column.Name] = v
+	}
+
+	return properties, nil
+}
+
+func decodeFeature(buf []byte, header *Header) (f *geojson.Feature, bounds nodeItem, err error) {
+	defer fbRecover(&err)
+
+	t := fbRoot(buf)
+	f = &geojson.Feature{}
+	bounds = emptyNodeItem()
+	if g, ok := t.table(featureGeometry); ok {
+		geometry, err := decodeGeometry(g, header.GeometryType, &bounds)
+		if err != nil {
+			return nil, bounds, err
+		}
+
+		f.Geometry = geometry
+	}
+
+	columns := header.Columns
+	if c := t.tables(featureColumns); len(c) > 0 {
+		columns = make([]Column, len(c))
+		for i := range c {
+			columns[i] = Column{
+				Name: c[i].string(columnName),
+				Type: ColumnType(c[i].uint8(columnType, 0)),
+			}
+		}
+	}
+
+	properties, err := decodeProperties(t.bytes(featureProperties), columns)
+	if err != nil {
+		return nil, bounds, err
+	}
+
+	f.Properties = properties
+	return f, bounds, nil
+}
+
+// Reader reads features from a FlatGeobuf dataset.
+type Reader struct {
+	r      *bufio.Reader
+	seeker io.Seeker
+	base   int64
+	pos    int64
+
+	header        *Header
+	featuresStart int64
+
+	filtered bool
+	query    []nodeItem
+	hits     []searchResult
+	started  bool
+}
+
+// NewReader reads the FlatGeobuf magic bytes and header from r. If r is also
+// an io.Seeker, filtered reads seek over features that do not match instead
+// of reading through them.
+func NewReader(r io.Reader) (*Reader, error) {
+	fr := &Reader{
+		r: bufio.NewReader(r),
+	}
+
+	if seeker, ok := r.(io.Seeker); ok {
+		base, err := seeker.Seek(0, io.SeekCurrent)
+		if err == nil {
+			fr.seeker = seeker
+			fr.base = base
+		}
+	}
+
+	magic := make([]byte, len(magicBytes))
+	if err := fr.readFull(magic); err != nil {
+		return nil, err
+	}
+
+	if !bytes.Equal(magic[:3], magicBytes[:3]) || !bytes.Equal(magic[4:7], magicBytes[4:7]) || magic[3] != magicBytes[3] {
+		return nil, errors.New("flatgeobuf: invalid magic bytes")
+	}
+
+	buf, err := fr.readSizePrefixed()
+	if err != nil {
+		return nil, err
+	}
+
+	header, err := decodeHeader(buf)
+	if err != nil {
+		return nil, err
+	}
+
+	fr.header = header
+	fr.featuresStart = fr.pos + int64(indexSize(header.FeaturesCount, header.IndexNodeSize))
+	return fr, nil
+}
+
+// Header returns the header of the dataset.
+func (fr *Reader) Header() *Header {
+	return fr.header
+}
+
+func (fr *Reader) readFull(buf []byte) error {
+	n, err := io.ReadFull(fr.r, buf)
+	fr.pos += int64(n)
+	return err
+}
+
+func (fr *Reader) readSizePrefixed() ([]byte, error) {
+	var size [4]byte
+	if err := fr.readFull(size[:]); err != nil {
+		return nil, err
+	}
+
+	n := binary.LittleEndian.Uint32(size[:])
+	if n > maxBufferSize {
+		return nil, fmt.Errorf("flatgeobuf: buffer size %d too large", n)
+	}
+
+	return fr.readBuffer(int(n))
+}
+
+// readBuffer reads n bytes in chunks of at most readChunkSize, growing the
+// buffer as they arrive.
+func (fr *Reader) readBuffer(n int) ([]byte, error) {
+	buf := []byte{}
+	for len(buf) < n {
+		chunk := n - len(buf)
+		if chunk > readChunkSize {
+			chunk = readChunkSize
+		}
+
+		start := len(buf)
+		buf = append(buf, make([]byte, chunk)...)
+		if err := fr.readFull(buf[start:]); err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+
+			return nil, err
+		}
+	}
+
+	return buf, nil
+}
+
+// skipTo moves forward to the absolute stream position pos.
+func (fr *Reader) skipTo(pos int64) error {
+	if pos < fr.pos {
+		return fmt.Errorf("flatgeobuf: cannot move backwards to offset %d", pos)
+	}
+
+	if fr.seeker != nil && pos-fr.pos > int64(fr.r.Buffered())+seekThreshold {
+		if _, err := fr.seeker.Seek(fr.base+pos, io.SeekStart); err != nil {
+			return err
+		}
+
+		fr.r.Reset(fr.seeker.(io.Reader))
+		fr.pos = pos
+		return nil
+	}
+
+	n, err := fr.r.Discard(int(pos - fr.pos))
+	fr.pos += int64(n)
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+
+	return err
+}
+
+// Filter restricts subsequent reads to features whose bounding boxes intersect
+// rect. When the dataset has a spatial index it is used to locate the matching
+// features; otherwise every feature is decoded and tested. Filter must be
+// called before the first call to Read.
+func (fr *Reader) Filter(rect s2.Rect) error {
+	if fr.started {
+		return errFilterAfterRead
+	}
+
+	fr.filtered = true
+	fr.query = rectQuery(rect)
+
+	header := fr.header
+	if header.IndexNodeSize == 0 || header.FeaturesCount == 0 {
+		return nil
+	}
+
+	indexStart := fr.pos
+	nodeSize := clampNodeSize(header.IndexNodeSize)
+	numNodes := uint64(indexSize(header.FeaturesCount, nodeSize)) / nodeItemSize
+
+	// Without a seeker, the index has to be read in full anyway.
+	var index []byte
+	if fr.seeker == nil {
+		if numNodes > maxBufferSize/nodeItemSize {
+			return fmt.Errorf("flatgeobuf: index of %d nodes too large", numNodes)
+		}
+
+		var err error
+		index, err = fr.readBuffer(int(numNodes * nodeItemSize))
+		if err != nil {
+			return err
+		}
+	}
+
+	readNodes := func(start, count uint64) ([]nodeItem, error) {
+		if start+count > numNodes {
+			return nil, errInvalidIndex
+		}
+
+		var buf []byte
+		if index != nil {
+			buf = index[start*nodeItemSize : (start+count)*nodeItemSize]
+		} else {
+			buf = make([]byte, count*nodeItemSize)
+			offset := fr.base + indexStart + int64(start*nodeItemSize)
+			if _, err := fr.seeker.Seek(offset, io.SeekStart); err != nil {
+				return nil, err
+			}
+
+			if _, err := io.ReadFull(fr.seeker.(io.Reader), buf); err != nil {
+				return nil, err
+			}
+		}
+
+		nodes := make([]nodeItem, count)
+		for i := range nodes {
+			nodes[i] = decodeNodeItem(buf[i*nodeItemSize:])
+		}
+
+		return nodes, nil
+	}
+
+	fr.hits = []searchResult{}
+	seen := map[uint64]bool{}
+	for _, query := range fr.query {
+		results, err := searchIndex(header.FeaturesCount, nodeSize, query, readNodes)
+		if err != nil {
+			return err
+		}
+
+		for _, result := range results {
+			if !seen[result.offset] {
+				seen[result.offset] = true
+				fr.hits = append(fr.hits, result)
+			}
+		}
+	}
+
+	sort.Slice(fr.hits, func(i, j int) bool {
+		return fr.hits[i].offset < fr.hits[j].offset
+	})
+
+	// Searching through a seeker moved the underlying stream.
+	if fr.seeker != nil {
+		if _, err := fr.seeker.Seek(fr.base+fr.pos, io.SeekStart); err != nil {
+			return err
+		}
+
+		fr.r.Reset(fr.seeker.(io.Reader))
+	}
+
+	return nil
+}
+
+// rectQuery converts rect into planar query boxes, splitting rectangles that
+// cross the antimeridian.
+func rectQuery(rect s2.Rect) []nodeItem {
+	lo := rect.Lat.Lo * 180 / math.Pi
+	hi := rect.Lat.Hi * 180 / math.Pi
+	if rect.Lng.IsInverted() {
+		return []nodeItem{
+			{minX: s1.Angle(rect.Lng.Lo).Degrees(), minY: lo, maxX: 180, maxY: hi},
+			{minX: -180, minY: lo, maxX: s1.Angle(rect.Lng.Hi).Degrees(), maxY: hi},
+		}
+	}
+
+	return []nodeItem{{
+		minX: s1.Angle(rect.Lng.Lo).Degrees(),
+		minY: lo,
+		maxX: s1.Angle(rect.Lng.Hi).Degrees(),
+		maxY: hi,
+	}}
+}
+
+func (fr *Reader) matches(bounds nodeItem) bool {
+	for _, query := range fr.query {
+		if bounds.intersects(query) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Read returns the next feature of the dataset, or io.EOF once every feature
+// has been read.
+func (fr *Reader) Read() (*geojson.Feature, error) {
+	if !fr.started {
+		fr.started = true
+		if fr.hits == nil {
+			if err := fr.skipTo(fr.featuresStart); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	for {
+		if fr.hits != nil {
+			if len(fr.hits) == 0 {
+				return nil, io.EOF
+			}
+
+			if err := fr.skipTo(fr.featuresStart + int64(fr.hits[0].offset)); err != nil {
+				return nil, err
+			}
+
+			fr.hits = fr.hits[1:]
+		}
+
+		buf, err := fr.readSizePrefixed()
+		if err != nil {
+			return nil, err
+		}
+
+		f, bounds, err := decodeFeature(buf, fr.header)
+		if err != nil {
+			return nil, err
+		}
+
+		if fr.hits != nil || !fr.filtered || fr.matches(bounds) {
+			return f, nil
+		}
+	}
+}
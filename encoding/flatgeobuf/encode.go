@@ -0,0 +1,578 @@
+package flatgeobuf
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/golang/geo/s2"
+
+	"github.com/topos-ai/geoutil"
+	"github.com/topos-ai/geoutil/encoding/geojson"
+)
+
+func inferColumnType(v interface{}) ColumnType {
+	switch v.(type) {
+	case bool:
+		return ColumnTypeBool
+	case int8:
+		return ColumnTypeByte
+	case uint8:
+		return ColumnTypeUByte
+	case int16:
+		return ColumnTypeShort
+	case uint16:
+		return ColumnTypeUShort
+	case int32:
+		return ColumnTypeInt
+	case uint32:
+		return ColumnTypeUInt
+	case int, int64:
+		return ColumnTypeLong
+	case uint, uint64:
+		return ColumnTypeULong
+	case float32:
+		return ColumnTypeFloat
+	case float64:
+		return ColumnTypeDouble
+	case string:
+		return ColumnTypeString
+	case time.Time:
+		return ColumnTypeDateTime
+	case []byte:
+		return ColumnTypeBinary
+	default:
+		return ColumnTypeJSON
+	}
+}
+
+func toFloat64(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int8:
+		return float64(n), true
+	case uint8:
+		return float64(n), true
+	case int16:
+		return float64(n), true
+	case uint16:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case uint32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case float32:
+		return float64(n), true
+	case float64:
+		return n, true
+	default:
+		return 0, false
+	}
+}
+
+func toInt64(v interface{}) (int64, bool) {
+	switch n := v.(type) {
+	case int8:
+		return int64(n), true
+	case uint8:
+		return int64(n), true
+	case int16:
+		return int64(n), true
+	case uint16:
+		return int64(n), true
+	case int32:
+		return int64(n), true
+	case uint32:
+		return int64(n), true
+	case int:
+		return int64(n), true
+	case int64:
+		return n, true
+	case uint:
+		return int64(n), true
+	case uint64:
+		return int64(n), true
+	case float32:
+		return int64(n), float32(int64(n)) == n
+	case float64:
+		return int64(n), float64(int64(n)) == n
+	default:
+		return 0, false
+	}
+}
+
+func appendLengthPrefixed(buf, data []byte) []byte {
+	var size [4]byte
+	binary.LittleEndian.PutUint32(size[:], uint32(len(data)))
+	buf = append(buf, size[:]...)
+	return append(buf, data...)
+}
+
+func appendProperty(buf []byte, column Column, v interface{}) ([]byte, error) {
+	var scratch [8]byte
+	switch column.Type {
+	case ColumnTypeBool:
+		b, ok := v.(bool)
+		if !ok {
+			break
+		}
+
+		if b {
+			return append(buf, 1), nil
+		}
+
+		return append(buf, 0), nil
+
+	case ColumnTypeByte, ColumnTypeUByte, ColumnTypeShort, ColumnTypeUShort, ColumnTypeInt, ColumnTypeUInt, ColumnTypeLong, ColumnTypeULong:
+		n, ok := toInt64(v)
+		if !ok {
+			break
+		}
+
+		binary.LittleEndian.PutUint64(scratch[:], uint64(n))
+		switch column.Type {
+		case ColumnTypeByte, ColumnTypeUByte:
+			return append(buf, scratch[:1]...), nil
+		case ColumnTypeShort, ColumnTypeUShort:
+			return append(buf, scratch[:2]...), nil
+		case ColumnTypeInt, ColumnTypeUInt:
+			return append(buf, scratch[:4]...), nil
+		default:
+			return append(buf, scratch[:8]...), nil
+		}
+
+	case ColumnTypeFloat:
+		f, ok := toFloat64(v)
+		if !ok {
+			break
+		}
+
+		binary.LittleEndian.PutUint32(scratch[:], math.Float32bits(float32(f)))
+		return append(buf, scratch[:4]...), nil
+
+	case ColumnTypeDouble:
+		f, ok := toFloat64(v)
+		if !ok {
+			break
+		}
+
+		binary.LittleEndian.PutUint64(scratch[:], math.Float64bits(f))
+		return append(buf, scratch[:8]...), nil
+
+	case ColumnTypeString:
+		s, ok := v.(string)
+		if !ok {
+			break
+		}
+
+		return appendLengthPrefixed(buf, []byte(s)), nil
+
+	case ColumnTypeDateTime:
+		switch t := v.(type) {
+		case time.Time:
+			return appendLengthPrefixed(buf, []byte(t.Format(time.RFC3339Nano))), nil
+		case string:
+			return appendLengthPrefixed(buf, []byte(t)), nil
+		}
+
+	case ColumnTypeBinary:
+		b, ok := v.([]byte)
+		if !ok {
+			break
+		}
+
+		return appendLengthPrefixed(buf, b), nil
+
+	case ColumnTypeJSON:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+
+		return appendLengthPrefixed(buf, data), nil
+
+	default:
+		return nil, fmt.Errorf("flatgeobuf: unknown column type %d", column.Type)
+	}
+
+	return nil, fmt.Errorf("flatgeobuf: cannot encode %T in column %s of type %d", v, column.Name, column.Type)
+}
+
+// encodedGeometry is a Geometry table along with the planar bounds of its
+// coordinates.
+type encodedGeometry struct {
+	table        *fbTable
+	geometryType GeometryType
+	bounds       nodeItem
+}
+
+func (g *encodedGeometry) addXY(coords [][]float64) []float64 {
+	xy := make([]float64, 0, 2*len(coords))
+	for _, c := range coords {
+		xy = append(xy, c[0], c[1])
+		g.bounds.expandXY(c[0], c[1])
+	}
+
+	return xy
+}
+
+// addLines stores multiple coordinate sequences in one xy vector, recording
+// their end indices when there is more than one.
+func (g *encodedGeometry) addLines(t *fbTable, lines [][][]float64) {
+	xy := []float64{}
+	ends := make([]uint32, 0, len(lines))
+	for _, line := range lines {
+		xy = append(xy, g.addXY(line)...)
+		ends = append(ends, uint32(len(xy)/2))
+	}
+
+	if len(ends) > 1 {
+		t.addObject(geometryEnds, fbUint32s(ends))
+	}
+
+	if len(xy) > 0 {
+		t.addObject(geometryXY, fbFloat64s(xy))
+	}
+}
+
+func encodeGeometry(geometry interface{}, precision int) (*encodedGeometry, error) {
+	g := &encodedGeometry{
+		table:  &fbTable{},
+		bounds: emptyNodeItem(),
+	}
+
+	switch geometry := geometry.(type) {
+	case s2.LatLng:
+		return encodeGeometry(s2.PointFromLatLng(geometry), precision)
+
+	case s2.Point:
+		coords, err := geoutil.PointCoordinates(geometry, precision)
+		if err != nil {
+			return nil, err
+		}
+
+		g.geometryType = GeometryTypePoint
+		g.table.addObject(geometryXY, fbFloat64s(g.addXY([][]float64{coords})))
+
+	case *s2.Polyline:
+		coords, err := geoutil.PolylineCoordinates(geometry, precision)
+		if err != nil {
+			return nil, err
+		}
+
+		g.geometryType = GeometryTypeLineString
+		g.addLines(g.table, [][][]float64{coords})
+
+	case []s2.Point:
+		coords := make([][]float64, len(geometry))
+		for i, point := range geometry {
+			pointCoords, err := geoutil.PointCoordinates(point, precision)
+			if err != nil {
+				return nil, err
+			}
+
+			coords[i] = pointCoords
+		}
+
+		g.geometryType = GeometryTypeMultiPoint
+		if len(coords) > 0 {
+			g.table.addObject(geometryXY, fbFloat64s(g.addXY(coords)))
+		}
+
+	case []*s2.Polyline:
+		lines := make([][][]float64, len(geometry))
+		for i, polyline := range geometry {
+			coords, err := geoutil.PolylineCoordinates(polyline, precision)
+			if err != nil {
+				return nil, err
+			}
+
+			lines[i] = coords
+		}
+
+		g.geometryType = GeometryTypeMultiLineString
+		g.addLines(g.table, lines)
+
+	case *s2.Polygon:
+		polygonCoordinates, err := geoutil.PolygonCoordinates(geometry, precision)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(polygonCoordinates) == 1 {
+			g.geometryType = GeometryTypePolygon
+			g.addLines(g.table, polygonCoordinates[0])
+			break
+		}
+
+		g.geometryType = GeometryTypeMultiPolygon
+		parts := make(fbTables, len(polygonCoordinates))
+		for i, coords := range polygonCoordinates {
+			parts[i] = &fbTable{}
+			parts[i].addUint8(geometryType, uint8(GeometryTypePolygon))
+			g.addLines(parts[i], coords)
+		}
+
+		if len(parts) > 0 {
+			g.table.addObject(geometryParts, parts)
+		}
+
+	default:
+		return nil, fmt.Errorf("flatgeobuf: unknown geometry type %T", geometry)
+	}
+
+	g.table.addUint8(geometryType, uint8(g.geometryType))
+	return g, nil
+}
+
+// Writer writes features to a FlatGeobuf dataset. Building the spatial index
+// requires every feature to be known before the first byte of the index is
+// written, so features are buffered in their encoded form until Close.
+type Writer struct {
+	w             io.Writer
+	header        Header
+	columnIndexes map[string]int
+	features      [][]byte
+	bounds        []nodeItem
+	geometryType  GeometryType
+	closed        bool
+}
+
+// NewWriter returns a Writer for a dataset described by header. The columns of
+// header are extended with any property encountered while writing, and the
+// geometry type, envelope and feature count are computed from the features.
+// Set header.IndexNodeSize to DefaultIndexNodeSize to build the spatial index.
+func NewWriter(w io.Writer, header *Header) *Writer {
+	fw := &Writer{
+		w:             w,
+		header:        *header,
+		columnIndexes: map[string]int{},
+	}
+
+	fw.header.Columns = append([]Column{}, header.Columns...)
+	for i, column := range fw.header.Columns {
+		fw.columnIndexes[column.Name] = i
+	}
+
+	return fw
+}
+
+func (fw *Writer) encodeProperties(properties map[string]interface{}) ([]byte, error) {
+
+	// Register any new columns in a deterministic order.
+	names := []string{}
+	for name, v := range properties {
+		if _, ok := fw.columnIndexes[name]; !ok && v != nil {
+			names = append(names, name)
+		}
+	}
+
+	sort.Strings(names)
+	for _, name := range names {
+		fw.columnIndexes[name] = len(fw.header.Columns)
+		fw.header.Columns = append(fw.header.Columns, Column{
+			Name: name,
+			Type: inferColumnType(properties[name]),
+		})
+	}
+
+	buf := []byte{}
+	for i, column := range fw.header.Columns {
+		v, ok := properties[column.Name]
+		if !ok || v == nil {
+			continue
+		}
+
+		var index [2]byte
+		binary.LittleEndian.PutUint16(index[:], uint16(i))
+		buf = append(buf, index[:]...)
+
+		var err error
+		if buf, err = appendProperty(buf, column, v); err != nil {
+			return nil, err
+		}
+	}
+
+	return buf, nil
+}
+
+// Write adds a feature to the dataset. Feature IDs are not part of the
+// FlatGeobuf model and are dropped.
+func (fw *Writer) Write(f *geojson.Feature) error {
+	if fw.closed {
+		return fmt.Errorf("flatgeobuf: write to closed Writer")
+	}
+
+	if len(fw.header.Columns) >= math.MaxUint16 {
+		return fmt.Errorf("flatgeobuf: too many columns")
+	}
+
+	table := &fbTable{}
+	bounds := emptyNodeItem()
+	if f.Geometry != nil {
+		g, err := encodeGeometry(f.Geometry, f.Precision)
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case len(fw.features) == 0:
+			fw.geometryType = g.geometryType
+		case fw.geometryType != g.geometryType:
+			fw.geometryType = GeometryTypeUnknown
+		}
+
+		table.addObject(featureGeometry, g.table)
+		bounds = g.bounds
+	}
+
+	properties, err := fw.encodeProperties(f.Properties)
+	if err != nil {
+		return err
+	}
+
+	if len(properties) > 0 {
+		table.addObject(featureProperties, fbBytes(properties))
+	}
+
+	fw.features = append(fw.features, fbFinish(table))
+	fw.bounds = append(fw.bounds, bounds)
+	return nil
+}
+
+func encodeHeader(header *Header) []byte {
+	table := &fbTable{}
+	table.addString(headerName, header.Name)
+	if len(header.Envelope) > 0 {
+		table.addObject(headerEnvelope, fbFloat64s(header.Envelope))
+	}
+
+	table.addUint8(headerGeometryType, uint8(header.GeometryType))
+	table.addBool(headerHasZ, header.HasZ)
+	table.addBool(headerHasM, header.HasM)
+	table.addBool(headerHasT, header.HasT)
+	table.addBool(headerHasTM, header.HasTM)
+	if len(header.Columns) > 0 {
+		columns := make(fbTables, len(header.Columns))
+		for i, column := range header.Columns {
+			columns[i] = &fbTable{}
+			columns[i].addString(columnName, column.Name)
+			columns[i].addUint8(columnType, uint8(column.Type))
+			columns[i].addString(columnTitle, column.Title)
+			columns[i].addString(columnDescription, column.Description)
+		}
+
+		table.addObject(headerColumns, columns)
+	}
+
+	table.addUint64(headerFeaturesCount, header.FeaturesCount)
+
+	// The schema default is 16, so a disabled index must be explicit.
+	table.addUint16(headerIndexNodeSize, header.IndexNodeSize)
+	if crs := header.CRS; crs != nil {
+		crsTable := &fbTable{}
+		crsTable.addString(crsOrg, crs.Org)
+		crsTable.addInt32(crsCode, crs.Code)
+		crsTable.addString(crsName, crs.Name)
+		crsTable.addString(crsDescription, crs.Description)
+		crsTable.addString(crsWKT, crs.WKT)
+		crsTable.addString(crsCodeString, crs.CodeString)
+		table.addObject(headerCRS, crsTable)
+	}
+
+	table.addString(headerTitle, header.Title)
+	table.addString(headerDescription, header.Description)
+	table.addString(headerMetadata, header.Metadata)
+	return fbFinish(table)
+}
+
+// Close writes the header, the spatial index and the buffered features. It
+// does not close the underlying writer.
+func (fw *Writer) Close() error {
+	if fw.closed {
+		return nil
+	}
+
+	fw.closed = true
+
+	header := &fw.header
+	header.GeometryType = fw.geometryType
+	header.FeaturesCount = uint64(len(fw.features))
+	if header.CRS == nil {
+		header.CRS = &CRS{
+			Org:  "EPSG",
+			Code: 4326,
+		}
+	}
+
+	extent := emptyNodeItem()
+	for _, bounds := range fw.bounds {
+		extent.expand(bounds)
+	}
+
+	header.Envelope = nil
+	if !extent.isEmpty() {
+		header.Envelope = []float64{extent.minX, extent.minY, extent.maxX, extent.maxY}
+	}
+
+	order := make([]int, len(fw.features))
+	for i := range order {
+		order[i] = i
+	}
+
+	var index []nodeItem
+	if header.IndexNodeSize > 0 && len(fw.features) > 0 {
+		header.IndexNodeSize = clampNodeSize(header.IndexNodeSize)
+		order = hilbertOrder(fw.bounds, extent)
+
+		offset := uint64(0)
+		leaves := make([]nodeItem, len(order))
+		for i, j := range order {
+			leaves[i] = fw.bounds[j]
+			leaves[i].offset = offset
+			offset += uint64(4 + len(fw.features[j]))
+		}
+
+		index = buildIndex(leaves, header.IndexNodeSize)
+	}
+
+	if _, err := fw.w.Write(magicBytes[:]); err != nil {
+		return err
+	}
+
+	if _, err := fw.w.Write(appendLengthPrefixed(nil, encodeHeader(header))); err != nil {
+		return err
+	}
+
+	if len(index) > 0 {
+		buf := make([]byte, nodeItemSize*len(index))
+		for i, node := range index {
+			node.encode(buf[nodeItemSize*i:])
+		}
+
+		if _, err := fw.w.Write(buf); err != nil {
+			return err
+		}
+	}
+
+	for _, i := range order {
+		if _, err := fw.w.Write(appendLengthPrefixed(nil, fw.features[i])); err != nil {
+			return err
+		}
+	}
+
+	fw.features = nil
+	fw.bounds = nil
+	return nil
+}
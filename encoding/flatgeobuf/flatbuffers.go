@@ -0,0 +1,419 @@
+package flatgeobuf
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"sort"
+)
+
+// The FlatBuffers support below covers only what the FlatGeobuf schema needs:
+// tables of scalars, strings, scalar vectors and vectors of tables.
+
+// fbBuilder serializes FlatBuffers front to back. Unlike the reference builder,
+// children are written after the tables that reference them, which keeps every
+// unsigned offset pointing forward without a second pass.
+type fbBuilder struct {
+	buf []byte
+}
+
+func (b *fbBuilder) align(n int) {
+	for len(b.buf)%n != 0 {
+		b.buf = append(b.buf, 0)
+	}
+}
+
+func (b *fbBuilder) appendUint32(v uint32) {
+	b.buf = append(b.buf, 0, 0, 0, 0)
+	binary.LittleEndian.PutUint32(b.buf[len(b.buf)-4:], v)
+}
+
+// alignVector pads the buffer so that the elements following the vector
+// length are aligned to elementSize.
+func (b *fbBuilder) alignVector(elementSize int) {
+	b.align(4)
+	for (len(b.buf)+4)%elementSize != 0 {
+		b.buf = append(b.buf, 0)
+	}
+}
+
+func (b *fbBuilder) patchOffset(at, target int) {
+	binary.LittleEndian.PutUint32(b.buf[at:], uint32(target-at))
+}
+
+type fbObject interface {
+	build(b *fbBuilder) int
+}
+
+type fbString string
+
+func (s fbString) build(b *fbBuilder) int {
+	b.align(4)
+	pos := len(b.buf)
+	b.appendUint32(uint32(len(s)))
+	b.buf = append(b.buf, s...)
+	b.buf = append(b.buf, 0)
+	return pos
+}
+
+type fbBytes []byte
+
+func (v fbBytes) build(b *fbBuilder) int {
+	b.align(4)
+	pos := len(b.buf)
+	b.appendUint32(uint32(len(v)))
+	b.buf = append(b.buf, v...)
+	return pos
+}
+
+type fbUint32s []uint32
+
+func (v fbUint32s) build(b *fbBuilder) int {
+	b.align(4)
+	pos := len(b.buf)
+	b.appendUint32(uint32(len(v)))
+	for _, e := range v {
+		b.appendUint32(e)
+	}
+
+	return pos
+}
+
+type fbFloat64s []float64
+
+func (v fbFloat64s) build(b *fbBuilder) int {
+	b.alignVector(8)
+	pos := len(b.buf)
+	b.appendUint32(uint32(len(v)))
+	for _, e := range v {
+		b.buf = append(b.buf, 0, 0, 0, 0, 0, 0, 0, 0)
+		binary.LittleEndian.PutUint64(b.buf[len(b.buf)-8:], math.Float64bits(e))
+	}
+
+	return pos
+}
+
+type fbTables []*fbTable
+
+func (v fbTables) build(b *fbBuilder) int {
+	b.align(4)
+	pos := len(b.buf)
+	b.appendUint32(uint32(len(v)))
+	offsets := len(b.buf)
+	for range v {
+		b.appendUint32(0)
+	}
+
+	for i, t := range v {
+		b.patchOffset(offsets+4*i, t.build(b))
+	}
+
+	return pos
+}
+
+type fbField struct {
+	slot   int
+	size   int
+	bits   uint64
+	object fbObject
+}
+
+type fbTable struct {
+	fields []fbField
+}
+
+func (t *fbTable) addScalar(slot, size int, bits uint64) {
+	t.fields = append(t.fields, fbField{slot: slot, size: size, bits: bits})
+}
+
+func (t *fbTable) addUint8(slot int, v uint8) {
+	t.addScalar(slot, 1, uint64(v))
+}
+
+func (t *fbTable) addBool(slot int, v bool) {
+	if v {
+		t.addScalar(slot, 1, 1)
+	}
+}
+
+func (t *fbTable) addUint16(slot int, v uint16) {
+	t.addScalar(slot, 2, uint64(v))
+}
+
+func (t *fbTable) addInt32(slot int, v int32) {
+	t.addScalar(slot, 4, uint64(uint32(v)))
+}
+
+func (t *fbTable) addUint64(slot int, v uint64) {
+	t.addScalar(slot, 8, v)
+}
+
+func (t *fbTable) addString(slot int, s string) {
+	if s != "" {
+		t.addObject(slot, fbString(s))
+	}
+}
+
+func (t *fbTable) addObject(slot int, object fbObject) {
+	t.fields = append(t.fields, fbField{slot: slot, size: 4, object: object})
+}
+
+func (t *fbTable) build(b *fbBuilder) int {
+	numSlots := 0
+	for _, field := range t.fields {
+		if field.slot >= numSlots {
+			numSlots = field.slot + 1
+		}
+	}
+
+	// Lay out the inline fields largest first so that each one is naturally
+	// aligned relative to the 8-byte aligned table start.
+	order := make([]int, len(t.fields))
+	for i := range order {
+		order[i] = i
+	}
+
+	sort.SliceStable(order, func(i, j int) bool {
+		return t.fields[order[i]].size > t.fields[order[j]].size
+	})
+
+	fieldOffsets := make([]int, len(t.fields))
+	size := 4
+	for _, i := range order {
+		for size%t.fields[i].size != 0 {
+			size++
+		}
+
+		fieldOffsets[i] = size
+		size += t.fields[i].size
+	}
+
+	// The vtable precedes the table.
+	b.align(2)
+	vtable := len(b.buf)
+	b.buf = append(b.buf, make([]byte, 4+2*numSlots)...)
+	binary.LittleEndian.PutUint16(b.buf[vtable:], uint16(4+2*numSlots))
+	binary.LittleEndian.PutUint16(b.buf[vtable+2:], uint16(size))
+	for i, field := range t.fields {
+		binary.LittleEndian.PutUint16(b.buf[vtable+4+2*field.slot:], uint16(fieldOffsets[i]))
+	}
+
+	b.align(8)
+	pos := len(b.buf)
+	b.buf = append(b.buf, make([]byte, size)...)
+	binary.LittleEndian.PutUint32(b.buf[pos:], uint32(int32(pos-vtable)))
+	for i, field := range t.fields {
+		at := b.buf[pos+fieldOffsets[i]:]
+		switch field.size {
+		case 1:
+			at[0] = byte(field.bits)
+		case 2:
+			binary.LittleEndian.PutUint16(at, uint16(field.bits))
+		case 4:
+			binary.LittleEndian.PutUint32(at, uint32(field.bits))
+		case 8:
+			binary.LittleEndian.PutUint64(at, field.bits)
+		}
+	}
+
+	for i, field := range t.fields {
+		if field.object != nil {
+			b.patchOffset(pos+fieldOffsets[i], field.object.build(b))
+		}
+	}
+
+	return pos
+}
+
+func fbFinish(root *fbTable) []byte {
+	b := &fbBuilder{
+		buf: make([]byte, 4),
+	}
+
+	b.patchOffset(0, root.build(b))
+	return b.buf
+}
+
+var errFlatBufferBounds = errors.New("flatgeobuf: flatbuffer offset out of bounds")
+
+// fbError is used to unwind out of a malformed buffer. It is recovered by
+// fbRecover at the decoding entry points.
+type fbError struct {
+	err error
+}
+
+func fbRecover(err *error) {
+	if r := recover(); r != nil {
+		e, ok := r.(fbError)
+		if !ok {
+			panic(r)
+		}
+
+		*err = e.err
+	}
+}
+
+type fbTableReader struct {
+	buf    []byte
+	pos    int
+	vtable int
+}
+
+func fbCheck(buf []byte, pos, n int) {
+	if pos < 0 || n < 0 || pos > len(buf) || len(buf)-pos < n {
+		panic(fbError{errFlatBufferBounds})
+	}
+}
+
+func fbUint32(buf []byte, pos int) int {
+	fbCheck(buf, pos, 4)
+	return int(binary.LittleEndian.Uint32(buf[pos:]))
+}
+
+func fbTableAt(buf []byte, pos int) fbTableReader {
+	fbCheck(buf, pos, 4)
+	vtable := pos - int(int32(binary.LittleEndian.Uint32(buf[pos:])))
+	fbCheck(buf, vtable, 4)
+	return fbTableReader{
+		buf:    buf,
+		pos:    pos,
+		vtable: vtable,
+	}
+}
+
+func fbRoot(buf []byte) fbTableReader {
+	return fbTableAt(buf, fbUint32(buf, 0))
+}
+
+// field returns the absolute position of a field, or zero if it is absent.
+func (t fbTableReader) field(slot int) int {
+	entry := 4 + 2*slot
+	if entry+2 > int(binary.LittleEndian.Uint16(t.buf[t.vtable:])) {
+		return 0
+	}
+
+	fbCheck(t.buf, t.vtable+entry, 2)
+	offset := int(binary.LittleEndian.Uint16(t.buf[t.vtable+entry:]))
+	if offset == 0 {
+		return 0
+	}
+
+	return t.pos + offset
+}
+
+func (t fbTableReader) uint8(slot int, def uint8) uint8 {
+	pos := t.field(slot)
+	if pos == 0 {
+		return def
+	}
+
+	fbCheck(t.buf, pos, 1)
+	return t.buf[pos]
+}
+
+func (t fbTableReader) bool(slot int) bool {
+	return t.uint8(slot, 0) != 0
+}
+
+func (t fbTableReader) uint16(slot int, def uint16) uint16 {
+	pos := t.field(slot)
+	if pos == 0 {
+		return def
+	}
+
+	fbCheck(t.buf, pos, 2)
+	return binary.LittleEndian.Uint16(t.buf[pos:])
+}
+
+func (t fbTableReader) int32(slot int, def int32) int32 {
+	pos := t.field(slot)
+	if pos == 0 {
+		return def
+	}
+
+	fbCheck(t.buf, pos, 4)
+	return int32(binary.LittleEndian.Uint32(t.buf[pos:]))
+}
+
+func (t fbTableReader) uint64(slot int, def uint64) uint64 {
+	pos := t.field(slot)
+	if pos == 0 {
+		return def
+	}
+
+	fbCheck(t.buf, pos, 8)
+	return binary.LittleEndian.Uint64(t.buf[pos:])
+}
+
+// indirect follows the offset stored in a field and returns the absolute
+// position of the referenced object, or zero if the field is absent.
+func (t fbTableReader) indirect(slot int) int {
+	pos := t.field(slot)
+	if pos == 0 {
+		return 0
+	}
+
+	return pos + fbUint32(t.buf, pos)
+}
+
+func (t fbTableReader) table(slot int) (fbTableReader, bool) {
+	pos := t.indirect(slot)
+	if pos == 0 {
+		return fbTableReader{}, false
+	}
+
+	return fbTableAt(t.buf, pos), true
+}
+
+// vector returns the position of the first element of a vector and its
+// length, checking that elementSize*length bytes are available.
+func (t fbTableReader) vector(slot, elementSize int) (int, int) {
+	pos := t.indirect(slot)
+	if pos == 0 {
+		return 0, 0
+	}
+
+	n := fbUint32(t.buf, pos)
+	fbCheck(t.buf, pos+4, n*elementSize)
+	return pos + 4, n
+}
+
+func (t fbTableReader) bytes(slot int) []byte {
+	start, n := t.vector(slot, 1)
+	return t.buf[start : start+n]
+}
+
+func (t fbTableReader) string(slot int) string {
+	return string(t.bytes(slot))
+}
+
+func (t fbTableReader) uint32s(slot int) []uint32 {
+	start, n := t.vector(slot, 4)
+	v := make([]uint32, n)
+	for i := range v {
+		v[i] = binary.LittleEndian.Uint32(t.buf[start+4*i:])
+	}
+
+	return v
+}
+
+func (t fbTableReader) float64s(slot int) []float64 {
+	start, n := t.vector(slot, 8)
+	v := make([]float64, n)
+	for i := range v {
+		v[i] = math.Float64frombits(binary.LittleEndian.Uint64(t.buf[start+8*i:]))
+	}
+
+	return v
+}
+
+func (t fbTableReader) tables(slot int) []fbTableReader {
+	start, n := t.vector(slot, 4)
+	v := make([]fbTableReader, n)
+	for i := range v {
+		pos := start + 4*i
+		v[i] = fbTableAt(t.buf, pos+fbUint32(t.buf, pos))
+	}
+
+	return v
+}
@@ -0,0 +1,134 @@
+package flatgeobuf
+
+var magicBytes = [8]byte{0x66, 0x67, 0x62, 0x03, 0x66, 0x67, 0x62, 0x00}
+
+// DefaultIndexNodeSize is the node size of the packed Hilbert R-tree used by
+// the reference implementations.
+const DefaultIndexNodeSize = 16
+
+// GeometryType is the FlatGeobuf geometry type enumeration.
+type GeometryType uint8
+
+const (
+	GeometryTypeUnknown            GeometryType = 0
+	GeometryTypePoint              GeometryType = 1
+	GeometryTypeLineString         GeometryType = 2
+	GeometryTypePolygon            GeometryType = 3
+	GeometryTypeMultiPoint         GeometryType = 4
+	GeometryTypeMultiLineString    GeometryType = 5
+	GeometryTypeMultiPolygon       GeometryType = 6
+	GeometryTypeGeometryCollection GeometryType = 7
+)
+
+// ColumnType is the FlatGeobuf attribute column type enumeration.
+type ColumnType uint8
+
+const (
+	ColumnTypeByte     ColumnType = 0
+	ColumnTypeUByte    ColumnType = 1
+	ColumnTypeBool     ColumnType = 2
+	ColumnTypeShort    ColumnType = 3
+	ColumnTypeUShort   ColumnType = 4
+	ColumnTypeInt      ColumnType = 5
+	ColumnTypeUInt     ColumnType = 6
+	ColumnTypeLong     ColumnType = 7
+	ColumnTypeULong    ColumnType = 8
+	ColumnTypeFloat    ColumnType = 9
+	ColumnTypeDouble   ColumnType = 10
+	ColumnTypeString   ColumnType = 11
+	ColumnTypeJSON     ColumnType = 12
+	ColumnTypeDateTime ColumnType = 13
+	ColumnTypeBinary   ColumnType = 14
+)
+
+// Column describes an attribute column of a FlatGeobuf dataset.
+type Column struct {
+	Name        string
+	Type        ColumnType
+	Title       string
+	Description string
+}
+
+// CRS describes the coordinate reference system of a FlatGeobuf dataset.
+type CRS struct {
+	Org         string
+	Code        int32
+	Name        string
+	Description string
+	WKT         string
+	CodeString  string
+}
+
+// Header represents the FlatGeobuf header. An IndexNodeSize of zero means the
+// dataset does not carry a spatial index.
+type Header struct {
+	Name          string
+	Envelope      []float64
+	GeometryType  GeometryType
+	HasZ          bool
+	HasM          bool
+	HasT          bool
+	HasTM         bool
+	Columns       []Column
+	FeaturesCount uint64
+	IndexNodeSize uint16
+	CRS           *CRS
+	Title         string
+	Description   string
+	Metadata      string
+}
+
+// Slots of the Header table.
+const (
+	headerName = iota
+	headerEnvelope
+	headerGeometryType
+	headerHasZ
+	headerHasM
+	headerHasT
+	headerHasTM
+	headerColumns
+	headerFeaturesCount
+	headerIndexNodeSize
+	headerCRS
+	headerTitle
+	headerDescription
+	headerMetadata
+)
+
+// Slots of the Column table.
+const (
+	columnName = iota
+	columnType
+	columnTitle
+	columnDescription
+)
+
+// Slots of the Crs table.
+const (
+	crsOrg = iota
+	crsCode
+	crsName
+	crsDescription
+	crsWKT
+	crsCodeString
+)
+
+// Slots of the Geometry table.
+const (
+	geometryEnds = iota
+	geometryXY
+	geometryZ
+	geometryM
+	geometryT
+	geometryTM
+	geometryType
+	geometryParts
+)
+
+// Slots of the Feature table.
+const (
+	featureGeometry = iota
+	featureProperties
+	featureColumns
+)
@@ -0,0 +1,457 @@
+package flatgeobuf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"math"
+	"reflect"
+	"runtime"
+	"sort"
+	"strconv"
+	"testing"
+
+	"github.com/golang/geo/s2"
+
+	"github.com/topos-ai/geoutil"
+	"github.com/topos-ai/geoutil/encoding/geojson"
+)
+
+// readerOnly hides the Seek method of a reader, so that filtered reads go
+// through the stream.
+type readerOnly struct {
+	io.Reader
+}
+
+func readAll(t *testing.T, r io.Reader, filter *s2.Rect) (*Header, []*geojson.Feature) {
+	t.Helper()
+	fr, err := NewReader(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if filter != nil {
+		if err := fr.Filter(*filter); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	features := []*geojson.Feature{}
+	for {
+		f, err := fr.Read()
+		if err == io.EOF {
+			return fr.Header(), features
+		}
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		features = append(features, f)
+	}
+}
+
+func readFixture(t *testing.T, name string) []byte {
+	t.Helper()
+	data, err := ioutil.ReadFile("testdata/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return data
+}
+
+func names(features []*geojson.Feature) []string {
+	names := []string{}
+	for _, f := range features {
+		names = append(names, f.Properties["name"].(string))
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+func rectFromDegrees(south, west, north, east float64) s2.Rect {
+	return s2.RectFromLatLng(s2.LatLngFromDegrees(south, west)).AddPoint(s2.LatLngFromDegrees(north, east))
+}
+
+// The fixtures were written from the FlatGeobuf schema independently of this
+// package. regions.fgb holds 20 polygons of 4° squares at (-170+17i,
+// -60+6i) with a spatial index of node size 16, the squares of multiples of
+// 5 having a hole and those of i%7 == 3 a second 2° square 5° east of them.
+// stops.fgb holds 5 points without an index.
+
+func TestReadFixture(t *testing.T) {
+	header, features := readAll(t, bytes.NewReader(readFixture(t, "regions.fgb")), nil)
+	if header.Name != "regions" || header.FeaturesCount != 20 || header.IndexNodeSize != 16 {
+		t.Fatalf("unexpected header %+v", header)
+	}
+
+	if header.CRS == nil || header.CRS.Org != "EPSG" || header.CRS.Code != 4326 {
+		t.Errorf("unexpected CRS %+v", header.CRS)
+	}
+
+	if want := []float64{-170, -60, 157, 58}; !reflect.DeepEqual(header.Envelope, want) {
+		t.Errorf("envelope %v, want %v", header.Envelope, want)
+	}
+
+	if len(features) != 20 {
+		t.Fatalf("read %d features, want 20", len(features))
+	}
+
+	for _, f := range features {
+		i := int(f.Properties["population"].(int64)-7) / 1000
+		if name := f.Properties["name"]; name != "region "+strconv.Itoa(i) {
+			t.Errorf("feature %d has name %v", i, name)
+		}
+
+		if density := f.Properties["density"].(float64); density != float64(i)/4 {
+			t.Errorf("feature %d has density %v", i, density)
+		}
+
+		if coastal := f.Properties["coastal"].(bool); coastal != (i%2 == 0) {
+			t.Errorf("feature %d has coastal %v", i, coastal)
+		}
+
+		polygon := f.Geometry.(*s2.Polygon)
+		wantLoops := 1
+		if i%5 == 0 {
+			wantLoops++
+		}
+
+		if i%7 == 3 {
+			wantLoops++
+		}
+
+		if polygon.NumLoops() != wantLoops {
+			t.Errorf("feature %d has %d loops, want %d", i, polygon.NumLoops(), wantLoops)
+		}
+
+		x, y := -170+17*float64(i), -60+6*float64(i)
+		inside := s2.PointFromLatLng(s2.LatLngFromDegrees(y+0.5, x+0.5))
+		if !polygon.ContainsPoint(inside) {
+			t.Errorf("feature %d does not contain its corner", i)
+		}
+
+		hole := s2.PointFromLatLng(s2.LatLngFromDegrees(y+2, x+2))
+		if polygon.ContainsPoint(hole) != (i%5 != 0) {
+			t.Errorf("feature %d contains its center: %v", i, polygon.ContainsPoint(hole))
+		}
+	}
+}
+
+func TestReadFixtureFilter(t *testing.T) {
+	data := readFixture(t, "regions.fgb")
+	tests := []struct {
+		name string
+		rect s2.Rect
+		want []string
+	}{
+		{"one", rectFromDegrees(-35, -101, -34, -100), []string{"region 4"}},
+		{"two", rectFromDegrees(-60, -170, -48, -140), []string{"region 0", "region 1"}},
+		{"second part", rectFromDegrees(-42, -113, -41, -112), []string{"region 3"}},
+		{"antimeridian", s2.RectFromLatLng(s2.LatLngFromDegrees(-60, 150)).AddPoint(s2.LatLngFromDegrees(60, -165)), []string{"region 0", "region 19"}},
+		{"none", rectFromDegrees(70, 0, 80, 10), []string{}},
+	}
+
+	for _, test := range tests {
+		for _, r := range []io.Reader{bytes.NewReader(data), readerOnly{bytes.NewReader(data)}} {
+			_, features := readAll(t, r, &test.rect)
+			if got := names(features); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("%s: filtered %v, want %v", test.name, got, test.want)
+			}
+		}
+	}
+}
+
+func TestReadFixtureWithoutIndex(t *testing.T) {
+	data := readFixture(t, "stops.fgb")
+	header, features := readAll(t, bytes.NewReader(data), nil)
+	if header.GeometryType != GeometryTypePoint || header.IndexNodeSize != 0 || len(features) != 5 {
+		t.Fatalf("unexpected header %+v with %d features", header, len(features))
+	}
+
+	for i, f := range features {
+		coords, err := geoutil.PointCoordinates(f.Geometry.(s2.Point), geoutil.PrecisionE6)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if want := []float64{-122.5 + 0.25*float64(i), 37.5 + 0.125*float64(i)}; !reflect.DeepEqual(coords, want) {
+			t.Errorf("point %d is %v, want %v", i, coords, want)
+		}
+
+		if len(f.Properties) != 1 {
+			t.Errorf("point %d has properties %v", i, f.Properties)
+		}
+	}
+
+	rect := rectFromDegrees(37.6, -122.3, 37.8, -121.9)
+	_, features = readAll(t, bytes.NewReader(data), &rect)
+	if got, want := names(features), []string{"stop 1", "stop 2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("filtered %v, want %v", got, want)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	polyline, _ := geoutil.PolylineFromLineStringCoordinates([][]float64{{0, 0}, {1, 1}, {2, 0}})
+	polylines, _ := geoutil.PolylinesFromMultiLineStringCoordinates([][][]float64{{{10, 10}, {11, 11}}, {{12, 12}, {13, 12}}})
+	points, _ := geoutil.PointsFromMultiPointCoordinates([][]float64{{-20, 5}, {-21, 6}})
+	polygon, _ := geoutil.PolygonFromMultiPolygonCoordinates([][][][]float64{
+		{{{30, 30}, {34, 30}, {34, 34}, {30, 34}, {30, 30}}, {{31, 31}, {31, 33}, {33, 33}, {33, 31}, {31, 31}}},
+		{{{40, 30}, {41, 30}, {41, 31}, {40, 31}, {40, 30}}},
+	})
+
+	features := []*geojson.Feature{
+		{Geometry: s2.PointFromLatLng(s2.LatLngFromDegrees(45, -70)), Properties: map[string]interface{}{"name": "point", "rank": int64(1)}},
+		{Geometry: polyline, Properties: map[string]interface{}{"name": "polyline", "length": 2.5}},
+		{Geometry: polylines, Properties: map[string]interface{}{"name": "polylines", "open": true}},
+		{Geometry: points, Properties: map[string]interface{}{"name": "points"}},
+		{Geometry: polygon, Properties: map[string]interface{}{"name": "polygon", "tags": map[string]interface{}{"a": "b"}}},
+	}
+
+	for _, nodeSize := range []uint16{0, 2, DefaultIndexNodeSize} {
+		buf := &bytes.Buffer{}
+		w := NewWriter(buf, &Header{Name: "mixed", IndexNodeSize: nodeSize})
+		for _, f := range features {
+			if err := w.Write(f); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		header, read := readAll(t, bytes.NewReader(buf.Bytes()), nil)
+		if header.GeometryType != GeometryTypeUnknown || header.FeaturesCount != uint64(len(features)) {
+			t.Fatalf("unexpected header %+v", header)
+		}
+
+		if len(read) != len(features) {
+			t.Fatalf("read %d features, want %d", len(read), len(features))
+		}
+
+		byName := map[string]*geojson.Feature{}
+		for _, f := range read {
+			byName[f.Properties["name"].(string)] = f
+		}
+
+		for _, want := range features {
+			got := byName[want.Properties["name"].(string)]
+			if got == nil {
+				t.Fatalf("missing feature %v", want.Properties["name"])
+			}
+
+			if !reflect.DeepEqual(got.Properties, want.Properties) {
+				t.Errorf("properties %v, want %v", got.Properties, want.Properties)
+			}
+
+			if got, want := coordinates(t, got.Geometry), coordinates(t, want.Geometry); !reflect.DeepEqual(got, want) {
+				t.Errorf("node size %d: coordinates %v, want %v", nodeSize, got, want)
+			}
+		}
+	}
+}
+
+// coordinates returns the coordinates of a geometry rounded to microdegrees.
+func coordinates(t *testing.T, geometry interface{}) interface{} {
+	t.Helper()
+	var coords interface{}
+	var err error
+	switch geometry := geometry.(type) {
+	case s2.Point:
+		coords, err = geoutil.PointCoordinates(geometry, geoutil.PrecisionE6)
+	case []s2.Point:
+		points := [][]float64{}
+		for _, point := range geometry {
+			pointCoords, _ := geoutil.PointCoordinates(point, geoutil.PrecisionE6)
+			points = append(points, pointCoords)
+		}
+
+		coords = points
+	case *s2.Polyline:
+		coords, err = geoutil.PolylineCoordinates(geometry, geoutil.PrecisionE6)
+	case []*s2.Polyline:
+		polylines := [][][]float64{}
+		for _, polyline := range geometry {
+			lineStringCoords, _ := geoutil.PolylineCoordinates(polyline, geoutil.PrecisionE6)
+			polylines = append(polylines, lineStringCoords)
+		}
+
+		coords = polylines
+	case *s2.Polygon:
+		coords, err = geoutil.PolygonCoordinates(geometry, geoutil.PrecisionE6)
+	default:
+		t.Fatalf("unexpected geometry type %T", geometry)
+	}
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return coords
+}
+
+func TestFilterMatchesBruteForce(t *testing.T) {
+	buf := &bytes.Buffer{}
+	w := NewWriter(buf, &Header{IndexNodeSize: 4})
+	for lat := -80; lat <= 80; lat += 10 {
+		for lng := -175; lng <= 175; lng += 10 {
+			f := &geojson.Feature{
+				Geometry:   s2.PointFromLatLng(s2.LatLngFromDegrees(float64(lat), float64(lng))),
+				Properties: map[string]interface{}{"name": strconv.Itoa(lat+90) + "/" + strconv.Itoa(lng+180)},
+			}
+
+			if err := w.Write(f); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	_, all := readAll(t, bytes.NewReader(buf.Bytes()), nil)
+	rects := []s2.Rect{
+		rectFromDegrees(-33, -40, 12, 60),
+		rectFromDegrees(0, 0, 0.5, 0.5),
+		s2.RectFromLatLng(s2.LatLngFromDegrees(-5, 160)).AddPoint(s2.LatLngFromDegrees(25, -150)),
+		s2.FullRect(),
+	}
+
+	for _, rect := range rects {
+		want := []*geojson.Feature{}
+		for _, f := range all {
+			if rect.ContainsPoint(f.Geometry.(s2.Point)) {
+				want = append(want, f)
+			}
+		}
+
+		for _, r := range []io.Reader{bytes.NewReader(buf.Bytes()), readerOnly{bytes.NewReader(buf.Bytes())}} {
+			_, got := readAll(t, r, &rect)
+			if !reflect.DeepEqual(names(got), names(want)) {
+				t.Errorf("filter %v: got %v, want %v", rect, names(got), names(want))
+			}
+		}
+	}
+}
+
+func TestFilterAfterRead(t *testing.T) {
+	fr, err := NewReader(bytes.NewReader(readFixture(t, "stops.fgb")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := fr.Read(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := fr.Filter(s2.FullRect()); err != errFilterAfterRead {
+		t.Errorf("Filter after Read returned %v", err)
+	}
+}
+
+func TestReadNonFiniteCoordinates(t *testing.T) {
+	tests := []struct {
+		fixture string
+		x       float64
+	}{
+		{"stops.fgb", -122.5},
+		{"regions.fgb", -170},
+	}
+
+	for _, test := range tests {
+		for _, v := range []float64{math.NaN(), math.Inf(1)} {
+			data := readFixture(t, test.fixture)
+			var x [8]byte
+			binary.LittleEndian.PutUint64(x[:], math.Float64bits(test.x))
+			i := bytes.LastIndex(data, x[:])
+			if i < 0 {
+				t.Fatal("coordinate not found")
+			}
+
+			binary.LittleEndian.PutUint64(data[i:], math.Float64bits(v))
+			fr, err := NewReader(bytes.NewReader(data))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for err == nil {
+				_, err = fr.Read()
+			}
+
+			if err == io.EOF {
+				t.Errorf("%s: reading coordinate %v returned no error", test.fixture, v)
+			}
+		}
+	}
+}
+
+func TestReadShortRings(t *testing.T) {
+	var ends [12]byte
+	binary.LittleEndian.PutUint32(ends[:], 2)
+	binary.LittleEndian.PutUint32(ends[4:], 5)
+	binary.LittleEndian.PutUint32(ends[8:], 10)
+
+	// The shells of the squares with a hole end at 5 of 10 positions. Moving
+	// that end leaves an empty shell or a hole of 3 positions.
+	for _, end := range []uint32{0, 7} {
+		data := readFixture(t, "regions.fgb")
+		i := bytes.Index(data, ends[:])
+		if i < 0 {
+			t.Fatal("ends not found")
+		}
+
+		binary.LittleEndian.PutUint32(data[i+4:], end)
+		fr, err := NewReader(bytes.NewReader(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for err == nil {
+			_, err = fr.Read()
+		}
+
+		if err == io.EOF {
+			t.Errorf("reading a ring ending at %d returned no error", end)
+		}
+	}
+}
+
+func TestReadTruncated(t *testing.T) {
+	data := append([]byte{}, magicBytes[:]...)
+	data = append(data, 0, 0, 0, 0x20)
+	data = append(data, make([]byte, 88)...)
+
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	if _, err := NewReader(bytes.NewReader(data)); err != io.ErrUnexpectedEOF {
+		t.Errorf("reading a truncated header returned %v", err)
+	}
+
+	runtime.ReadMemStats(&after)
+	if allocated := after.TotalAlloc - before.TotalAlloc; allocated > 16<<20 {
+		t.Errorf("reading a truncated header allocated %d bytes", allocated)
+	}
+
+	full := readFixture(t, "regions.fgb")
+	for _, n := range []int{len(full) / 2, len(full) - 1} {
+		fr, err := NewReader(bytes.NewReader(full[:n]))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for {
+			if _, err = fr.Read(); err != nil {
+				break
+			}
+		}
+
+		if err != io.ErrUnexpectedEOF {
+			t.Errorf("reading %d of %d bytes returned %v", n, len(full), err)
+		}
+	}
+}
@@ -0,0 +1,282 @@
+package flatgeobuf
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"sort"
+)
+
+var errInvalidIndex = errors.New("flatgeobuf: invalid spatial index")
+
+const nodeItemSize = 40
+
+// nodeItem is an entry of the packed Hilbert R-tree. For leaves, offset is the
+// byte offset of the feature relative to the start of the feature section; for
+// interior nodes it is the index of the first child node.
+type nodeItem struct {
+	minX, minY, maxX, maxY float64
+	offset                 uint64
+}
+
+func emptyNodeItem() nodeItem {
+	return nodeItem{
+		minX: math.Inf(1),
+		minY: math.Inf(1),
+		maxX: math.Inf(-1),
+		maxY: math.Inf(-1),
+	}
+}
+
+func (n *nodeItem) expand(o nodeItem) {
+	n.minX = math.Min(n.minX, o.minX)
+	n.minY = math.Min(n.minY, o.minY)
+	n.maxX = math.Max(n.maxX, o.maxX)
+	n.maxY = math.Max(n.maxY, o.maxY)
+}
+
+func (n *nodeItem) expandXY(x, y float64) {
+	n.minX = math.Min(n.minX, x)
+	n.minY = math.Min(n.minY, y)
+	n.maxX = math.Max(n.maxX, x)
+	n.maxY = math.Max(n.maxY, y)
+}
+
+func (n nodeItem) isEmpty() bool {
+	return n.minX > n.maxX || n.minY > n.maxY
+}
+
+func (n nodeItem) intersects(o nodeItem) bool {
+	return n.minX <= o.maxX && n.minY <= o.maxY && n.maxX >= o.minX && n.maxY >= o.minY
+}
+
+func (n nodeItem) encode(buf []byte) {
+	binary.LittleEndian.PutUint64(buf[0:], math.Float64bits(n.minX))
+	binary.LittleEndian.PutUint64(buf[8:], math.Float64bits(n.minY))
+	binary.LittleEndian.PutUint64(buf[16:], math.Float64bits(n.maxX))
+	binary.LittleEndian.PutUint64(buf[24:], math.Float64bits(n.maxY))
+	binary.LittleEndian.PutUint64(buf[32:], n.offset)
+}
+
+func decodeNodeItem(buf []byte) nodeItem {
+	return nodeItem{
+		minX:   math.Float64frombits(binary.LittleEndian.Uint64(buf[0:])),
+		minY:   math.Float64frombits(binary.LittleEndian.Uint64(buf[8:])),
+		maxX:   math.Float64frombits(binary.LittleEndian.Uint64(buf[16:])),
+		maxY:   math.Float64frombits(binary.LittleEndian.Uint64(buf[24:])),
+		offset: binary.LittleEndian.Uint64(buf[32:]),
+	}
+}
+
+type levelBound struct {
+	start, end uint64
+}
+
+// levelBounds returns the node index range of every tree level, leaves first.
+// The root is stored at index zero.
+func levelBounds(numItems uint64, nodeSize uint16) []levelBound {
+	size := uint64(nodeSize)
+	n := numItems
+	numNodes := n
+	levelNumNodes := []uint64{n}
+	for {
+		n = (n + size - 1) / size
+		numNodes += n
+		levelNumNodes = append(levelNumNodes, n)
+		if n == 1 {
+			break
+		}
+	}
+
+	bounds := make([]levelBound, len(levelNumNodes))
+	n = numNodes
+	for i, levelSize := range levelNumNodes {
+		bounds[i] = levelBound{n - levelSize, n}
+		n -= levelSize
+	}
+
+	return bounds
+}
+
+func indexSize(numItems uint64, nodeSize uint16) uint64 {
+	if numItems == 0 || nodeSize == 0 {
+		return 0
+	}
+
+	return levelBounds(numItems, clampNodeSize(nodeSize))[0].end * nodeItemSize
+}
+
+func clampNodeSize(nodeSize uint16) uint16 {
+	if nodeSize < 2 {
+		return 2
+	}
+
+	return nodeSize
+}
+
+// buildIndex builds a packed R-tree from leaves that are already in Hilbert
+// order.
+func buildIndex(leaves []nodeItem, nodeSize uint16) []nodeItem {
+	bounds := levelBounds(uint64(len(leaves)), nodeSize)
+	nodes := make([]nodeItem, bounds[0].end)
+	copy(nodes[bounds[0].start:], leaves)
+	for i := 0; i < len(bounds)-1; i++ {
+		pos := bounds[i].start
+		end := bounds[i].end
+		parent := bounds[i+1].start
+		for pos < end {
+			node := emptyNodeItem()
+			node.offset = pos
+			for j := uint16(0); j < nodeSize && pos < end; j++ {
+				node.expand(nodes[pos])
+				pos++
+			}
+
+			nodes[parent] = node
+			parent++
+		}
+	}
+
+	return nodes
+}
+
+// hilbert returns the position of (x, y) on a Hilbert curve covering a 2^16 by
+// 2^16 grid.
+func hilbert(x, y uint32) uint32 {
+	a := x ^ y
+	b := 0xFFFF ^ a
+	c := 0xFFFF ^ (x | y)
+	d := x & (y ^ 0xFFFF)
+
+	A := a | (b >> 1)
+	B := (a >> 1) ^ a
+	C := ((c >> 1) ^ (b & (d >> 1))) ^ c
+	D := ((a & (c >> 1)) ^ (d >> 1)) ^ d
+
+	a, b, c, d = A, B, C, D
+	A = (a & (a >> 2)) ^ (b & (b >> 2))
+	B = (a & (b >> 2)) ^ (b & ((a ^ b) >> 2))
+	C ^= (a & (c >> 2)) ^ (b & (d >> 2))
+	D ^= (b & (c >> 2)) ^ ((a ^ b) & (d >> 2))
+
+	a, b, c, d = A, B, C, D
+	A = (a & (a >> 4)) ^ (b & (b >> 4))
+	B = (a & (b >> 4)) ^ (b & ((a ^ b) >> 4))
+	C ^= (a & (c >> 4)) ^ (b & (d >> 4))
+	D ^= (b & (c >> 4)) ^ ((a ^ b) & (d >> 4))
+
+	a, b, c, d = A, B, C, D
+	C ^= (a & (c >> 8)) ^ (b & (d >> 8))
+	D ^= (b & (c >> 8)) ^ ((a ^ b) & (d >> 8))
+
+	a = C ^ (C >> 1)
+	b = D ^ (D >> 1)
+
+	i0 := x ^ y
+	i1 := b | (0xFFFF ^ (i0 | a))
+
+	i0 = (i0 | (i0 << 8)) & 0x00FF00FF
+	i0 = (i0 | (i0 << 4)) & 0x0F0F0F0F
+	i0 = (i0 | (i0 << 2)) & 0x33333333
+	i0 = (i0 | (i0 << 1)) & 0x55555555
+
+	i1 = (i1 | (i1 << 8)) & 0x00FF00FF
+	i1 = (i1 | (i1 << 4)) & 0x0F0F0F0F
+	i1 = (i1 | (i1 << 2)) & 0x33333333
+	i1 = (i1 | (i1 << 1)) & 0x55555555
+
+	return (i1 << 1) | i0
+}
+
+// hilbertOrder returns the permutation that sorts items by the Hilbert value
+// of their centers within extent, in the same order as the reference writer.
+func hilbertOrder(items []nodeItem, extent nodeItem) []int {
+	const hilbertMax = (1 << 16) - 1
+
+	width := extent.maxX - extent.minX
+	height := extent.maxY - extent.minY
+	values := make([]uint32, len(items))
+	for i, item := range items {
+		var x, y uint32
+		if !item.isEmpty() {
+			if width > 0 {
+				x = uint32(math.Floor(hilbertMax * ((item.minX+item.maxX)/2 - extent.minX) / width))
+			}
+
+			if height > 0 {
+				y = uint32(math.Floor(hilbertMax * ((item.minY+item.maxY)/2 - extent.minY) / height))
+			}
+		}
+
+		values[i] = hilbert(x, y)
+	}
+
+	order := make([]int, len(items))
+	for i := range order {
+		order[i] = i
+	}
+
+	sort.SliceStable(order, func(i, j int) bool {
+		return values[order[i]] > values[order[j]]
+	})
+
+	return order
+}
+
+type searchResult struct {
+	offset uint64
+	index  uint64
+}
+
+// searchIndex returns the leaves intersecting query, ordered by feature
+// offset. readNodes must return count nodes starting at node index start.
+func searchIndex(numItems uint64, nodeSize uint16, query nodeItem, readNodes func(start, count uint64) ([]nodeItem, error)) ([]searchResult, error) {
+	bounds := levelBounds(numItems, nodeSize)
+	leafStart := bounds[0].start
+
+	type queued struct {
+		index uint64
+		level int
+	}
+
+	results := []searchResult{}
+	queue := []queued{{0, len(bounds) - 1}}
+	for len(queue) > 0 {
+		next := queue[0]
+		queue = queue[1:]
+
+		levelEnd := bounds[next.level].end
+		if next.index < bounds[next.level].start || next.index >= levelEnd {
+			return nil, errInvalidIndex
+		}
+
+		end := next.index + uint64(nodeSize)
+		if end > levelEnd {
+			end = levelEnd
+		}
+
+		nodes, err := readNodes(next.index, end-next.index)
+		if err != nil {
+			return nil, err
+		}
+
+		for i, node := range nodes {
+			if !node.intersects(query) {
+				continue
+			}
+
+			pos := next.index + uint64(i)
+			if pos >= leafStart {
+				results = append(results, searchResult{node.offset, pos - leafStart})
+			} else {
+				queue = append(queue, queued{node.offset, next.level - 1})
+			}
+		}
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].offset < results[j].offset
+	})
+
+	return results, nil
+}
@@ -0,0 +1,384 @@
+package geobuf
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+
+	"github.com/topos-ai/geoutil"
+	"github.com/topos-ai/geoutil/encoding/geojson"
+	"github.com/topos-ai/geoutil/internal/pbf"
+)
+
+// decoder holds the keys table and coordinate layout of a Data message.
+type decoder struct {
+	keys       []string
+	dimensions int
+	e          float64
+}
+
+// readLine decodes count delta encoded positions from coords, returning the
+// line and the remaining coords. Rings are closed by repeating their first
+// position.
+func (dec *decoder) readLine(coords []int64, count int, closed bool) ([][]float64, []int64, error) {
+	if count < 0 || count > len(coords)/dec.dimensions {
+		return nil, nil, fmt.Errorf("geobuf: truncated geometry coordinates")
+	}
+
+	if closed && count < 3 {
+		return nil, nil, fmt.Errorf("geobuf: ring of %d positions", count)
+	}
+
+	sums := make([]int64, dec.dimensions)
+	line := make([][]float64, 0, count+1)
+	for i := 0; i < count; i++ {
+		for j := range sums {
+			sums[j] += coords[i*dec.dimensions+j]
+		}
+
+		line = append(line, []float64{float64(sums[0]) / dec.e, float64(sums[1]) / dec.e})
+	}
+
+	if closed {
+		line = append(line, line[0])
+	}
+
+	return line, coords[count*dec.dimensions:], nil
+}
+
+// readLines decodes lines of the given lengths, or a single line holding every
+// position when there are no lengths.
+func (dec *decoder) readLines(coords []int64, lengths []uint64, closed bool) ([][][]float64, []int64, error) {
+	if len(lengths) == 0 {
+		lengths = []uint64{uint64(len(coords) / dec.dimensions)}
+	}
+
+	lines := make([][][]float64, len(lengths))
+	for i, length := range lengths {
+		line, rest, err := dec.readLine(coords, int(length), closed)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		lines[i] = line
+		coords = rest
+	}
+
+	return lines, coords, nil
+}
+
+func (dec *decoder) readMultiPolygon(coords []int64, lengths []uint64) ([][][][]float64, error) {
+	if len(lengths) == 0 {
+		rings, _, err := dec.readLines(coords, nil, true)
+		if err != nil {
+			return nil, err
+		}
+
+		return [][][][]float64{rings}, nil
+	}
+
+	next := func() (uint64, error) {
+		if len(lengths) == 0 {
+			return 0, fmt.Errorf("geobuf: truncated geometry lengths")
+		}
+
+		length := lengths[0]
+		lengths = lengths[1:]
+		return length, nil
+	}
+
+	numPolygons, err := next()
+	if err != nil {
+		return nil, err
+	}
+
+	polygons := [][][][]float64{}
+	for i := uint64(0); i < numPolygons; i++ {
+		numRings, err := next()
+		if err != nil {
+			return nil, err
+		}
+
+		if numRings > uint64(len(lengths)) {
+			return nil, fmt.Errorf("geobuf: truncated geometry lengths")
+		}
+
+		rings, rest, err := dec.readLines(coords, lengths[:numRings], true)
+		if err != nil {
+			return nil, err
+		}
+
+		polygons = append(polygons, rings)
+		lengths = lengths[numRings:]
+		coords = rest
+	}
+
+	return polygons, nil
+}
+
+func (dec *decoder) readGeometry(data []byte) (interface{}, error) {
+	kind := 0
+	lengths := []uint64{}
+	coords := []int64{}
+	r := pbf.NewReader(data)
+	for r.Next() {
+		switch r.Field() {
+		case geometryType:
+			kind = int(r.Uint64())
+		case geometryLengths:
+			lengths = r.Uint64s(lengths)
+		case geometryCoords:
+			coords = r.Sint64s(coords)
+		default:
+			r.Skip()
+		}
+	}
+
+	if err := r.Err(); err != nil {
+		return nil, err
+	}
+
+	switch kind {
+	case geometryTypePoint:
+		line, _, err := dec.readLine(coords, 1, false)
+		if err != nil {
+			return nil, err
+		}
+
+		return geoutil.PointFromPointCoordinates(line[0])
+
+	case geometryTypeMultiPoint:
+		line, _, err := dec.readLine(coords, len(coords)/dec.dimensions, false)
+		if err != nil {
+			return nil, err
+		}
+
+		return geoutil.PointsFromMultiPointCoordinates(line)
+
+	case geometryTypeLineString:
+		line, _, err := dec.readLine(coords, len(coords)/dec.dimensions, false)
+		if err != nil {
+			return nil, err
+		}
+
+		return geoutil.PolylineFromLineStringCoordinates(line)
+
+	case geometryTypeMultiLineString:
+		lines, _, err := dec.readLines(coords, lengths, false)
+		if err != nil {
+			return nil, err
+		}
+
+		return geoutil.PolylinesFromMultiLineStringCoordinates(lines)
+
+	case geometryTypePolygon:
+		rings, _, err := dec.readLines(coords, lengths, true)
+		if err != nil {
+			return nil, err
+		}
+
+		return geoutil.PolygonFromPolygonCoordinates(rings)
+
+	case geometryTypeMultiPolygon:
+		polygons, err := dec.readMultiPolygon(coords, lengths)
+		if err != nil {
+			return nil, err
+		}
+
+		return geoutil.PolygonFromMultiPolygonCoordinates(polygons)
+
+	case geometryTypeGeometryCollection:
+		return nil, fmt.Errorf("geobuf: geometry collections are not supported")
+
+	default:
+		return nil, fmt.Errorf("geobuf: invalid geometry type %d", kind)
+	}
+}
+
+func decodeValue(data []byte) (interface{}, error) {
+	var v interface{}
+	r := pbf.NewReader(data)
+	for r.Next() {
+		switch r.Field() {
+		case valueString:
+			v = r.String()
+		case valueDouble:
+			v = r.Double()
+		case valuePosInt:
+			v = float64(r.Uint64())
+		case valueNegInt:
+			v = -float64(r.Uint64())
+		case valueBool:
+			v = r.Bool()
+		case valueJSON:
+			if err := json.Unmarshal(r.Bytes(), &v); err != nil {
+				return nil, err
+			}
+		default:
+			r.Skip()
+		}
+	}
+
+	return v, r.Err()
+}
+
+func (dec *decoder) readFeature(data []byte) (*geojson.Feature, error) {
+	f := &geojson.Feature{}
+	values := []interface{}{}
+	properties := []uint32{}
+	r := pbf.NewReader(data)
+	for r.Next() {
+		switch r.Field() {
+		case featureGeometry:
+			geometry, err := dec.readGeometry(r.Bytes())
+			if err != nil {
+				return nil, err
+			}
+
+			f.Geometry = geometry
+		case featureID:
+			f.ID = r.String()
+		case featureIntID:
+			f.ID = float64(r.Sint64())
+		case featureValues:
+			v, err := decodeValue(r.Bytes())
+			if err != nil {
+				return nil, err
+			}
+
+			values = append(values, v)
+		case featureProperties:
+			properties = r.Uint32s(properties)
+		default:
+			r.Skip()
+		}
+	}
+
+	if err := r.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(properties)%2 != 0 {
+		return nil, fmt.Errorf("geobuf: odd number of property indexes")
+	}
+
+	f.Properties = make(map[string]interface{}, len(properties)/2)
+	for i := 0; i < len(properties); i += 2 {
+		keyIndex, valueIndex := int(properties[i]), int(properties[i+1])
+		if keyIndex >= len(dec.keys) || valueIndex >= len(values) {
+			return nil, fmt.Errorf("geobuf: property index out of range")
+		}
+
+		f.Properties[dec.keys[keyIndex]] = values[valueIndex]
+	}
+
+	return f, nil
+}
+
+func (dec *decoder) readFeatureCollection(data []byte) (*geojson.FeatureCollection, error) {
+	fc := &geojson.FeatureCollection{
+		Features: []*geojson.Feature{},
+	}
+
+	r := pbf.NewReader(data)
+	for r.Next() {
+		switch r.Field() {
+		case featureCollectionFeatures:
+			f, err := dec.readFeature(r.Bytes())
+			if err != nil {
+				return nil, err
+			}
+
+			fc.Features = append(fc.Features, f)
+		default:
+			r.Skip()
+		}
+	}
+
+	return fc, r.Err()
+}
+
+// Unmarshal decodes Geobuf data into v, which must be a
+// *geojson.FeatureCollection or a *geojson.Feature. A bare geometry is decoded
+// into a *geojson.Feature without properties.
+func Unmarshal(data []byte, v interface{}) error {
+	dec := &decoder{
+		dimensions: defaultDimensions,
+	}
+
+	d := defaultDigits
+	dataType := 0
+	var body []byte
+	r := pbf.NewReader(data)
+	for r.Next() {
+		switch r.Field() {
+		case dataKeys:
+			dec.keys = append(dec.keys, r.String())
+		case dataDimensions:
+			dec.dimensions = int(r.Uint64())
+		case dataPrecision:
+			d = int(r.Uint64())
+		case dataFeatureCollection, dataFeature, dataGeometry:
+			dataType = r.Field()
+			body = r.Bytes()
+		default:
+			r.Skip()
+		}
+	}
+
+	if err := r.Err(); err != nil {
+		return err
+	}
+
+	if dec.dimensions < 2 || dec.dimensions > maxDimensions {
+		return fmt.Errorf("geobuf: cannot process coordinates with dimension %d", dec.dimensions)
+	}
+
+	if dataType == 0 {
+		return errNoData
+	}
+
+	dec.e = math.Pow10(d)
+	switch target := v.(type) {
+	case *geojson.FeatureCollection:
+		if dataType != dataFeatureCollection {
+			return fmt.Errorf("geobuf: message does not hold a feature collection")
+		}
+
+		fc, err := dec.readFeatureCollection(body)
+		if err != nil {
+			return err
+		}
+
+		*target = *fc
+
+	case *geojson.Feature:
+		switch dataType {
+		case dataFeature:
+			f, err := dec.readFeature(body)
+			if err != nil {
+				return err
+			}
+
+			*target = *f
+
+		case dataGeometry:
+			geometry, err := dec.readGeometry(body)
+			if err != nil {
+				return err
+			}
+
+			*target = geojson.Feature{
+				Geometry: geometry,
+			}
+
+		default:
+			return fmt.Errorf("geobuf: message does not hold a feature")
+		}
+
+	default:
+		return fmt.Errorf("geobuf: cannot decode into %T", v)
+	}
+
+	return nil
+}
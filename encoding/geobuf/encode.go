@@ -0,0 +1,361 @@
+package geobuf
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+
+	"github.com/golang/geo/s2"
+
+	"github.com/topos-ai/geoutil"
+	"github.com/topos-ai/geoutil/encoding/geojson"
+	"github.com/topos-ai/geoutil/internal/pbf"
+)
+
+// coordinates holds the coordinates of a geometry, nested according to kind.
+type coordinates struct {
+	kind     int
+	line     [][]float64
+	lines    [][][]float64
+	polygons [][][][]float64
+}
+
+func geometryCoordinates(v interface{}, precision int) (*coordinates, error) {
+	switch geometry := v.(type) {
+	case s2.LatLng:
+		return geometryCoordinates(s2.PointFromLatLng(geometry), precision)
+
+	case s2.Point:
+		coords, err := geoutil.PointCoordinates(geometry, precision)
+		if err != nil {
+			return nil, err
+		}
+
+		return &coordinates{kind: geometryTypePoint, line: [][]float64{coords}}, nil
+
+	case []s2.Point:
+		line := make([][]float64, len(geometry))
+		for i, point := range geometry {
+			coords, err := geoutil.PointCoordinates(point, precision)
+			if err != nil {
+				return nil, err
+			}
+
+			line[i] = coords
+		}
+
+		return &coordinates{kind: geometryTypeMultiPoint, line: line}, nil
+
+	case *s2.Polyline:
+		line, err := geoutil.PolylineCoordinates(geometry, precision)
+		if err != nil {
+			return nil, err
+		}
+
+		return &coordinates{kind: geometryTypeLineString, line: line}, nil
+
+	case []*s2.Polyline:
+		lines := make([][][]float64, len(geometry))
+		for i, polyline := range geometry {
+			line, err := geoutil.PolylineCoordinates(polyline, precision)
+			if err != nil {
+				return nil, err
+			}
+
+			lines[i] = line
+		}
+
+		return &coordinates{kind: geometryTypeMultiLineString, lines: lines}, nil
+
+	case *s2.Polygon:
+		polygons, err := geoutil.PolygonCoordinates(geometry, precision)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(polygons) == 1 {
+			return &coordinates{kind: geometryTypePolygon, lines: polygons[0]}, nil
+		}
+
+		return &coordinates{kind: geometryTypeMultiPolygon, polygons: polygons}, nil
+
+	default:
+		return nil, fmt.Errorf("geobuf: unknown geometry type %T", v)
+	}
+}
+
+func (c *coordinates) each(fn func([]float64)) {
+	for _, position := range c.line {
+		fn(position)
+	}
+
+	for _, line := range c.lines {
+		for _, position := range line {
+			fn(position)
+		}
+	}
+
+	for _, polygon := range c.polygons {
+		for _, ring := range polygon {
+			for _, position := range ring {
+				fn(position)
+			}
+		}
+	}
+}
+
+// encoder accumulates the keys table of a Data message.
+type encoder struct {
+	e          float64
+	keys       []string
+	keyIndexes map[string]int
+}
+
+// writeLine appends delta encoded positions, omitting the closing position of
+// rings.
+func (enc *encoder) writeLine(coords []int64, line [][]float64, closed bool) []int64 {
+	n := len(line)
+	if closed && n > 0 {
+		n--
+	}
+
+	x, y := int64(0), int64(0)
+	for _, position := range line[:n] {
+		px := int64(math.Round(position[0] * enc.e))
+		py := int64(math.Round(position[1] * enc.e))
+		coords = append(coords, px-x, py-y)
+		x, y = px, py
+	}
+
+	return coords
+}
+
+func (enc *encoder) writeGeometry(c *coordinates) *pbf.Writer {
+	w := &pbf.Writer{}
+	w.Uint64(geometryType, uint64(c.kind))
+
+	lengths := []uint64{}
+	coords := []int64{}
+	switch c.kind {
+	case geometryTypePoint:
+		coords = append(coords, int64(math.Round(c.line[0][0]*enc.e)), int64(math.Round(c.line[0][1]*enc.e)))
+
+	case geometryTypeMultiPoint, geometryTypeLineString:
+		coords = enc.writeLine(coords, c.line, false)
+
+	case geometryTypeMultiLineString, geometryTypePolygon:
+		closed := c.kind == geometryTypePolygon
+		if len(c.lines) != 1 {
+			for _, line := range c.lines {
+				n := len(line)
+				if closed {
+					n--
+				}
+
+				lengths = append(lengths, uint64(n))
+			}
+		}
+
+		for _, line := range c.lines {
+			coords = enc.writeLine(coords, line, closed)
+		}
+
+	case geometryTypeMultiPolygon:
+		if len(c.polygons) != 1 || len(c.polygons[0]) != 1 {
+			lengths = append(lengths, uint64(len(c.polygons)))
+			for _, polygon := range c.polygons {
+				lengths = append(lengths, uint64(len(polygon)))
+				for _, ring := range polygon {
+					lengths = append(lengths, uint64(len(ring)-1))
+				}
+			}
+		}
+
+		for _, polygon := range c.polygons {
+			for _, ring := range polygon {
+				coords = enc.writeLine(coords, ring, true)
+			}
+		}
+	}
+
+	w.PackedUint64s(geometryLengths, lengths)
+	w.PackedSint64s(geometryCoords, coords)
+	return w
+}
+
+func encodeValue(w *pbf.Writer, v interface{}) error {
+	switch v := v.(type) {
+	case string:
+		w.String(valueString, v)
+	case bool:
+		w.Bool(valueBool, v)
+	case float64:
+		switch {
+		case v != math.Trunc(v) || math.Abs(v) >= 1<<53:
+			w.Double(valueDouble, v)
+		case v >= 0:
+			w.Uint64(valuePosInt, uint64(v))
+		default:
+			w.Uint64(valueNegInt, uint64(-v))
+		}
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+
+		w.String(valueJSON, string(data))
+	}
+
+	return nil
+}
+
+func (enc *encoder) writeFeature(f *geojson.Feature, geometry *coordinates) (*pbf.Writer, error) {
+	w := &pbf.Writer{}
+	if geometry != nil {
+		w.Message(featureGeometry, enc.writeGeometry(geometry))
+	}
+
+	switch id := f.ID.(type) {
+	case nil:
+	case string:
+		w.String(featureID, id)
+	case float64:
+		if id == math.Trunc(id) && math.Abs(id) < 1<<53 {
+			w.Sint64(featureIntID, int64(id))
+		} else {
+			w.String(featureID, strconv.FormatFloat(id, 'g', -1, 64))
+		}
+	default:
+		return nil, fmt.Errorf("geobuf: invalid Feature ID type %T", f.ID)
+	}
+
+	names := make([]string, 0, len(f.Properties))
+	for name := range f.Properties {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	properties := make([]uint32, 0, 2*len(names))
+	for valueIndex, name := range names {
+		keyIndex, ok := enc.keyIndexes[name]
+		if !ok {
+			keyIndex = len(enc.keys)
+			enc.keyIndexes[name] = keyIndex
+			enc.keys = append(enc.keys, name)
+		}
+
+		vw := &pbf.Writer{}
+		if err := encodeValue(vw, f.Properties[name]); err != nil {
+			return nil, err
+		}
+
+		w.Message(featureValues, vw)
+		properties = append(properties, uint32(keyIndex), uint32(valueIndex))
+	}
+
+	w.PackedUint32s(featureProperties, properties)
+	return w, nil
+}
+
+// chooseDigits returns the smallest number of decimal digits that represents
+// every position exactly, up to maxDigits.
+func chooseDigits(geometries []*coordinates) int {
+	d, e := 0, 1.0
+	for _, c := range geometries {
+		if c == nil {
+			continue
+		}
+
+		c.each(func(position []float64) {
+			for _, v := range position {
+				for d < maxDigits && math.Round(v*e)/e != v {
+					d++
+					e *= 10
+				}
+			}
+		})
+	}
+
+	return d
+}
+
+// Marshal encodes a *geojson.FeatureCollection or a *geojson.Feature as
+// Geobuf. Coordinates are rounded to the decimal digits of the geoutil
+// precision level; PrecisionMax keeps as many digits as the coordinates need,
+// up to 12.
+func Marshal(v interface{}, precision int) ([]byte, error) {
+	d, err := digits(precision)
+	if err != nil {
+		return nil, err
+	}
+
+	var features []*geojson.Feature
+	switch v := v.(type) {
+	case *geojson.FeatureCollection:
+		features = v.Features
+	case *geojson.Feature:
+		features = []*geojson.Feature{v}
+	default:
+		return nil, fmt.Errorf("geobuf: cannot encode %T", v)
+	}
+
+	geometries := make([]*coordinates, len(features))
+	for i, f := range features {
+		if f.Geometry == nil {
+			continue
+		}
+
+		c, err := geometryCoordinates(f.Geometry, precision)
+		if err != nil {
+			return nil, err
+		}
+
+		geometries[i] = c
+	}
+
+	if precision == geoutil.PrecisionMax {
+		d = chooseDigits(geometries)
+	}
+
+	enc := &encoder{
+		e:          math.Pow10(d),
+		keyIndexes: map[string]int{},
+	}
+
+	fws := make([]*pbf.Writer, len(features))
+	for i, f := range features {
+		fw, err := enc.writeFeature(f, geometries[i])
+		if err != nil {
+			return nil, err
+		}
+
+		fws[i] = fw
+	}
+
+	w := &pbf.Writer{}
+	for _, key := range enc.keys {
+		w.String(dataKeys, key)
+	}
+
+	if d != defaultDigits {
+		w.Uint64(dataPrecision, uint64(d))
+	}
+
+	switch v.(type) {
+	case *geojson.FeatureCollection:
+		fcw := &pbf.Writer{}
+		for _, fw := range fws {
+			fcw.Message(featureCollectionFeatures, fw)
+		}
+
+		w.Message(dataFeatureCollection, fcw)
+	case *geojson.Feature:
+		w.Message(dataFeature, fws[0])
+	}
+
+	return w.Data(), nil
+}
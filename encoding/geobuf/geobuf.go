@@ -0,0 +1,96 @@
+// Package geobuf implements Geobuf, a compact protocol buffer encoding of
+// GeoJSON with delta encoded integer coordinates.
+package geobuf
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/topos-ai/geoutil"
+)
+
+// Fields of the Data message.
+const (
+	dataKeys              = 1
+	dataDimensions        = 2
+	dataPrecision         = 3
+	dataFeatureCollection = 4
+	dataFeature           = 5
+	dataGeometry          = 6
+)
+
+// Fields of the FeatureCollection message.
+const (
+	featureCollectionFeatures = 1
+)
+
+// Fields of the Feature message.
+const (
+	featureGeometry   = 1
+	featureID         = 11
+	featureIntID      = 12
+	featureValues     = 13
+	featureProperties = 14
+)
+
+// Fields of the Geometry message.
+const (
+	geometryType       = 1
+	geometryLengths    = 2
+	geometryCoords     = 3
+	geometryGeometries = 4
+)
+
+// Geometry types.
+const (
+	geometryTypePoint              = 0
+	geometryTypeMultiPoint         = 1
+	geometryTypeLineString         = 2
+	geometryTypeMultiLineString    = 3
+	geometryTypePolygon            = 4
+	geometryTypeMultiPolygon       = 5
+	geometryTypeGeometryCollection = 6
+)
+
+// Fields of the Value message.
+const (
+	valueString = 1
+	valueDouble = 2
+	valuePosInt = 3
+	valueNegInt = 4
+	valueBool   = 5
+	valueJSON   = 6
+)
+
+const (
+	defaultDimensions = 2
+	defaultDigits     = 6
+
+	// maxDimensions bounds the number of values of decoded positions, which
+	// hold at most an elevation and a measure beyond their coordinates.
+	maxDimensions = 4
+
+	// maxDigits bounds the number of decimal digits kept with PrecisionMax,
+	// keeping quantized coordinates well within the range of an int64.
+	maxDigits = 12
+)
+
+var errNoData = errors.New("geobuf: message holds no data")
+
+// digits returns the number of decimal digits kept for coordinates at a
+// geoutil precision level. PrecisionMax yields 0, meaning the digits are
+// chosen from the coordinates themselves.
+func digits(precision int) (int, error) {
+	switch precision {
+	case geoutil.PrecisionMax:
+		return 0, nil
+	case geoutil.PrecisionE5:
+		return 5, nil
+	case geoutil.PrecisionE6:
+		return 6, nil
+	case geoutil.PrecisionE7:
+		return 7, nil
+	default:
+		return 0, fmt.Errorf("geobuf: invalid precision level %d", precision)
+	}
+}
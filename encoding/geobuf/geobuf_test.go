@@ -0,0 +1,303 @@
+package geobuf
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/golang/geo/s2"
+
+	"github.com/topos-ai/geoutil"
+	"github.com/topos-ai/geoutil/encoding/geojson"
+	"github.com/topos-ai/geoutil/internal/pbf"
+)
+
+// message builds a Data message holding a single feature, following the
+// Geobuf schema field by field.
+func message(precision, dimensions int, geometry *pbf.Writer, keys []string, values []*pbf.Writer, properties []uint32) []byte {
+	fw := &pbf.Writer{}
+	if geometry != nil {
+		fw.Message(featureGeometry, geometry)
+	}
+
+	fw.Sint64(featureIntID, -4)
+	for _, vw := range values {
+		fw.Message(featureValues, vw)
+	}
+
+	fw.PackedUint32s(featureProperties, properties)
+
+	w := &pbf.Writer{}
+	for _, key := range keys {
+		w.String(dataKeys, key)
+	}
+
+	if dimensions != 0 {
+		w.Uint64(dataDimensions, uint64(dimensions))
+	}
+
+	if precision != 0 {
+		w.Uint64(dataPrecision, uint64(precision))
+	}
+
+	w.Message(dataFeature, fw)
+	return w.Data()
+}
+
+func geometryMessage(kind int, lengths []uint64, coords []int64) *pbf.Writer {
+	gw := &pbf.Writer{}
+	gw.Uint64(geometryType, uint64(kind))
+	gw.PackedUint64s(geometryLengths, lengths)
+	gw.PackedSint64s(geometryCoords, coords)
+	return gw
+}
+
+func TestUnmarshalMessage(t *testing.T) {
+	name := &pbf.Writer{}
+	name.String(valueString, "a")
+	count := &pbf.Writer{}
+	count.Uint64(valueNegInt, 3)
+	nested := &pbf.Writer{}
+	nested.String(valueJSON, `{"b":[1,2]}`)
+
+	// A polygon with a hole, whose rings omit their closing positions and
+	// restart their deltas.
+	data := message(2, 0, geometryMessage(geometryTypePolygon, []uint64{4, 4}, []int64{
+		0, 0, 1000, 0, 0, 1000, -1000, 0,
+		200, 200, 0, 200, 200, 0, 0, -200,
+	}), []string{"name", "count", "nested"}, []*pbf.Writer{name, count, nested}, []uint32{0, 0, 1, 1, 2, 2})
+
+	f := &geojson.Feature{}
+	if err := Unmarshal(data, f); err != nil {
+		t.Fatal(err)
+	}
+
+	if f.ID != -4.0 {
+		t.Errorf("decoded ID %v, want -4", f.ID)
+	}
+
+	want := map[string]interface{}{
+		"name":   "a",
+		"count":  -3.0,
+		"nested": map[string]interface{}{"b": []interface{}{1.0, 2.0}},
+	}
+
+	if !reflect.DeepEqual(f.Properties, want) {
+		t.Errorf("decoded properties %v, want %v", f.Properties, want)
+	}
+
+	polygon, ok := f.Geometry.(*s2.Polygon)
+	if !ok {
+		t.Fatalf("decoded %T, want a polygon", f.Geometry)
+	}
+
+	coords, err := geoutil.PolygonCoordinates(polygon, geoutil.PrecisionE6)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantCoords := [][][][]float64{{
+		{{0, 0}, {10, 0}, {10, 10}, {0, 10}, {0, 0}},
+		{{2, 2}, {2, 4}, {4, 4}, {4, 2}, {2, 2}},
+	}}
+
+	if !reflect.DeepEqual(coords, wantCoords) {
+		t.Errorf("decoded polygon %v, want %v", coords, wantCoords)
+	}
+}
+
+func TestUnmarshalDimensions(t *testing.T) {
+
+	// The third dimension is skipped.
+	data := message(0, 3, geometryMessage(geometryTypeLineString, nil, []int64{
+		1000000, 2000000, 5, 1000000, 1000000, -5,
+	}), nil, nil, nil)
+
+	f := &geojson.Feature{}
+	if err := Unmarshal(data, f); err != nil {
+		t.Fatal(err)
+	}
+
+	coords, _ := geoutil.PolylineCoordinates(f.Geometry.(*s2.Polyline), geoutil.PrecisionE6)
+	if want := [][]float64{{1, 2}, {2, 3}}; !reflect.DeepEqual(coords, want) {
+		t.Errorf("decoded line %v, want %v", coords, want)
+	}
+}
+
+func TestUnmarshalMultiPolygon(t *testing.T) {
+	data := message(0, 0, geometryMessage(geometryTypeMultiPolygon, []uint64{2, 1, 4, 1, 3}, []int64{
+		0, 0, 1000000, 0, 0, 1000000, -1000000, 0,
+		5000000, 5000000, 1000000, 0, 0, 1000000,
+	}), nil, nil, nil)
+
+	f := &geojson.Feature{}
+	if err := Unmarshal(data, f); err != nil {
+		t.Fatal(err)
+	}
+
+	coords, err := geoutil.PolygonCoordinates(f.Geometry.(*s2.Polygon), geoutil.PrecisionE6)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(coords) != 2 || len(coords[0][0]) != 5 || len(coords[1][0]) != 4 {
+		t.Errorf("decoded multipolygon %v", coords)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	point := s2.PointFromLatLng(s2.LatLngFromDegrees(2.5, 1.25))
+	points, _ := geoutil.PointsFromMultiPointCoordinates([][]float64{{1, 2}, {3, 4}})
+	polyline, _ := geoutil.PolylineFromLineStringCoordinates([][]float64{{0, 0}, {1, 1}, {2, 0}})
+	polylines, _ := geoutil.PolylinesFromMultiLineStringCoordinates([][][]float64{{{0, 0}, {1, 1}}, {{2, 2}, {3, 3}}})
+	polygon, _ := geoutil.PolygonFromPolygonCoordinates([][][]float64{
+		{{0, 0}, {10, 0}, {10, 10}, {0, 10}, {0, 0}},
+		{{2, 2}, {2, 4}, {4, 4}, {4, 2}, {2, 2}},
+	})
+	multipolygon, _ := geoutil.PolygonFromMultiPolygonCoordinates([][][][]float64{
+		{{{0, 0}, {1, 0}, {1, 1}, {0, 1}, {0, 0}}},
+		{{{5, 5}, {6, 5}, {6, 6}, {5, 6}, {5, 5}}},
+	})
+
+	fc := &geojson.FeatureCollection{
+		Features: []*geojson.Feature{
+			{ID: "point", Geometry: point, Properties: map[string]interface{}{"name": "a", "open": true}},
+			{ID: 7.0, Geometry: points, Properties: map[string]interface{}{"count": -3.0, "ratio": 0.5}},
+			{ID: 1.5, Geometry: polyline, Properties: map[string]interface{}{"big": 1e300, "tags": []interface{}{"x"}}},
+			{Geometry: polylines},
+			{Geometry: polygon},
+			{Geometry: multipolygon},
+			{Properties: map[string]interface{}{"name": "b"}},
+		},
+	}
+
+	for _, precision := range []int{geoutil.PrecisionMax, geoutil.PrecisionE5, geoutil.PrecisionE6, geoutil.PrecisionE7} {
+		data, err := Marshal(fc, precision)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		decoded := &geojson.FeatureCollection{}
+		if err := Unmarshal(data, decoded); err != nil {
+			t.Fatal(err)
+		}
+
+		if len(decoded.Features) != len(fc.Features) {
+			t.Fatalf("precision %d: decoded %d features, want %d", precision, len(decoded.Features), len(fc.Features))
+		}
+
+		for i, f := range decoded.Features {
+			want := fc.Features[i]
+			wantID := want.ID
+			if wantID == 1.5 {
+
+				// Non-integer IDs are kept as strings.
+				wantID = "1.5"
+			}
+
+			if f.ID != wantID {
+				t.Errorf("precision %d: feature %d has ID %v, want %v", precision, i, f.ID, wantID)
+			}
+
+			if len(want.Properties) > 0 && !reflect.DeepEqual(f.Properties, want.Properties) {
+				t.Errorf("precision %d: feature %d has properties %v, want %v", precision, i, f.Properties, want.Properties)
+			}
+
+			if reflect.TypeOf(f.Geometry) != reflect.TypeOf(want.Geometry) {
+				t.Errorf("precision %d: feature %d has geometry %T, want %T", precision, i, f.Geometry, want.Geometry)
+			}
+		}
+
+		p := decoded.Features[0].Geometry.(s2.Point)
+		if p.Distance(point).Degrees() > 1e-5 {
+			t.Errorf("precision %d: decoded point %v, want %v", precision, p, point)
+		}
+
+		got, _ := geoutil.PolygonCoordinates(decoded.Features[4].Geometry.(*s2.Polygon), geoutil.PrecisionE5)
+		want, _ := geoutil.PolygonCoordinates(polygon, geoutil.PrecisionE5)
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("precision %d: decoded polygon %v, want %v", precision, got, want)
+		}
+
+		got, _ = geoutil.PolygonCoordinates(decoded.Features[5].Geometry.(*s2.Polygon), geoutil.PrecisionE5)
+		want, _ = geoutil.PolygonCoordinates(multipolygon, geoutil.PrecisionE5)
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("precision %d: decoded multipolygon %v, want %v", precision, got, want)
+		}
+	}
+}
+
+func TestPrecision(t *testing.T) {
+	point, _ := geoutil.PointFromPointCoordinates([]float64{1.234567, 0})
+	f := &geojson.Feature{Geometry: point}
+	data, err := Marshal(f, geoutil.PrecisionE5)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decoded := &geojson.Feature{}
+	if err := Unmarshal(data, decoded); err != nil {
+		t.Fatal(err)
+	}
+
+	coords, _ := geoutil.PointCoordinates(decoded.Geometry.(s2.Point), geoutil.PrecisionMax)
+	if math.Abs(coords[0]-1.23457) > 1e-12 {
+		t.Errorf("decoded longitude %v, want 1.23457", coords[0])
+	}
+
+	// PrecisionMax keeps as many digits as the coordinates need.
+	chosen := chooseDigits([]*coordinates{{kind: geometryTypePoint, line: [][]float64{{1.25, 2.5}}}})
+	if chosen != 2 {
+		t.Errorf("chose %d digits for 1.25, want 2", chosen)
+	}
+
+	if chosen := chooseDigits([]*coordinates{{kind: geometryTypePoint, line: [][]float64{{math.Pi, 0}}}}); chosen != maxDigits {
+		t.Errorf("chose %d digits for pi, want %d", chosen, maxDigits)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	tests := map[string][]byte{
+		"truncated coordinates": message(0, 0, geometryMessage(geometryTypePoint, nil, []int64{1}), nil, nil, nil),
+		"truncated rings":       message(0, 0, geometryMessage(geometryTypePolygon, []uint64{4, 4}, []int64{0, 0, 1, 0, 0, 1, -1, 0}), nil, nil, nil),
+		"multipolygon lengths":  message(0, 0, geometryMessage(geometryTypeMultiPolygon, []uint64{2, 1, 4}, []int64{0, 0, 1, 0, 0, 1, -1, 0}), nil, nil, nil),
+		"collection":            message(0, 0, geometryMessage(geometryTypeGeometryCollection, nil, nil), nil, nil, nil),
+		"geometry type":         message(0, 0, geometryMessage(9, nil, nil), nil, nil, nil),
+		"dimensions":            message(0, 1, nil, nil, nil, nil),
+		"large dimensions":      message(0, 1<<40, geometryMessage(geometryTypePoint, nil, []int64{0, 0}), nil, nil, nil),
+		"line length":           message(0, 4, geometryMessage(geometryTypeMultiLineString, []uint64{1 << 62}, []int64{0, 0, 0, 0}), nil, nil, nil),
+		"empty ring":            message(0, 0, geometryMessage(geometryTypePolygon, nil, nil), nil, nil, nil),
+		"short ring":            message(0, 0, geometryMessage(geometryTypePolygon, nil, []int64{0, 0, 1, 0}), nil, nil, nil),
+		"short polygon ring":    message(0, 0, geometryMessage(geometryTypeMultiPolygon, []uint64{1, 2, 3, 2}, []int64{0, 0, 1, 0, 0, 1, -1, 0, 0, 1}), nil, nil, nil),
+		"odd properties":        message(0, 0, nil, []string{"a"}, nil, []uint32{0}),
+		"property index":        message(0, 0, nil, []string{"a"}, nil, []uint32{0, 0}),
+		"no data":               nil,
+	}
+
+	for name, data := range tests {
+		if err := Unmarshal(data, &geojson.Feature{}); err == nil {
+			t.Errorf("%s: no error", name)
+		}
+	}
+
+	data := message(0, 0, nil, nil, nil, nil)
+	if err := Unmarshal(data, &geojson.FeatureCollection{}); err == nil {
+		t.Errorf("decoding a feature into a collection returned no error")
+	}
+
+	if err := Unmarshal(data, &s2.Polygon{}); err == nil {
+		t.Errorf("decoding into a polygon returned no error")
+	}
+
+	if _, err := Marshal(&geojson.Feature{}, 9); err == nil {
+		t.Errorf("invalid precision returned no error")
+	}
+
+	if _, err := Marshal(&geojson.Feature{ID: 1}, geoutil.PrecisionE6); err == nil {
+		t.Errorf("integer ID returned no error")
+	}
+
+	if _, err := Marshal(s2.Point{}, geoutil.PrecisionE6); err == nil {
+		t.Errorf("encoding a bare geometry returned no error")
+	}
+}
@@ -0,0 +1,65 @@
+package geojson
+
+import (
+	"encoding/json"
+
+	"github.com/golang/geo/s2"
+
+	"github.com/topos-ai/geoutil"
+)
+
+func cellCoordinates(cellID s2.CellID, precision int) ([][][]float64, error) {
+	loopCoordinates, err := geoutil.LoopCoordinates(s2.LoopFromCell(s2.CellFromCellID(cellID)), precision)
+	if err != nil {
+		return nil, err
+	}
+
+	return [][][]float64{loopCoordinates}, nil
+}
+
+// marshalCellUnion marshals the cells of a cell union as a MultiPolygon of one
+// polygon per cell. The cells are not merged, so that they can be told apart
+// when debugging.
+func marshalCellUnion(cellUnion s2.CellUnion, precision int) ([]byte, error) {
+	multiPolygonCoordinates := make([][][][]float64, len(cellUnion))
+	for i, cellID := range cellUnion {
+		polygonCoordinates, err := cellCoordinates(cellID, precision)
+		if err != nil {
+			return nil, err
+		}
+
+		multiPolygonCoordinates[i] = polygonCoordinates
+	}
+
+	coordinates, err := json.Marshal(multiPolygonCoordinates)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(&rawGeometry{
+		Type:        "MultiPolygon",
+		Coordinates: coordinates,
+	})
+}
+
+// NewCellUnionFeatureCollection returns a FeatureCollection of one Feature per
+// cell of a cell union. Each Feature is identified by the token of its cell
+// and has the level of the cell as a property.
+func NewCellUnionFeatureCollection(cellUnion s2.CellUnion, precision int) *FeatureCollection {
+	fc := &FeatureCollection{
+		Features: make([]*Feature, len(cellUnion)),
+	}
+
+	for i, cellID := range cellUnion {
+		fc.Features[i] = &Feature{
+			ID: cellID.ToToken(),
+			Properties: map[string]interface{}{
+				"level": cellID.Level(),
+			},
+			Geometry:  s2.CellUnion{cellID},
+			Precision: precision,
+		}
+	}
+
+	return fc
+}
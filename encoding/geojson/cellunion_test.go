@@ -0,0 +1,85 @@
+package geojson
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/golang/geo/s2"
+
+	"github.com/topos-ai/geoutil"
+)
+
+func TestCellUnionFeatureCollection(t *testing.T) {
+	cellUnion := s2.CellUnion{
+		s2.CellIDFromFace(0).ChildBeginAtLevel(4),
+		s2.CellIDFromFace(2).ChildBeginAtLevel(10).Next(),
+	}
+
+	fc := NewCellUnionFeatureCollection(cellUnion, geoutil.PrecisionE7)
+	data, err := json.Marshal(fc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	raw := struct {
+		Features []struct {
+			ID         string                 `json:"id"`
+			Properties map[string]interface{} `json:"properties"`
+			Geometry   struct {
+				Type        string          `json:"type"`
+				Coordinates [][][][]float64 `json:"coordinates"`
+			} `json:"geometry"`
+		} `json:"features"`
+	}{}
+
+	if err := json.Unmarshal(data, &raw); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(raw.Features) != len(cellUnion) {
+		t.Fatalf("marshaled %d features, want %d", len(raw.Features), len(cellUnion))
+	}
+
+	for i, f := range raw.Features {
+		cellID := cellUnion[i]
+		if f.ID != cellID.ToToken() || f.Properties["level"] != float64(cellID.Level()) {
+			t.Errorf("cell %d: marshaled ID %s and properties %v", i, f.ID, f.Properties)
+		}
+
+		coords := f.Geometry.Coordinates
+		if f.Geometry.Type != "MultiPolygon" || len(coords) != 1 || len(coords[0]) != 1 || len(coords[0][0]) != 5 {
+			t.Errorf("cell %d: marshaled %s %v", i, f.Geometry.Type, coords)
+			continue
+		}
+
+		polygon, err := geoutil.PolygonFromMultiPolygonCoordinates(coords)
+		if err != nil {
+			t.Errorf("cell %d: %v", i, err)
+			continue
+		}
+
+		cell := s2.CellFromCellID(cellID)
+		if !polygon.ContainsPoint(cell.Center()) || polygon.ContainsPoint(s2.CellFromCellID(cellID.Next()).Center()) {
+			t.Errorf("cell %d: marshaled polygon does not match the cell", i)
+		}
+
+		if area := polygon.Area(); area < 0.99*cell.ExactArea() || area > 1.01*cell.ExactArea() {
+			t.Errorf("cell %d: marshaled polygon of area %v, want %v", i, area, cell.ExactArea())
+		}
+	}
+
+	// A feature holding a cell union marshals one polygon per cell.
+	data, err = json.Marshal(&Feature{Geometry: cellUnion, Precision: geoutil.PrecisionE6})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f := &Feature{}
+	if err := json.Unmarshal(data, f); err != nil {
+		t.Fatal(err)
+	}
+
+	if polygon, ok := f.Geometry.(*s2.Polygon); !ok || polygon.NumLoops() != len(cellUnion) {
+		t.Errorf("unmarshaled cell union as %v", f.Geometry)
+	}
+}
@@ -0,0 +1,506 @@
+package geojson
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/golang/geo/s2"
+
+	"github.com/topos-ai/geoutil"
+	"github.com/topos-ai/geoutil/internal/wkt"
+)
+
+var errNoCSVGeometryColumns = errors.New("geojson: no CSV geometry columns")
+
+// Column names recognized when detecting the geometry columns of a CSV file.
+var (
+	csvWKTColumns = []string{"wkt", "geometry", "geom", "the_geom", "shape"}
+	csvLatColumns = []string{"lat", "latitude", "y"}
+	csvLonColumns = []string{"lon", "lng", "long", "longitude", "x"}
+)
+
+// csvNumber matches the numbers inferred from CSV values. Numbers with leading
+// zeros, such as postal codes, are kept as strings.
+var csvNumber = regexp.MustCompile(`^-?(0|[1-9][0-9]*)(\.[0-9]+)?([eE][-+]?[0-9]+)?$`)
+
+// csvKind is the type inferred for the values of a CSV column.
+type csvKind int
+
+const (
+	csvKindString csvKind = iota
+	csvKindNumber
+	csvKindBool
+)
+
+func isCSVNumber(s string) bool {
+	if !csvNumber.MatchString(s) {
+		return false
+	}
+
+	_, err := strconv.ParseFloat(s, 64)
+	return err == nil
+}
+
+func isCSVBool(s string) bool {
+	return strings.EqualFold(s, "true") || strings.EqualFold(s, "false")
+}
+
+// inferCSVKinds returns the type of each column from the values of every
+// record. A column holds numbers or booleans when every one of its non-empty
+// values is one, and strings otherwise.
+func inferCSVKinds(columns int, records [][]string) []csvKind {
+	kinds := make([]csvKind, columns)
+	for i := range kinds {
+		numbers, bools, empty := true, true, true
+		for _, record := range records {
+			if record[i] == "" {
+				continue
+			}
+
+			empty = false
+			numbers = numbers && isCSVNumber(record[i])
+			bools = bools && isCSVBool(record[i])
+		}
+
+		switch {
+		case empty:
+			kinds[i] = csvKindString
+		case numbers:
+			kinds[i] = csvKindNumber
+		case bools:
+			kinds[i] = csvKindBool
+		}
+	}
+
+	return kinds
+}
+
+// value converts a CSV value to the JSON type of its column. Empty values
+// become nil.
+func (k csvKind) value(s string) interface{} {
+	switch {
+	case s == "":
+		return nil
+	case k == csvKindNumber:
+		f, _ := strconv.ParseFloat(s, 64)
+		return f
+	case k == csvKindBool:
+		return strings.EqualFold(s, "true")
+	default:
+		return s
+	}
+}
+
+func findCSVColumn(header []string, names []string) int {
+	for _, name := range names {
+		for i, column := range header {
+			if strings.EqualFold(strings.TrimSpace(column), name) {
+				return i
+			}
+		}
+	}
+
+	return -1
+}
+
+// CSVReader reads features from the rows of a CSV file with a header row.
+// Geometries are read either from a WKT column or from latitude and
+// longitude columns. Unless configured before the first call to Read, the
+// geometry columns are detected from the header, preferring a WKT column. The
+// remaining columns become properties. The type of each column is inferred
+// from all of its values: a column whose non-empty values are all numbers
+// holds float64 values, one whose values are all true or false holds
+// booleans, and any other column holds strings, so that a column of postal
+// codes such as 01234 and 10001 stays a column of strings. Empty values
+// become nil. Since every row is needed to infer the types, the first call to
+// Read reads the whole file.
+type CSVReader struct {
+	WKTColumn string
+	LatColumn string
+	LonColumn string
+
+	// IDColumn optionally names the column holding feature IDs.
+	IDColumn string
+
+	r       *csv.Reader
+	header  []string
+	records [][]string
+	kinds   []csvKind
+	wkt     int
+	lat     int
+	lon     int
+	id      int
+	started bool
+}
+
+func NewCSVReader(r io.Reader) *CSVReader {
+	return &CSVReader{
+		r: csv.NewReader(r),
+	}
+}
+
+func (cr *CSVReader) column(name string) (int, error) {
+	if name == "" {
+		return -1, nil
+	}
+
+	i := findCSVColumn(cr.header, []string{name})
+	if i < 0 {
+		return -1, fmt.Errorf("geojson: unknown CSV column %s", name)
+	}
+
+	return i, nil
+}
+
+func (cr *CSVReader) readHeader() error {
+	header, err := cr.r.Read()
+	if err != nil {
+		return err
+	}
+
+	cr.header = header
+	if cr.wkt, err = cr.column(cr.WKTColumn); err != nil {
+		return err
+	}
+
+	if cr.lat, err = cr.column(cr.LatColumn); err != nil {
+		return err
+	}
+
+	if cr.lon, err = cr.column(cr.LonColumn); err != nil {
+		return err
+	}
+
+	if cr.id, err = cr.column(cr.IDColumn); err != nil {
+		return err
+	}
+
+	if cr.wkt < 0 && cr.lat < 0 && cr.lon < 0 {
+		cr.wkt = findCSVColumn(header, csvWKTColumns)
+		if cr.wkt < 0 {
+			cr.lat = findCSVColumn(header, csvLatColumns)
+			cr.lon = findCSVColumn(header, csvLonColumns)
+		}
+	}
+
+	if cr.wkt < 0 && (cr.lat < 0 || cr.lon < 0) {
+		return errNoCSVGeometryColumns
+	}
+
+	return nil
+}
+
+func (cr *CSVReader) geometry(record []string) (interface{}, error) {
+	if cr.wkt >= 0 {
+		if strings.TrimSpace(record[cr.wkt]) == "" {
+			return nil, nil
+		}
+
+		return wkt.Unmarshal([]byte(record[cr.wkt]))
+	}
+
+	lat, lon := strings.TrimSpace(record[cr.lat]), strings.TrimSpace(record[cr.lon])
+	if lat == "" && lon == "" {
+		return nil, nil
+	}
+
+	latDegrees, err := strconv.ParseFloat(lat, 64)
+	if err != nil || math.IsNaN(latDegrees) || math.Abs(latDegrees) > 90 {
+		return nil, fmt.Errorf("geojson: invalid CSV latitude %s", lat)
+	}
+
+	lonDegrees, err := strconv.ParseFloat(lon, 64)
+	if err != nil || math.IsNaN(lonDegrees) || math.Abs(lonDegrees) > 180 {
+		return nil, fmt.Errorf("geojson: invalid CSV longitude %s", lon)
+	}
+
+	return geoutil.PointFromPointCoordinates([]float64{lonDegrees, latDegrees})
+}
+
+// Read reads the feature of the next row, returning io.EOF after the last
+// row.
+func (cr *CSVReader) Read() (*Feature, error) {
+	if !cr.started {
+		if err := cr.readHeader(); err != nil {
+			return nil, err
+		}
+
+		records, err := cr.r.ReadAll()
+		if err != nil {
+			return nil, err
+		}
+
+		cr.records = records
+		cr.kinds = inferCSVKinds(len(cr.header), records)
+		cr.started = true
+	}
+
+	if len(cr.records) == 0 {
+		return nil, io.EOF
+	}
+
+	record := cr.records[0]
+	cr.records = cr.records[1:]
+
+	geometry, err := cr.geometry(record)
+	if err != nil {
+		return nil, err
+	}
+
+	f := &Feature{
+		Properties: make(map[string]interface{}, len(record)),
+		Geometry:   geometry,
+	}
+
+	for i, value := range record {
+		switch i {
+		case cr.wkt, cr.lat, cr.lon:
+		case cr.id:
+
+			// Feature IDs are strings or numbers.
+			f.ID = cr.kinds[i].value(value)
+			if cr.kinds[i] == csvKindBool {
+				f.ID = value
+			}
+		default:
+			f.Properties[cr.header[i]] = cr.kinds[i].value(value)
+		}
+	}
+
+	return f, nil
+}
+
+// ReadAll reads the features of the remaining rows.
+func (cr *CSVReader) ReadAll() (*FeatureCollection, error) {
+	fc := &FeatureCollection{
+		Features: []*Feature{},
+	}
+
+	for {
+		f, err := cr.Read()
+		if err == io.EOF {
+			return fc, nil
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		fc.Features = append(fc.Features, f)
+	}
+}
+
+// CSVGeometryFormat selects how a CSVWriter writes geometries.
+type CSVGeometryFormat int
+
+const (
+	CSVGeometryWKT CSVGeometryFormat = iota
+	CSVGeometryLatLon
+)
+
+// CSVWriter writes features as the rows of a CSV file with a header row.
+// Geometries are written as WKT, or as latitude and longitude columns for
+// point geometries. Unless Columns is set, the property columns are the
+// sorted property names of the first feature written, or of every feature
+// with WriteAll.
+type CSVWriter struct {
+	GeometryFormat CSVGeometryFormat
+	WKTColumn      string
+	LatColumn      string
+	LonColumn      string
+	IDColumn       string
+	Columns        []string
+
+	// Precision is the precision of the coordinates of features whose own
+	// Precision is PrecisionMax.
+	Precision int
+
+	w       *csv.Writer
+	started bool
+}
+
+func NewCSVWriter(w io.Writer) *CSVWriter {
+	return &CSVWriter{
+		WKTColumn: "wkt",
+		LatColumn: "lat",
+		LonColumn: "lon",
+		w:         csv.NewWriter(w),
+	}
+}
+
+func formatCSVValue(v interface{}) (string, error) {
+	switch v := v.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return "", err
+		}
+
+		return string(data), nil
+	}
+}
+
+func (cw *CSVWriter) writeHeader() error {
+	header := []string{}
+	if cw.IDColumn != "" {
+		header = append(header, cw.IDColumn)
+	}
+
+	switch cw.GeometryFormat {
+	case CSVGeometryWKT:
+		header = append(header, cw.WKTColumn)
+	case CSVGeometryLatLon:
+		header = append(header, cw.LatColumn, cw.LonColumn)
+	default:
+		return fmt.Errorf("geojson: invalid CSV geometry format %d", cw.GeometryFormat)
+	}
+
+	cw.started = true
+	return cw.w.Write(append(header, cw.Columns...))
+}
+
+func (cw *CSVWriter) geometry(v interface{}, precision int) ([]string, error) {
+	if cw.GeometryFormat == CSVGeometryWKT {
+		if v == nil {
+			return []string{""}, nil
+		}
+
+		data, err := wkt.Marshal(v, precision)
+		if err != nil {
+			return nil, err
+		}
+
+		return []string{string(data)}, nil
+	}
+
+	switch geometry := v.(type) {
+	case nil:
+		return []string{"", ""}, nil
+	case s2.LatLng:
+		return cw.geometry(s2.PointFromLatLng(geometry), precision)
+	case s2.Point:
+		coords, err := geoutil.PointCoordinates(geometry, precision)
+		if err != nil {
+			return nil, err
+		}
+
+		return []string{
+			strconv.FormatFloat(coords[1], 'f', -1, 64),
+			strconv.FormatFloat(coords[0], 'f', -1, 64),
+		}, nil
+	default:
+		return nil, fmt.Errorf("geojson: cannot write %T as CSV latitude and longitude", v)
+	}
+}
+
+func csvColumns(features []*Feature) []string {
+	names := map[string]bool{}
+	for _, f := range features {
+		for name := range f.Properties {
+			names[name] = true
+		}
+	}
+
+	columns := make([]string, 0, len(names))
+	for name := range names {
+		columns = append(columns, name)
+	}
+
+	sort.Strings(columns)
+	return columns
+}
+
+// Write writes the row of a feature. Rows are buffered until Flush.
+func (cw *CSVWriter) Write(f *Feature) error {
+	if !cw.started {
+		if cw.Columns == nil {
+			cw.Columns = csvColumns([]*Feature{f})
+		}
+
+		if err := cw.writeHeader(); err != nil {
+			return err
+		}
+	}
+
+	record := []string{}
+	if cw.IDColumn != "" {
+		switch f.ID.(type) {
+		case string, float64, nil:
+		default:
+			return fmt.Errorf("geojson: invalid Feature ID type %T", f.ID)
+		}
+
+		id, err := formatCSVValue(f.ID)
+		if err != nil {
+			return err
+		}
+
+		record = append(record, id)
+	}
+
+	precision := f.Precision
+	if precision == geoutil.PrecisionMax {
+		precision = cw.Precision
+	}
+
+	geometry, err := cw.geometry(f.Geometry, precision)
+	if err != nil {
+		return err
+	}
+
+	record = append(record, geometry...)
+	for _, column := range cw.Columns {
+		value, err := formatCSVValue(f.Properties[column])
+		if err != nil {
+			return err
+		}
+
+		record = append(record, value)
+	}
+
+	return cw.w.Write(record)
+}
+
+// WriteAll writes the rows of every feature of a FeatureCollection and
+// flushes the output.
+func (cw *CSVWriter) WriteAll(fc *FeatureCollection) error {
+	if !cw.started && cw.Columns == nil {
+		cw.Columns = csvColumns(fc.Features)
+	}
+
+	if !cw.started {
+		if err := cw.writeHeader(); err != nil {
+			return err
+		}
+	}
+
+	for _, f := range fc.Features {
+		if err := cw.Write(f); err != nil {
+			return err
+		}
+	}
+
+	return cw.Flush()
+}
+
+// Flush writes buffered rows to the underlying writer.
+func (cw *CSVWriter) Flush() error {
+	cw.w.Flush()
+	return cw.w.Error()
+}
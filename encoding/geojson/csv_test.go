@@ -0,0 +1,213 @@
+package geojson
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/golang/geo/s2"
+
+	"github.com/topos-ai/geoutil"
+)
+
+func readCSV(t *testing.T, data string) *FeatureCollection {
+	t.Helper()
+	fc, err := NewCSVReader(strings.NewReader(data)).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return fc
+}
+
+func TestCSVReaderGeometryColumns(t *testing.T) {
+	tests := []string{
+		"name,wkt\na,POINT (2 1)\n",
+		"name,Latitude,Longitude\na,1,2\n",
+		"name,y,x\na,1,2\n",
+		"lat,lon,the_geom\n5,5,POINT (2 1)\n",
+	}
+
+	for _, test := range tests {
+		fc := readCSV(t, test)
+		if len(fc.Features) != 1 {
+			t.Errorf("%q: read %d features", test, len(fc.Features))
+			continue
+		}
+
+		point, ok := fc.Features[0].Geometry.(s2.Point)
+		if !ok {
+			t.Errorf("%q: read geometry %v", test, fc.Features[0].Geometry)
+			continue
+		}
+
+		coords, _ := geoutil.PointCoordinates(point, geoutil.PrecisionE6)
+		if !reflect.DeepEqual(coords, []float64{2, 1}) {
+			t.Errorf("%q: read point %v, want [2 1]", test, coords)
+		}
+	}
+
+	cr := NewCSVReader(strings.NewReader("a,b,c,d\n1,2,3,4\n"))
+	cr.LatColumn, cr.LonColumn = "c", "b"
+	f, err := cr.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	coords, _ := geoutil.PointCoordinates(f.Geometry.(s2.Point), geoutil.PrecisionE6)
+	if !reflect.DeepEqual(coords, []float64{2, 3}) {
+		t.Errorf("read point %v from configured columns, want [2 3]", coords)
+	}
+
+	if !reflect.DeepEqual(f.Properties, map[string]interface{}{"a": 1.0, "d": 4.0}) {
+		t.Errorf("read properties %v", f.Properties)
+	}
+}
+
+func TestCSVReaderProperties(t *testing.T) {
+	fc := readCSV(t, strings.Join([]string{
+		"id,lat,lon,zip,population,capital,mixed,empty",
+		"a,1,2,01234,12.5,true,1,",
+		"b,3,4,10001,-3e2,FALSE,yes,",
+		"c,,,,,,,",
+	}, "\n"))
+
+	want := []map[string]interface{}{
+		{"id": "a", "zip": "01234", "population": 12.5, "capital": true, "mixed": "1", "empty": nil},
+		{"id": "b", "zip": "10001", "population": -300.0, "capital": false, "mixed": "yes", "empty": nil},
+		{"id": "c", "zip": nil, "population": nil, "capital": nil, "mixed": nil, "empty": nil},
+	}
+
+	if len(fc.Features) != len(want) {
+		t.Fatalf("read %d features, want %d", len(fc.Features), len(want))
+	}
+
+	for i, f := range fc.Features {
+		if !reflect.DeepEqual(f.Properties, want[i]) {
+			t.Errorf("feature %d: read properties %v, want %v", i, f.Properties, want[i])
+		}
+	}
+
+	if fc.Features[2].Geometry != nil {
+		t.Errorf("read geometry %v for empty coordinates", fc.Features[2].Geometry)
+	}
+
+	cr := NewCSVReader(strings.NewReader("fid,wkt\n1,POINT (0 0)\n2,\n"))
+	cr.IDColumn = "fid"
+	fc, err := cr.ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if fc.Features[0].ID != 1.0 || fc.Features[1].ID != 2.0 || fc.Features[1].Geometry != nil {
+		t.Errorf("read IDs %v and %v", fc.Features[0].ID, fc.Features[1].ID)
+	}
+
+	cr = NewCSVReader(strings.NewReader("fid,wkt\ntrue,POINT (0 0)\n"))
+	cr.IDColumn = "fid"
+	if f, err := cr.Read(); err != nil || f.ID != "true" {
+		t.Errorf("read boolean ID %#v, %v", f.ID, err)
+	}
+}
+
+func TestCSVReaderErrors(t *testing.T) {
+	tests := map[string]string{
+		"no geometry columns": "name,value\na,1\n",
+		"no longitude":        "name,lat\na,1\n",
+		"invalid WKT":         "wkt\nPOINT (1)\n",
+		"invalid latitude":    "lat,lon\nnorth,1\n",
+		"NaN latitude":        "lat,lon\nNaN,1\n",
+		"infinite longitude":  "lat,lon\n1,inf\n",
+		"latitude range":      "lat,lon\n91,1\n",
+		"longitude range":     "lat,lon\n1,-180.5\n",
+		"field count":         "lat,lon\n1,2,3\n",
+	}
+
+	for name, data := range tests {
+		if _, err := NewCSVReader(strings.NewReader(data)).ReadAll(); err == nil {
+			t.Errorf("%s: no error", name)
+		}
+	}
+
+	cr := NewCSVReader(strings.NewReader("a,b\n1,2\n"))
+	cr.WKTColumn = "geometry"
+	if _, err := cr.Read(); err == nil {
+		t.Errorf("unknown column: no error")
+	}
+}
+
+func TestCSVWriter(t *testing.T) {
+	point := s2.PointFromLatLng(s2.LatLngFromDegrees(1, 2))
+	fc := &FeatureCollection{
+		Features: []*Feature{
+			{ID: "a", Geometry: point, Precision: geoutil.PrecisionE6, Properties: map[string]interface{}{"zip": "01234", "n": 1.5}},
+			{ID: 2.0, Properties: map[string]interface{}{"ok": true, "tags": []interface{}{"x"}}},
+		},
+	}
+
+	b := &strings.Builder{}
+	cw := NewCSVWriter(b)
+	cw.IDColumn = "id"
+	if err := cw.WriteAll(fc); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "id,wkt,n,ok,tags,zip\na,POINT (2 1),1.5,,,01234\n2,,,true,\"[\"\"x\"\"]\",\n"
+	if b.String() != want {
+		t.Errorf("wrote %q, want %q", b.String(), want)
+	}
+
+	// The precision of the writer applies to features without their own.
+	b.Reset()
+	cw = NewCSVWriter(b)
+	cw.GeometryFormat = CSVGeometryLatLon
+	cw.Precision = geoutil.PrecisionE5
+	cw.Columns = []string{}
+	features := []*Feature{
+		{Geometry: s2.LatLngFromDegrees(1.234567, 2)},
+		{Geometry: s2.LatLngFromDegrees(1.234567, 2), Precision: geoutil.PrecisionE6},
+		{},
+	}
+
+	for _, f := range features {
+		if err := cw.Write(f); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := cw.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	want = "lat,lon\n1.23457,2\n1.234567,2\n,\n"
+	if b.String() != want {
+		t.Errorf("wrote %q, want %q", b.String(), want)
+	}
+
+	read := readCSV(t, b.String())
+	if len(read.Features) != 3 || read.Features[2].Geometry != nil {
+		t.Errorf("read back %d features", len(read.Features))
+	}
+}
+
+func TestCSVWriterErrors(t *testing.T) {
+	polyline, _ := geoutil.PolylineFromLineStringCoordinates([][]float64{{0, 0}, {1, 1}})
+	tests := map[string]struct {
+		format   CSVGeometryFormat
+		idColumn string
+		feature  *Feature
+	}{
+		"format":  {format: 2, feature: &Feature{}},
+		"lat/lon": {format: CSVGeometryLatLon, feature: &Feature{Geometry: polyline}},
+		"id":      {idColumn: "id", feature: &Feature{ID: true}},
+	}
+
+	for name, test := range tests {
+		cw := NewCSVWriter(&strings.Builder{})
+		cw.GeometryFormat = test.format
+		cw.IDColumn = test.idColumn
+		if err := cw.Write(test.feature); err == nil {
+			t.Errorf("%s: no error", name)
+		}
+	}
+}
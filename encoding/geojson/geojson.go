@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 
+	"github.com/golang/geo/s1"
 	"github.com/golang/geo/s2"
 
 	"github.com/topos-ai/geoutil"
@@ -51,6 +52,11 @@ type Feature struct {
 	Properties map[string]interface{}
 	Geometry   interface{}
 	Precision  int
+
+	// Densify, if positive, inserts vertices along the geodesic edges of a
+	// polygon when marshaling, so that planar viewers drawing straight lines
+	// between its coordinates draw them within Densify of its edges.
+	Densify s1.Angle
 }
 
 type rawGeometry struct {
@@ -66,6 +72,10 @@ type rawFeature struct {
 }
 
 func (f *Feature) UnmarshalJSON(data []byte) error {
+	return f.unmarshalJSON(data, UnmarshalOptions{})
+}
+
+func (f *Feature) unmarshalJSON(data []byte, options UnmarshalOptions) error {
 	rf := &rawFeature{}
 	if err := json.Unmarshal(data, rf); err != nil {
 		return err
@@ -98,6 +108,12 @@ func (f *Feature) UnmarshalJSON(data []byte) error {
 				return err
 			}
 
+			if options.Strict {
+				if err := geoutil.ErrorFromProblems(geoutil.ValidatePointCoordinates(pointCoords)); err != nil {
+					return err
+				}
+			}
+
 			point, err := geoutil.PointFromPointCoordinates(pointCoords)
 			if err != nil {
 				return err
@@ -111,6 +127,13 @@ func (f *Feature) UnmarshalJSON(data []byte) error {
 				return err
 			}
 
+			if options.Strict {
+				if err := geoutil.ErrorFromProblems(geoutil.ValidateLineStringCoordinates(lineStringCoords)); err != nil {
+					return err
+				}
+			}
+
+			lineStringCoords = geoutil.DensifyPlanarCoordinates(lineStringCoords, options.Densify)
 			polyline, err := geoutil.PolylineFromLineStringCoordinates(lineStringCoords)
 			if err != nil {
 				return err
@@ -124,6 +147,16 @@ func (f *Feature) UnmarshalJSON(data []byte) error {
 				return err
 			}
 
+			if options.Strict {
+				if err := geoutil.ErrorFromProblems(geoutil.ValidatePolygonCoordinates(polygonCoords)); err != nil {
+					return err
+				}
+			}
+
+			for i, ring := range polygonCoords {
+				polygonCoords[i] = geoutil.DensifyPlanarCoordinates(ring, options.Densify)
+			}
+
 			polygon, err := geoutil.PolygonFromPolygonCoordinates(polygonCoords)
 			if err != nil {
 				return err
@@ -137,6 +170,12 @@ func (f *Feature) UnmarshalJSON(data []byte) error {
 				return err
 			}
 
+			if options.Strict {
+				if err := geoutil.ErrorFromProblems(geoutil.ValidateMultiPointCoordinates(multipointCoords)); err != nil {
+					return err
+				}
+			}
+
 			points, err := geoutil.PointsFromMultiPointCoordinates(multipointCoords)
 			if err != nil {
 				return err
@@ -150,6 +189,16 @@ func (f *Feature) UnmarshalJSON(data []byte) error {
 				return err
 			}
 
+			if options.Strict {
+				if err := geoutil.ErrorFromProblems(geoutil.ValidateMultiLineStringCoordinates(multiLineStringCoords)); err != nil {
+					return err
+				}
+			}
+
+			for i, lineString := range multiLineStringCoords {
+				multiLineStringCoords[i] = geoutil.DensifyPlanarCoordinates(lineString, options.Densify)
+			}
+
 			polylines, err := geoutil.PolylinesFromMultiLineStringCoordinates(multiLineStringCoords)
 			if err != nil {
 				return err
@@ -163,6 +212,18 @@ func (f *Feature) UnmarshalJSON(data []byte) error {
 				return err
 			}
 
+			if options.Strict {
+				if err := geoutil.ErrorFromProblems(geoutil.ValidateMultiPolygonCoordinates(multipolygonCoords)); err != nil {
+					return err
+				}
+			}
+
+			for _, polygonCoords := range multipolygonCoords {
+				for i, ring := range polygonCoords {
+					polygonCoords[i] = geoutil.DensifyPlanarCoordinates(ring, options.Densify)
+				}
+			}
+
 			polygon, err := geoutil.PolygonFromMultiPolygonCoordinates(multipolygonCoords)
 			if err != nil {
 				return err
@@ -199,7 +260,20 @@ func (f *Feature) MarshalJSON() ([]byte, error) {
 
 	switch geometry := f.Geometry.(type) {
 	case *s2.Polygon:
-		data, err := marshalPolygon(geometry, f.Precision)
+		densified, err := geoutil.DensifyGeodesic(geometry, f.Densify)
+		if err != nil {
+			return nil, err
+		}
+
+		data, err := marshalPolygon(densified.(*s2.Polygon), f.Precision)
+		if err != nil {
+			return nil, err
+		}
+
+		rf.Geometry = data
+
+	case s2.CellUnion:
+		data, err := marshalCellUnion(geometry, f.Precision)
 		if err != nil {
 			return nil, err
 		}
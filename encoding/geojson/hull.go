@@ -0,0 +1,32 @@
+package geojson
+
+import (
+	"github.com/golang/geo/s2"
+
+	"github.com/topos-ai/geoutil"
+)
+
+// Geometries returns the geometries of the features of a FeatureCollection,
+// skipping null features and features without a geometry.
+func (fc *FeatureCollection) Geometries() []interface{} {
+	geometries := make([]interface{}, 0, len(fc.Features))
+	for _, feature := range fc.Features {
+		if feature != nil && feature.Geometry != nil {
+			geometries = append(geometries, feature.Geometry)
+		}
+	}
+
+	return geometries
+}
+
+// ConvexHull returns the convex hull of the geometries of a
+// FeatureCollection.
+func (fc *FeatureCollection) ConvexHull() (*s2.Polygon, error) {
+	return geoutil.ConvexHull(fc.Geometries()...)
+}
+
+// ConcaveHull returns the concave hull of the geometries of a
+// FeatureCollection, as computed by geoutil.ConcaveHull.
+func (fc *FeatureCollection) ConcaveHull(concavity float64) (*s2.Polygon, error) {
+	return geoutil.ConcaveHull(concavity, fc.Geometries()...)
+}
@@ -0,0 +1,49 @@
+package geojson
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/golang/geo/s2"
+)
+
+func TestFeatureCollectionHulls(t *testing.T) {
+	data := []byte(`{"type":"FeatureCollection","features":[
+		null,
+		{"type":"Feature","properties":{},"geometry":null},
+		{"type":"Feature","properties":{},"geometry":{"type":"MultiPoint","coordinates":[[0,0],[2,0],[2,2],[0,2],[1,1]]}}
+	]}`)
+
+	fc := &FeatureCollection{}
+	if err := json.Unmarshal(data, fc); err != nil {
+		t.Fatal(err)
+	}
+
+	if geometries := fc.Geometries(); len(geometries) != 1 {
+		t.Errorf("%d geometries, want 1", len(geometries))
+	}
+
+	hull, err := fc.ConvexHull()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	center := s2.PointFromLatLng(s2.LatLngFromDegrees(1, 1))
+	if hull.NumLoops() != 1 || hull.Loop(0).NumVertices() != 4 || !hull.ContainsPoint(center) {
+		t.Errorf("convex hull %v", hull)
+	}
+
+	hull, err = fc.ConcaveHull(1)
+	if err != nil || hull.NumLoops() != 1 {
+		t.Errorf("concave hull %v, %v", hull, err)
+	}
+
+	fc = &FeatureCollection{}
+	if err := UnmarshalStrict(data, fc); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := fc.ConvexHull(); err != nil {
+		t.Errorf("convex hull after UnmarshalStrict: %v", err)
+	}
+}
@@ -0,0 +1,81 @@
+package geojson
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/golang/geo/s1"
+)
+
+// FeatureError reports an error decoding a Feature of a FeatureCollection.
+type FeatureError struct {
+	Index int
+	Err   error
+}
+
+func (e *FeatureError) Error() string {
+	return fmt.Sprintf("geojson: Feature %d: %v", e.Index, e.Err)
+}
+
+// UnmarshalOptions are the options of decoding geometries.
+type UnmarshalOptions struct {
+
+	// Strict validates the coordinates of each geometry before building it.
+	Strict bool
+
+	// Densify, if positive, inserts coordinates along the straight lines
+	// between the coordinates of LineStrings and rings, so that the geodesic
+	// edges of their geometries stay within Densify of the lines, as drawn
+	// by planar viewers.
+	Densify s1.Angle
+}
+
+// UnmarshalStrict decodes a *Feature or a *FeatureCollection like
+// json.Unmarshal, but validates the coordinates of each geometry before
+// building it. Invalid geometries are reported by a *geoutil.ValidationError,
+// wrapped in a *FeatureError for the Features of a FeatureCollection.
+func UnmarshalStrict(data []byte, v interface{}) error {
+	return UnmarshalWithOptions(data, v, UnmarshalOptions{Strict: true})
+}
+
+// UnmarshalWithOptions decodes a *Feature or a *FeatureCollection like
+// json.Unmarshal with options. Errors decoding the Features of a
+// FeatureCollection are wrapped in a *FeatureError.
+func UnmarshalWithOptions(data []byte, v interface{}, options UnmarshalOptions) error {
+	switch v := v.(type) {
+	case *Feature:
+		return v.unmarshalJSON(data, options)
+	case *FeatureCollection:
+		rfc := &rawFeatureCollection{}
+		if err := json.Unmarshal(data, rfc); err != nil {
+			return err
+		}
+
+		if rfc.Type != "FeatureCollection" {
+			return fmt.Errorf("geojson: invalid FeatureCollection Type value %s", rfc.Type)
+		}
+
+		rawFeatures := []json.RawMessage{}
+		if err := json.Unmarshal(rfc.Features, &rawFeatures); err != nil {
+			return err
+		}
+
+		features := make([]*Feature, len(rawFeatures))
+		for i, rawFeature := range rawFeatures {
+			if bytes.Equal(rawFeature, []byte("null")) {
+				continue
+			}
+
+			features[i] = &Feature{}
+			if err := features[i].unmarshalJSON(rawFeature, options); err != nil {
+				return &FeatureError{Index: i, Err: err}
+			}
+		}
+
+		v.Features = features
+		return nil
+	default:
+		return fmt.Errorf("geojson: cannot unmarshal into %T", v)
+	}
+}
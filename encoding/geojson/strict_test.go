@@ -0,0 +1,76 @@
+package geojson
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/golang/geo/s2"
+
+	"github.com/topos-ai/geoutil"
+)
+
+func TestUnmarshalWithOptions(t *testing.T) {
+	data := []byte(`{"type":"Feature","properties":{},"geometry":{"type":"LineString","coordinates":[[0,60],[90,60]]}}`)
+	tolerance := geoutil.AngleFromMeters(100)
+
+	// The coordinates inserted along the straight line between coordinates
+	// of a parallel keep the line on the parallel.
+	f := &Feature{}
+	if err := UnmarshalWithOptions(data, f, UnmarshalOptions{Densify: tolerance}); err != nil {
+		t.Fatal(err)
+	}
+
+	polyline := f.Geometry.(*s2.Polyline)
+	if len(*polyline) <= 2 {
+		t.Fatalf("decoded %d vertices, want more than 2", len(*polyline))
+	}
+
+	for _, point := range *polyline {
+		if lat := s2.LatLngFromPoint(point).Lat.Degrees(); lat < 60-1e-9 || lat > 60+1e-9 {
+			t.Errorf("decoded vertex at latitude %v, want 60", lat)
+		}
+	}
+
+	f = &Feature{}
+	if err := UnmarshalWithOptions(data, f, UnmarshalOptions{}); err != nil || len(*f.Geometry.(*s2.Polyline)) != 2 {
+		t.Errorf("no densification: decoded %v, %v", f.Geometry, err)
+	}
+
+	invalid := []byte(`{"type":"Feature","properties":{},"geometry":{"type":"LineString","coordinates":[[0,60],[0,60]]}}`)
+	if err := UnmarshalWithOptions(invalid, &Feature{}, UnmarshalOptions{Strict: true, Densify: tolerance}); err == nil {
+		t.Errorf("strict: no error")
+	}
+}
+
+func TestMarshalDensify(t *testing.T) {
+	polygon, err := geoutil.PolygonFromPolygonCoordinates([][][]float64{{{0, 50}, {40, 50}, {40, 60}, {0, 60}, {0, 50}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f := &Feature{
+		Properties: map[string]interface{}{},
+		Geometry:   polygon,
+		Precision:  geoutil.PrecisionMax,
+		Densify:    geoutil.AngleFromMeters(100),
+	}
+
+	data, err := json.Marshal(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	raw := struct {
+		Geometry struct {
+			Coordinates [][][]float64 `json:"coordinates"`
+		} `json:"geometry"`
+	}{}
+
+	if err := json.Unmarshal(data, &raw); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := len(raw.Geometry.Coordinates[0]); n <= 5 {
+		t.Errorf("marshaled %d coordinates, want more than 5", n)
+	}
+}
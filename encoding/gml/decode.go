@@ -0,0 +1,590 @@
+package gml
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/golang/geo/s2"
+
+	"github.com/topos-ai/geoutil"
+)
+
+// node is a parsed XML element. GML elements are matched by local name so
+// that both GML 3.1 and 3.2 namespaces are accepted.
+type node struct {
+	name     string
+	attrs    []xml.Attr
+	children []*node
+	text     strings.Builder
+}
+
+func readNode(d *xml.Decoder, start *xml.StartElement) (*node, error) {
+	n := &node{
+		name:  start.Name.Local,
+		attrs: start.Attr,
+	}
+
+	for {
+		token, err := d.Token()
+		if err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+
+			return nil, err
+		}
+
+		switch token := token.(type) {
+		case xml.StartElement:
+			child, err := readNode(d, &token)
+			if err != nil {
+				return nil, err
+			}
+
+			n.children = append(n.children, child)
+		case xml.CharData:
+			n.text.Write(token)
+		case xml.EndElement:
+			return n, nil
+		}
+	}
+}
+
+func (n *node) attr(name string) string {
+	for _, attr := range n.attrs {
+		if attr.Name.Local == name {
+			return attr.Value
+		}
+	}
+
+	return ""
+}
+
+func (n *node) child(name string) *node {
+	for _, child := range n.children {
+		if child.name == name {
+			return child
+		}
+	}
+
+	return nil
+}
+
+// context holds the coordinate layout inherited from ancestor elements.
+type context struct {
+	latLon    bool
+	dimension int
+}
+
+func (c context) enter(n *node) (context, error) {
+	if srsName := n.attr("srsName"); srsName != "" {
+		latLon, err := axisOrder(srsName)
+		if err != nil {
+			return c, err
+		}
+
+		c.latLon = latLon
+	}
+
+	if srsDimension := n.attr("srsDimension"); srsDimension != "" {
+		dimension, err := strconv.Atoi(srsDimension)
+		if err != nil || dimension < 2 {
+			return c, fmt.Errorf("gml: invalid srsDimension %s", srsDimension)
+		}
+
+		c.dimension = dimension
+	}
+
+	return c, nil
+}
+
+// position orders the first two values of a tuple as longitude, latitude.
+func (c context) position(values []float64) []float64 {
+	if c.latLon {
+		return []float64{values[1], values[0]}
+	}
+
+	return []float64{values[0], values[1]}
+}
+
+func parseFloats(fields []string) ([]float64, error) {
+	values := make([]float64, len(fields))
+	for i, field := range fields {
+		v, err := strconv.ParseFloat(field, 64)
+		if err != nil {
+			return nil, fmt.Errorf("gml: invalid coordinate %s", field)
+		}
+
+		values[i] = v
+	}
+
+	return values, nil
+}
+
+// posList parses a pos or posList element.
+func (c context) posList(n *node) ([][]float64, error) {
+	c, err := c.enter(n)
+	if err != nil {
+		return nil, err
+	}
+
+	values, err := parseFloats(strings.Fields(n.text.String()))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(values)%c.dimension != 0 {
+		return nil, fmt.Errorf("gml: %d coordinates do not match srsDimension %d", len(values), c.dimension)
+	}
+
+	positions := make([][]float64, 0, len(values)/c.dimension)
+	for i := 0; i < len(values); i += c.dimension {
+		positions = append(positions, c.position(values[i:i+c.dimension]))
+	}
+
+	return positions, nil
+}
+
+// coordinates parses a GML 2 style coordinates element.
+func (c context) coordinates(n *node) ([][]float64, error) {
+	cs, ts, decimal := n.attr("cs"), n.attr("ts"), n.attr("decimal")
+	if cs == "" {
+		cs = ","
+	}
+
+	if decimal == "" {
+		decimal = "."
+	}
+
+	var tuples []string
+	if ts == "" || strings.TrimSpace(ts) == "" {
+		tuples = strings.Fields(n.text.String())
+	} else {
+		tuples = strings.Split(strings.TrimSpace(n.text.String()), ts)
+	}
+
+	positions := make([][]float64, 0, len(tuples))
+	for _, tuple := range tuples {
+		fields := strings.Split(strings.TrimSpace(tuple), cs)
+		for i := range fields {
+			fields[i] = strings.Replace(strings.TrimSpace(fields[i]), decimal, ".", 1)
+		}
+
+		values, err := parseFloats(fields)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(values) < 2 {
+			return nil, fmt.Errorf("gml: cannot process coordinates with dimension %d", len(values))
+		}
+
+		positions = append(positions, c.position(values))
+	}
+
+	return positions, nil
+}
+
+// positions parses the positions held directly by a point or curve element.
+func (c context) positions(n *node) ([][]float64, error) {
+	positions := [][]float64{}
+	for _, child := range n.children {
+		switch child.name {
+		case "posList", "pos":
+			p, err := c.posList(child)
+			if err != nil {
+				return nil, err
+			}
+
+			positions = append(positions, p...)
+
+		case "coordinates":
+			p, err := c.coordinates(child)
+			if err != nil {
+				return nil, err
+			}
+
+			positions = append(positions, p...)
+
+		case "coord":
+			x, y := child.child("X"), child.child("Y")
+			if x == nil || y == nil {
+				return nil, fmt.Errorf("gml: coord without X and Y")
+			}
+
+			values, err := parseFloats([]string{strings.TrimSpace(x.text.String()), strings.TrimSpace(y.text.String())})
+			if err != nil {
+				return nil, err
+			}
+
+			positions = append(positions, c.position(values))
+
+		case "pointProperty", "pointRep":
+			for _, point := range child.children {
+				pc, err := c.enter(point)
+				if err != nil {
+					return nil, err
+				}
+
+				p, err := pc.positions(point)
+				if err != nil {
+					return nil, err
+				}
+
+				positions = append(positions, p...)
+			}
+		}
+	}
+
+	return positions, nil
+}
+
+// appendLine concatenates curve segments, which share their end points.
+func appendLine(line, segment [][]float64) [][]float64 {
+	if n := len(line); n > 0 && len(segment) > 0 && line[n-1][0] == segment[0][0] && line[n-1][1] == segment[0][1] {
+		segment = segment[1:]
+	}
+
+	return append(line, segment...)
+}
+
+// line parses a LineString, LinearRing, Curve or Ring element.
+func (c context) line(n *node) ([][]float64, error) {
+	c, err := c.enter(n)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n.name {
+	case "LineString", "LinearRing", "LineStringSegment":
+		return c.positions(n)
+
+	case "Curve":
+		segments := n.child("segments")
+		if segments == nil {
+			return nil, fmt.Errorf("gml: Curve without segments")
+		}
+
+		line := [][]float64{}
+		for _, segment := range segments.children {
+			if segment.name != "LineStringSegment" {
+				return nil, fmt.Errorf("gml: unsupported curve segment %s", segment.name)
+			}
+
+			positions, err := c.line(segment)
+			if err != nil {
+				return nil, err
+			}
+
+			line = appendLine(line, positions)
+		}
+
+		return line, nil
+
+	case "Ring":
+		line := [][]float64{}
+		for _, member := range n.children {
+			if member.name != "curveMember" {
+				continue
+			}
+
+			for _, curve := range member.children {
+				positions, err := c.line(curve)
+				if err != nil {
+					return nil, err
+				}
+
+				line = appendLine(line, positions)
+			}
+		}
+
+		return line, nil
+
+	default:
+		return nil, fmt.Errorf("gml: unsupported curve element %s", n.name)
+	}
+}
+
+// ring parses the single ring held by a boundary element. Rings must hold at
+// least four positions and end where they start.
+func (c context) ring(boundary *node) ([][]float64, error) {
+	if len(boundary.children) != 1 {
+		return nil, fmt.Errorf("gml: %s must hold a single ring", boundary.name)
+	}
+
+	ring, err := c.line(boundary.children[0])
+	if err != nil {
+		return nil, err
+	}
+
+	if len(ring) < 4 {
+		return nil, fmt.Errorf("gml: ring with %d positions", len(ring))
+	}
+
+	if first, last := ring[0], ring[len(ring)-1]; first[0] != last[0] || first[1] != last[1] {
+		return nil, fmt.Errorf("gml: ring is not closed")
+	}
+
+	return ring, nil
+}
+
+// polygons parses a Polygon or Surface element into the coordinates of one or
+// more polygons.
+func (c context) polygons(n *node) ([][][][]float64, error) {
+	c, err := c.enter(n)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n.name {
+	case "Polygon", "PolygonPatch":
+		polygon := [][][]float64{}
+		for _, boundary := range n.children {
+			switch boundary.name {
+			case "exterior", "outerBoundaryIs":
+				ring, err := c.ring(boundary)
+				if err != nil {
+					return nil, err
+				}
+
+				polygon = append([][][]float64{ring}, polygon...)
+
+			case "interior", "innerBoundaryIs":
+				ring, err := c.ring(boundary)
+				if err != nil {
+					return nil, err
+				}
+
+				polygon = append(polygon, ring)
+			}
+		}
+
+		if len(polygon) == 0 {
+			return [][][][]float64{}, nil
+		}
+
+		return [][][][]float64{polygon}, nil
+
+	case "Surface":
+		patches := n.child("patches")
+		if patches == nil {
+			return nil, fmt.Errorf("gml: Surface without patches")
+		}
+
+		polygons := [][][][]float64{}
+		for _, patch := range patches.children {
+			if patch.name != "PolygonPatch" {
+				return nil, fmt.Errorf("gml: unsupported surface patch %s", patch.name)
+			}
+
+			p, err := c.polygons(patch)
+			if err != nil {
+				return nil, err
+			}
+
+			polygons = append(polygons, p...)
+		}
+
+		return polygons, nil
+
+	default:
+		return nil, fmt.Errorf("gml: unsupported surface element %s", n.name)
+	}
+}
+
+// members returns the geometries held by the member elements of a multi
+// geometry, whether held one per member or several per members element.
+func members(n *node, member, members string) []*node {
+	geometries := []*node{}
+	for _, child := range n.children {
+		if child.name == member || child.name == members {
+			geometries = append(geometries, child.children...)
+		}
+	}
+
+	return geometries
+}
+
+func (c context) geometry(n *node) (interface{}, error) {
+	c, err := c.enter(n)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n.name {
+	case "Point":
+		positions, err := c.positions(n)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(positions) != 1 {
+			return nil, fmt.Errorf("gml: Point with %d positions", len(positions))
+		}
+
+		return geoutil.PointFromPointCoordinates(positions[0])
+
+	case "LineString", "Curve":
+		line, err := c.line(n)
+		if err != nil {
+			return nil, err
+		}
+
+		return geoutil.PolylineFromLineStringCoordinates(line)
+
+	case "Polygon", "Surface":
+		polygons, err := c.polygons(n)
+		if err != nil {
+			return nil, err
+		}
+
+		return geoutil.PolygonFromMultiPolygonCoordinates(polygons)
+
+	case "MultiPoint":
+		points := [][]float64{}
+		for _, point := range members(n, "pointMember", "pointMembers") {
+			pc, err := c.enter(point)
+			if err != nil {
+				return nil, err
+			}
+
+			positions, err := pc.positions(point)
+			if err != nil {
+				return nil, err
+			}
+
+			points = append(points, positions...)
+		}
+
+		return geoutil.PointsFromMultiPointCoordinates(points)
+
+	case "MultiCurve", "MultiLineString":
+		lines := [][][]float64{}
+		for _, curve := range append(members(n, "curveMember", "curveMembers"), members(n, "lineStringMember", "")...) {
+			line, err := c.line(curve)
+			if err != nil {
+				return nil, err
+			}
+
+			lines = append(lines, line)
+		}
+
+		return geoutil.PolylinesFromMultiLineStringCoordinates(lines)
+
+	case "MultiSurface", "MultiPolygon":
+		polygons := [][][][]float64{}
+		for _, surface := range append(members(n, "surfaceMember", "surfaceMembers"), members(n, "polygonMember", "")...) {
+			p, err := c.polygons(surface)
+			if err != nil {
+				return nil, err
+			}
+
+			polygons = append(polygons, p...)
+		}
+
+		return geoutil.PolygonFromMultiPolygonCoordinates(polygons)
+
+	default:
+		return nil, fmt.Errorf("gml: unsupported geometry element %s", n.name)
+	}
+}
+
+// DecodeElement decodes the GML geometry element starting at start, so that
+// geometries embedded in other XML documents such as WFS responses can be
+// read. The geometry is returned as one of the s2 types used by geoutil:
+// s2.Point, []s2.Point, *s2.Polyline, []*s2.Polyline or *s2.Polygon.
+//
+// Coordinates follow the axis order of the srsName of the element or of its
+// ancestors. EPSG:4326 and EPSG:4258 are in latitude, longitude order; the
+// OGC CRS84 identifiers are in longitude, latitude order. Elements without
+// srsName are assumed to be in EPSG:4326.
+func DecodeElement(d *xml.Decoder, start *xml.StartElement) (interface{}, error) {
+	n, err := readNode(d, start)
+	if err != nil {
+		return nil, err
+	}
+
+	c := context{
+		latLon:    true,
+		dimension: 2,
+	}
+
+	return c.geometry(n)
+}
+
+// Unmarshal decodes a GML geometry document into v, which must be a
+// *s2.Point, *s2.LatLng, *[]s2.Point, *s2.Polyline, *[]*s2.Polyline or
+// *s2.Polygon matching the geometry, or an *interface{} receiving any of them.
+// See DecodeElement.
+func Unmarshal(data []byte, v interface{}) error {
+	d := xml.NewDecoder(bytes.NewReader(data))
+	var start xml.StartElement
+	for {
+		token, err := d.Token()
+		if err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+
+			return err
+		}
+
+		if t, ok := token.(xml.StartElement); ok {
+			start = t
+			break
+		}
+	}
+
+	decoded, err := DecodeElement(d, &start)
+	if err != nil {
+		return err
+	}
+
+	switch target := v.(type) {
+	case *interface{}:
+		*target = decoded
+		return nil
+
+	case *s2.LatLng:
+		if point, ok := decoded.(s2.Point); ok {
+			*target = s2.LatLngFromPoint(point)
+			return nil
+		}
+
+	case *s2.Point:
+		if point, ok := decoded.(s2.Point); ok {
+			*target = point
+			return nil
+		}
+
+	case *[]s2.Point:
+		if points, ok := decoded.([]s2.Point); ok {
+			*target = points
+			return nil
+		}
+
+	case *s2.Polyline:
+		if polyline, ok := decoded.(*s2.Polyline); ok {
+			*target = *polyline
+			return nil
+		}
+
+	case *[]*s2.Polyline:
+		if polylines, ok := decoded.([]*s2.Polyline); ok {
+			*target = polylines
+			return nil
+		}
+
+	case *s2.Polygon:
+		if polygon, ok := decoded.(*s2.Polygon); ok {
+			*target = *polygon
+			return nil
+		}
+	}
+
+	return fmt.Errorf("gml: %s cannot be decoded into %T", start.Name.Local, v)
+}
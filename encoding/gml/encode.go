@@ -0,0 +1,222 @@
+package gml
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/golang/geo/s2"
+
+	"github.com/topos-ai/geoutil"
+)
+
+// Encoder writes GML geometry elements to an output stream. Every element is
+// written with the gml prefix bound on the geometry itself. GML 3.2 requires
+// gml:id attributes, which are numbered per Encoder.
+type Encoder struct {
+	w         io.Writer
+	version   Version
+	precision int
+	count     int
+}
+
+// NewEncoder returns an Encoder writing geometries in the given GML version
+// with coordinates rounded to a geoutil precision level.
+func NewEncoder(w io.Writer, version Version, precision int) *Encoder {
+	return &Encoder{
+		w:         w,
+		version:   version,
+		precision: precision,
+	}
+}
+
+// element writes GML elements, assigning the identifiers of GML 3.2.
+type element struct {
+	b       *strings.Builder
+	version Version
+	id      string
+	count   int
+}
+
+func (e *element) open(name string, attrs string) {
+	e.b.WriteString("<gml:")
+	e.b.WriteString(name)
+	e.b.WriteString(attrs)
+	e.b.WriteString(">")
+}
+
+// openGeometry opens a geometry element, which carries a gml:id in GML 3.2.
+func (e *element) openGeometry(name string, attrs string) {
+	if e.version == Version32 {
+		attrs = fmt.Sprintf(" gml:id=\"%s.%d\"%s", e.id, e.count, attrs)
+		e.count++
+	}
+
+	e.open(name, attrs)
+}
+
+func (e *element) close(name string) {
+	e.b.WriteString("</gml:")
+	e.b.WriteString(name)
+	e.b.WriteString(">")
+}
+
+// posList writes positions in latitude, longitude order.
+func (e *element) posList(name string, positions [][]float64) {
+	e.open(name, "")
+	for i, position := range positions {
+		if i > 0 {
+			e.b.WriteString(" ")
+		}
+
+		e.b.WriteString(formatFloat(position[1]))
+		e.b.WriteString(" ")
+		e.b.WriteString(formatFloat(position[0]))
+	}
+
+	e.close(name)
+}
+
+func (e *element) point(attrs string, position []float64) {
+	e.openGeometry("Point", attrs)
+	e.posList("pos", [][]float64{position})
+	e.close("Point")
+}
+
+func (e *element) lineString(attrs string, line [][]float64) {
+	e.openGeometry("LineString", attrs)
+	e.posList("posList", line)
+	e.close("LineString")
+}
+
+func (e *element) polygon(attrs string, polygon [][][]float64) {
+	e.openGeometry("Polygon", attrs)
+	for i, ring := range polygon {
+		boundary := "interior"
+		if i == 0 {
+			boundary = "exterior"
+		}
+
+		e.open(boundary, "")
+		e.open("LinearRing", "")
+		e.posList("posList", ring)
+		e.close("LinearRing")
+		e.close(boundary)
+	}
+
+	e.close("Polygon")
+}
+
+func (e *element) encode(v interface{}, attrs string, precision int) error {
+	switch geometry := v.(type) {
+	case s2.LatLng:
+		return e.encode(s2.PointFromLatLng(geometry), attrs, precision)
+
+	case s2.Point:
+		coords, err := geoutil.PointCoordinates(geometry, precision)
+		if err != nil {
+			return err
+		}
+
+		e.point(attrs, coords)
+
+	case []s2.Point:
+		e.openGeometry("MultiPoint", attrs)
+		for _, point := range geometry {
+			coords, err := geoutil.PointCoordinates(point, precision)
+			if err != nil {
+				return err
+			}
+
+			e.open("pointMember", "")
+			e.point("", coords)
+			e.close("pointMember")
+		}
+
+		e.close("MultiPoint")
+
+	case *s2.Polyline:
+		coords, err := geoutil.PolylineCoordinates(geometry, precision)
+		if err != nil {
+			return err
+		}
+
+		e.lineString(attrs, coords)
+
+	case []*s2.Polyline:
+		e.openGeometry("MultiCurve", attrs)
+		for _, polyline := range geometry {
+			coords, err := geoutil.PolylineCoordinates(polyline, precision)
+			if err != nil {
+				return err
+			}
+
+			e.open("curveMember", "")
+			e.lineString("", coords)
+			e.close("curveMember")
+		}
+
+		e.close("MultiCurve")
+
+	case *s2.Polygon:
+		polygonCoordinates, err := geoutil.PolygonCoordinates(geometry, precision)
+		if err != nil {
+			return err
+		}
+
+		if len(polygonCoordinates) == 1 {
+			e.polygon(attrs, polygonCoordinates[0])
+			return nil
+		}
+
+		e.openGeometry("MultiSurface", attrs)
+		for _, polygonCoords := range polygonCoordinates {
+			e.open("surfaceMember", "")
+			e.polygon("", polygonCoords)
+			e.close("surfaceMember")
+		}
+
+		e.close("MultiSurface")
+
+	default:
+		return fmt.Errorf("gml: unknown geometry type %T", v)
+	}
+
+	return nil
+}
+
+// Encode writes the GML element of a geometry. Polygons with a single shell
+// are written as gml:Polygon and polygons with several shells as
+// gml:MultiSurface; multiple polylines are written as gml:MultiCurve.
+// Coordinates are written in the latitude, longitude order of SRSName.
+func (enc *Encoder) Encode(v interface{}) error {
+	namespace := Namespace32
+	if enc.version == Version31 {
+		namespace = Namespace31
+	}
+
+	e := &element{
+		b:       &strings.Builder{},
+		version: enc.version,
+		id:      fmt.Sprintf("geom%d", enc.count),
+	}
+
+	attrs := fmt.Sprintf(" xmlns:gml=\"%s\" srsName=\"%s\" srsDimension=\"2\"", namespace, SRSName)
+	if err := e.encode(v, attrs, enc.precision); err != nil {
+		return err
+	}
+
+	enc.count++
+	_, err := io.WriteString(enc.w, e.b.String())
+	return err
+}
+
+func Marshal(v interface{}, version Version, precision int) ([]byte, error) {
+	w := bytes.NewBuffer([]byte{})
+	if err := NewEncoder(w, version, precision).Encode(v); err != nil {
+		return nil, err
+	}
+
+	return w.Bytes(), nil
+}
@@ -0,0 +1,68 @@
+// Package gml reads and writes GML 3.1 and 3.2 simple feature geometries.
+package gml
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Version selects the GML version written by an Encoder.
+type Version int
+
+const (
+	Version32 Version = iota
+	Version31
+)
+
+const (
+	Namespace31 = "http://www.opengis.net/gml"
+	Namespace32 = "http://www.opengis.net/gml/3.2"
+)
+
+// SRSName is the spatial reference system written by an Encoder. Coordinates
+// are written in its latitude, longitude axis order.
+const SRSName = "urn:ogc:def:crs:EPSG::4326"
+
+// latLonCodes are the EPSG codes of the geographic reference systems that can
+// be read, all of which have a latitude, longitude axis order.
+var latLonCodes = map[string]bool{
+	"4326": true,
+	"4258": true,
+	"4979": true,
+}
+
+// axisOrder reports whether coordinates of a reference system are in
+// latitude, longitude order. Geometries without a reference system are
+// assumed to be in EPSG:4326 and so in latitude, longitude order. Only the
+// OGC CRS84 identifiers and the legacy GML 2 EPSG URLs are in longitude,
+// latitude order.
+func axisOrder(srsName string) (bool, error) {
+	if srsName == "" {
+		return true, nil
+	}
+
+	name := strings.ToLower(srsName)
+	if strings.HasSuffix(name, "crs84") || name == "crs:84" {
+		return false, nil
+	}
+
+	if code := strings.TrimPrefix(name, "http://www.opengis.net/gml/srs/epsg.xml#"); code != name {
+		if latLonCodes[code] {
+			return false, nil
+		}
+
+		return false, fmt.Errorf("gml: unsupported srsName %s", srsName)
+	}
+
+	code := name[strings.LastIndexAny(name, ":/#")+1:]
+	if strings.Contains(name, "epsg") && latLonCodes[code] {
+		return true, nil
+	}
+
+	return false, fmt.Errorf("gml: unsupported srsName %s", srsName)
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
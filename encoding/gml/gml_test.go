@@ -0,0 +1,375 @@
+package gml
+
+import (
+	"bytes"
+	"encoding/xml"
+	"reflect"
+	"testing"
+
+	"github.com/golang/geo/s2"
+
+	"github.com/topos-ai/geoutil"
+)
+
+// unmarshal decodes a GML document into a geometry of any type.
+func unmarshal(data []byte) (interface{}, error) {
+	var v interface{}
+	err := Unmarshal(data, &v)
+	return v, err
+}
+
+func polygonCoordinates(t *testing.T, v interface{}) [][][][]float64 {
+	t.Helper()
+	polygon, ok := v.(*s2.Polygon)
+	if !ok {
+		t.Fatalf("decoded %T, want a polygon", v)
+	}
+
+	coords, err := geoutil.PolygonCoordinates(polygon, geoutil.PrecisionE6)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return coords
+}
+
+func polylineCoordinates(t *testing.T, v interface{}) [][]float64 {
+	t.Helper()
+	polyline, ok := v.(*s2.Polyline)
+	if !ok {
+		t.Fatalf("decoded %T, want a polyline", v)
+	}
+
+	coords, err := geoutil.PolylineCoordinates(polyline, geoutil.PrecisionE6)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return coords
+}
+
+func TestAxisOrder(t *testing.T) {
+	tests := []struct {
+		srsName string
+		latLon  bool
+	}{
+		{"", true},
+		{"EPSG:4326", true},
+		{"urn:ogc:def:crs:EPSG::4326", true},
+		{"urn:ogc:def:crs:EPSG:6.6:4258", true},
+		{"http://www.opengis.net/def/crs/EPSG/0/4326", true},
+		{"urn:ogc:def:crs:OGC:1.3:CRS84", false},
+		{"http://www.opengis.net/def/crs/OGC/1.3/CRS84", false},
+		{"http://www.opengis.net/gml/srs/epsg.xml#4326", false},
+	}
+
+	for _, test := range tests {
+		latLon, err := axisOrder(test.srsName)
+		if err != nil {
+			t.Errorf("%s: %v", test.srsName, err)
+			continue
+		}
+
+		if latLon != test.latLon {
+			t.Errorf("%s: latitude first %v, want %v", test.srsName, latLon, test.latLon)
+		}
+	}
+
+	for _, srsName := range []string{"EPSG:3857", "urn:ogc:def:crs:EPSG::27700", "http://www.opengis.net/gml/srs/epsg.xml#3857"} {
+		if _, err := axisOrder(srsName); err == nil {
+			t.Errorf("%s: no error", srsName)
+		}
+	}
+}
+
+func TestUnmarshal(t *testing.T) {
+	point, err := unmarshal([]byte(`<gml:Point xmlns:gml="http://www.opengis.net/gml/3.2" srsName="EPSG:4326"><gml:pos>45.5 -73.5</gml:pos></gml:Point>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	coords, _ := geoutil.PointCoordinates(point.(s2.Point), geoutil.PrecisionE6)
+	if !reflect.DeepEqual(coords, []float64{-73.5, 45.5}) {
+		t.Errorf("decoded point %v, want [-73.5 45.5]", coords)
+	}
+
+	// GML 2 coordinates in longitude, latitude order.
+	line, err := unmarshal([]byte(`<gml:LineString xmlns:gml="http://www.opengis.net/gml" srsName="http://www.opengis.net/gml/srs/epsg.xml#4326">
+		<gml:coordinates decimal="," cs=";" ts=" ">1,5;2 3;4,5</gml:coordinates>
+	</gml:LineString>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := polylineCoordinates(t, line), [][]float64{{1.5, 2}, {3, 4.5}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("decoded line %v, want %v", got, want)
+	}
+
+	// Three dimensional positions inherit srsDimension and drop the height.
+	curve, err := unmarshal([]byte(`<gml:Curve xmlns:gml="http://www.opengis.net/gml/3.2" srsName="urn:ogc:def:crs:OGC:1.3:CRS84" srsDimension="3">
+		<gml:segments>
+			<gml:LineStringSegment><gml:posList>0 0 10 1 1 10</gml:posList></gml:LineStringSegment>
+			<gml:LineStringSegment><gml:posList>1 1 10 2 0 10</gml:posList></gml:LineStringSegment>
+		</gml:segments>
+	</gml:Curve>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := polylineCoordinates(t, curve), [][]float64{{0, 0}, {1, 1}, {2, 0}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("decoded curve %v, want %v", got, want)
+	}
+
+	polygon, err := unmarshal([]byte(`<gml:Polygon xmlns:gml="http://www.opengis.net/gml/3.2" gml:id="p1">
+		<gml:exterior><gml:LinearRing><gml:posList>0 0 0 10 10 10 10 0 0 0</gml:posList></gml:LinearRing></gml:exterior>
+		<gml:interior><gml:LinearRing><gml:posList>2 2 4 2 4 4 2 4 2 2</gml:posList></gml:LinearRing></gml:interior>
+	</gml:Polygon>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := [][][][]float64{{
+		{{0, 0}, {10, 0}, {10, 10}, {0, 10}, {0, 0}},
+		{{2, 2}, {2, 4}, {4, 4}, {4, 2}, {2, 2}},
+	}}
+
+	if got := polygonCoordinates(t, polygon); !reflect.DeepEqual(got, want) {
+		t.Errorf("decoded polygon %v, want %v", got, want)
+	}
+
+	// A GML 2 polygon whose ring is made of pos elements.
+	polygon, err = unmarshal([]byte(`<gml:Polygon xmlns:gml="http://www.opengis.net/gml">
+		<gml:outerBoundaryIs><gml:LinearRing>
+			<gml:pos>0 0</gml:pos><gml:pos>0 10</gml:pos><gml:pos>10 10</gml:pos><gml:pos>10 0</gml:pos><gml:pos>0 0</gml:pos>
+		</gml:LinearRing></gml:outerBoundaryIs>
+	</gml:Polygon>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := polygonCoordinates(t, polygon), [][][][]float64{want[0][:1]}; !reflect.DeepEqual(got, want) {
+		t.Errorf("decoded polygon %v, want %v", got, want)
+	}
+}
+
+func TestUnmarshalMulti(t *testing.T) {
+	points, err := unmarshal([]byte(`<gml:MultiPoint xmlns:gml="http://www.opengis.net/gml/3.2">
+		<gml:pointMember><gml:Point><gml:pos>1 2</gml:pos></gml:Point></gml:pointMember>
+		<gml:pointMembers><gml:Point><gml:pos>3 4</gml:pos></gml:Point><gml:Point><gml:pos>5 6</gml:pos></gml:Point></gml:pointMembers>
+	</gml:MultiPoint>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ps, ok := points.([]s2.Point); !ok || len(ps) != 3 {
+		t.Errorf("decoded points %v", points)
+	}
+
+	lines, err := unmarshal([]byte(`<gml:MultiCurve xmlns:gml="http://www.opengis.net/gml/3.2">
+		<gml:curveMember><gml:LineString><gml:posList>0 0 1 1</gml:posList></gml:LineString></gml:curveMember>
+		<gml:curveMember><gml:LineString><gml:posList>2 2 3 3</gml:posList></gml:LineString></gml:curveMember>
+	</gml:MultiCurve>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ls, ok := lines.([]*s2.Polyline); !ok || len(ls) != 2 {
+		t.Errorf("decoded lines %v", lines)
+	}
+
+	surfaces, err := unmarshal([]byte(`<gml:MultiSurface xmlns:gml="http://www.opengis.net/gml/3.2">
+		<gml:surfaceMember><gml:Polygon><gml:exterior><gml:LinearRing><gml:posList>0 0 0 1 1 1 1 0 0 0</gml:posList></gml:LinearRing></gml:exterior></gml:Polygon></gml:surfaceMember>
+		<gml:surfaceMember><gml:Surface><gml:patches><gml:PolygonPatch><gml:exterior>
+			<gml:Ring><gml:curveMember><gml:LineString><gml:posList>5 5 5 6 6 6</gml:posList></gml:LineString></gml:curveMember>
+			<gml:curveMember><gml:LineString><gml:posList>6 6 6 5 5 5</gml:posList></gml:LineString></gml:curveMember></gml:Ring>
+		</gml:exterior></gml:PolygonPatch></gml:patches></gml:Surface></gml:surfaceMember>
+	</gml:MultiSurface>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := polygonCoordinates(t, surfaces); len(got) != 2 || len(got[1][0]) != 5 {
+		t.Errorf("decoded surfaces %v", got)
+	}
+}
+
+func TestUnmarshalErrors(t *testing.T) {
+	tests := map[string]string{
+		"single position ring":  `<gml:Polygon xmlns:gml="http://www.opengis.net/gml/3.2"><gml:exterior><gml:LinearRing><gml:posList>0 0</gml:posList></gml:LinearRing></gml:exterior></gml:Polygon>`,
+		"empty ring":            `<gml:Polygon xmlns:gml="http://www.opengis.net/gml/3.2"><gml:exterior><gml:LinearRing><gml:posList></gml:posList></gml:LinearRing></gml:exterior></gml:Polygon>`,
+		"three position ring":   `<gml:Polygon xmlns:gml="http://www.opengis.net/gml/3.2"><gml:exterior><gml:LinearRing><gml:posList>0 0 1 1 0 0</gml:posList></gml:LinearRing></gml:exterior></gml:Polygon>`,
+		"unclosed ring":         `<gml:Polygon xmlns:gml="http://www.opengis.net/gml/3.2"><gml:exterior><gml:LinearRing><gml:posList>0 0 0 1 1 1 1 0</gml:posList></gml:LinearRing></gml:exterior></gml:Polygon>`,
+		"short interior":        `<gml:Polygon xmlns:gml="http://www.opengis.net/gml/3.2"><gml:exterior><gml:LinearRing><gml:posList>0 0 0 9 9 9 0 0</gml:posList></gml:LinearRing></gml:exterior><gml:interior><gml:LinearRing><gml:posList>1 1</gml:posList></gml:LinearRing></gml:interior></gml:Polygon>`,
+		"two rings":             `<gml:Polygon xmlns:gml="http://www.opengis.net/gml/3.2"><gml:exterior><gml:LinearRing/><gml:LinearRing/></gml:exterior></gml:Polygon>`,
+		"dimension mismatch":    `<gml:LineString xmlns:gml="http://www.opengis.net/gml/3.2" srsDimension="3"><gml:posList>0 0 1 1</gml:posList></gml:LineString>`,
+		"invalid srsDimension":  `<gml:LineString xmlns:gml="http://www.opengis.net/gml/3.2" srsDimension="1"><gml:posList>0 0 1 1</gml:posList></gml:LineString>`,
+		"invalid coordinate":    `<gml:Point xmlns:gml="http://www.opengis.net/gml/3.2"><gml:pos>0 x</gml:pos></gml:Point>`,
+		"point positions":       `<gml:Point xmlns:gml="http://www.opengis.net/gml/3.2"><gml:pos>0 0 1 1</gml:pos></gml:Point>`,
+		"unsupported srsName":   `<gml:Point xmlns:gml="http://www.opengis.net/gml/3.2" srsName="EPSG:3857"><gml:pos>0 0</gml:pos></gml:Point>`,
+		"unsupported geometry":  `<gml:Solid xmlns:gml="http://www.opengis.net/gml/3.2"/>`,
+		"unsupported segment":   `<gml:Curve xmlns:gml="http://www.opengis.net/gml/3.2"><gml:segments><gml:Arc/></gml:segments></gml:Curve>`,
+		"curve without segment": `<gml:Curve xmlns:gml="http://www.opengis.net/gml/3.2"/>`,
+		"truncated":             `<gml:Point xmlns:gml="http://www.opengis.net/gml/3.2"><gml:pos>0 0</gml:pos>`,
+		"empty":                 ``,
+	}
+
+	for name, data := range tests {
+		if _, err := unmarshal([]byte(data)); err == nil {
+			t.Errorf("%s: no error", name)
+		}
+	}
+}
+
+func TestUnmarshalTargets(t *testing.T) {
+	point := []byte(`<gml:Point xmlns:gml="http://www.opengis.net/gml/3.2"><gml:pos>45.5 -73.5</gml:pos></gml:Point>`)
+	var latLng s2.LatLng
+	if err := Unmarshal(point, &latLng); err != nil || !s2.PointFromLatLng(latLng).ApproxEqual(s2.PointFromLatLng(s2.LatLngFromDegrees(45.5, -73.5))) {
+		t.Errorf("decoded latlng %v, %v", latLng, err)
+	}
+
+	var p s2.Point
+	if err := Unmarshal(point, &p); err != nil || !p.ApproxEqual(s2.PointFromLatLng(s2.LatLngFromDegrees(45.5, -73.5))) {
+		t.Errorf("decoded point %v, %v", p, err)
+	}
+
+	var polyline s2.Polyline
+	if err := Unmarshal([]byte(`<gml:LineString xmlns:gml="http://www.opengis.net/gml/3.2"><gml:posList>0 0 1 1</gml:posList></gml:LineString>`), &polyline); err != nil || len(polyline) != 2 {
+		t.Errorf("decoded polyline %v, %v", polyline, err)
+	}
+
+	var polygon s2.Polygon
+	if err := Unmarshal([]byte(`<gml:Polygon xmlns:gml="http://www.opengis.net/gml/3.2"><gml:exterior><gml:LinearRing><gml:posList>0 0 0 1 1 1 1 0 0 0</gml:posList></gml:LinearRing></gml:exterior></gml:Polygon>`), &polygon); err != nil || polygon.NumLoops() != 1 {
+		t.Errorf("decoded polygon with %d loops, %v", polygon.NumLoops(), err)
+	}
+
+	var points []s2.Point
+	if err := Unmarshal([]byte(`<gml:MultiPoint xmlns:gml="http://www.opengis.net/gml/3.2"><gml:pointMember><gml:Point><gml:pos>1 2</gml:pos></gml:Point></gml:pointMember></gml:MultiPoint>`), &points); err != nil || len(points) != 1 {
+		t.Errorf("decoded points %v, %v", points, err)
+	}
+
+	var polylines []*s2.Polyline
+	if err := Unmarshal([]byte(`<gml:MultiCurve xmlns:gml="http://www.opengis.net/gml/3.2"><gml:curveMember><gml:LineString><gml:posList>0 0 1 1</gml:posList></gml:LineString></gml:curveMember></gml:MultiCurve>`), &polylines); err != nil || len(polylines) != 1 {
+		t.Errorf("decoded polylines %v, %v", polylines, err)
+	}
+
+	for _, v := range []interface{}{&polyline, &polygon, &points, s2.Point{}, nil} {
+		if err := Unmarshal(point, v); err == nil {
+			t.Errorf("decoding a point into %T returned no error", v)
+		}
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	point := s2.PointFromLatLng(s2.LatLngFromDegrees(45.5, -73.5))
+	points, _ := geoutil.PointsFromMultiPointCoordinates([][]float64{{1, 2}, {3, 4}})
+	polyline, _ := geoutil.PolylineFromLineStringCoordinates([][]float64{{0, 0}, {1, 1}, {2, 0}})
+	polylines, _ := geoutil.PolylinesFromMultiLineStringCoordinates([][][]float64{{{0, 0}, {1, 1}}, {{2, 2}, {3, 3}}})
+	polygon, _ := geoutil.PolygonFromPolygonCoordinates([][][]float64{
+		{{0, 0}, {10, 0}, {10, 10}, {0, 10}, {0, 0}},
+		{{2, 2}, {2, 4}, {4, 4}, {4, 2}, {2, 2}},
+	})
+	multipolygon, _ := geoutil.PolygonFromMultiPolygonCoordinates([][][][]float64{
+		{{{0, 0}, {1, 0}, {1, 1}, {0, 1}, {0, 0}}},
+		{{{5, 5}, {6, 5}, {6, 6}, {5, 6}, {5, 5}}},
+	})
+
+	for _, version := range []Version{Version31, Version32} {
+		for _, geometry := range []interface{}{point, points, polyline, polylines, polygon, multipolygon} {
+			data, err := Marshal(geometry, version, geoutil.PrecisionE6)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			decoded, err := unmarshal(data)
+			if err != nil {
+				t.Fatalf("version %d: %v in %s", version, err, data)
+			}
+
+			if reflect.TypeOf(decoded) != reflect.TypeOf(geometry) {
+				t.Errorf("version %d: decoded %T, want %T", version, decoded, geometry)
+				continue
+			}
+
+			switch geometry := geometry.(type) {
+			case s2.Point:
+				if decoded.(s2.Point).Distance(geometry).Degrees() > 1e-6 {
+					t.Errorf("version %d: decoded point %v, want %v", version, decoded, geometry)
+				}
+			case *s2.Polyline:
+				if got, want := polylineCoordinates(t, decoded), polylineCoordinates(t, geometry); !reflect.DeepEqual(got, want) {
+					t.Errorf("version %d: decoded line %v, want %v", version, got, want)
+				}
+			case *s2.Polygon:
+				if got, want := polygonCoordinates(t, decoded), polygonCoordinates(t, geometry); !reflect.DeepEqual(got, want) {
+					t.Errorf("version %d: decoded polygon %v, want %v", version, got, want)
+				}
+			}
+		}
+	}
+}
+
+func TestEncoder(t *testing.T) {
+	point := s2.PointFromLatLng(s2.LatLngFromDegrees(45.5, -73.5))
+	w := &bytes.Buffer{}
+	enc := NewEncoder(w, Version32, geoutil.PrecisionE6)
+	for i := 0; i < 2; i++ {
+		if err := enc.Encode(point); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Coordinates are written in latitude, longitude order and every
+	// geometry gets its own gml:id.
+	want := `<gml:Point gml:id="geom0.0" xmlns:gml="http://www.opengis.net/gml/3.2" srsName="urn:ogc:def:crs:EPSG::4326" srsDimension="2"><gml:pos>45.5 -73.5</gml:pos></gml:Point>` +
+		`<gml:Point gml:id="geom1.0" xmlns:gml="http://www.opengis.net/gml/3.2" srsName="urn:ogc:def:crs:EPSG::4326" srsDimension="2"><gml:pos>45.5 -73.5</gml:pos></gml:Point>`
+	if w.String() != want {
+		t.Errorf("encoded %s, want %s", w.String(), want)
+	}
+
+	data, err := Marshal(point, Version31, geoutil.PrecisionE6)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if bytes.Contains(data, []byte("gml:id")) {
+		t.Errorf("GML 3.1 geometry has a gml:id: %s", data)
+	}
+
+	if _, err := Marshal(1, Version32, geoutil.PrecisionE6); err == nil {
+		t.Errorf("encoding an unknown geometry returned no error")
+	}
+}
+
+func TestDecodeElement(t *testing.T) {
+
+	// A geometry embedded in a WFS response is decoded from its start element.
+	data := `<wfs:FeatureCollection xmlns:wfs="http://www.opengis.net/wfs/2.0" xmlns:gml="http://www.opengis.net/gml/3.2">
+		<wfs:member><app:road xmlns:app="http://example.com/app"><app:geometry>
+			<gml:LineString srsName="urn:ogc:def:crs:OGC:1.3:CRS84"><gml:posList>0 0 1 1</gml:posList></gml:LineString>
+		</app:geometry></app:road></wfs:member>
+	</wfs:FeatureCollection>`
+
+	d := xml.NewDecoder(bytes.NewReader([]byte(data)))
+	for {
+		token, err := d.Token()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		start, ok := token.(xml.StartElement)
+		if !ok || start.Name.Local != "LineString" {
+			continue
+		}
+
+		geometry, err := DecodeElement(d, &start)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got := polylineCoordinates(t, geometry); !reflect.DeepEqual(got, [][]float64{{0, 0}, {1, 1}}) {
+			t.Errorf("decoded line %v", got)
+		}
+
+		break
+	}
+}
@@ -0,0 +1,108 @@
+package gpkg
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/golang/geo/s2"
+
+	"github.com/topos-ai/geoutil/encoding/wkb"
+)
+
+var errShortHeader = errors.New("gpkg: geometry blob too short")
+
+// DecodeHeader decodes the header of a GeoPackage geometry blob and returns it
+// along with the remainder of the blob: WKB for standard blobs, or the
+// extension code and data for extended ones.
+func DecodeHeader(data []byte) (*Header, []byte, error) {
+	if len(data) < 8 {
+		return nil, nil, errShortHeader
+	}
+
+	if data[0] != magic[0] || data[1] != magic[1] {
+		return nil, nil, fmt.Errorf("gpkg: invalid magic %q", data[:2])
+	}
+
+	if data[2] != version {
+		return nil, nil, fmt.Errorf("gpkg: unknown version %d", data[2])
+	}
+
+	flags := data[3]
+	var order binary.ByteOrder = binary.BigEndian
+	if flags&flagByteOrder != 0 {
+		order = binary.LittleEndian
+	}
+
+	header := &Header{
+		SRID:         int32(order.Uint32(data[4:])),
+		EnvelopeType: EnvelopeType((flags & flagEnvelopeMask) >> 1),
+		Empty:        flags&flagEmpty != 0,
+		Extended:     flags&flagExtended != 0,
+	}
+
+	if header.EnvelopeType > EnvelopeXYZM {
+		return nil, nil, fmt.Errorf("gpkg: invalid envelope type %d", header.EnvelopeType)
+	}
+
+	n := header.EnvelopeType.size()
+	if len(data) < 8+n {
+		return nil, nil, errShortHeader
+	}
+
+	values := make([]float64, n/8)
+	if err := binary.Read(bytes.NewReader(data[8:8+n]), order, values); err != nil {
+		return nil, nil, err
+	}
+
+	if len(values) > 0 {
+		e := &header.Envelope
+		e.MinX, e.MaxX, e.MinY, e.MaxY = values[0], values[1], values[2], values[3]
+		switch header.EnvelopeType {
+		case EnvelopeXYZ:
+			e.MinZ, e.MaxZ = values[4], values[5]
+		case EnvelopeXYM:
+			e.MinM, e.MaxM = values[4], values[5]
+		case EnvelopeXYZM:
+			e.MinZ, e.MaxZ, e.MinM, e.MaxM = values[4], values[5], values[6], values[7]
+		}
+	}
+
+	return header, data[8+n:], nil
+}
+
+// Unmarshal decodes a GeoPackage geometry blob into v, which may be any of the
+// targets accepted by wkb.Unmarshal or an *Extension for extended blobs.
+func Unmarshal(data []byte, v interface{}) error {
+	header, body, err := DecodeHeader(data)
+	if err != nil {
+		return err
+	}
+
+	if header.Extended {
+		extension, ok := v.(*Extension)
+		if !ok {
+			return fmt.Errorf("gpkg: extended geometry cannot be decoded into %T", v)
+		}
+
+		if len(body) < len(extension.Code) {
+			return errShortHeader
+		}
+
+		copy(extension.Code[:], body)
+		extension.Data = append([]byte{}, body[len(extension.Code):]...)
+		return nil
+	}
+
+	switch v.(type) {
+	case *Extension:
+		return errors.New("gpkg: standard geometry cannot be decoded into *Extension")
+	case *s2.LatLng, *s2.Point:
+		if header.Empty {
+			return fmt.Errorf("gpkg: empty geometry cannot be decoded into %T", v)
+		}
+	}
+
+	return wkb.Unmarshal(body, v)
+}
@@ -0,0 +1,176 @@
+package gpkg
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/golang/geo/s2"
+
+	"github.com/topos-ai/geoutil/encoding/wkb"
+)
+
+func envelopeFromRect(rect s2.Rect) Envelope {
+	envelope := Envelope{
+		MinX: rect.Lo().Lng.Degrees(),
+		MaxX: rect.Hi().Lng.Degrees(),
+		MinY: rect.Lo().Lat.Degrees(),
+		MaxY: rect.Hi().Lat.Degrees(),
+	}
+
+	// A planar envelope cannot represent a bound crossing the antimeridian.
+	if rect.Lng.IsInverted() {
+		envelope.MinX = -180
+		envelope.MaxX = 180
+	}
+
+	return envelope
+}
+
+// rectBound returns the bound of a geometry, and false if the geometry is
+// empty.
+func rectBound(v interface{}) (s2.Rect, bool, error) {
+	switch geometry := v.(type) {
+	case s2.LatLng:
+		return s2.RectFromLatLng(geometry), true, nil
+	case s2.Point:
+		return geometry.RectBound(), true, nil
+	case *s2.Polyline:
+		return geometry.RectBound(), len(*geometry) > 0, nil
+	case []s2.Point:
+		rect := s2.EmptyRect()
+		for _, point := range geometry {
+			rect = rect.Union(point.RectBound())
+		}
+
+		return rect, len(geometry) > 0, nil
+	case []*s2.Polyline:
+		rect := s2.EmptyRect()
+		for _, polyline := range geometry {
+			if len(*polyline) > 0 {
+				rect = rect.Union(polyline.RectBound())
+			}
+		}
+
+		return rect, !rect.IsEmpty(), nil
+	case *s2.Polygon:
+		return geometry.RectBound(), !geometry.IsEmpty(), nil
+	default:
+		return s2.Rect{}, false, fmt.Errorf("gpkg: unknown geometry type %T", v)
+	}
+}
+
+func encodeHeader(w io.Writer, header *Header) error {
+	if header.EnvelopeType > EnvelopeXYZM {
+		return fmt.Errorf("gpkg: invalid envelope type %d", header.EnvelopeType)
+	}
+
+	// Big-endian, like the WKB that follows.
+	flags := byte(header.EnvelopeType) << 1
+	if header.Empty {
+		flags |= flagEmpty
+	}
+
+	if header.Extended {
+		flags |= flagExtended
+	}
+
+	if _, err := w.Write([]byte{magic[0], magic[1], version, flags}); err != nil {
+		return err
+	}
+
+	if err := binary.Write(w, binary.BigEndian, header.SRID); err != nil {
+		return err
+	}
+
+	e := header.Envelope
+	values := []float64{e.MinX, e.MaxX, e.MinY, e.MaxY}
+	switch header.EnvelopeType {
+	case EnvelopeNone:
+		values = nil
+	case EnvelopeXYZ:
+		values = append(values, e.MinZ, e.MaxZ)
+	case EnvelopeXYM:
+		values = append(values, e.MinM, e.MaxM)
+	case EnvelopeXYZM:
+		values = append(values, e.MinZ, e.MaxZ, e.MinM, e.MaxM)
+	}
+
+	return binary.Write(w, binary.BigEndian, values)
+}
+
+// Encoder writes GeoPackage geometry blobs to an output stream.
+type Encoder struct {
+	w            io.Writer
+	srid         int32
+	envelopeType EnvelopeType
+}
+
+// NewEncoder returns an Encoder that writes blobs with the given spatial
+// reference system identifier and envelope type. The envelope is computed from
+// the s2 bound of each geometry; the Z and M ranges of 2D geometries are zero.
+// Empty geometries are written without an envelope.
+func NewEncoder(w io.Writer, srid int32, envelopeType EnvelopeType) *Encoder {
+	return &Encoder{
+		w:            w,
+		srid:         srid,
+		envelopeType: envelopeType,
+	}
+}
+
+// Encode writes the blob of a geometry. In addition to the types accepted by
+// wkb.Encoder, v may be an *Extension, which is written as an
+// ExtendedGeoPackageBinary blob without an envelope.
+func (e *Encoder) Encode(v interface{}) error {
+	header := &Header{
+		SRID: e.srid,
+	}
+
+	if extension, ok := v.(*Extension); ok {
+		header.Extended = true
+		if err := encodeHeader(e.w, header); err != nil {
+			return err
+		}
+
+		if _, err := e.w.Write(extension.Code[:]); err != nil {
+			return err
+		}
+
+		_, err := e.w.Write(extension.Data)
+		return err
+	}
+
+	rect, ok, err := rectBound(v)
+	if err != nil {
+		return err
+	}
+
+	if ok {
+		header.EnvelopeType = e.envelopeType
+		header.Envelope = envelopeFromRect(rect)
+	} else {
+		header.Empty = true
+	}
+
+	data, err := wkb.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	if err := encodeHeader(e.w, header); err != nil {
+		return err
+	}
+
+	_, err = e.w.Write(data)
+	return err
+}
+
+func Marshal(v interface{}, srid int32, envelopeType EnvelopeType) ([]byte, error) {
+	w := bytes.NewBuffer([]byte{})
+	if err := NewEncoder(w, srid, envelopeType).Encode(v); err != nil {
+		return nil, err
+	}
+
+	return w.Bytes(), nil
+}
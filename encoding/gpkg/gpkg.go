@@ -0,0 +1,61 @@
+package gpkg
+
+var magic = [2]byte{'G', 'P'}
+
+const (
+	version byte = 0
+
+	flagByteOrder    byte = 0x01 // Little-endian header when set
+	flagEnvelopeMask byte = 0x0e
+	flagEmpty        byte = 0x10
+	flagExtended     byte = 0x20
+)
+
+// EnvelopeType identifies the envelope stored in a GeoPackage geometry header.
+type EnvelopeType uint8
+
+const (
+	EnvelopeNone EnvelopeType = 0
+	EnvelopeXY   EnvelopeType = 1
+	EnvelopeXYZ  EnvelopeType = 2
+	EnvelopeXYM  EnvelopeType = 3
+	EnvelopeXYZM EnvelopeType = 4
+)
+
+func (t EnvelopeType) size() int {
+	switch t {
+	case EnvelopeXY:
+		return 32
+	case EnvelopeXYZ, EnvelopeXYM:
+		return 48
+	case EnvelopeXYZM:
+		return 64
+	default:
+		return 0
+	}
+}
+
+// Envelope is the bounding box stored in a GeoPackage geometry header. Only
+// the ranges indicated by the envelope type are meaningful.
+type Envelope struct {
+	MinX, MaxX float64
+	MinY, MaxY float64
+	MinZ, MaxZ float64
+	MinM, MaxM float64
+}
+
+// Header represents the header of a GeoPackage geometry blob.
+type Header struct {
+	SRID         int32
+	EnvelopeType EnvelopeType
+	Envelope     Envelope
+	Empty        bool
+	Extended     bool
+}
+
+// Extension is the body of an ExtendedGeoPackageBinary blob: a four byte
+// extension code followed by geometry data in an extension-defined format.
+type Extension struct {
+	Code [4]byte
+	Data []byte
+}
@@ -0,0 +1,311 @@
+package gpkg
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/golang/geo/s2"
+
+	"github.com/topos-ai/geoutil"
+	"github.com/topos-ai/geoutil/encoding/wkb"
+)
+
+// blob builds a geometry blob from its parts in the given byte order.
+func blob(order binary.ByteOrder, flags byte, srid int32, envelope []float64, body []byte) []byte {
+	buf := &bytes.Buffer{}
+	buf.Write([]byte{'G', 'P', 0, flags})
+	binary.Write(buf, order, srid)
+	binary.Write(buf, order, envelope)
+	buf.Write(body)
+	return buf.Bytes()
+}
+
+func polygonCoordinates(t *testing.T, polygon *s2.Polygon) [][][][]float64 {
+	t.Helper()
+	coords, err := geoutil.PolygonCoordinates(polygon, geoutil.PrecisionE7)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return coords
+}
+
+func polylineCoordinates(t *testing.T, polyline *s2.Polyline) [][]float64 {
+	t.Helper()
+	coords, err := geoutil.PolylineCoordinates(polyline, geoutil.PrecisionE7)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return coords
+}
+
+func TestDecodeHeader(t *testing.T) {
+	body := []byte{1, 2, 3}
+	tests := []struct {
+		name string
+		data []byte
+		want Header
+	}{
+		{
+			name: "none big endian",
+			data: blob(binary.BigEndian, 0, 4326, nil, body),
+			want: Header{SRID: 4326},
+		},
+		{
+			name: "xy little endian",
+			data: blob(binary.LittleEndian, 0x03, 4326, []float64{1, 2, 3, 4}, body),
+			want: Header{SRID: 4326, EnvelopeType: EnvelopeXY, Envelope: Envelope{MinX: 1, MaxX: 2, MinY: 3, MaxY: 4}},
+		},
+		{
+			name: "xyz",
+			data: blob(binary.BigEndian, 0x04, -1, []float64{1, 2, 3, 4, 5, 6}, body),
+			want: Header{SRID: -1, EnvelopeType: EnvelopeXYZ, Envelope: Envelope{MinX: 1, MaxX: 2, MinY: 3, MaxY: 4, MinZ: 5, MaxZ: 6}},
+		},
+		{
+			name: "xym",
+			data: blob(binary.LittleEndian, 0x07, 0, []float64{1, 2, 3, 4, 5, 6}, body),
+			want: Header{EnvelopeType: EnvelopeXYM, Envelope: Envelope{MinX: 1, MaxX: 2, MinY: 3, MaxY: 4, MinM: 5, MaxM: 6}},
+		},
+		{
+			name: "xyzm",
+			data: blob(binary.LittleEndian, 0x09, 3857, []float64{1, 2, 3, 4, 5, 6, 7, 8}, body),
+			want: Header{SRID: 3857, EnvelopeType: EnvelopeXYZM, Envelope: Envelope{MinX: 1, MaxX: 2, MinY: 3, MaxY: 4, MinZ: 5, MaxZ: 6, MinM: 7, MaxM: 8}},
+		},
+		{
+			name: "empty extended",
+			data: blob(binary.BigEndian, 0x30, 4326, nil, body),
+			want: Header{SRID: 4326, Empty: true, Extended: true},
+		},
+	}
+
+	for _, test := range tests {
+		header, rest, err := DecodeHeader(test.data)
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+
+		if *header != test.want {
+			t.Errorf("%s: header %+v, want %+v", test.name, *header, test.want)
+		}
+
+		if !bytes.Equal(rest, body) {
+			t.Errorf("%s: body %v, want %v", test.name, rest, body)
+		}
+	}
+}
+
+func TestDecodeHeaderErrors(t *testing.T) {
+	tests := map[string][]byte{
+		"short":          []byte("GP\x00\x01"),
+		"magic":          blob(binary.BigEndian, 0, 0, nil, nil)[1:],
+		"version":        append([]byte("GP\x01"), make([]byte, 5)...),
+		"envelope type":  blob(binary.BigEndian, 0x0a, 0, nil, make([]byte, 80)),
+		"short envelope": blob(binary.BigEndian, 0x02, 0, []float64{1, 2, 3}, nil),
+	}
+
+	for name, data := range tests {
+		if _, _, err := DecodeHeader(data); err == nil {
+			t.Errorf("%s: no error", name)
+		}
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	polygon, err := geoutil.PolygonFromPolygonCoordinates([][][]float64{{{10, 20}, {12, 20}, {12, 23}, {10, 23}, {10, 20}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, envelopeType := range []EnvelopeType{EnvelopeNone, EnvelopeXY, EnvelopeXYZ, EnvelopeXYM, EnvelopeXYZM} {
+		data, err := Marshal(polygon, 4326, envelopeType)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		header, body, err := DecodeHeader(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if header.SRID != 4326 || header.EnvelopeType != envelopeType || header.Empty || header.Extended {
+			t.Errorf("unexpected header %+v", header)
+		}
+
+		if envelopeType != EnvelopeNone {
+			e := header.Envelope
+			got := []float64{e.MinX, e.MaxX, e.MinY, e.MaxY}
+			want := []float64{10, 12, 20, 23}
+			for i := range got {
+				if math.Abs(got[i]-want[i]) > 1e-2 {
+					t.Errorf("envelope type %d: envelope %v, want about %v", envelopeType, got, want)
+					break
+				}
+			}
+
+			// The edges of the polygon bulge poleward from its corners.
+			if e.MaxY <= 23 || e.MinY > 20 {
+				t.Errorf("envelope %v does not contain the edges of the polygon", got)
+			}
+		}
+
+		wkbData, err := wkb.Marshal(polygon)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !bytes.Equal(body, wkbData) {
+			t.Errorf("envelope type %d: the body is not the WKB of the polygon", envelopeType)
+		}
+
+		decoded := &s2.Polygon{}
+		if err := Unmarshal(data, decoded); err != nil {
+			t.Fatal(err)
+		}
+
+		if !reflect.DeepEqual(polygonCoordinates(t, decoded), polygonCoordinates(t, polygon)) {
+			t.Errorf("envelope type %d: decoded a different polygon", envelopeType)
+		}
+	}
+}
+
+func TestRoundTripTypes(t *testing.T) {
+	point := s2.PointFromLatLng(s2.LatLngFromDegrees(45, -70))
+	polyline, _ := geoutil.PolylineFromLineStringCoordinates([][]float64{{0, 0}, {1, 1}})
+	points, _ := geoutil.PointsFromMultiPointCoordinates([][]float64{{1, 2}, {3, 4}})
+	polylines, _ := geoutil.PolylinesFromMultiLineStringCoordinates([][][]float64{{{0, 0}, {1, 1}}, {{2, 2}, {3, 3}}})
+
+	data, err := Marshal(point, 4326, EnvelopeXY)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decodedPoint := s2.Point{}
+	if err := Unmarshal(data, &decodedPoint); err != nil {
+		t.Fatal(err)
+	}
+
+	if decodedPoint.Distance(point) > 1e-15 {
+		t.Errorf("decoded point %v, want %v", decodedPoint, point)
+	}
+
+	data, err = Marshal(polyline, 4326, EnvelopeXY)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decodedPolyline := &s2.Polyline{}
+	if err := Unmarshal(data, decodedPolyline); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(polylineCoordinates(t, decodedPolyline), polylineCoordinates(t, polyline)) {
+		t.Errorf("decoded polyline %v, want %v", decodedPolyline, polyline)
+	}
+
+	data, err = Marshal(points, 4326, EnvelopeXY)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decodedPoints := []s2.Point{}
+	if err := Unmarshal(data, &decodedPoints); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(decodedPoints) != 2 {
+		t.Errorf("decoded %d points, want 2", len(decodedPoints))
+	}
+
+	data, err = Marshal(polylines, 4326, EnvelopeXY)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decodedPolylines := []*s2.Polyline{}
+	if err := Unmarshal(data, &decodedPolylines); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(decodedPolylines) != 2 || !reflect.DeepEqual(polylineCoordinates(t, decodedPolylines[1]), polylineCoordinates(t, polylines[1])) {
+		t.Errorf("decoded polylines %v, want %v", decodedPolylines, polylines)
+	}
+}
+
+func TestEmpty(t *testing.T) {
+	data, err := Marshal(s2.PolygonFromLoops(nil), 4326, EnvelopeXY)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	header, _, err := DecodeHeader(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !header.Empty || header.EnvelopeType != EnvelopeNone {
+		t.Errorf("empty polygon has header %+v", header)
+	}
+
+	polygon := &s2.Polygon{}
+	if err := Unmarshal(data, polygon); err != nil {
+		t.Fatal(err)
+	}
+
+	if !polygon.IsEmpty() {
+		t.Errorf("decoded a non-empty polygon")
+	}
+
+	// An empty point cannot be represented by s2 types.
+	emptyPoint := blob(binary.BigEndian, flagEmpty, 4326, nil, nil)
+	if err := Unmarshal(emptyPoint, &s2.Point{}); err == nil {
+		t.Errorf("decoding an empty point returned no error")
+	}
+}
+
+func TestAntimeridianEnvelope(t *testing.T) {
+	polyline, _ := geoutil.PolylineFromLineStringCoordinates([][]float64{{179, 0}, {-179, 1}})
+	data, err := Marshal(polyline, 4326, EnvelopeXY)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	header, _, err := DecodeHeader(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if header.Envelope.MinX != -180 || header.Envelope.MaxX != 180 {
+		t.Errorf("envelope %+v does not span every longitude", header.Envelope)
+	}
+}
+
+func TestExtension(t *testing.T) {
+	extension := &Extension{Code: [4]byte{'T', 'E', 'S', 'T'}, Data: []byte{1, 2, 3}}
+	data, err := Marshal(extension, 4326, EnvelopeXY)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decoded := &Extension{}
+	if err := Unmarshal(data, decoded); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(decoded, extension) {
+		t.Errorf("decoded %+v, want %+v", decoded, extension)
+	}
+
+	if err := Unmarshal(data, &s2.Polygon{}); err == nil {
+		t.Errorf("decoding an extension into a polygon returned no error")
+	}
+
+	point, _ := Marshal(s2.PointFromLatLng(s2.LatLngFromDegrees(1, 2)), 4326, EnvelopeNone)
+	if err := Unmarshal(point, &Extension{}); err == nil {
+		t.Errorf("decoding a point into an extension returned no error")
+	}
+}
@@ -0,0 +1,162 @@
+package mvt
+
+import "math"
+
+type point struct {
+	x, y float64
+}
+
+type bbox struct {
+	minX, minY, maxX, maxY float64
+}
+
+// clipSegment clips the segment pq to b using the Liang-Barsky algorithm.
+func clipSegment(p, q point, b bbox) (point, point, bool) {
+	t0, t1 := 0.0, 1.0
+	dx, dy := q.x-p.x, q.y-p.y
+	for _, e := range [4][2]float64{
+		{-dx, p.x - b.minX},
+		{dx, b.maxX - p.x},
+		{-dy, p.y - b.minY},
+		{dy, b.maxY - p.y},
+	} {
+		if e[0] == 0 {
+			if e[1] < 0 {
+				return point{}, point{}, false
+			}
+
+			continue
+		}
+
+		r := e[1] / e[0]
+		if e[0] < 0 {
+			if r > t1 {
+				return point{}, point{}, false
+			}
+
+			t0 = math.Max(t0, r)
+		} else {
+			if r < t0 {
+				return point{}, point{}, false
+			}
+
+			t1 = math.Min(t1, r)
+		}
+	}
+
+	return point{p.x + t0*dx, p.y + t0*dy}, point{p.x + t1*dx, p.y + t1*dy}, true
+}
+
+// clipLine clips a line to b, splitting it wherever it leaves the box.
+func clipLine(line []point, b bbox) [][]point {
+	parts := [][]point{}
+	current := []point{}
+	flush := func() {
+		if len(current) >= 2 {
+			parts = append(parts, current)
+		}
+
+		current = []point{}
+	}
+
+	for i := 0; i+1 < len(line); i++ {
+		p, q, ok := clipSegment(line[i], line[i+1], b)
+		if !ok {
+			flush()
+			continue
+		}
+
+		if len(current) == 0 || current[len(current)-1] != p {
+			flush()
+			current = append(current, p)
+		}
+
+		current = append(current, q)
+		if q != line[i+1] {
+			flush()
+		}
+	}
+
+	flush()
+	return parts
+}
+
+// clipRing clips an open ring to b using the Sutherland-Hodgman algorithm.
+// Parts of the ring outside the box collapse onto its edges, which keeps the
+// result a single ring.
+func clipRing(ring []point, b bbox) []point {
+	edges := []struct {
+		inside    func(p point) bool
+		intersect func(p, q point) point
+	}{
+		{
+			func(p point) bool { return p.x >= b.minX },
+			func(p, q point) point { return point{b.minX, p.y + (q.y-p.y)*(b.minX-p.x)/(q.x-p.x)} },
+		},
+		{
+			func(p point) bool { return p.x <= b.maxX },
+			func(p, q point) point { return point{b.maxX, p.y + (q.y-p.y)*(b.maxX-p.x)/(q.x-p.x)} },
+		},
+		{
+			func(p point) bool { return p.y >= b.minY },
+			func(p, q point) point { return point{p.x + (q.x-p.x)*(b.minY-p.y)/(q.y-p.y), b.minY} },
+		},
+		{
+			func(p point) bool { return p.y <= b.maxY },
+			func(p, q point) point { return point{p.x + (q.x-p.x)*(b.maxY-p.y)/(q.y-p.y), b.maxY} },
+		},
+	}
+
+	for _, edge := range edges {
+		if len(ring) == 0 {
+			break
+		}
+
+		clipped := make([]point, 0, len(ring))
+		prev := ring[len(ring)-1]
+		for _, p := range ring {
+			switch {
+			case edge.inside(p):
+				if !edge.inside(prev) {
+					clipped = append(clipped, edge.intersect(prev, p))
+				}
+
+				clipped = append(clipped, p)
+			case edge.inside(prev):
+				clipped = append(clipped, edge.intersect(prev, p))
+			}
+
+			prev = p
+		}
+
+		ring = clipped
+	}
+
+	return ring
+}
+
+// ringArea returns twice the signed area of an open ring in tile coordinates.
+// Since the y axis points down, clockwise rings have a positive area.
+func ringArea(ring []point) float64 {
+	area := 0.0
+	for i, p := range ring {
+		q := ring[(i+1)%len(ring)]
+		area += p.x*q.y - q.x*p.y
+	}
+
+	return area
+}
+
+// quantize rounds a sequence of points to the integer grid, dropping
+// consecutive duplicates.
+func quantize(points []point) []point {
+	quantized := make([]point, 0, len(points))
+	for _, p := range points {
+		q := point{math.Round(p.x), math.Round(p.y)}
+		if len(quantized) == 0 || quantized[len(quantized)-1] != q {
+			quantized = append(quantized, q)
+		}
+	}
+
+	return quantized
+}
@@ -0,0 +1,291 @@
+package mvt
+
+import (
+	"fmt"
+
+	"github.com/topos-ai/geoutil"
+	"github.com/topos-ai/geoutil/encoding/geojson"
+	"github.com/topos-ai/geoutil/internal/pbf"
+)
+
+func decodeValue(data []byte) (interface{}, error) {
+	var v interface{}
+	r := pbf.NewReader(data)
+	for r.Next() {
+		switch r.Field() {
+		case valueString:
+			v = r.String()
+		case valueFloat:
+			v = r.Float()
+		case valueDouble:
+			v = r.Double()
+		case valueInt:
+			v = r.Int64()
+		case valueUint:
+			v = r.Uint64()
+		case valueSint:
+			v = r.Sint64()
+		case valueBool:
+			v = r.Bool()
+		default:
+			r.Skip()
+		}
+	}
+
+	return v, r.Err()
+}
+
+// decodeCommands decodes a geometry command sequence into sequences of tile
+// coordinates. Every MoveTo starts a new sequence.
+func decodeCommands(commands []uint32, geomType int) ([][]point, error) {
+	sequences := [][]point{}
+	cursor := point{}
+	for i := 0; i < len(commands); {
+		id := int(commands[i] & 0x7)
+		count := int(commands[i] >> 3)
+		i++
+
+		switch id {
+		case commandMoveTo, commandLineTo:
+			if len(commands)-i < 2*count {
+				return nil, fmt.Errorf("mvt: truncated geometry command")
+			}
+
+			for j := 0; j < count; j++ {
+				cursor.x += float64(pbf.DecodeZigZag(uint64(commands[i])))
+				cursor.y += float64(pbf.DecodeZigZag(uint64(commands[i+1])))
+				i += 2
+
+				// Points of a multi-point share a single MoveTo.
+				if id == commandMoveTo && (j == 0 || geomType == geomTypePoint) {
+					sequences = append(sequences, []point{})
+				}
+
+				if len(sequences) == 0 {
+					return nil, fmt.Errorf("mvt: LineTo before MoveTo")
+				}
+
+				last := len(sequences) - 1
+				sequences[last] = append(sequences[last], cursor)
+			}
+
+		case commandClosePath:
+		default:
+			return nil, fmt.Errorf("mvt: unknown geometry command %d", id)
+		}
+	}
+
+	return sequences, nil
+}
+
+func unprojectSequence(tile Tile, extent int, sequence []point) [][]float64 {
+	coords := make([][]float64, len(sequence))
+	for i, p := range sequence {
+		lng, lat := unproject(tile, extent, p.x, p.y)
+		coords[i] = []float64{lng, lat}
+	}
+
+	return coords
+}
+
+func decodeGeometry(tile Tile, extent int, geomType int, commands []uint32) (interface{}, error) {
+	sequences, err := decodeCommands(commands, geomType)
+	if err != nil {
+		return nil, err
+	}
+
+	switch geomType {
+	case geomTypePoint:
+		points := make([][]float64, len(sequences))
+		for i, sequence := range sequences {
+			points[i] = unprojectSequence(tile, extent, sequence)[0]
+		}
+
+		if len(points) == 1 {
+			return geoutil.PointFromPointCoordinates(points[0])
+		}
+
+		return geoutil.PointsFromMultiPointCoordinates(points)
+
+	case geomTypeLineString:
+		lines := make([][][]float64, len(sequences))
+		for i, sequence := range sequences {
+			lines[i] = unprojectSequence(tile, extent, sequence)
+		}
+
+		if len(lines) == 1 {
+			return geoutil.PolylineFromLineStringCoordinates(lines[0])
+		}
+
+		return geoutil.PolylinesFromMultiLineStringCoordinates(lines)
+
+	case geomTypePolygon:
+
+		// Exterior rings are clockwise in tile coordinates and start a new
+		// polygon; counter-clockwise rings are holes of the previous one.
+		polygons := [][][][]float64{}
+		for _, sequence := range sequences {
+			area := 0.0
+			if len(sequence) >= 3 {
+				area = ringArea(sequence)
+			}
+
+			if area == 0 {
+				continue
+			}
+
+			ring := unprojectSequence(tile, extent, sequence)
+			ring = append(ring, ring[0])
+			if area > 0 || len(polygons) == 0 {
+				polygons = append(polygons, [][][]float64{ring})
+			} else {
+				last := len(polygons) - 1
+				polygons[last] = append(polygons[last], ring)
+			}
+		}
+
+		if len(polygons) == 1 {
+			return geoutil.PolygonFromPolygonCoordinates(polygons[0])
+		}
+
+		return geoutil.PolygonFromMultiPolygonCoordinates(polygons)
+
+	default:
+		return nil, fmt.Errorf("mvt: unsupported geometry type %d", geomType)
+	}
+}
+
+type rawFeature struct {
+	id       uint64
+	hasID    bool
+	tags     []uint32
+	geomType int
+	geometry []uint32
+}
+
+func decodeFeature(data []byte) (*rawFeature, error) {
+	rf := &rawFeature{}
+	r := pbf.NewReader(data)
+	for r.Next() {
+		switch r.Field() {
+		case featureID:
+			rf.id = r.Uint64()
+			rf.hasID = true
+		case featureTags:
+			rf.tags = r.Uint32s(rf.tags)
+		case featureType:
+			rf.geomType = int(r.Uint64())
+		case featureGeometry:
+			rf.geometry = r.Uint32s(rf.geometry)
+		default:
+			r.Skip()
+		}
+	}
+
+	return rf, r.Err()
+}
+
+func decodeLayer(data []byte, tile Tile) (*Layer, error) {
+	layer := &Layer{
+		Extent: DefaultExtent,
+	}
+
+	keys := []string{}
+	values := []interface{}{}
+	features := [][]byte{}
+
+	r := pbf.NewReader(data)
+	for r.Next() {
+		switch r.Field() {
+		case layerName:
+			layer.Name = r.String()
+		case layerFeatures:
+			features = append(features, r.Bytes())
+		case layerKeys:
+			keys = append(keys, r.String())
+		case layerValues:
+			v, err := decodeValue(r.Bytes())
+			if err != nil {
+				return nil, err
+			}
+
+			values = append(values, v)
+		case layerExtent:
+			layer.Extent = int(r.Uint64())
+		default:
+			r.Skip()
+		}
+	}
+
+	if err := r.Err(); err != nil {
+		return nil, err
+	}
+
+	if layer.Extent <= 0 {
+		return nil, fmt.Errorf("mvt: invalid layer extent %d", layer.Extent)
+	}
+
+	for _, data := range features {
+		rf, err := decodeFeature(data)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(rf.tags)%2 != 0 {
+			return nil, fmt.Errorf("mvt: odd number of feature tags %d", len(rf.tags))
+		}
+
+		f := &geojson.Feature{
+			Properties: make(map[string]interface{}, len(rf.tags)/2),
+		}
+
+		if rf.hasID {
+			f.ID = float64(rf.id)
+		}
+
+		for i := 0; i < len(rf.tags); i += 2 {
+			key, value := int(rf.tags[i]), int(rf.tags[i+1])
+			if key >= len(keys) || value >= len(values) {
+				return nil, fmt.Errorf("mvt: feature tag out of range")
+			}
+
+			f.Properties[keys[key]] = values[value]
+		}
+
+		geometry, err := decodeGeometry(tile, layer.Extent, rf.geomType, rf.geometry)
+		if err != nil {
+			return nil, err
+		}
+
+		f.Geometry = geometry
+		layer.Features = append(layer.Features, f)
+	}
+
+	return layer, nil
+}
+
+// Unmarshal decodes a Mapbox Vector Tile into layers of features, unprojecting
+// tile coordinates back to geographic coordinates.
+func Unmarshal(data []byte, tile Tile) ([]*Layer, error) {
+	layers := []*Layer{}
+	r := pbf.NewReader(data)
+	for r.Next() {
+		if r.Field() != tileLayers {
+			r.Skip()
+			continue
+		}
+
+		layer, err := decodeLayer(r.Bytes(), tile)
+		if err != nil {
+			return nil, err
+		}
+
+		layers = append(layers, layer)
+	}
+
+	if err := r.Err(); err != nil {
+		return nil, err
+	}
+
+	return layers, nil
+}
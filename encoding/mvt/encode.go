@@ -0,0 +1,364 @@
+package mvt
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/golang/geo/s2"
+
+	"github.com/topos-ai/geoutil"
+	"github.com/topos-ai/geoutil/internal/pbf"
+)
+
+// value is a comparable representation of a Value message, used to share
+// entries of the values table of a layer.
+type value struct {
+	field int
+	s     string
+	f     float64
+	i     int64
+	u     uint64
+	b     bool
+}
+
+func encodeValue(v interface{}) (value, error) {
+	switch v := v.(type) {
+	case string:
+		return value{field: valueString, s: v}, nil
+	case bool:
+		return value{field: valueBool, b: v}, nil
+	case float32:
+		return value{field: valueFloat, f: float64(v)}, nil
+	case float64:
+		return value{field: valueDouble, f: v}, nil
+	case int:
+		return value{field: valueSint, i: int64(v)}, nil
+	case int8:
+		return value{field: valueSint, i: int64(v)}, nil
+	case int16:
+		return value{field: valueSint, i: int64(v)}, nil
+	case int32:
+		return value{field: valueSint, i: int64(v)}, nil
+	case int64:
+		return value{field: valueSint, i: v}, nil
+	case uint:
+		return value{field: valueUint, u: uint64(v)}, nil
+	case uint8:
+		return value{field: valueUint, u: uint64(v)}, nil
+	case uint16:
+		return value{field: valueUint, u: uint64(v)}, nil
+	case uint32:
+		return value{field: valueUint, u: uint64(v)}, nil
+	case uint64:
+		return value{field: valueUint, u: v}, nil
+	default:
+
+		// Vector tiles only carry scalars; nested values are kept as JSON.
+		data, err := json.Marshal(v)
+		if err != nil {
+			return value{}, err
+		}
+
+		return value{field: valueString, s: string(data)}, nil
+	}
+}
+
+func (v value) encode(w *pbf.Writer) {
+	switch v.field {
+	case valueString:
+		w.String(valueString, v.s)
+	case valueFloat:
+		w.Float(valueFloat, float32(v.f))
+	case valueDouble:
+		w.Double(valueDouble, v.f)
+	case valueSint:
+		w.Sint64(valueSint, v.i)
+	case valueUint:
+		w.Uint64(valueUint, v.u)
+	case valueBool:
+		w.Bool(valueBool, v.b)
+	}
+}
+
+// geometryEncoder projects, clips and quantizes geometries, and encodes them
+// as zigzag delta encoded commands.
+type geometryEncoder struct {
+	tile     Tile
+	extent   int
+	box      bbox
+	cursor   point
+	commands []uint32
+}
+
+func (e *geometryEncoder) project(coords [][]float64) []point {
+	points := make([]point, len(coords))
+	for i, c := range coords {
+		x, y := project(e.tile, e.extent, c[0], c[1])
+		points[i] = point{x, y}
+	}
+
+	return points
+}
+
+func (e *geometryEncoder) command(id, count int) {
+	e.commands = append(e.commands, uint32(id&0x7|count<<3))
+}
+
+func (e *geometryEncoder) params(points []point) {
+	for _, p := range points {
+		dx := int64(p.x - e.cursor.x)
+		dy := int64(p.y - e.cursor.y)
+		e.commands = append(e.commands, uint32(pbf.EncodeZigZag(dx)), uint32(pbf.EncodeZigZag(dy)))
+		e.cursor = p
+	}
+}
+
+func (e *geometryEncoder) encodePoints(coords [][]float64) {
+	points := []point{}
+	for _, p := range quantize(e.project(coords)) {
+		if p.x >= e.box.minX && p.x <= e.box.maxX && p.y >= e.box.minY && p.y <= e.box.maxY {
+			points = append(points, p)
+		}
+	}
+
+	if len(points) > 0 {
+		e.command(commandMoveTo, len(points))
+		e.params(points)
+	}
+}
+
+func (e *geometryEncoder) encodeLine(coords [][]float64) {
+	for _, part := range clipLine(e.project(coords), e.box) {
+		part = quantize(part)
+		if len(part) < 2 {
+			continue
+		}
+
+		e.command(commandMoveTo, 1)
+		e.params(part[:1])
+		e.command(commandLineTo, len(part)-1)
+		e.params(part[1:])
+	}
+}
+
+// encodeRing encodes a closed ring, reversing it as needed so that exterior
+// rings are clockwise and interior rings counter-clockwise in tile
+// coordinates. It reports whether the ring survived clipping.
+func (e *geometryEncoder) encodeRing(coords [][]float64, exterior bool) bool {
+	if len(coords) > 0 {
+		coords = coords[:len(coords)-1]
+	}
+
+	ring := quantize(clipRing(e.project(coords), e.box))
+	if n := len(ring); n > 1 && ring[0] == ring[n-1] {
+		ring = ring[:n-1]
+	}
+
+	area := 0.0
+	if len(ring) >= 3 {
+		area = ringArea(ring)
+	}
+
+	if area == 0 {
+		return false
+	}
+
+	if (area > 0) != exterior {
+		for i, j := 0, len(ring)-1; i < j; i, j = i+1, j-1 {
+			ring[i], ring[j] = ring[j], ring[i]
+		}
+	}
+
+	e.command(commandMoveTo, 1)
+	e.params(ring[:1])
+	e.command(commandLineTo, len(ring)-1)
+	e.params(ring[1:])
+	e.command(commandClosePath, 1)
+	return true
+}
+
+func (e *geometryEncoder) encodePolygon(polygonCoords [][][]float64) {
+	for i, ringCoords := range polygonCoords {
+		if !e.encodeRing(ringCoords, i == 0) && i == 0 {
+			return
+		}
+	}
+}
+
+func (e *geometryEncoder) encode(geometry interface{}) (int, error) {
+	switch geometry := geometry.(type) {
+	case s2.LatLng:
+		return e.encode(s2.PointFromLatLng(geometry))
+
+	case s2.Point:
+		coords, err := geoutil.PointCoordinates(geometry, geoutil.PrecisionMax)
+		if err != nil {
+			return 0, err
+		}
+
+		e.encodePoints([][]float64{coords})
+		return geomTypePoint, nil
+
+	case []s2.Point:
+		coords := make([][]float64, len(geometry))
+		for i, point := range geometry {
+			pointCoords, err := geoutil.PointCoordinates(point, geoutil.PrecisionMax)
+			if err != nil {
+				return 0, err
+			}
+
+			coords[i] = pointCoords
+		}
+
+		e.encodePoints(coords)
+		return geomTypePoint, nil
+
+	case *s2.Polyline:
+		coords, err := geoutil.PolylineCoordinates(geometry, geoutil.PrecisionMax)
+		if err != nil {
+			return 0, err
+		}
+
+		e.encodeLine(coords)
+		return geomTypeLineString, nil
+
+	case []*s2.Polyline:
+		for _, polyline := range geometry {
+			coords, err := geoutil.PolylineCoordinates(polyline, geoutil.PrecisionMax)
+			if err != nil {
+				return 0, err
+			}
+
+			e.encodeLine(coords)
+		}
+
+		return geomTypeLineString, nil
+
+	case *s2.Polygon:
+		polygonCoordinates, err := geoutil.PolygonCoordinates(geometry, geoutil.PrecisionMax)
+		if err != nil {
+			return 0, err
+		}
+
+		for _, polygonCoords := range polygonCoordinates {
+			e.encodePolygon(polygonCoords)
+		}
+
+		return geomTypePolygon, nil
+
+	default:
+		return 0, fmt.Errorf("mvt: unknown geometry type %T", geometry)
+	}
+}
+
+func encodeLayer(tile Tile, layer *Layer) (*pbf.Writer, error) {
+	extent := layer.extent()
+	buffer := float64(layer.Buffer)
+	box := bbox{-buffer, -buffer, float64(extent) + buffer, float64(extent) + buffer}
+
+	keys := []string{}
+	keyIndexes := map[string]int{}
+	values := []value{}
+	valueIndexes := map[value]int{}
+
+	w := &pbf.Writer{}
+	w.Uint64(layerVersion, 2)
+	w.String(layerName, layer.Name)
+	for _, f := range layer.Features {
+		if f.Geometry == nil {
+			continue
+		}
+
+		e := &geometryEncoder{
+			tile:   tile,
+			extent: extent,
+			box:    box,
+		}
+
+		geomType, err := e.encode(f.Geometry)
+		if err != nil {
+			return nil, err
+		}
+
+		// Skip features clipped away entirely.
+		if len(e.commands) == 0 {
+			continue
+		}
+
+		names := make([]string, 0, len(f.Properties))
+		for name, v := range f.Properties {
+			if v != nil {
+				names = append(names, name)
+			}
+		}
+
+		sort.Strings(names)
+
+		tags := make([]uint32, 0, 2*len(names))
+		for _, name := range names {
+			v, err := encodeValue(f.Properties[name])
+			if err != nil {
+				return nil, err
+			}
+
+			keyIndex, ok := keyIndexes[name]
+			if !ok {
+				keyIndex = len(keys)
+				keyIndexes[name] = keyIndex
+				keys = append(keys, name)
+			}
+
+			valueIndex, ok := valueIndexes[v]
+			if !ok {
+				valueIndex = len(values)
+				valueIndexes[v] = valueIndex
+				values = append(values, v)
+			}
+
+			tags = append(tags, uint32(keyIndex), uint32(valueIndex))
+		}
+
+		fw := &pbf.Writer{}
+		if id, ok := f.ID.(float64); ok && id >= 0 && id == math.Trunc(id) {
+			fw.Uint64(featureID, uint64(id))
+		}
+
+		fw.PackedUint32s(featureTags, tags)
+		fw.Uint64(featureType, uint64(geomType))
+		fw.PackedUint32s(featureGeometry, e.commands)
+		w.Message(layerFeatures, fw)
+	}
+
+	for _, key := range keys {
+		w.String(layerKeys, key)
+	}
+
+	for _, v := range values {
+		vw := &pbf.Writer{}
+		v.encode(vw)
+		w.Message(layerValues, vw)
+	}
+
+	w.Uint64(layerExtent, uint64(extent))
+	return w, nil
+}
+
+// Marshal encodes layers of features as a Mapbox Vector Tile. Geometries are
+// projected to Web Mercator, clipped to the tile and its buffer, and quantized
+// to the extent of their layer. Numeric feature IDs are kept; string IDs are
+// dropped since vector tiles only support unsigned integer IDs.
+func Marshal(tile Tile, layers ...*Layer) ([]byte, error) {
+	w := &pbf.Writer{}
+	for _, layer := range layers {
+		lw, err := encodeLayer(tile, layer)
+		if err != nil {
+			return nil, err
+		}
+
+		w.Message(tileLayers, lw)
+	}
+
+	return w.Data(), nil
+}
@@ -0,0 +1,98 @@
+package mvt
+
+import (
+	"math"
+
+	"github.com/topos-ai/geoutil/encoding/geojson"
+)
+
+// DefaultExtent is the number of integer units across a tile used when a layer
+// does not specify one.
+const DefaultExtent = 4096
+
+const (
+	geomTypeUnknown    = 0
+	geomTypePoint      = 1
+	geomTypeLineString = 2
+	geomTypePolygon    = 3
+
+	commandMoveTo    = 1
+	commandLineTo    = 2
+	commandClosePath = 7
+)
+
+// Fields of the Tile, Layer, Feature and Value messages.
+const (
+	tileLayers = 3
+
+	layerName     = 1
+	layerFeatures = 2
+	layerKeys     = 3
+	layerValues   = 4
+	layerExtent   = 5
+	layerVersion  = 15
+
+	featureID       = 1
+	featureTags     = 2
+	featureType     = 3
+	featureGeometry = 4
+
+	valueString = 1
+	valueFloat  = 2
+	valueDouble = 3
+	valueInt    = 4
+	valueUint   = 5
+	valueSint   = 6
+	valueBool   = 7
+)
+
+// maxLatitude is the latitude at which the Web Mercator projection becomes
+// square.
+const maxLatitude = 85.05112877980659
+
+// Tile identifies a tile of the Web Mercator tiling scheme.
+type Tile struct {
+	Z, X, Y int
+}
+
+// Layer represents a named layer of a vector tile.
+type Layer struct {
+	Name     string
+	Features []*geojson.Feature
+
+	// Extent is the number of integer units across the tile. Zero selects
+	// DefaultExtent.
+	Extent int
+
+	// Buffer is the number of integer units features are kept beyond the edges
+	// of the tile when clipping.
+	Buffer int
+}
+
+func (l *Layer) extent() int {
+	if l.Extent <= 0 {
+		return DefaultExtent
+	}
+
+	return l.Extent
+}
+
+// project maps degrees to tile coordinates.
+func project(tile Tile, extent int, lng, lat float64) (float64, float64) {
+	lat = math.Max(-maxLatitude, math.Min(maxLatitude, lat))
+	n := math.Exp2(float64(tile.Z))
+	sin := math.Sin(lat * math.Pi / 180)
+	x := n * (lng + 180) / 360
+	y := n * (0.5 - math.Log((1+sin)/(1-sin))/(4*math.Pi))
+	return (x - float64(tile.X)) * float64(extent), (y - float64(tile.Y)) * float64(extent)
+}
+
+// unproject maps tile coordinates to degrees.
+func unproject(tile Tile, extent int, x, y float64) (float64, float64) {
+	n := math.Exp2(float64(tile.Z))
+	wx := (float64(tile.X) + x/float64(extent)) / n
+	wy := (float64(tile.Y) + y/float64(extent)) / n
+	lng := wx*360 - 180
+	lat := math.Atan(math.Sinh(math.Pi*(1-2*wy))) * 180 / math.Pi
+	return lng, lat
+}
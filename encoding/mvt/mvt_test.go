@@ -0,0 +1,461 @@
+package mvt
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/golang/geo/s2"
+
+	"github.com/topos-ai/geoutil"
+	"github.com/topos-ai/geoutil/encoding/geojson"
+	"github.com/topos-ai/geoutil/internal/pbf"
+)
+
+var testTile = Tile{Z: 10, X: 518, Y: 352}
+
+// at returns the coordinates of a position in tile units of testTile.
+func at(x, y float64) []float64 {
+	lng, lat := unproject(testTile, DefaultExtent, x, y)
+	return []float64{lng, lat}
+}
+
+// tileCoords returns the coordinates of points in tile units of testTile.
+func tileCoords(points [][]float64) [][]float64 {
+	coords := make([][]float64, len(points))
+	for i, p := range points {
+		coords[i] = at(p[0], p[1])
+	}
+
+	return coords
+}
+
+// units returns the position of a point in tile units of testTile.
+func units(p s2.Point) point {
+	latLng := s2.LatLngFromPoint(p)
+	x, y := project(testTile, DefaultExtent, latLng.Lng.Degrees(), latLng.Lat.Degrees())
+	return point{math.Round(x*1e6) / 1e6, math.Round(y*1e6) / 1e6}
+}
+
+func TestProject(t *testing.T) {
+	tests := []struct {
+		tile     Tile
+		lng, lat float64
+		x, y     float64
+	}{
+		{Tile{0, 0, 0}, 0, 0, 2048, 2048},
+		{Tile{0, 0, 0}, -180, maxLatitude, 0, 0},
+		{Tile{0, 0, 0}, 180, -maxLatitude, 4096, 4096},
+		{Tile{1, 1, 0}, 0, maxLatitude, 0, 0},
+		{Tile{1, 1, 1}, 90, 0, 2048, 0},
+	}
+
+	for _, test := range tests {
+		x, y := project(test.tile, DefaultExtent, test.lng, test.lat)
+		if math.Abs(x-test.x) > 1e-6 || math.Abs(y-test.y) > 1e-6 {
+			t.Errorf("project(%v, %v, %v) = %v, %v, want %v, %v", test.tile, test.lng, test.lat, x, y, test.x, test.y)
+		}
+
+		lng, lat := unproject(test.tile, DefaultExtent, test.x, test.y)
+		if math.Abs(lng-test.lng) > 1e-9 || math.Abs(lat-test.lat) > 1e-9 {
+			t.Errorf("unproject(%v, %v, %v) = %v, %v, want %v, %v", test.tile, test.x, test.y, lng, lat, test.lng, test.lat)
+		}
+	}
+
+	// Latitudes beyond the reach of the projection are clamped.
+	if _, y := project(Tile{0, 0, 0}, DefaultExtent, 0, 89); math.Abs(y) > 1e-6 {
+		t.Errorf("project clamped latitude 89 to y %v, want 0", y)
+	}
+}
+
+// The command sequences of the examples of the specification.
+func TestDecodeCommands(t *testing.T) {
+	tests := []struct {
+		name     string
+		geomType int
+		commands []uint32
+		want     [][]point
+	}{
+		{"point", geomTypePoint, []uint32{9, 50, 34}, [][]point{{{25, 17}}}},
+		{"multipoint", geomTypePoint, []uint32{17, 10, 14, 3, 9}, [][]point{{{5, 7}}, {{3, 2}}}},
+		{"linestring", geomTypeLineString, []uint32{9, 4, 4, 18, 0, 16, 16, 0}, [][]point{{{2, 2}, {2, 10}, {10, 10}}}},
+		{
+			"multilinestring", geomTypeLineString,
+			[]uint32{9, 4, 4, 18, 0, 16, 16, 0, 9, 17, 17, 10, 4, 8},
+			[][]point{{{2, 2}, {2, 10}, {10, 10}}, {{1, 1}, {3, 5}}},
+		},
+		{"polygon", geomTypePolygon, []uint32{9, 6, 12, 18, 10, 12, 24, 44, 15}, [][]point{{{3, 6}, {8, 12}, {20, 34}}}},
+	}
+
+	for _, test := range tests {
+		sequences, err := decodeCommands(test.commands, test.geomType)
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(sequences, test.want) {
+			t.Errorf("%s: decoded %v, want %v", test.name, sequences, test.want)
+		}
+	}
+}
+
+func TestDecodeCommandsErrors(t *testing.T) {
+	tests := map[string][]uint32{
+		"truncated":      {9, 50},
+		"lineto first":   {10, 4, 4},
+		"unknown":        {11, 4, 4},
+		"truncated line": {9, 4, 4, 26, 0, 16},
+	}
+
+	for name, commands := range tests {
+		if _, err := decodeCommands(commands, geomTypeLineString); err == nil {
+			t.Errorf("%s: no error", name)
+		}
+	}
+}
+
+func TestEncodeCommands(t *testing.T) {
+	pt, _ := geoutil.PointFromPointCoordinates(at(25, 17))
+	points, _ := geoutil.PointsFromMultiPointCoordinates(tileCoords([][]float64{{5, 7}, {3, 2}}))
+	polyline, _ := geoutil.PolylineFromLineStringCoordinates(tileCoords([][]float64{{2, 2}, {2, 10}, {10, 10}}))
+	tests := []struct {
+		name     string
+		geometry interface{}
+		geomType int
+		want     []uint32
+	}{
+		{"point", pt, geomTypePoint, []uint32{9, 50, 34}},
+		{"multipoint", points, geomTypePoint, []uint32{17, 10, 14, 3, 9}},
+		{"linestring", polyline, geomTypeLineString, []uint32{9, 4, 4, 18, 0, 16, 16, 0}},
+	}
+
+	for _, test := range tests {
+		e := &geometryEncoder{tile: testTile, extent: DefaultExtent, box: bbox{0, 0, DefaultExtent, DefaultExtent}}
+		geomType, err := e.encode(test.geometry)
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+
+		if geomType != test.geomType || !reflect.DeepEqual(e.commands, test.want) {
+			t.Errorf("%s: encoded type %d commands %v, want type %d commands %v", test.name, geomType, e.commands, test.geomType, test.want)
+		}
+	}
+}
+
+func TestEncodeRingWinding(t *testing.T) {
+	polygon, err := geoutil.PolygonFromPolygonCoordinates([][][]float64{
+		tileCoords([][]float64{{100, 100}, {1000, 100}, {1000, 1000}, {100, 1000}, {100, 100}}),
+		tileCoords([][]float64{{300, 300}, {300, 600}, {600, 600}, {600, 300}, {300, 300}}),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e := &geometryEncoder{tile: testTile, extent: DefaultExtent, box: bbox{0, 0, DefaultExtent, DefaultExtent}}
+	if _, err := e.encode(polygon); err != nil {
+		t.Fatal(err)
+	}
+
+	sequences, err := decodeCommands(e.commands, geomTypePolygon)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(sequences) != 2 {
+		t.Fatalf("encoded %d rings, want 2", len(sequences))
+	}
+
+	// Exterior rings are clockwise and interior rings counter-clockwise in
+	// tile coordinates.
+	if area := ringArea(sequences[0]); area != 2*900*900 {
+		t.Errorf("exterior ring has area %v, want %v", area, 2*900*900)
+	}
+
+	if area := ringArea(sequences[1]); area != -2*300*300 {
+		t.Errorf("interior ring has area %v, want %v", area, -2*300*300)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	pt, _ := geoutil.PointFromPointCoordinates(at(25, 17))
+	points, _ := geoutil.PointsFromMultiPointCoordinates(tileCoords([][]float64{{5, 7}, {3, 2}}))
+	polyline, _ := geoutil.PolylineFromLineStringCoordinates(tileCoords([][]float64{{2, 2}, {2, 10}, {10, 10}}))
+	polylines, _ := geoutil.PolylinesFromMultiLineStringCoordinates([][][]float64{
+		tileCoords([][]float64{{2, 2}, {2, 10}}),
+		tileCoords([][]float64{{20, 20}, {40, 30}}),
+	})
+	polygon, err := geoutil.PolygonFromPolygonCoordinates([][][]float64{
+		tileCoords([][]float64{{100, 100}, {1000, 100}, {1000, 1000}, {100, 1000}, {100, 100}}),
+		tileCoords([][]float64{{300, 300}, {300, 600}, {600, 600}, {600, 300}, {300, 300}}),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	multipolygon, err := geoutil.PolygonFromMultiPolygonCoordinates([][][][]float64{
+		{tileCoords([][]float64{{100, 100}, {200, 100}, {200, 200}, {100, 200}, {100, 100}})},
+		{tileCoords([][]float64{{2000, 2000}, {2500, 2000}, {2500, 2500}, {2000, 2500}, {2000, 2000}})},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	layer := &Layer{
+		Name: "test",
+		Features: []*geojson.Feature{
+			{ID: 1.0, Geometry: pt},
+			{ID: 2.0, Geometry: points},
+			{ID: 3.0, Geometry: polyline},
+			{ID: 4.0, Geometry: polylines},
+			{ID: 5.0, Geometry: polygon},
+			{ID: 6.0, Geometry: multipolygon},
+		},
+	}
+
+	data, err := Marshal(testTile, layer)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	layers, err := Unmarshal(data, testTile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(layers) != 1 || layers[0].Name != "test" || layers[0].Extent != DefaultExtent {
+		t.Fatalf("decoded layers %+v", layers)
+	}
+
+	features := layers[0].Features
+	if len(features) != len(layer.Features) {
+		t.Fatalf("decoded %d features, want %d", len(features), len(layer.Features))
+	}
+
+	for i, f := range features {
+		if f.ID != layer.Features[i].ID {
+			t.Errorf("feature %d has ID %v, want %v", i, f.ID, layer.Features[i].ID)
+		}
+	}
+
+	if p, ok := features[0].Geometry.(s2.Point); !ok || units(p) != (point{25, 17}) {
+		t.Errorf("decoded point %v", features[0].Geometry)
+	}
+
+	if ps, ok := features[1].Geometry.([]s2.Point); !ok || len(ps) != 2 || units(ps[0]) != (point{5, 7}) || units(ps[1]) != (point{3, 2}) {
+		t.Errorf("decoded points %v", features[1].Geometry)
+	}
+
+	if l, ok := features[2].Geometry.(*s2.Polyline); !ok || len(*l) != 3 || units((*l)[1]) != (point{2, 10}) {
+		t.Errorf("decoded polyline %v", features[2].Geometry)
+	}
+
+	if ls, ok := features[3].Geometry.([]*s2.Polyline); !ok || len(ls) != 2 || units((*ls[1])[1]) != (point{40, 30}) {
+		t.Errorf("decoded polylines %v", features[3].Geometry)
+	}
+
+	for i, want := range []*s2.Polygon{polygon, multipolygon} {
+		got, ok := features[4+i].Geometry.(*s2.Polygon)
+		if !ok {
+			t.Errorf("decoded %T, want a polygon", features[4+i].Geometry)
+			continue
+		}
+
+		if got.NumLoops() != want.NumLoops() || math.Abs(got.Area()-want.Area()) > 1e-9*want.Area() {
+			t.Errorf("decoded polygon with %d loops and area %v, want %d loops and area %v", got.NumLoops(), got.Area(), want.NumLoops(), want.Area())
+		}
+	}
+
+	if hole := features[4].Geometry.(*s2.Polygon).Loop(1); !hole.IsHole() {
+		t.Errorf("the second loop of the polygon is not a hole")
+	}
+}
+
+func TestProperties(t *testing.T) {
+	pt, _ := geoutil.PointFromPointCoordinates(at(10, 10))
+	layer := &Layer{
+		Name: "properties",
+		Features: []*geojson.Feature{
+			{
+				ID:       "dropped",
+				Geometry: pt,
+				Properties: map[string]interface{}{
+					"name":    "a",
+					"count":   -3,
+					"big":     uint64(math.MaxUint64),
+					"ratio":   0.25,
+					"single":  float32(1.5),
+					"open":    true,
+					"tags":    []string{"x", "y"},
+					"missing": nil,
+				},
+			},
+			{
+				ID:       0.0,
+				Geometry: pt,
+				Properties: map[string]interface{}{
+					"name":  "a",
+					"count": int64(-3),
+				},
+			},
+		},
+	}
+
+	data, err := Marshal(testTile, layer)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	layers, err := Unmarshal(data, testTile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	features := layers[0].Features
+	want := map[string]interface{}{
+		"name":   "a",
+		"count":  int64(-3),
+		"big":    uint64(math.MaxUint64),
+		"ratio":  0.25,
+		"single": float32(1.5),
+		"open":   true,
+		"tags":   `["x","y"]`,
+	}
+
+	if !reflect.DeepEqual(features[0].Properties, want) {
+		t.Errorf("decoded properties %v, want %v", features[0].Properties, want)
+	}
+
+	if features[0].ID != nil {
+		t.Errorf("string ID was kept as %v", features[0].ID)
+	}
+
+	if features[1].ID != 0.0 {
+		t.Errorf("decoded ID %v, want 0", features[1].ID)
+	}
+
+	if !reflect.DeepEqual(features[1].Properties, map[string]interface{}{"name": "a", "count": int64(-3)}) {
+		t.Errorf("decoded properties %v", features[1].Properties)
+	}
+
+	// Keys and values are shared between the features of a layer.
+	keys, values := 0, 0
+	r := pbf.NewReader(data)
+	for r.Next() {
+		lr := pbf.NewReader(r.Bytes())
+		for lr.Next() {
+			switch lr.Field() {
+			case layerKeys:
+				keys++
+			case layerValues:
+				values++
+			}
+
+			lr.Skip()
+		}
+	}
+
+	if keys != 7 || values != 7 {
+		t.Errorf("layer has %d keys and %d values, want 7 and 7", keys, values)
+	}
+}
+
+func TestClip(t *testing.T) {
+	inside, _ := geoutil.PointFromPointCoordinates(at(-10, 10))
+	outside, _ := geoutil.PointFromPointCoordinates(at(-100, 10))
+	polyline, _ := geoutil.PolylineFromLineStringCoordinates(tileCoords([][]float64{{-1000, 100}, {5000, 100}}))
+	far, _ := geoutil.PolylineFromLineStringCoordinates(tileCoords([][]float64{{-1000, -1000}, {-500, -1000}}))
+	polygon, err := geoutil.PolygonFromPolygonCoordinates([][][]float64{
+		tileCoords([][]float64{{-1000, -1000}, {5000, -1000}, {5000, 5000}, {-1000, 5000}, {-1000, -1000}}),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	layer := &Layer{
+		Name:   "clip",
+		Buffer: 64,
+		Features: []*geojson.Feature{
+			{ID: 1.0, Geometry: inside},
+			{ID: 2.0, Geometry: outside},
+			{ID: 3.0, Geometry: polyline},
+			{ID: 4.0, Geometry: far},
+			{ID: 5.0, Geometry: polygon},
+		},
+	}
+
+	data, err := Marshal(testTile, layer)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	layers, err := Unmarshal(data, testTile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	features := layers[0].Features
+	ids := []interface{}{}
+	for _, f := range features {
+		ids = append(ids, f.ID)
+	}
+
+	if !reflect.DeepEqual(ids, []interface{}{1.0, 3.0, 5.0}) {
+		t.Fatalf("kept features %v, want 1, 3 and 5", ids)
+	}
+
+	l := *features[1].Geometry.(*s2.Polyline)
+	if len(l) != 2 || units(l[0]) != (point{-64, 100}) || units(l[1]) != (point{4160, 100}) {
+		t.Errorf("clipped polyline to %v", l)
+	}
+
+	loop := features[2].Geometry.(*s2.Polygon).Loop(0)
+	if loop.NumVertices() != 4 {
+		t.Fatalf("clipped polygon has %d vertices, want 4", loop.NumVertices())
+	}
+
+	for _, v := range loop.Vertices() {
+		p := units(v)
+		if (p.x != -64 && p.x != 4160) || (p.y != -64 && p.y != 4160) {
+			t.Errorf("clipped polygon has vertex %v off the corners of the buffer", p)
+		}
+	}
+}
+
+func TestUnmarshalErrors(t *testing.T) {
+	layer := func(extent uint64, tags, commands []uint32) []byte {
+		fw := &pbf.Writer{}
+		fw.PackedUint32s(featureTags, tags)
+		fw.Uint64(featureType, geomTypePoint)
+		fw.PackedUint32s(featureGeometry, commands)
+
+		lw := &pbf.Writer{}
+		lw.String(layerName, "errors")
+		lw.Message(layerFeatures, fw)
+		lw.String(layerKeys, "key")
+		lw.Uint64(layerExtent, extent)
+
+		w := &pbf.Writer{}
+		w.Message(tileLayers, lw)
+		return w.Data()
+	}
+
+	if _, err := Unmarshal(layer(DefaultExtent, nil, []uint32{9, 50, 34}), testTile); err != nil {
+		t.Errorf("valid layer: %v", err)
+	}
+
+	tests := map[string][]byte{
+		"extent":           layer(0, nil, []uint32{9, 50, 34}),
+		"odd tags":         layer(DefaultExtent, []uint32{0}, []uint32{9, 50, 34}),
+		"tag out of range": layer(DefaultExtent, []uint32{0, 0}, []uint32{9, 50, 34}),
+		"truncated":        layer(DefaultExtent, nil, []uint32{9, 50}),
+		"truncated tile":   layer(DefaultExtent, nil, []uint32{9, 50, 34})[:10],
+	}
+
+	for name, data := range tests {
+		if _, err := Unmarshal(data, testTile); err == nil {
+			t.Errorf("%s: no error", name)
+		}
+	}
+}
@@ -0,0 +1,268 @@
+package topojson
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/topos-ai/geoutil"
+	"github.com/topos-ai/geoutil/encoding/geojson"
+)
+
+// decodeArcs removes the delta encoding and quantization of the arcs.
+func (t *Topology) decodeArcs() [][][]float64 {
+	if t.decodedArcs != nil {
+		return t.decodedArcs
+	}
+
+	arcs := make([][][]float64, len(t.Arcs))
+	for i, arc := range t.Arcs {
+		x, y := 0.0, 0.0
+		positions := make([][]float64, 0, len(arc))
+		for _, position := range arc {
+			if len(position) < 2 {
+				continue
+			}
+
+			if t.Transform == nil {
+				positions = append(positions, []float64{position[0], position[1]})
+				continue
+			}
+
+			x += position[0]
+			y += position[1]
+			positions = append(positions, []float64{
+				x*t.Transform.Scale[0] + t.Transform.Translate[0],
+				y*t.Transform.Scale[1] + t.Transform.Translate[1],
+			})
+		}
+
+		arcs[i] = positions
+	}
+
+	t.decodedArcs = arcs
+	return arcs
+}
+
+func (t *Topology) position(position []float64) ([]float64, error) {
+	if d := len(position); d < 2 {
+		return nil, fmt.Errorf("topojson: cannot process position with dimension %d", d)
+	}
+
+	if t.Transform == nil {
+		return position[:2], nil
+	}
+
+	return []float64{
+		position[0]*t.Transform.Scale[0] + t.Transform.Translate[0],
+		position[1]*t.Transform.Scale[1] + t.Transform.Translate[1],
+	}, nil
+}
+
+// line concatenates arcs, where a negative index ^i refers to arc i reversed.
+func (t *Topology) line(indexes []int) ([][]float64, error) {
+	arcs := t.decodeArcs()
+	line := [][]float64{}
+	for _, i := range indexes {
+		reversed := i < 0
+		if reversed {
+			i = ^i
+		}
+
+		if i >= len(arcs) {
+			return nil, fmt.Errorf("topojson: arc index %d out of range", i)
+		}
+
+		arc := arcs[i]
+		for k := range arc {
+			position := arc[k]
+			if reversed {
+				position = arc[len(arc)-1-k]
+			}
+
+			// Consecutive arcs share their end points.
+			if k == 0 && len(line) > 0 {
+				continue
+			}
+
+			line = append(line, position)
+		}
+	}
+
+	return line, nil
+}
+
+func (t *Topology) lines(indexes [][]int) ([][][]float64, error) {
+	lines := make([][][]float64, len(indexes))
+	for i, lineIndexes := range indexes {
+		line, err := t.line(lineIndexes)
+		if err != nil {
+			return nil, err
+		}
+
+		lines[i] = line
+	}
+
+	return lines, nil
+}
+
+func (t *Topology) rings(indexes [][]int) ([][][]float64, error) {
+	rings, err := t.lines(indexes)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, ring := range rings {
+		if len(ring) == 0 {
+			return nil, fmt.Errorf("topojson: empty polygon ring")
+		}
+	}
+
+	return rings, nil
+}
+
+func (t *Topology) decodeGeometry(g *Geometry) (interface{}, error) {
+	switch g.Type {
+	case "":
+		return nil, nil
+
+	case "Point":
+		coords := []float64{}
+		if err := json.Unmarshal(g.Coordinates, &coords); err != nil {
+			return nil, err
+		}
+
+		position, err := t.position(coords)
+		if err != nil {
+			return nil, err
+		}
+
+		return geoutil.PointFromPointCoordinates(position)
+
+	case "MultiPoint":
+		coords := [][]float64{}
+		if err := json.Unmarshal(g.Coordinates, &coords); err != nil {
+			return nil, err
+		}
+
+		positions := make([][]float64, len(coords))
+		for i := range coords {
+			position, err := t.position(coords[i])
+			if err != nil {
+				return nil, err
+			}
+
+			positions[i] = position
+		}
+
+		return geoutil.PointsFromMultiPointCoordinates(positions)
+
+	case "LineString":
+		indexes := []int{}
+		if err := json.Unmarshal(g.Arcs, &indexes); err != nil {
+			return nil, err
+		}
+
+		line, err := t.line(indexes)
+		if err != nil {
+			return nil, err
+		}
+
+		return geoutil.PolylineFromLineStringCoordinates(line)
+
+	case "MultiLineString":
+		indexes := [][]int{}
+		if err := json.Unmarshal(g.Arcs, &indexes); err != nil {
+			return nil, err
+		}
+
+		lines, err := t.lines(indexes)
+		if err != nil {
+			return nil, err
+		}
+
+		return geoutil.PolylinesFromMultiLineStringCoordinates(lines)
+
+	case "Polygon":
+		indexes := [][]int{}
+		if err := json.Unmarshal(g.Arcs, &indexes); err != nil {
+			return nil, err
+		}
+
+		rings, err := t.rings(indexes)
+		if err != nil {
+			return nil, err
+		}
+
+		return geoutil.PolygonFromPolygonCoordinates(rings)
+
+	case "MultiPolygon":
+		indexes := [][][]int{}
+		if err := json.Unmarshal(g.Arcs, &indexes); err != nil {
+			return nil, err
+		}
+
+		polygons := make([][][][]float64, len(indexes))
+		for i, polygonIndexes := range indexes {
+			rings, err := t.rings(polygonIndexes)
+			if err != nil {
+				return nil, err
+			}
+
+			polygons[i] = rings
+		}
+
+		return geoutil.PolygonFromMultiPolygonCoordinates(polygons)
+
+	default:
+		return nil, fmt.Errorf("topojson: invalid Geometry Type value %s", g.Type)
+	}
+}
+
+func (t *Topology) feature(g *Geometry) (*geojson.Feature, error) {
+	switch g.ID.(type) {
+	case string, float64, nil:
+	default:
+		return nil, fmt.Errorf("topojson: invalid Geometry ID type %T", g.ID)
+	}
+
+	geometry, err := t.decodeGeometry(g)
+	if err != nil {
+		return nil, err
+	}
+
+	return &geojson.Feature{
+		ID:         g.ID,
+		Properties: g.Properties,
+		Geometry:   geometry,
+	}, nil
+}
+
+// FeatureCollection converts the named object of the topology into a
+// FeatureCollection. A GeometryCollection object yields one feature per
+// geometry; any other object yields a single feature.
+func (t *Topology) FeatureCollection(name string) (*geojson.FeatureCollection, error) {
+	object, ok := t.Objects[name]
+	if !ok {
+		return nil, fmt.Errorf("topojson: unknown object %s", name)
+	}
+
+	geometries := []*Geometry{object}
+	if object.Type == "GeometryCollection" {
+		geometries = object.Geometries
+	}
+
+	fc := &geojson.FeatureCollection{
+		Features: make([]*geojson.Feature, len(geometries)),
+	}
+
+	for i, g := range geometries {
+		f, err := t.feature(g)
+		if err != nil {
+			return nil, err
+		}
+
+		fc.Features[i] = f
+	}
+
+	return fc, nil
+}
@@ -0,0 +1,558 @@
+package topojson
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/golang/geo/s2"
+
+	"github.com/topos-ai/geoutil"
+	"github.com/topos-ai/geoutil/encoding/geojson"
+)
+
+type position [2]float64
+
+func (p position) less(o position) bool {
+	return p[0] < o[0] || p[0] == o[0] && p[1] < o[1]
+}
+
+// sequence is a line or an open ring awaiting cutting into arcs.
+type sequence struct {
+	positions []position
+	ring      bool
+}
+
+// neighbors is the unordered pair of positions adjacent to a position.
+type neighbors [2]position
+
+func newNeighbors(a, b position) neighbors {
+	if b.less(a) {
+		a, b = b, a
+	}
+
+	return neighbors{a, b}
+}
+
+type bbox [4]float64
+
+func emptyBBox() bbox {
+	return bbox{math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)}
+}
+
+func (b *bbox) extend(coords [][]float64) {
+	for _, c := range coords {
+		b[0] = math.Min(b[0], c[0])
+		b[1] = math.Min(b[1], c[1])
+		b[2] = math.Max(b[2], c[0])
+		b[3] = math.Max(b[3], c[1])
+	}
+}
+
+func (b bbox) isEmpty() bool {
+	return b[0] > b[2]
+}
+
+// builder accumulates the lines and rings of the encoded geometries and cuts
+// them into shared arcs.
+type builder struct {
+	transform *Transform
+	sequences []*sequence
+	arcs      [][]position
+	arcKeys   map[string]int
+}
+
+func (b *builder) quantize(coords []float64) position {
+	if b.transform == nil {
+		return position{coords[0], coords[1]}
+	}
+
+	return position{
+		math.Round((coords[0] - b.transform.Translate[0]) / b.transform.Scale[0]),
+		math.Round((coords[1] - b.transform.Translate[1]) / b.transform.Scale[1]),
+	}
+}
+
+// addSequence registers a line or a closed ring and returns its index.
+func (b *builder) addSequence(coords [][]float64, ring bool) int {
+	positions := make([]position, 0, len(coords))
+	for _, c := range coords {
+		p := b.quantize(c)
+		if len(positions) == 0 || positions[len(positions)-1] != p {
+			positions = append(positions, p)
+		}
+	}
+
+	if ring && len(positions) > 1 && positions[0] == positions[len(positions)-1] {
+		positions = positions[:len(positions)-1]
+	}
+
+	b.sequences = append(b.sequences, &sequence{
+		positions: positions,
+		ring:      ring,
+	})
+
+	return len(b.sequences) - 1
+}
+
+// junctions returns the positions where sequences meet or diverge: the end
+// points of lines, and every position reached from different neighbors.
+func (b *builder) junctions() map[position]bool {
+	junctions := map[position]bool{}
+	seen := map[position]neighbors{}
+	visit := func(p, prev, next position) {
+		n := newNeighbors(prev, next)
+		if previous, ok := seen[p]; !ok {
+			seen[p] = n
+		} else if previous != n {
+			junctions[p] = true
+		}
+	}
+
+	for _, s := range b.sequences {
+		n := len(s.positions)
+		if n == 0 {
+			continue
+		}
+
+		if !s.ring {
+			junctions[s.positions[0]] = true
+			junctions[s.positions[n-1]] = true
+			for i := 1; i < n-1; i++ {
+				visit(s.positions[i], s.positions[i-1], s.positions[i+1])
+			}
+
+			continue
+		}
+
+		for i := range s.positions {
+			visit(s.positions[i], s.positions[(i+n-1)%n], s.positions[(i+1)%n])
+		}
+	}
+
+	return junctions
+}
+
+func arcKey(arc []position) string {
+	buf := make([]byte, 16*len(arc))
+	for i, p := range arc {
+		binary.LittleEndian.PutUint64(buf[16*i:], math.Float64bits(p[0]))
+		binary.LittleEndian.PutUint64(buf[16*i+8:], math.Float64bits(p[1]))
+	}
+
+	return string(buf)
+}
+
+func reversed(arc []position) []position {
+	r := make([]position, len(arc))
+	for i, p := range arc {
+		r[len(arc)-1-i] = p
+	}
+
+	return r
+}
+
+// rotateToMin rotates a closed ring so that it starts at its smallest
+// position, giving identical rings the same representation.
+func rotateToMin(ring []position) []position {
+	open := ring[:len(ring)-1]
+	k := 0
+	for i, p := range open {
+		if p.less(open[k]) {
+			k = i
+		}
+	}
+
+	rotated := append(append([]position{}, open[k:]...), open[:k]...)
+	return append(rotated, rotated[0])
+}
+
+// arcIndex returns the index of an arc, reusing an existing arc when it has
+// the same positions in either direction.
+func (b *builder) arcIndex(arc []position) int {
+	if i, ok := b.arcKeys[arcKey(arc)]; ok {
+		return i
+	}
+
+	if i, ok := b.arcKeys[arcKey(reversed(arc))]; ok {
+		return ^i
+	}
+
+	i := len(b.arcs)
+	b.arcs = append(b.arcs, arc)
+	b.arcKeys[arcKey(arc)] = i
+	return i
+}
+
+// cut splits every sequence at the junctions and returns the arc indexes of
+// each sequence.
+func (b *builder) cut() [][]int {
+	junctions := b.junctions()
+	b.arcKeys = map[string]int{}
+	indexes := make([][]int, len(b.sequences))
+	for i, s := range b.sequences {
+		positions := s.positions
+		if len(positions) == 0 {
+			indexes[i] = []int{}
+			continue
+		}
+
+		if s.ring {
+			start := -1
+			for j, p := range positions {
+				if junctions[p] {
+					start = j
+					break
+				}
+			}
+
+			closed := append(append([]position{}, positions...), positions[0])
+
+			// A ring without junctions is a single arc, matched regardless of
+			// its starting point and direction.
+			if start < 0 {
+				arc := rotateToMin(closed)
+				if j, ok := b.arcKeys[arcKey(rotateToMin(reversed(closed)))]; ok {
+					indexes[i] = []int{^j}
+				} else {
+					indexes[i] = []int{b.arcIndex(arc)}
+				}
+
+				continue
+			}
+
+			positions = append(append([]position{}, positions[start:]...), positions[:start]...)
+			positions = append(positions, positions[0])
+		}
+
+		if len(positions) == 1 {
+			indexes[i] = []int{b.arcIndex(positions)}
+			continue
+		}
+
+		arcs := []int{}
+		start := 0
+		for j := 1; j < len(positions); j++ {
+			if j == len(positions)-1 || junctions[positions[j]] {
+				arcs = append(arcs, b.arcIndex(positions[start:j+1]))
+				start = j
+			}
+		}
+
+		indexes[i] = arcs
+	}
+
+	return indexes
+}
+
+// pending records how the sequences of a geometry map back to its structure.
+type pending struct {
+	geometry *Geometry
+	lines    [][]int
+	polygons [][][]int
+}
+
+func (b *builder) addGeometry(v interface{}, precision int) (*pending, error) {
+	p := &pending{
+		geometry: &Geometry{},
+	}
+
+	switch geometry := v.(type) {
+	case nil:
+
+	case s2.LatLng:
+		return b.addGeometry(s2.PointFromLatLng(geometry), precision)
+
+	case s2.Point:
+		coords, err := geoutil.PointCoordinates(geometry, precision)
+		if err != nil {
+			return nil, err
+		}
+
+		data, err := json.Marshal(b.quantize(coords))
+		if err != nil {
+			return nil, err
+		}
+
+		p.geometry.Type = "Point"
+		p.geometry.Coordinates = data
+
+	case []s2.Point:
+		positions := make([]position, len(geometry))
+		for i, point := range geometry {
+			coords, err := geoutil.PointCoordinates(point, precision)
+			if err != nil {
+				return nil, err
+			}
+
+			positions[i] = b.quantize(coords)
+		}
+
+		data, err := json.Marshal(positions)
+		if err != nil {
+			return nil, err
+		}
+
+		p.geometry.Type = "MultiPoint"
+		p.geometry.Coordinates = data
+
+	case *s2.Polyline:
+		coords, err := geoutil.PolylineCoordinates(geometry, precision)
+		if err != nil {
+			return nil, err
+		}
+
+		p.geometry.Type = "LineString"
+		p.lines = [][]int{{b.addSequence(coords, false)}}
+
+	case []*s2.Polyline:
+		p.geometry.Type = "MultiLineString"
+		p.lines = [][]int{}
+		for _, polyline := range geometry {
+			coords, err := geoutil.PolylineCoordinates(polyline, precision)
+			if err != nil {
+				return nil, err
+			}
+
+			p.lines = append(p.lines, []int{b.addSequence(coords, false)})
+		}
+
+	case *s2.Polygon:
+		polygonCoordinates, err := geoutil.PolygonCoordinates(geometry, precision)
+		if err != nil {
+			return nil, err
+		}
+
+		p.geometry.Type = "Polygon"
+		if len(polygonCoordinates) != 1 {
+			p.geometry.Type = "MultiPolygon"
+		}
+
+		p.polygons = make([][][]int, len(polygonCoordinates))
+		for i, polygonCoords := range polygonCoordinates {
+			for _, ringCoords := range polygonCoords {
+				p.polygons[i] = append(p.polygons[i], []int{b.addSequence(ringCoords, true)})
+			}
+		}
+
+	default:
+		return nil, fmt.Errorf("topojson: unknown geometry type %T", v)
+	}
+
+	return p, nil
+}
+
+// resolve fills in the arcs of a geometry once the sequences are cut.
+func (p *pending) resolve(indexes [][]int) error {
+	flatten := func(sequences []int) []int {
+		arcs := []int{}
+		for _, s := range sequences {
+			arcs = append(arcs, indexes[s]...)
+		}
+
+		return arcs
+	}
+
+	var arcs interface{}
+	switch p.geometry.Type {
+	case "LineString":
+		arcs = flatten(p.lines[0])
+	case "MultiLineString":
+		lines := make([][]int, len(p.lines))
+		for i, line := range p.lines {
+			lines[i] = flatten(line)
+		}
+
+		arcs = lines
+	case "Polygon", "MultiPolygon":
+		polygons := make([][][]int, len(p.polygons))
+		for i, polygon := range p.polygons {
+			polygons[i] = make([][]int, len(polygon))
+			for j, ring := range polygon {
+				polygons[i][j] = flatten(ring)
+			}
+		}
+
+		if p.geometry.Type == "Polygon" {
+			arcs = polygons[0]
+		} else {
+			arcs = polygons
+		}
+
+	default:
+		return nil
+	}
+
+	data, err := json.Marshal(arcs)
+	if err != nil {
+		return err
+	}
+
+	p.geometry.Arcs = data
+	return nil
+}
+
+// bounds returns the bounding box of every coordinate of the feature
+// collections, which determines the quantization transform.
+func bounds(objects map[string]*geojson.FeatureCollection) (bbox, error) {
+	b := emptyBBox()
+	for _, fc := range objects {
+		for _, f := range fc.Features {
+			switch geometry := f.Geometry.(type) {
+			case s2.LatLng:
+				b.extend([][]float64{{geometry.Lng.Degrees(), geometry.Lat.Degrees()}})
+
+			case s2.Point:
+				coords, err := geoutil.PointCoordinates(geometry, f.Precision)
+				if err != nil {
+					return b, err
+				}
+
+				b.extend([][]float64{coords})
+
+			case []s2.Point:
+				for _, point := range geometry {
+					coords, err := geoutil.PointCoordinates(point, f.Precision)
+					if err != nil {
+						return b, err
+					}
+
+					b.extend([][]float64{coords})
+				}
+
+			case *s2.Polyline:
+				coords, err := geoutil.PolylineCoordinates(geometry, f.Precision)
+				if err != nil {
+					return b, err
+				}
+
+				b.extend(coords)
+
+			case []*s2.Polyline:
+				for _, polyline := range geometry {
+					coords, err := geoutil.PolylineCoordinates(polyline, f.Precision)
+					if err != nil {
+						return b, err
+					}
+
+					b.extend(coords)
+				}
+
+			case *s2.Polygon:
+				polygonCoordinates, err := geoutil.PolygonCoordinates(geometry, f.Precision)
+				if err != nil {
+					return b, err
+				}
+
+				for _, polygonCoords := range polygonCoordinates {
+					for _, ringCoords := range polygonCoords {
+						b.extend(ringCoords)
+					}
+				}
+			}
+		}
+	}
+
+	return b, nil
+}
+
+// NewTopology builds a topology with one GeometryCollection object per
+// feature collection. Lines and polygon rings are cut into arcs wherever they
+// meet or diverge, so that borders shared by adjacent polygons are stored once
+// and referenced by both. A positive quantization quantizes coordinates to
+// that many distinct values per axis and delta-encodes the arcs.
+func NewTopology(objects map[string]*geojson.FeatureCollection, quantization int) (*Topology, error) {
+	bbox, err := bounds(objects)
+	if err != nil {
+		return nil, err
+	}
+
+	b := &builder{}
+	if quantization > 1 && !bbox.isEmpty() {
+		b.transform = &Transform{
+			Scale:     [2]float64{1, 1},
+			Translate: [2]float64{bbox[0], bbox[1]},
+		}
+
+		if bbox[2] > bbox[0] {
+			b.transform.Scale[0] = (bbox[2] - bbox[0]) / float64(quantization-1)
+		}
+
+		if bbox[3] > bbox[1] {
+			b.transform.Scale[1] = (bbox[3] - bbox[1]) / float64(quantization-1)
+		}
+	}
+
+	// Visit the objects in a deterministic order so that arcs are numbered
+	// consistently.
+	names := make([]string, 0, len(objects))
+	for name := range objects {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	t := &Topology{
+		Transform: b.transform,
+		Objects:   map[string]*Geometry{},
+	}
+
+	pendings := []*pending{}
+	for _, name := range names {
+		collection := &Geometry{
+			Type:       "GeometryCollection",
+			Geometries: []*Geometry{},
+		}
+
+		for _, f := range objects[name].Features {
+			switch f.ID.(type) {
+			case string, float64, nil:
+			default:
+				return nil, fmt.Errorf("topojson: invalid Feature ID type %T", f.ID)
+			}
+
+			p, err := b.addGeometry(f.Geometry, f.Precision)
+			if err != nil {
+				return nil, err
+			}
+
+			p.geometry.ID = f.ID
+			p.geometry.Properties = f.Properties
+			collection.Geometries = append(collection.Geometries, p.geometry)
+			pendings = append(pendings, p)
+		}
+
+		t.Objects[name] = collection
+	}
+
+	indexes := b.cut()
+	for _, p := range pendings {
+		if err := p.resolve(indexes); err != nil {
+			return nil, err
+		}
+	}
+
+	if !bbox.isEmpty() {
+		t.BBox = bbox[:]
+	}
+
+	t.Arcs = make([][][]float64, len(b.arcs))
+	for i, arc := range b.arcs {
+		positions := make([][]float64, len(arc))
+		previous := position{}
+		for j, p := range arc {
+			if b.transform != nil {
+				positions[j] = []float64{p[0] - previous[0], p[1] - previous[1]}
+				previous = p
+			} else {
+				positions[j] = []float64{p[0], p[1]}
+			}
+		}
+
+		t.Arcs[i] = positions
+	}
+
+	return t, nil
+}
@@ -0,0 +1,99 @@
+package topojson
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Transform represents the quantization transform of a topology.
+type Transform struct {
+	Scale     [2]float64 `json:"scale"`
+	Translate [2]float64 `json:"translate"`
+}
+
+// Geometry represents a TopoJSON geometry object. Arcs and Coordinates are
+// kept in their raw form since their nesting depends on Type. An empty Type
+// represents a null object.
+type Geometry struct {
+	Type        string                 `json:"type"`
+	ID          interface{}            `json:"id,omitempty"`
+	Properties  map[string]interface{} `json:"properties,omitempty"`
+	Arcs        json.RawMessage        `json:"arcs,omitempty"`
+	Coordinates json.RawMessage        `json:"coordinates,omitempty"`
+	Geometries  []*Geometry            `json:"geometries,omitempty"`
+}
+
+type rawGeometry Geometry
+
+func (g *Geometry) MarshalJSON() ([]byte, error) {
+	rg := (*rawGeometry)(g)
+	if g.Type != "" {
+		return json.Marshal(rg)
+	}
+
+	// Null objects have a null type rather than an empty one.
+	return json.Marshal(struct {
+		*rawGeometry
+		Type *string `json:"type"`
+	}{rawGeometry: rg})
+}
+
+// Topology represents a TopoJSON Topology object.
+type Topology struct {
+	BBox      []float64
+	Transform *Transform
+	Objects   map[string]*Geometry
+	Arcs      [][][]float64
+
+	// decodedArcs caches the arcs with delta encoding and quantization
+	// removed.
+	decodedArcs [][][]float64
+}
+
+type rawTopology struct {
+	Type      string               `json:"type"`
+	BBox      []float64            `json:"bbox,omitempty"`
+	Transform *Transform           `json:"transform,omitempty"`
+	Objects   map[string]*Geometry `json:"objects"`
+	Arcs      [][][]float64        `json:"arcs"`
+}
+
+func (t *Topology) UnmarshalJSON(data []byte) error {
+	rt := &rawTopology{}
+	if err := json.Unmarshal(data, rt); err != nil {
+		return err
+	}
+
+	if rt.Type != "Topology" {
+		return fmt.Errorf("topojson: invalid Topology Type value %s", rt.Type)
+	}
+
+	*t = Topology{
+		BBox:      rt.BBox,
+		Transform: rt.Transform,
+		Objects:   rt.Objects,
+		Arcs:      rt.Arcs,
+	}
+
+	return nil
+}
+
+func (t *Topology) MarshalJSON() ([]byte, error) {
+	rt := &rawTopology{
+		Type:      "Topology",
+		BBox:      t.BBox,
+		Transform: t.Transform,
+		Objects:   t.Objects,
+		Arcs:      t.Arcs,
+	}
+
+	if rt.Objects == nil {
+		rt.Objects = map[string]*Geometry{}
+	}
+
+	if rt.Arcs == nil {
+		rt.Arcs = [][][]float64{}
+	}
+
+	return json.Marshal(rt)
+}
@@ -0,0 +1,382 @@
+package topojson
+
+import (
+	"encoding/json"
+	"math"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/golang/geo/s2"
+
+	"github.com/topos-ai/geoutil"
+	"github.com/topos-ai/geoutil/encoding/geojson"
+)
+
+// example is the example topology of the TopoJSON specification.
+const example = `{
+  "type": "Topology",
+  "transform": {"scale": [0.0005, 0.0001], "translate": [100, 0]},
+  "objects": {
+    "example": {
+      "type": "GeometryCollection",
+      "geometries": [
+        {"type": "Point", "properties": {"prop0": "value0"}, "coordinates": [4000, 5000]},
+        {"type": "LineString", "properties": {"prop0": "value0", "prop1": 0}, "arcs": [0]},
+        {"type": "Polygon", "properties": {"prop0": "value0", "prop1": {"this": "that"}}, "arcs": [[-2]]}
+      ]
+    }
+  },
+  "arcs": [
+    [[4000, 0], [1999, 9999], [2000, -9999], [2000, 9999]],
+    [[0, 0], [0, 9999], [2000, 0], [0, -9999], [-2000, 0]]
+  ]
+}`
+
+func near(a, b [][]float64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if math.Abs(a[i][0]-b[i][0]) > 1e-9 || math.Abs(a[i][1]-b[i][1]) > 1e-9 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// vertices returns the sorted vertices of a polygon at PrecisionE6, which do
+// not depend on the starting vertex or direction of its loops.
+func vertices(t *testing.T, polygon *s2.Polygon) [][]float64 {
+	t.Helper()
+	polygonCoordinates, err := geoutil.PolygonCoordinates(polygon, geoutil.PrecisionE6)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vertices := [][]float64{}
+	for _, polygonCoords := range polygonCoordinates {
+		for _, ringCoords := range polygonCoords {
+			vertices = append(vertices, ringCoords[:len(ringCoords)-1]...)
+		}
+	}
+
+	sort.Slice(vertices, func(i, j int) bool {
+		return vertices[i][0] < vertices[j][0] || vertices[i][0] == vertices[j][0] && vertices[i][1] < vertices[j][1]
+	})
+
+	return vertices
+}
+
+func square(x, y, size float64) [][]float64 {
+	return [][]float64{{x, y}, {x + size, y}, {x + size, y + size}, {x, y + size}, {x, y}}
+}
+
+func polygonFeature(t *testing.T, id interface{}, rings ...[][]float64) *geojson.Feature {
+	t.Helper()
+	polygon, err := geoutil.PolygonFromPolygonCoordinates(rings)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &geojson.Feature{ID: id, Geometry: polygon, Precision: geoutil.PrecisionE6}
+}
+
+func TestDecodeExample(t *testing.T) {
+	topology := &Topology{}
+	if err := json.Unmarshal([]byte(example), topology); err != nil {
+		t.Fatal(err)
+	}
+
+	fc, err := topology.FeatureCollection("example")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(fc.Features) != 3 {
+		t.Fatalf("decoded %d features, want 3", len(fc.Features))
+	}
+
+	point, ok := fc.Features[0].Geometry.(s2.Point)
+	if !ok {
+		t.Fatalf("decoded %T, want a point", fc.Features[0].Geometry)
+	}
+
+	coords, _ := geoutil.PointCoordinates(point, geoutil.PrecisionMax)
+	if !near([][]float64{coords}, [][]float64{{102, 0.5}}) {
+		t.Errorf("decoded point %v, want [102 0.5]", coords)
+	}
+
+	if fc.Features[0].Properties["prop0"] != "value0" {
+		t.Errorf("decoded properties %v", fc.Features[0].Properties)
+	}
+
+	polyline, ok := fc.Features[1].Geometry.(*s2.Polyline)
+	if !ok {
+		t.Fatalf("decoded %T, want a polyline", fc.Features[1].Geometry)
+	}
+
+	lineCoords, _ := geoutil.PolylineCoordinates(polyline, geoutil.PrecisionMax)
+	if want := [][]float64{{102, 0}, {102.9995, 0.9999}, {103.9995, 0}, {104.9995, 0.9999}}; !near(lineCoords, want) {
+		t.Errorf("decoded line %v, want %v", lineCoords, want)
+	}
+
+	polygon, ok := fc.Features[2].Geometry.(*s2.Polygon)
+	if !ok {
+		t.Fatalf("decoded %T, want a polygon", fc.Features[2].Geometry)
+	}
+
+	want := [][]float64{{100, 0}, {100, 0.9999}, {101, 0}, {101, 0.9999}}
+	if got := vertices(t, polygon); !reflect.DeepEqual(got, want) {
+		t.Errorf("decoded polygon vertices %v, want %v", got, want)
+	}
+
+	// The ring is the reversed arc, which runs counter-clockwise, so the
+	// polygon is the small square rather than its complement.
+	if polygon.NumLoops() != 1 || polygon.Area() > 1e-3 {
+		t.Errorf("decoded polygon with %d loops and area %v", polygon.NumLoops(), polygon.Area())
+	}
+}
+
+func TestSharedBorder(t *testing.T) {
+	objects := map[string]*geojson.FeatureCollection{
+		"squares": {
+			Features: []*geojson.Feature{
+				polygonFeature(t, "a", square(0, 0, 1)),
+				polygonFeature(t, "b", square(1, 0, 1)),
+			},
+		},
+	}
+
+	// Quantizing the two degrees of the bounding box to 2000001 values puts
+	// every vertex on the grid.
+	for _, quantization := range []int{0, 2000001} {
+		topology, err := NewTopology(objects, quantization)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if quantization == 0 && topology.Transform != nil {
+			t.Errorf("unquantized topology has transform %+v", topology.Transform)
+		}
+
+		if quantization > 0 && topology.Transform == nil {
+			t.Errorf("quantized topology has no transform")
+		}
+
+		// The border is shared, and each square has one other arc.
+		if len(topology.Arcs) != 3 {
+			t.Errorf("quantization %d: %d arcs, want 3", quantization, len(topology.Arcs))
+		}
+
+		if !reflect.DeepEqual(topology.BBox, []float64{0, 0, 2, 1}) {
+			t.Errorf("quantization %d: bbox %v, want [0 0 2 1]", quantization, topology.BBox)
+		}
+
+		data, err := json.Marshal(topology)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		decoded := &Topology{}
+		if err := json.Unmarshal(data, decoded); err != nil {
+			t.Fatal(err)
+		}
+
+		fc, err := decoded.FeatureCollection("squares")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for i, f := range fc.Features {
+			want := objects["squares"].Features[i]
+			if f.ID != want.ID {
+				t.Errorf("quantization %d: feature %d has ID %v, want %v", quantization, i, f.ID, want.ID)
+			}
+
+			got := vertices(t, f.Geometry.(*s2.Polygon))
+			if !reflect.DeepEqual(got, vertices(t, want.Geometry.(*s2.Polygon))) {
+				t.Errorf("quantization %d: feature %d has vertices %v", quantization, i, got)
+			}
+		}
+	}
+}
+
+func TestSharedRing(t *testing.T) {
+	objects := map[string]*geojson.FeatureCollection{
+		"a": {Features: []*geojson.Feature{polygonFeature(t, 1.0, square(0, 0, 1))}},
+		"b": {Features: []*geojson.Feature{polygonFeature(t, 2.0, square(0, 0, 1))}},
+	}
+
+	topology, err := NewTopology(objects, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A ring without junctions is stored once whatever its starting point.
+	if len(topology.Arcs) != 1 {
+		t.Errorf("%d arcs, want 1", len(topology.Arcs))
+	}
+
+	for _, name := range []string{"a", "b"} {
+		fc, err := topology.FeatureCollection(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got := vertices(t, fc.Features[0].Geometry.(*s2.Polygon)); !reflect.DeepEqual(got, [][]float64{{0, 0}, {0, 1}, {1, 0}, {1, 1}}) {
+			t.Errorf("object %s has vertices %v", name, got)
+		}
+	}
+}
+
+func TestHole(t *testing.T) {
+	objects := map[string]*geojson.FeatureCollection{
+		"holes": {
+			Features: []*geojson.Feature{
+				polygonFeature(t, nil, square(0, 0, 4), [][]float64{{1, 1}, {1, 3}, {3, 3}, {3, 1}, {1, 1}}),
+			},
+		},
+	}
+
+	topology, err := NewTopology(objects, 10001)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fc, err := topology.FeatureCollection("holes")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	polygon := fc.Features[0].Geometry.(*s2.Polygon)
+	want := objects["holes"].Features[0].Geometry.(*s2.Polygon)
+	if polygon.NumLoops() != 2 || !polygon.Loop(1).IsHole() {
+		t.Fatalf("decoded polygon with %d loops", polygon.NumLoops())
+	}
+
+	if math.Abs(polygon.Area()-want.Area()) > 1e-9*want.Area() {
+		t.Errorf("decoded polygon with area %v, want %v", polygon.Area(), want.Area())
+	}
+}
+
+func TestLinesAndPoints(t *testing.T) {
+	point := s2.PointFromLatLng(s2.LatLngFromDegrees(1, 2))
+	points, _ := geoutil.PointsFromMultiPointCoordinates([][]float64{{0, 0}, {3, 3}})
+	first, _ := geoutil.PolylineFromLineStringCoordinates([][]float64{{0, 0}, {1, 1}, {2, 1}})
+	second, _ := geoutil.PolylineFromLineStringCoordinates([][]float64{{1, 1}, {2, 1}, {3, 0}})
+	objects := map[string]*geojson.FeatureCollection{
+		"mixed": {
+			Features: []*geojson.Feature{
+				{Geometry: point, Properties: map[string]interface{}{"kind": "point"}},
+				{Geometry: points},
+				{Geometry: []*s2.Polyline{first, second}, Precision: geoutil.PrecisionE6},
+				{Geometry: nil},
+			},
+		},
+	}
+
+	topology, err := NewTopology(objects, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The lines are cut where they meet and diverge, and share the middle arc.
+	if len(topology.Arcs) != 3 {
+		t.Errorf("%d arcs, want 3", len(topology.Arcs))
+	}
+
+	data, err := json.Marshal(topology)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decoded := &Topology{}
+	if err := json.Unmarshal(data, decoded); err != nil {
+		t.Fatal(err)
+	}
+
+	fc, err := decoded.FeatureCollection("mixed")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if p, ok := fc.Features[0].Geometry.(s2.Point); !ok || p.Distance(point) > 1e-12 {
+		t.Errorf("decoded point %v, want %v", fc.Features[0].Geometry, point)
+	}
+
+	if fc.Features[0].Properties["kind"] != "point" {
+		t.Errorf("decoded properties %v", fc.Features[0].Properties)
+	}
+
+	if ps, ok := fc.Features[1].Geometry.([]s2.Point); !ok || len(ps) != 2 {
+		t.Errorf("decoded points %v", fc.Features[1].Geometry)
+	}
+
+	polylines, ok := fc.Features[2].Geometry.([]*s2.Polyline)
+	if !ok || len(polylines) != 2 {
+		t.Fatalf("decoded polylines %v", fc.Features[2].Geometry)
+	}
+
+	for i, want := range [][][]float64{{{0, 0}, {1, 1}, {2, 1}}, {{1, 1}, {2, 1}, {3, 0}}} {
+		got, _ := geoutil.PolylineCoordinates(polylines[i], geoutil.PrecisionE6)
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("decoded line %d %v, want %v", i, got, want)
+		}
+	}
+
+	if fc.Features[3].Geometry != nil {
+		t.Errorf("decoded null geometry as %v", fc.Features[3].Geometry)
+	}
+
+	// Null objects are encoded with a null type.
+	null, err := json.Marshal(&Geometry{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(null) != `{"type":null}` {
+		t.Errorf("null object encoded as %s", null)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	tests := map[string]string{
+		"arc out of range": `{"type":"Topology","objects":{"o":{"type":"LineString","arcs":[2]}},"arcs":[[[0,0],[1,1]]]}`,
+		"reversed arc":     `{"type":"Topology","objects":{"o":{"type":"LineString","arcs":[-3]}},"arcs":[[[0,0],[1,1]]]}`,
+		"empty ring":       `{"type":"Topology","objects":{"o":{"type":"Polygon","arcs":[[]]}},"arcs":[]}`,
+		"geometry type":    `{"type":"Topology","objects":{"o":{"type":"Circle"}},"arcs":[]}`,
+		"position":         `{"type":"Topology","objects":{"o":{"type":"Point","coordinates":[1]}},"arcs":[]}`,
+		"id":               `{"type":"Topology","objects":{"o":{"type":"Point","id":[1],"coordinates":[1,2]}},"arcs":[]}`,
+	}
+
+	for name, data := range tests {
+		topology := &Topology{}
+		if err := json.Unmarshal([]byte(data), topology); err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+
+		if _, err := topology.FeatureCollection("o"); err == nil {
+			t.Errorf("%s: no error", name)
+		}
+	}
+
+	if err := json.Unmarshal([]byte(`{"type":"FeatureCollection"}`), &Topology{}); err == nil {
+		t.Errorf("decoding a FeatureCollection as a Topology returned no error")
+	}
+
+	if _, err := (&Topology{}).FeatureCollection("missing"); err == nil {
+		t.Errorf("unknown object returned no error")
+	}
+
+	objects := map[string]*geojson.FeatureCollection{
+		"o": {Features: []*geojson.Feature{{ID: 1, Geometry: s2.PointFromLatLng(s2.LatLngFromDegrees(0, 0))}}},
+	}
+
+	if _, err := NewTopology(objects, 0); err == nil {
+		t.Errorf("integer feature ID returned no error")
+	}
+}
@@ -6,7 +6,10 @@ import (
 	"fmt"
 	"io"
 
+	"github.com/golang/geo/s1"
 	"github.com/golang/geo/s2"
+
+	"github.com/topos-ai/geoutil"
 )
 
 func decodeOrder(r io.ByteReader) (binary.ByteOrder, error) {
@@ -334,3 +337,41 @@ func Unmarshal(data []byte, v interface{}) error {
 
 	return nil
 }
+
+// UnmarshalPlanar decodes a geometry like Unmarshal, inserting vertices along
+// the straight lines between the coordinates of polylines and polygons, so
+// that their geodesic edges stay within tolerance of the lines drawn by
+// planar readers.
+func UnmarshalPlanar(data []byte, v interface{}, tolerance s1.Angle) error {
+	if err := Unmarshal(data, v); err != nil {
+		return err
+	}
+
+	switch geometry := v.(type) {
+	case *s2.Polyline:
+		densified, err := geoutil.DensifyPlanar(geometry, tolerance)
+		if err != nil {
+			return err
+		}
+
+		*geometry = *densified.(*s2.Polyline)
+
+	case *s2.Polygon:
+		densified, err := geoutil.DensifyPlanar(geometry, tolerance)
+		if err != nil {
+			return err
+		}
+
+		*geometry = *densified.(*s2.Polygon)
+
+	case *[]*s2.Polyline:
+		densified, err := geoutil.DensifyPlanar(*geometry, tolerance)
+		if err != nil {
+			return err
+		}
+
+		*geometry = densified.([]*s2.Polyline)
+	}
+
+	return nil
+}
@@ -6,7 +6,10 @@ import (
 	"fmt"
 	"io"
 
+	"github.com/golang/geo/s1"
 	"github.com/golang/geo/s2"
+
+	"github.com/topos-ai/geoutil"
 )
 
 func encodePointFromLatLng(w io.Writer, latLng s2.LatLng) error {
@@ -228,6 +231,35 @@ func encodeWKBMultiPolygon(w writer, polygon *s2.Polygon) error {
 	return nil
 }
 
+// encodeWKBCellUnion encodes the cells of a cell union as a multipolygon of
+// one polygon per cell.
+func encodeWKBCellUnion(w writer, cellUnion s2.CellUnion) error {
+
+	// Endianess.
+	if err := w.WriteByte(wkbXDR); err != nil {
+		return err
+	}
+
+	// Geometry type.
+	if err := binary.Write(w, binary.BigEndian, wkbMultiPolygon); err != nil {
+		return err
+	}
+
+	// Number of polygons.
+	if err := binary.Write(w, binary.BigEndian, uint32(len(cellUnion))); err != nil {
+		return err
+	}
+
+	for _, cellID := range cellUnion {
+		loop := s2.LoopFromCell(s2.CellFromCellID(cellID))
+		if err := encodeWKBPolygon(w, []*s2.Loop{loop}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 type byteWriter struct {
 	w io.Writer
 }
@@ -248,7 +280,8 @@ func (bw *byteWriter) WriteByte(c byte) error {
 }
 
 type Encoder struct {
-	w writer
+	w       writer
+	densify s1.Angle
 }
 
 func NewEncoder(w io.Writer) *Encoder {
@@ -262,7 +295,25 @@ func NewEncoder(w io.Writer) *Encoder {
 	return e
 }
 
+// SetDensify makes the encoder insert vertices along the geodesic edges of
+// polylines and polygons, so that planar readers drawing straight lines
+// between their coordinates draw them within tolerance of their edges. A
+// tolerance of zero disables densification.
+func (e *Encoder) SetDensify(tolerance s1.Angle) {
+	e.densify = tolerance
+}
+
 func (e *Encoder) Encode(v interface{}) error {
+	switch v.(type) {
+	case *s2.Polyline, []*s2.Polyline, *s2.Polygon:
+		densified, err := geoutil.DensifyGeodesic(v, e.densify)
+		if err != nil {
+			return err
+		}
+
+		v = densified
+	}
+
 	switch geometry := v.(type) {
 	case s2.LatLng:
 		return encodeWKBPointFromLatLng(e.w, geometry)
@@ -276,6 +327,8 @@ func (e *Encoder) Encode(v interface{}) error {
 		return encodeWKBMultiLineString(e.w, geometry)
 	case *s2.Polygon:
 		return encodeWKBMultiPolygon(e.w, geometry)
+	case s2.CellUnion:
+		return encodeWKBCellUnion(e.w, geometry)
 	default:
 		return fmt.Errorf("wkb: unknown geometry type %T", v)
 	}
@@ -0,0 +1,86 @@
+package wkb
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/golang/geo/s2"
+
+	"github.com/topos-ai/geoutil"
+)
+
+func TestEncodeCellUnion(t *testing.T) {
+	cellUnion := s2.CellUnion{
+		s2.CellIDFromFace(1).ChildBeginAtLevel(5),
+		s2.CellIDFromFace(4).ChildBeginAtLevel(12).Next(),
+	}
+
+	b := &bytes.Buffer{}
+	if err := NewEncoder(b).Encode(cellUnion); err != nil {
+		t.Fatal(err)
+	}
+
+	polygon := &s2.Polygon{}
+	if err := Unmarshal(b.Bytes(), polygon); err != nil {
+		t.Fatal(err)
+	}
+
+	if polygon.NumLoops() != len(cellUnion) {
+		t.Fatalf("decoded %d loops, want %d", polygon.NumLoops(), len(cellUnion))
+	}
+
+	for _, cellID := range cellUnion {
+		if !polygon.ContainsPoint(s2.CellFromCellID(cellID).Center()) || polygon.ContainsPoint(s2.CellFromCellID(cellID.Next()).Center()) {
+			t.Errorf("decoded polygon does not match cell %s", cellID.ToToken())
+		}
+	}
+}
+
+func TestEncodeDensify(t *testing.T) {
+
+	// The geodesic edge between two points of a parallel bulges towards the
+	// pole, away from the straight line drawn by planar readers.
+	polyline := s2.Polyline{
+		s2.PointFromLatLng(s2.LatLngFromDegrees(60, 0)),
+		s2.PointFromLatLng(s2.LatLngFromDegrees(60, 90)),
+	}
+
+	tolerance := geoutil.AngleFromMeters(100)
+	b := &bytes.Buffer{}
+	e := NewEncoder(b)
+	e.SetDensify(tolerance)
+	if err := e.Encode(&polyline); err != nil {
+		t.Fatal(err)
+	}
+
+	decoded := s2.Polyline{}
+	if err := Unmarshal(b.Bytes(), &decoded); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(decoded) <= len(polyline) || decoded[0] != polyline[0] {
+		t.Errorf("encoded %d vertices from %v, want more than %d from %v", len(decoded), decoded[0], len(polyline), polyline[0])
+	}
+
+	// Decoding the polyline with planar semantics keeps its vertices on
+	// the parallel.
+	b.Reset()
+	if err := NewEncoder(b).Encode(&polyline); err != nil {
+		t.Fatal(err)
+	}
+
+	planar := s2.Polyline{}
+	if err := UnmarshalPlanar(b.Bytes(), &planar, tolerance); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(planar) <= len(polyline) {
+		t.Fatalf("decoded %d vertices, want more than %d", len(planar), len(polyline))
+	}
+
+	for _, point := range planar {
+		if lat := s2.LatLngFromPoint(point).Lat.Degrees(); lat < 60-1e-9 || lat > 60+1e-9 {
+			t.Errorf("decoded vertex at latitude %v, want 60", lat)
+		}
+	}
+}
@@ -0,0 +1,182 @@
+package wkb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/topos-ai/geoutil"
+)
+
+// decodePointCoordinates reads the coordinates of a point as they are
+// encoded, without converting them to a location.
+func decodePointCoordinates(r io.Reader, order binary.ByteOrder) ([]float64, error) {
+	coords := make([]float64, 2)
+	if err := binary.Read(r, order, coords); err != nil {
+		return nil, err
+	}
+
+	return coords, nil
+}
+
+func decodeSequenceCoordinates(r io.Reader, order binary.ByteOrder) ([][]float64, error) {
+	var n uint32
+	if err := binary.Read(r, order, &n); err != nil {
+		return nil, err
+	}
+
+	coords := make([][]float64, n)
+	for i := range coords {
+		pointCoords, err := decodePointCoordinates(r, order)
+		if err != nil {
+			return nil, err
+		}
+
+		coords[i] = pointCoords
+	}
+
+	return coords, nil
+}
+
+func decodePolygonCoordinates(r io.Reader, order binary.ByteOrder) ([][][]float64, error) {
+	var n uint32
+	if err := binary.Read(r, order, &n); err != nil {
+		return nil, err
+	}
+
+	coords := make([][][]float64, n)
+	for i := range coords {
+		linearRingCoords, err := decodeSequenceCoordinates(r, order)
+		if err != nil {
+			return nil, err
+		}
+
+		coords[i] = linearRingCoords
+	}
+
+	return coords, nil
+}
+
+// validateWKB reads the coordinates of a geometry and returns their
+// problems.
+func validateWKB(r reader) ([]geoutil.Problem, error) {
+	order, err := decodeOrder(r)
+	if err != nil {
+		return nil, err
+	}
+
+	geometryType, err := decodeGeometryType(r, order)
+	if err != nil {
+		return nil, err
+	}
+
+	switch geometryType {
+	case wkbPoint:
+		coords, err := decodePointCoordinates(r, order)
+		if err != nil {
+			return nil, err
+		}
+
+		return geoutil.ValidatePointCoordinates(coords), nil
+
+	case wkbLineString:
+		coords, err := decodeSequenceCoordinates(r, order)
+		if err != nil {
+			return nil, err
+		}
+
+		return geoutil.ValidateLineStringCoordinates(coords), nil
+
+	case wkbPolygon:
+		coords, err := decodePolygonCoordinates(r, order)
+		if err != nil {
+			return nil, err
+		}
+
+		return geoutil.ValidatePolygonCoordinates(coords), nil
+
+	case wkbMultiPoint, wkbMultiLineString, wkbMultiPolygon:
+	default:
+		return nil, fmt.Errorf("wkb: unknown geometry type %d", geometryType)
+	}
+
+	var n uint32
+	if err := binary.Read(r, order, &n); err != nil {
+		return nil, err
+	}
+
+	// The parts of multi-geometries are geometries with their own byte order.
+	multiPointCoords := [][]float64{}
+	multiLineStringCoords := [][][]float64{}
+	multiPolygonCoords := [][][][]float64{}
+	for i := uint32(0); i < n; i++ {
+		partOrder, err := decodeOrder(r)
+		if err != nil {
+			return nil, err
+		}
+
+		switch geometryType {
+		case wkbMultiPoint:
+			if err := verifyGeometryType(r, partOrder, wkbPoint); err != nil {
+				return nil, err
+			}
+
+			coords, err := decodePointCoordinates(r, partOrder)
+			if err != nil {
+				return nil, err
+			}
+
+			multiPointCoords = append(multiPointCoords, coords)
+
+		case wkbMultiLineString:
+			if err := verifyGeometryType(r, partOrder, wkbLineString); err != nil {
+				return nil, err
+			}
+
+			coords, err := decodeSequenceCoordinates(r, partOrder)
+			if err != nil {
+				return nil, err
+			}
+
+			multiLineStringCoords = append(multiLineStringCoords, coords)
+
+		case wkbMultiPolygon:
+			if err := verifyGeometryType(r, partOrder, wkbPolygon); err != nil {
+				return nil, err
+			}
+
+			coords, err := decodePolygonCoordinates(r, partOrder)
+			if err != nil {
+				return nil, err
+			}
+
+			multiPolygonCoords = append(multiPolygonCoords, coords)
+		}
+	}
+
+	switch geometryType {
+	case wkbMultiPoint:
+		return geoutil.ValidateMultiPointCoordinates(multiPointCoords), nil
+	case wkbMultiLineString:
+		return geoutil.ValidateMultiLineStringCoordinates(multiLineStringCoords), nil
+	default:
+		return geoutil.ValidateMultiPolygonCoordinates(multiPolygonCoords), nil
+	}
+}
+
+// UnmarshalStrict decodes a geometry like Unmarshal, but validates its
+// coordinates first. Invalid geometries are reported by a
+// *geoutil.ValidationError.
+func UnmarshalStrict(data []byte, v interface{}) error {
+	problems, err := validateWKB(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+
+	if err := geoutil.ErrorFromProblems(problems); err != nil {
+		return err
+	}
+
+	return Unmarshal(data, v)
+}
@@ -0,0 +1,857 @@
+package geoutil
+
+import (
+	"math"
+)
+
+// geodesic solves the inverse geodesic problem on an ellipsoid of
+// revolution with the series of C. F. F. Karney, Algorithms for geodesics,
+// J. Geodesy 87, 43-55 (2013), as implemented by GeographicLib, to 6th order
+// in the flattening.
+
+const (
+	geodesicOrder = 6
+	nA1           = geodesicOrder
+	nC1           = geodesicOrder
+	nA2           = geodesicOrder
+	nC2           = geodesicOrder
+	nA3           = geodesicOrder
+	nC3           = geodesicOrder
+	nC4           = geodesicOrder
+	nA3x          = nA3
+	nC3x          = (nC3 * (nC3 - 1)) / 2
+	nC4x          = (nC4 * (nC4 + 1)) / 2
+
+	maxit1 = 20
+	maxit2 = maxit1 + 53 + 10
+
+	degree = math.Pi / 180
+)
+
+var (
+	tiny    = math.Sqrt(math.SmallestNonzeroFloat64 * (1 << 52))
+	tol0    = math.Nextafter(1, 2) - 1
+	tol1    = 200 * tol0
+	tol2    = math.Sqrt(tol0)
+	tolb    = tol0 * tol2
+	xthresh = 1000 * tol2
+)
+
+type geodesic struct {
+	a, f, f1, e2, ep2, n, b, c2, etol2 float64
+
+	a3x [nA3x]float64
+	c3x [nC3x]float64
+	c4x [nC4x]float64
+}
+
+func newGeodesic(a, f float64) *geodesic {
+	g := &geodesic{
+		a:  a,
+		f:  f,
+		f1: 1 - f,
+		e2: f * (2 - f),
+		n:  f / (2 - f),
+	}
+
+	g.ep2 = g.e2 / (g.f1 * g.f1)
+	g.b = a * g.f1
+
+	// c2 is the authalic radius squared.
+	switch {
+	case g.e2 == 0:
+		g.c2 = a * a
+	case g.e2 > 0:
+		g.c2 = (a*a + g.b*g.b*math.Atanh(math.Sqrt(g.e2))/math.Sqrt(g.e2)) / 2
+	default:
+		g.c2 = (a*a + g.b*g.b*math.Atan(math.Sqrt(-g.e2))/math.Sqrt(-g.e2)) / 2
+	}
+
+	g.etol2 = 0.1 * tol2 / math.Sqrt(math.Max(0.001, math.Abs(f))*math.Min(1, 1-f/2)/2)
+	g.a3coeff()
+	g.c3coeff()
+	g.c4coeff()
+	return g
+}
+
+// polyval evaluates the polynomial of degree n with coefficients p, highest
+// degree first.
+func polyval(n int, p []float64, x float64) float64 {
+	if n < 0 {
+		return 0
+	}
+
+	y := p[0]
+	for i := 1; i <= n; i++ {
+		y = y*x + p[i]
+	}
+
+	return y
+}
+
+// sumx returns the sum of two numbers and the rounding error of the sum.
+func sumx(u, v float64) (float64, float64) {
+	s := u + v
+	up := s - v
+	vpp := s - up
+	up -= u
+	vpp -= v
+	return s, -(up + vpp)
+}
+
+func angNormalize(x float64) float64 {
+	x = math.Remainder(x, 360)
+	if x == -180 {
+		return 180
+	}
+
+	return x
+}
+
+// angDiff returns the difference y - x of two angles in degrees, reduced to
+// [-180, 180], and its rounding error.
+func angDiff(x, y float64) (float64, float64) {
+	d, t := sumx(angNormalize(-x), angNormalize(y))
+	d = angNormalize(d)
+	if d == 180 && t > 0 {
+		d = -180
+	}
+
+	return sumx(d, t)
+}
+
+// angRound rounds tiny angles so that they are exactly representable.
+func angRound(x float64) float64 {
+	const z = 1.0 / 16
+	if x == 0 {
+		return 0
+	}
+
+	y := math.Abs(x)
+	if y < z {
+		y = z - (z - y)
+	}
+
+	return math.Copysign(y, x)
+}
+
+// sincosd returns the sine and cosine of an angle in degrees, exactly for
+// multiples of 90 degrees.
+func sincosd(x float64) (float64, float64) {
+	r := math.Mod(x, 360)
+	q := int(math.Floor(r/90 + 0.5))
+	r -= 90 * float64(q)
+	r *= degree
+	s, c := math.Sin(r), math.Cos(r)
+	switch q & 3 {
+	case 0:
+		return s, c
+	case 1:
+		return c, -s
+	case 2:
+		return -s, -c
+	default:
+		return -c, s
+	}
+}
+
+func norm2(x, y float64) (float64, float64) {
+	r := math.Hypot(x, y)
+	return x / r, y / r
+}
+
+// sinCosSeries evaluates the sine series, c[1..n], or the cosine series,
+// c[0..n-1], by Clenshaw summation.
+func sinCosSeries(sinp bool, sinx, cosx float64, c []float64, n int) float64 {
+	k := n
+	if sinp {
+		k++
+	}
+
+	ar := 2 * (cosx - sinx) * (cosx + sinx)
+	y0, y1 := 0.0, 0.0
+	if n&1 != 0 {
+		k--
+		y0 = c[k]
+	}
+
+	for n /= 2; n > 0; n-- {
+		k--
+		y1 = ar*y0 - y1 + c[k]
+		k--
+		y0 = ar*y1 - y0 + c[k]
+	}
+
+	if sinp {
+		return 2 * sinx * cosx * y0
+	}
+
+	return cosx * (y0 - y1)
+}
+
+func a1m1f(eps float64) float64 {
+	coeff := []float64{1, 4, 64, 0, 256}
+	m := nA1 / 2
+	t := polyval(m, coeff, eps*eps) / coeff[m+1]
+	return (t + eps) / (1 - eps)
+}
+
+func c1f(eps float64, c []float64) {
+	coeff := []float64{
+		-1, 6, -16, 32,
+		-9, 64, -128, 2048,
+		9, -16, 768,
+		3, -5, 512,
+		-7, 1280,
+		-7, 2048,
+	}
+
+	eps2, d, o := eps*eps, eps, 0
+	for l := 1; l <= nC1; l++ {
+		m := (nC1 - l) / 2
+		c[l] = d * polyval(m, coeff[o:], eps2) / coeff[o+m+1]
+		o += m + 2
+		d *= eps
+	}
+}
+
+func a2m1f(eps float64) float64 {
+	coeff := []float64{-11, -28, -192, 0, 256}
+	m := nA2 / 2
+	t := polyval(m, coeff, eps*eps) / coeff[m+1]
+	return (t - eps) / (1 + eps)
+}
+
+func c2f(eps float64, c []float64) {
+	coeff := []float64{
+		1, 2, 16, 32,
+		35, 64, 384, 2048,
+		15, 80, 768,
+		7, 35, 512,
+		63, 1280,
+		77, 2048,
+	}
+
+	eps2, d, o := eps*eps, eps, 0
+	for l := 1; l <= nC2; l++ {
+		m := (nC2 - l) / 2
+		c[l] = d * polyval(m, coeff[o:], eps2) / coeff[o+m+1]
+		o += m + 2
+		d *= eps
+	}
+}
+
+func (g *geodesic) a3coeff() {
+	coeff := []float64{
+		-3, 128,
+		-2, -3, 64,
+		-1, -3, -1, 16,
+		3, -1, -2, 8,
+		1, -1, 2,
+		1, 1,
+	}
+
+	o, k := 0, 0
+	for j := nA3 - 1; j >= 0; j-- {
+		m := j
+		if nA3-j-1 < j {
+			m = nA3 - j - 1
+		}
+
+		g.a3x[k] = polyval(m, coeff[o:], g.n) / coeff[o+m+1]
+		k++
+		o += m + 2
+	}
+}
+
+func (g *geodesic) c3coeff() {
+	coeff := []float64{
+		3, 128,
+		2, 5, 128,
+		-1, 3, 3, 64,
+		-1, 0, 1, 8,
+		-1, 1, 4,
+		5, 256,
+		1, 3, 128,
+		-3, -2, 3, 64,
+		1, -3, 2, 32,
+		7, 512,
+		-10, 9, 384,
+		5, -9, 5, 192,
+		7, 512,
+		-14, 7, 512,
+		21, 2560,
+	}
+
+	o, k := 0, 0
+	for l := 1; l < nC3; l++ {
+		for j := nC3 - 1; j >= l; j-- {
+			m := j
+			if nC3-j-1 < j {
+				m = nC3 - j - 1
+			}
+
+			g.c3x[k] = polyval(m, coeff[o:], g.n) / coeff[o+m+1]
+			k++
+			o += m + 2
+		}
+	}
+}
+
+func (g *geodesic) c4coeff() {
+	coeff := []float64{
+		97, 15015,
+		1088, 156, 45045,
+		-224, -4784, 1573, 45045,
+		-10656, 14144, -4576, -858, 45045,
+		64, 624, -4576, 6864, -3003, 15015,
+		100, 208, 572, 3432, -12012, 30030, 45045,
+		1, 9009,
+		-2944, 468, 135135,
+		5792, 1040, -1287, 135135,
+		5952, -11648, 9152, -2574, 135135,
+		-64, -624, 4576, -6864, 3003, 135135,
+		8, 10725,
+		1856, -936, 225225,
+		-8448, 4992, -1144, 225225,
+		-1440, 4160, -4576, 1716, 225225,
+		-136, 63063,
+		1024, -208, 105105,
+		3584, -3328, 1144, 315315,
+		-128, 135135,
+		-2560, 832, 405405,
+		128, 99099,
+	}
+
+	o, k := 0, 0
+	for l := 0; l < nC4; l++ {
+		for j := nC4 - 1; j >= l; j-- {
+			m := nC4 - j - 1
+			g.c4x[k] = polyval(m, coeff[o:], g.n) / coeff[o+m+1]
+			k++
+			o += m + 2
+		}
+	}
+}
+
+func (g *geodesic) a3f(eps float64) float64 {
+	return polyval(nA3-1, g.a3x[:], eps)
+}
+
+func (g *geodesic) c3f(eps float64, c []float64) {
+	mult, o := 1.0, 0
+	for l := 1; l < nC3; l++ {
+		m := nC3 - l - 1
+		mult *= eps
+		c[l] = mult * polyval(m, g.c3x[o:], eps)
+		o += m + 1
+	}
+}
+
+func (g *geodesic) c4f(eps float64, c []float64) {
+	mult, o := 1.0, 0
+	for l := 0; l < nC4; l++ {
+		m := nC4 - l - 1
+		c[l] = mult * polyval(m, g.c4x[o:], eps)
+		o += m + 1
+		mult *= eps
+	}
+}
+
+// lengths returns the distance and the reduced length of a geodesic, scaled
+// to the minor axis, and the m0 coefficient.
+func (g *geodesic) lengths(eps, sig12, ssig1, csig1, dn1, ssig2, csig2, dn2 float64) (float64, float64, float64) {
+	ca := make([]float64, nC1+1)
+	cb := make([]float64, nC2+1)
+	a1 := a1m1f(eps)
+	c1f(eps, ca)
+	a2 := a2m1f(eps)
+	c2f(eps, cb)
+	m0 := a1 - a2
+	a1++
+	a2++
+
+	b1 := sinCosSeries(true, ssig2, csig2, ca, nC1) - sinCosSeries(true, ssig1, csig1, ca, nC1)
+	s12b := a1 * (sig12 + b1)
+	b2 := sinCosSeries(true, ssig2, csig2, cb, nC2) - sinCosSeries(true, ssig1, csig1, cb, nC2)
+	j12 := m0*sig12 + (a1*b1 - a2*b2)
+	m12b := dn2*(csig1*ssig2) - dn1*(ssig1*csig2) - csig1*csig2*j12
+	return s12b, m12b, m0
+}
+
+// astroid solves k^4+2*k^3-(x^2+y^2-1)*k^2-2*y^2*k-y^2 = 0 for the positive
+// root k.
+func astroid(x, y float64) float64 {
+	p, q := x*x, y*y
+	r := (p + q - 1) / 6
+	if q == 0 && r <= 0 {
+		return 0
+	}
+
+	s := p * q / 4
+	r2 := r * r
+	r3 := r * r2
+	disc := s * (s + 2*r3)
+	u := r
+	if disc >= 0 {
+		t3 := s + r3
+		if t3 < 0 {
+			t3 -= math.Sqrt(disc)
+		} else {
+			t3 += math.Sqrt(disc)
+		}
+
+		t := math.Cbrt(t3)
+		u += t
+		if t != 0 {
+			u += r2 / t
+		}
+	} else {
+		ang := math.Atan2(math.Sqrt(-disc), -(s + r3))
+		u += 2 * r * math.Cos(ang/3)
+	}
+
+	v := math.Sqrt(u*u + q)
+	uv := u + v
+	if u < 0 {
+		uv = q / (v - u)
+	}
+
+	w := (uv - q) / (2 * v)
+	return uv / (math.Sqrt(uv+w*w) + w)
+}
+
+// inverseStart returns a starting point for Newton's method, and the arc
+// length of short lines.
+func (g *geodesic) inverseStart(sbet1, cbet1, dn1, sbet2, cbet2, dn2, lam12, slam12, clam12 float64) (sig12, salp1, calp1, salp2, calp2, dnm float64) {
+	sig12 = -1
+	sbet12 := sbet2*cbet1 - cbet2*sbet1
+	cbet12 := cbet2*cbet1 + sbet2*sbet1
+	sbet12a := sbet2*cbet1 + cbet2*sbet1
+	shortline := cbet12 >= 0 && sbet12 < 0.5 && cbet2*lam12 < 0.5
+	somg12, comg12 := slam12, clam12
+	if shortline {
+		sbetm2 := (sbet1 + sbet2) * (sbet1 + sbet2)
+		sbetm2 /= sbetm2 + (cbet1+cbet2)*(cbet1+cbet2)
+		dnm = math.Sqrt(1 + g.ep2*sbetm2)
+		omg12 := lam12 / (g.f1 * dnm)
+		somg12, comg12 = math.Sin(omg12), math.Cos(omg12)
+	}
+
+	salp1 = cbet2 * somg12
+	if comg12 >= 0 {
+		calp1 = sbet12 + cbet2*sbet1*somg12*somg12/(1+comg12)
+	} else {
+		calp1 = sbet12a - cbet2*sbet1*somg12*somg12/(1-comg12)
+	}
+
+	ssig12 := math.Hypot(salp1, calp1)
+	csig12 := sbet1*sbet2 + cbet1*cbet2*comg12
+
+	switch {
+	case shortline && ssig12 < g.etol2:
+		salp2 = cbet1 * somg12
+		if comg12 >= 0 {
+			calp2 = sbet12 - cbet1*sbet2*somg12*somg12/(1+comg12)
+		} else {
+			calp2 = sbet12 - cbet1*sbet2*(1-comg12)
+		}
+
+		salp2, calp2 = norm2(salp2, calp2)
+		sig12 = math.Atan2(ssig12, csig12)
+
+	// The zeroth order spherical approximation is good enough.
+	case math.Abs(g.n) > 0.1 || csig12 >= 0 || ssig12 >= 6*math.Abs(g.n)*math.Pi*cbet1*cbet1:
+
+	// Nearly antipodal points.
+	default:
+		var x, y, lamscale, betscale float64
+		lam12x := math.Atan2(-slam12, -clam12)
+		if g.f >= 0 {
+			k2 := sbet1 * sbet1 * g.ep2
+			eps := k2 / (2*(1+math.Sqrt(1+k2)) + k2)
+			lamscale = g.f * cbet1 * g.a3f(eps) * math.Pi
+			betscale = lamscale * cbet1
+			x = lam12x / lamscale
+			y = sbet12a / betscale
+		} else {
+			cbet12a := cbet2*cbet1 - sbet2*sbet1
+			bet12a := math.Atan2(sbet12a, cbet12a)
+			_, m12b, m0 := g.lengths(g.n, math.Pi+bet12a, sbet1, -cbet1, dn1, sbet2, cbet2, dn2)
+			x = -1 + m12b/(cbet1*cbet2*m0*math.Pi)
+			if x < -0.01 {
+				betscale = sbet12a / x
+			} else {
+				betscale = -g.f * cbet1 * cbet1 * math.Pi
+			}
+
+			lamscale = betscale / cbet1
+			y = lam12x / lamscale
+		}
+
+		if y > -tol1 && x > -1-xthresh {
+			if g.f >= 0 {
+				salp1 = math.Min(1, -x)
+				calp1 = -math.Sqrt(1 - salp1*salp1)
+			} else {
+				calp1 = x
+				if x > -tol1 {
+					calp1 = math.Max(0, x)
+				} else {
+					calp1 = math.Max(-1, x)
+				}
+
+				salp1 = math.Sqrt(1 - calp1*calp1)
+			}
+		} else {
+			k := astroid(x, y)
+			omg12a := lamscale * (-y * (1 + k) / k)
+			if g.f >= 0 {
+				omg12a = lamscale * (-x * k / (1 + k))
+			}
+
+			somg12, comg12 = math.Sin(omg12a), -math.Cos(omg12a)
+			salp1 = cbet2 * somg12
+			calp1 = sbet12a - cbet2*sbet1*somg12*somg12/(1-comg12)
+		}
+	}
+
+	if salp1 > 0 {
+		salp1, calp1 = norm2(salp1, calp1)
+	} else {
+		salp1, calp1 = 1, 0
+	}
+
+	return sig12, salp1, calp1, salp2, calp2, dnm
+}
+
+// lambda12State is the state of an iteration of the solution of the inverse
+// problem.
+type lambda12State struct {
+	salp2, calp2, sig12, ssig1, csig1, ssig2, csig2, eps, domg12 float64
+}
+
+// lambda12 returns the longitude difference of the geodesic of a given
+// azimuth, and its derivative if requested.
+func (g *geodesic) lambda12(sbet1, cbet1, dn1, sbet2, cbet2, dn2, salp1, calp1, slam120, clam120 float64, diffp bool) (float64, float64, lambda12State) {
+	st := lambda12State{}
+	if sbet1 == 0 && calp1 == 0 {
+		calp1 = -tiny
+	}
+
+	salp0 := salp1 * cbet1
+	calp0 := math.Hypot(calp1, salp1*sbet1)
+
+	st.ssig1 = sbet1
+	somg1 := salp0 * sbet1
+	st.csig1 = calp1 * cbet1
+	comg1 := st.csig1
+	st.ssig1, st.csig1 = norm2(st.ssig1, st.csig1)
+
+	st.salp2 = salp1
+	if cbet2 != cbet1 {
+		st.salp2 = salp0 / cbet2
+	}
+
+	st.calp2 = math.Abs(calp1)
+	if cbet2 != cbet1 || math.Abs(sbet2) != -sbet1 {
+		t := (sbet1 - sbet2) * (sbet1 + sbet2)
+		if cbet1 < -sbet1 {
+			t = (cbet2 - cbet1) * (cbet1 + cbet2)
+		}
+
+		st.calp2 = math.Sqrt((calp1*cbet1)*(calp1*cbet1)+t) / cbet2
+	}
+
+	st.ssig2 = sbet2
+	somg2 := salp0 * sbet2
+	st.csig2 = st.calp2 * cbet2
+	comg2 := st.csig2
+	st.ssig2, st.csig2 = norm2(st.ssig2, st.csig2)
+
+	st.sig12 = math.Atan2(math.Max(0, st.csig1*st.ssig2-st.ssig1*st.csig2), st.csig1*st.csig2+st.ssig1*st.ssig2)
+	somg12 := math.Max(0, comg1*somg2-somg1*comg2)
+	comg12 := comg1*comg2 + somg1*somg2
+	eta := math.Atan2(somg12*clam120-comg12*slam120, comg12*clam120+somg12*slam120)
+
+	k2 := calp0 * calp0 * g.ep2
+	st.eps = k2 / (2*(1+math.Sqrt(1+k2)) + k2)
+	c3 := make([]float64, nC3)
+	g.c3f(st.eps, c3)
+	b312 := sinCosSeries(true, st.ssig2, st.csig2, c3, nC3-1) - sinCosSeries(true, st.ssig1, st.csig1, c3, nC3-1)
+	st.domg12 = -g.f * g.a3f(st.eps) * salp0 * (st.sig12 + b312)
+	lam12 := eta + st.domg12
+
+	dlam12 := 0.0
+	if diffp {
+		if st.calp2 == 0 {
+			dlam12 = -2 * g.f1 * dn1 / sbet1
+		} else {
+			_, m12b, _ := g.lengths(st.eps, st.sig12, st.ssig1, st.csig1, dn1, st.ssig2, st.csig2, dn2)
+			dlam12 = m12b * g.f1 / (st.calp2 * cbet2)
+		}
+	}
+
+	return lam12, dlam12, st
+}
+
+// inverse returns the length in meters of the geodesic between two points
+// given in degrees, and the area in square meters between the geodesic and
+// the equator, counterclockwise.
+func (g *geodesic) inverse(lat1, lon1, lat2, lon2 float64) (float64, float64) {
+	lon12, lon12s := angDiff(lon1, lon2)
+
+	// The longitude difference is made positive.
+	lonsign := 1.0
+	if lon12 < 0 {
+		lonsign = -1
+	}
+
+	lon12 = lonsign * angRound(lon12)
+	lon12s = angRound((180 - lon12) - lonsign*lon12s)
+	lam12 := lon12 * degree
+	var slam12, clam12 float64
+	if lon12 > 90 {
+		slam12, clam12 = sincosd(lon12s)
+		clam12 = -clam12
+	} else {
+		slam12, clam12 = sincosd(lon12)
+	}
+
+	lat1, lat2 = angRound(lat1), angRound(lat2)
+
+	// The point of higher absolute latitude is made the first point, and
+	// its latitude negative.
+	swapp := 1.0
+	if math.Abs(lat1) < math.Abs(lat2) {
+		swapp = -1
+		lonsign = -lonsign
+		lat1, lat2 = lat2, lat1
+	}
+
+	latsign := -1.0
+	if lat1 < 0 {
+		latsign = 1
+	}
+
+	lat1 *= latsign
+	lat2 *= latsign
+
+	sbet1, cbet1 := sincosd(lat1)
+	sbet1, cbet1 = norm2(g.f1*sbet1, cbet1)
+	cbet1 = math.Max(tiny, cbet1)
+
+	sbet2, cbet2 := sincosd(lat2)
+	sbet2, cbet2 = norm2(g.f1*sbet2, cbet2)
+	cbet2 = math.Max(tiny, cbet2)
+
+	if cbet1 < -sbet1 {
+		if cbet2 == cbet1 {
+			if sbet2 < 0 {
+				sbet2 = sbet1
+			} else {
+				sbet2 = -sbet1
+			}
+		}
+	} else if math.Abs(sbet2) == -sbet1 {
+		cbet2 = cbet1
+	}
+
+	dn1 := math.Sqrt(1 + g.ep2*sbet1*sbet1)
+	dn2 := math.Sqrt(1 + g.ep2*sbet2*sbet2)
+
+	var s12x, sig12, salp1, calp1, salp2, calp2, omg12 float64
+	somg12, comg12 := 2.0, 0.0
+
+	meridian := lat1 == -90 || slam12 == 0
+	if meridian {
+
+		// The geodesic runs along a meridian.
+		calp1, salp1 = clam12, slam12
+		calp2, salp2 = 1, 0
+		ssig1, csig1 := sbet1, calp1*cbet1
+		ssig2, csig2 := sbet2, calp2*cbet2
+		sig12 = math.Atan2(math.Max(0, csig1*ssig2-ssig1*csig2), csig1*csig2+ssig1*ssig2)
+		s12b, m12b, _ := g.lengths(g.n, sig12, ssig1, csig1, dn1, ssig2, csig2, dn2)
+		if sig12 < 1 || m12b >= 0 {
+			if sig12 < 3*tiny {
+				sig12, s12b = 0, 0
+			}
+
+			s12x = s12b * g.b
+		} else {
+			meridian = false
+		}
+	}
+
+	switch {
+	case meridian:
+
+	// The geodesic runs along the equator.
+	case sbet1 == 0 && (g.f <= 0 || lon12s >= g.f*180):
+		calp1, calp2 = 0, 0
+		salp1, salp2 = 1, 1
+		s12x = g.a * lam12
+		sig12 = lam12 / g.f1
+		omg12 = sig12
+
+	default:
+		var dnm float64
+		sig12, salp1, calp1, salp2, calp2, dnm = g.inverseStart(sbet1, cbet1, dn1, sbet2, cbet2, dn2, lam12, slam12, clam12)
+		if sig12 >= 0 {
+
+			// Short lines.
+			s12x = sig12 * g.b * dnm
+			omg12 = lam12 / (g.f1 * dnm)
+			break
+		}
+
+		// Newton's method, falling back to bisection.
+		st := lambda12State{}
+		salp1a, calp1a := tiny, 1.0
+		salp1b, calp1b := tiny, -1.0
+		tripn, tripb := false, false
+		for numit := 0; numit < maxit2; numit++ {
+			var v, dv float64
+			v, dv, st = g.lambda12(sbet1, cbet1, dn1, sbet2, cbet2, dn2, salp1, calp1, slam12, clam12, numit < maxit1)
+			salp2, calp2 = st.salp2, st.calp2
+			limit := tol0
+			if tripn {
+				limit = 8 * tol0
+			}
+
+			if tripb || !(math.Abs(v) >= limit) {
+				break
+			}
+
+			if v > 0 && (numit > maxit1 || calp1/salp1 > calp1b/salp1b) {
+				salp1b, calp1b = salp1, calp1
+			} else if v < 0 && (numit > maxit1 || calp1/salp1 < calp1a/salp1a) {
+				salp1a, calp1a = salp1, calp1
+			}
+
+			if numit < maxit1 && dv > 0 {
+				dalp1 := -v / dv
+				if math.Abs(dalp1) < math.Pi {
+					sdalp1, cdalp1 := math.Sin(dalp1), math.Cos(dalp1)
+					nsalp1 := salp1*cdalp1 + calp1*sdalp1
+					if nsalp1 > 0 {
+						calp1 = calp1*cdalp1 - salp1*sdalp1
+						salp1, calp1 = norm2(nsalp1, calp1)
+						tripn = math.Abs(v) <= 16*tol0
+						continue
+					}
+				}
+			}
+
+			salp1, calp1 = norm2((salp1a+salp1b)/2, (calp1a+calp1b)/2)
+			tripn = false
+			tripb = math.Abs(salp1a-salp1)+(calp1a-calp1) < tolb || math.Abs(salp1-salp1b)+(calp1-calp1b) < tolb
+		}
+
+		s12b, _, _ := g.lengths(st.eps, st.sig12, st.ssig1, st.csig1, dn1, st.ssig2, st.csig2, dn2)
+		s12x = s12b * g.b
+		sdomg12, cdomg12 := math.Sin(st.domg12), math.Cos(st.domg12)
+		somg12 = slam12*cdomg12 - clam12*sdomg12
+		comg12 = clam12*cdomg12 + slam12*sdomg12
+	}
+
+	// Area between the geodesic and the equator.
+	var s12 float64
+	salp0 := salp1 * cbet1
+	calp0 := math.Hypot(calp1, salp1*sbet1)
+	if calp0 != 0 && salp0 != 0 {
+		ssig1, csig1 := norm2(sbet1, calp1*cbet1)
+		ssig2, csig2 := norm2(sbet2, calp2*cbet2)
+		k2 := calp0 * calp0 * g.ep2
+		eps := k2 / (2*(1+math.Sqrt(1+k2)) + k2)
+		a4 := g.a * g.a * calp0 * salp0 * g.e2
+		c4 := make([]float64, nC4)
+		g.c4f(eps, c4)
+		b41 := sinCosSeries(false, ssig1, csig1, c4, nC4)
+		b42 := sinCosSeries(false, ssig2, csig2, c4, nC4)
+		s12 = a4 * (b42 - b41)
+	}
+
+	if !meridian && somg12 == 2 {
+		somg12, comg12 = math.Sin(omg12), math.Cos(omg12)
+	}
+
+	var alp12 float64
+	if !meridian && comg12 > -0.7071 && sbet2-sbet1 < 1.75 {
+		domg12 := 1 + comg12
+		dbet1 := 1 + cbet1
+		dbet2 := 1 + cbet2
+		alp12 = 2 * math.Atan2(somg12*(sbet1*dbet2+sbet2*dbet1), domg12*(sbet1*sbet2+dbet1*dbet2))
+	} else {
+		salp12 := salp2*calp1 - calp2*salp1
+		calp12 := calp2*calp1 + salp2*salp1
+		if salp12 == 0 && calp12 < 0 {
+			salp12 = tiny * calp1
+			calp12 = -1
+		}
+
+		alp12 = math.Atan2(salp12, calp12)
+	}
+
+	s12 += g.c2 * alp12
+	s12 *= swapp * lonsign * latsign
+	return s12x, s12 + 0
+}
+
+// transit returns 1 or -1 if an edge crosses the prime meridian eastward or
+// westward, and 0 otherwise.
+func transit(lon1, lon2 float64) int {
+	lon12, _ := angDiff(lon1, lon2)
+	lon1, lon2 = angNormalize(lon1), angNormalize(lon2)
+	switch {
+	case lon12 > 0 && (lon1 < 0 && lon2 >= 0 || lon1 > 0 && lon2 == 0):
+		return 1
+	case lon12 < 0 && lon1 >= 0 && lon2 < 0:
+		return -1
+	default:
+		return 0
+	}
+}
+
+// polygonArea returns the perimeter in meters and the area in square meters,
+// between 0 and the area of the ellipsoid, of the region on the left of a
+// ring of points given in degrees as latitude and longitude pairs.
+func (g *geodesic) polygonArea(points [][2]float64) (float64, float64) {
+	n := len(points)
+	perimeter, area := 0.0, 0.0
+	areaErr := 0.0
+	crossings := 0
+	for i, p := range points {
+		q := points[(i+1)%n]
+		s12, s12Area := g.inverse(p[0], p[1], q[0], q[1])
+		perimeter += s12
+
+		// The area is summed with compensation of rounding errors.
+		var e float64
+		area, e = sumx(area, s12Area+areaErr)
+		areaErr = e
+		crossings += transit(p[1], q[1])
+	}
+
+	area += areaErr
+	area0 := 4 * math.Pi * g.c2
+	area = math.Remainder(area, area0)
+	if crossings&1 != 0 {
+		if area < 0 {
+			area += area0 / 2
+		} else {
+			area -= area0 / 2
+		}
+	}
+
+	// The areas under the edges are counted clockwise.
+	area = -area
+	if area >= area0 {
+		area -= area0
+	} else if area < 0 {
+		area += area0
+	}
+
+	return perimeter, area
+}
@@ -0,0 +1,156 @@
+package geohash
+
+import (
+	"sort"
+
+	"github.com/golang/geo/s1"
+	"github.com/golang/geo/s2"
+)
+
+// DefaultMaxCells is the number of cells of a covering when Coverer.MaxCells
+// is not set.
+const DefaultMaxCells = 8
+
+// edgeVertices is the number of vertices approximating each edge of a cell,
+// whose north and south edges follow parallels rather than geodesics.
+const edgeVertices = 8
+
+// Coverer computes geohash coverings of polygons, in the manner of
+// s2.RegionCoverer: cells are subdivided, coarsest first, until the covering
+// would exceed MaxCells. Cells entirely inside the polygon are not
+// subdivided.
+type Coverer struct {
+	MinPrecision int
+	MaxPrecision int
+	MaxCells     int
+}
+
+// cellPolygon approximates the cell of a geohash by an s2 polygon.
+func cellPolygon(rect s2.Rect) *s2.Polygon {
+	corners := []s2.LatLng{
+		rect.Vertex(0),
+		rect.Vertex(1),
+		rect.Vertex(2),
+		rect.Vertex(3),
+	}
+
+	points := []s2.Point{}
+	for i, a := range corners {
+		b := corners[(i+1)%len(corners)]
+		for j := 0; j < edgeVertices; j++ {
+			t := s1.Angle(j) / edgeVertices
+			point := s2.PointFromLatLng(s2.LatLng{
+				Lat: a.Lat + (b.Lat-a.Lat)*t,
+				Lng: a.Lng + (b.Lng-a.Lng)*t,
+			})
+
+			// Vertices on the poles coincide.
+			if n := len(points); n > 0 && points[n-1].ApproxEqual(point) {
+				continue
+			}
+
+			points = append(points, point)
+		}
+	}
+
+	if n := len(points); n > 1 && points[0].ApproxEqual(points[n-1]) {
+		points = points[:n-1]
+	}
+
+	return s2.PolygonFromLoops([]*s2.Loop{s2.LoopFromPoints(points)})
+}
+
+type candidate struct {
+	hash      string
+	contained bool
+}
+
+func children(hash string) []string {
+	hashes := make([]string, len(base32))
+	for i := range base32 {
+		hashes[i] = hash + base32[i:i+1]
+	}
+
+	return hashes
+}
+
+// classify returns the cells among hashes that intersect the polygon.
+func classify(polygon *s2.Polygon, hashes []string) []*candidate {
+	candidates := []*candidate{}
+	for _, hash := range hashes {
+		rect, err := Bounds(hash)
+		if err != nil || !rect.Intersects(polygon.RectBound()) {
+			continue
+		}
+
+		cell := cellPolygon(rect)
+		if polygon.Contains(cell) {
+			candidates = append(candidates, &candidate{hash: hash, contained: true})
+		} else if polygon.Intersects(cell) {
+			candidates = append(candidates, &candidate{hash: hash})
+		}
+	}
+
+	return candidates
+}
+
+// Covering returns sorted geohashes whose cells cover a polygon.
+func (c *Coverer) Covering(polygon *s2.Polygon) ([]string, error) {
+	minPrecision, maxPrecision, maxCells := c.MinPrecision, c.MaxPrecision, c.MaxCells
+	if minPrecision == 0 {
+		minPrecision = 1
+	}
+
+	if maxPrecision == 0 {
+		maxPrecision = MaxPrecision
+	}
+
+	if err := validatePrecision(minPrecision); err != nil {
+		return nil, err
+	}
+
+	if err := validatePrecision(maxPrecision); err != nil {
+		return nil, err
+	}
+
+	if maxPrecision < minPrecision {
+		maxPrecision = minPrecision
+	}
+
+	if maxCells <= 0 {
+		maxCells = DefaultMaxCells
+	}
+
+	if polygon.IsEmpty() {
+		return []string{}, nil
+	}
+
+	result := []string{}
+	queue := classify(polygon, children(""))
+	for len(queue) > 0 {
+
+		// Subdivide the coarsest candidate first.
+		sort.SliceStable(queue, func(i, j int) bool {
+			return len(queue[i].hash) < len(queue[j].hash)
+		})
+
+		next := queue[0]
+		queue = queue[1:]
+		precision := len(next.hash)
+		if precision >= minPrecision && (next.contained || precision >= maxPrecision) {
+			result = append(result, next.hash)
+			continue
+		}
+
+		childCandidates := classify(polygon, children(next.hash))
+		if precision >= minPrecision && len(result)+len(queue)+len(childCandidates) > maxCells {
+			result = append(result, next.hash)
+			continue
+		}
+
+		queue = append(queue, childCandidates...)
+	}
+
+	sort.Strings(result)
+	return result, nil
+}
@@ -0,0 +1,121 @@
+package geohash
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/golang/geo/s2"
+)
+
+func rectPolygon(latLo, lngLo, latHi, lngHi float64) *s2.Polygon {
+	return s2.PolygonFromLoops([]*s2.Loop{s2.LoopFromPoints([]s2.Point{
+		s2.PointFromLatLng(s2.LatLngFromDegrees(latLo, lngLo)),
+		s2.PointFromLatLng(s2.LatLngFromDegrees(latLo, lngHi)),
+		s2.PointFromLatLng(s2.LatLngFromDegrees(latHi, lngHi)),
+		s2.PointFromLatLng(s2.LatLngFromDegrees(latHi, lngLo)),
+	})})
+}
+
+func covers(hashes []string, latLng s2.LatLng) bool {
+	for _, hash := range hashes {
+		if rect, _ := Bounds(hash); rect.ContainsLatLng(latLng) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func TestCovering(t *testing.T) {
+	polygon := rectPolygon(48.80, 2.25, 48.90, 2.42)
+	tests := []Coverer{
+		{},
+		{MaxCells: 20},
+		{MinPrecision: 4, MaxPrecision: 5, MaxCells: 100},
+		{MaxPrecision: 3},
+	}
+
+	for _, c := range tests {
+		hashes, err := c.Covering(polygon)
+		if err != nil {
+			t.Errorf("%+v: %v", c, err)
+			continue
+		}
+
+		if !sort.StringsAreSorted(hashes) {
+			t.Errorf("%+v: unsorted covering %v", c, hashes)
+		}
+
+		maxCells := c.MaxCells
+		if maxCells == 0 {
+			maxCells = DefaultMaxCells
+		}
+
+		if len(hashes) == 0 || len(hashes) > maxCells {
+			t.Errorf("%+v: covering of %d cells", c, len(hashes))
+		}
+
+		for _, hash := range hashes {
+			if (c.MinPrecision > 0 && len(hash) < c.MinPrecision) || (c.MaxPrecision > 0 && len(hash) > c.MaxPrecision) {
+				t.Errorf("%+v: covering cell %s out of the precision range", c, hash)
+			}
+		}
+
+		for lat := 48.80; lat <= 48.90; lat += 0.02 {
+			for lng := 2.25; lng <= 2.42; lng += 0.02 {
+				if latLng := s2.LatLngFromDegrees(lat, lng); !covers(hashes, latLng) {
+					t.Errorf("%+v: covering %v misses %v", c, hashes, latLng)
+				}
+			}
+		}
+	}
+
+	// Cells inside the polygon are kept whole.
+	c := &Coverer{MaxPrecision: 2, MaxCells: 100}
+	polygon = rectPolygon(-10, -10, 60, 60)
+	hashes, err := c.Covering(polygon)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	short := 0
+	for _, hash := range hashes {
+		if len(hash) == 1 {
+			short++
+		}
+
+		if rect, _ := Bounds(hash); !rect.Intersects(polygon.RectBound()) {
+			t.Errorf("covering cell %s is outside the polygon", hash)
+		}
+	}
+
+	if short == 0 {
+		t.Errorf("covering %v has no cell of precision 1", hashes)
+	}
+
+	// The cells west of the antimeridian cover points at longitude -180.
+	hashes, err = (&Coverer{MaxCells: 20}).Covering(rectPolygon(10, -180, 11, -179))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for lat := 10.0; lat <= 11; lat += 0.25 {
+		if latLng := s2.LatLngFromDegrees(lat, -180); !covers(hashes, latLng) {
+			t.Errorf("covering %v misses %v", hashes, latLng)
+		}
+	}
+}
+
+func TestCoveringErrors(t *testing.T) {
+	polygon := rectPolygon(0, 0, 1, 1)
+	for _, c := range []Coverer{{MinPrecision: -1}, {MaxPrecision: 13}} {
+		if _, err := c.Covering(polygon); err == nil {
+			t.Errorf("%+v: no error", c)
+		}
+	}
+
+	hashes, err := (&Coverer{}).Covering(&s2.Polygon{})
+	if err != nil || len(hashes) != 0 {
+		t.Errorf("covered an empty polygon with %v, %v", hashes, err)
+	}
+}
@@ -0,0 +1,201 @@
+// Package geohash converts between s2 types and geohash strings.
+package geohash
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/golang/geo/r1"
+	"github.com/golang/geo/s1"
+	"github.com/golang/geo/s2"
+)
+
+// MaxPrecision is the maximum number of characters of a geohash.
+const MaxPrecision = 12
+
+// ErrNoNeighbor is returned by Neighbor for the northern neighbors of cells
+// on the north pole and the southern neighbors of cells on the south pole.
+var ErrNoNeighbor = errors.New("geohash: no neighbor beyond the pole")
+
+const base32 = "0123456789bcdefghjkmnpqrstuvwxyz"
+
+var base32Indexes = func() [256]int {
+	indexes := [256]int{}
+	for i := range indexes {
+		indexes[i] = -1
+	}
+
+	for i := 0; i < len(base32); i++ {
+		indexes[base32[i]] = i
+	}
+
+	return indexes
+}()
+
+func validatePrecision(precision int) error {
+	if precision < 1 || precision > MaxPrecision {
+		return fmt.Errorf("geohash: invalid precision %d", precision)
+	}
+
+	return nil
+}
+
+// Encode returns the geohash of the given number of characters containing a
+// location.
+func Encode(latLng s2.LatLng, precision int) (string, error) {
+	if err := validatePrecision(precision); err != nil {
+		return "", err
+	}
+
+	lat := r1.Interval{Lo: -90, Hi: 90}
+	lng := r1.Interval{Lo: -180, Hi: 180}
+	latDegrees := latLng.Lat.Degrees()
+	lngDegrees := latLng.Normalized().Lng.Degrees()
+
+	hash := make([]byte, precision)
+	even := true
+	for i := range hash {
+		index := 0
+		for bit := 0; bit < 5; bit++ {
+			interval, v := &lat, latDegrees
+			if even {
+				interval, v = &lng, lngDegrees
+			}
+
+			index <<= 1
+			if mid := interval.Center(); v >= mid {
+				index |= 1
+				interval.Lo = mid
+			} else {
+				interval.Hi = mid
+			}
+
+			even = !even
+		}
+
+		hash[i] = base32[index]
+	}
+
+	return string(hash), nil
+}
+
+// Bounds returns the cell of a geohash.
+func Bounds(hash string) (s2.Rect, error) {
+	if err := validatePrecision(len(hash)); err != nil {
+		return s2.EmptyRect(), err
+	}
+
+	lat := r1.Interval{Lo: -90, Hi: 90}
+	lng := r1.Interval{Lo: -180, Hi: 180}
+	even := true
+	for i := 0; i < len(hash); i++ {
+		index := base32Indexes[hash[i]]
+		if index < 0 {
+			return s2.EmptyRect(), fmt.Errorf("geohash: invalid character %q", hash[i])
+		}
+
+		for bit := 4; bit >= 0; bit-- {
+			interval := &lat
+			if even {
+				interval = &lng
+			}
+
+			if mid := interval.Center(); index>>uint(bit)&1 == 1 {
+				interval.Lo = mid
+			} else {
+				interval.Hi = mid
+			}
+
+			even = !even
+		}
+	}
+
+	return s2.Rect{
+		Lat: r1.Interval{Lo: lat.Lo * math.Pi / 180, Hi: lat.Hi * math.Pi / 180},
+		Lng: s1.IntervalFromEndpoints(lng.Lo*math.Pi/180, lng.Hi*math.Pi/180),
+	}, nil
+}
+
+// Decode returns the center of the cell of a geohash.
+func Decode(hash string) (s2.LatLng, error) {
+	rect, err := Bounds(hash)
+	if err != nil {
+		return s2.LatLng{}, err
+	}
+
+	return rect.Center(), nil
+}
+
+// Direction identifies one of the eight neighbors of a geohash cell.
+type Direction int
+
+const (
+	North Direction = iota
+	NorthEast
+	East
+	SouthEast
+	South
+	SouthWest
+	West
+	NorthWest
+)
+
+// directionOffsets are the latitude and longitude cell offsets of each
+// direction.
+var directionOffsets = [...][2]float64{
+	North:     {1, 0},
+	NorthEast: {1, 1},
+	East:      {0, 1},
+	SouthEast: {-1, 1},
+	South:     {-1, 0},
+	SouthWest: {-1, -1},
+	West:      {0, -1},
+	NorthWest: {1, -1},
+}
+
+// Neighbor returns the adjacent geohash of the same precision in a direction.
+// Neighbors wrap around the antimeridian; cells on the poles have no northern
+// or southern neighbors, for which ErrNoNeighbor is returned.
+func Neighbor(hash string, direction Direction) (string, error) {
+	if direction < North || direction > NorthWest {
+		return "", fmt.Errorf("geohash: invalid direction %d", direction)
+	}
+
+	rect, err := Bounds(hash)
+	if err != nil {
+		return "", err
+	}
+
+	offset := directionOffsets[direction]
+	size := rect.Size()
+	center := rect.Center()
+	lat := center.Lat + s1.Angle(offset[0])*size.Lat
+	if lat.Degrees() > 90 || lat.Degrees() < -90 {
+		return "", ErrNoNeighbor
+	}
+
+	lng := center.Lng + s1.Angle(offset[1])*size.Lng
+	return Encode(s2.LatLng{Lat: lat, Lng: lng}.Normalized(), len(hash))
+}
+
+// Neighbors returns the adjacent geohashes of the same precision, in the order
+// of the Direction constants. Missing neighbors beyond the poles are empty
+// strings.
+func Neighbors(hash string) ([]string, error) {
+	neighbors := make([]string, NorthWest+1)
+	for direction := range neighbors {
+		neighbor, err := Neighbor(hash, Direction(direction))
+		if err == ErrNoNeighbor {
+			continue
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		neighbors[direction] = neighbor
+	}
+
+	return neighbors, nil
+}
@@ -0,0 +1,127 @@
+package geohash
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/golang/geo/s2"
+)
+
+func TestEncode(t *testing.T) {
+	tests := []struct {
+		lat, lng  float64
+		precision int
+		want      string
+	}{
+		{42.6, -5.6, 5, "ezs42"},
+		{57.64911, 10.40744, 11, "u4pruydqqvj"},
+		{0, 0, 1, "s"},
+		{-90, -180, 4, "0000"},
+		{90, 180, 4, "zzzz"},
+		{0, 190, 2, "80"},
+	}
+
+	for _, test := range tests {
+		hash, err := Encode(s2.LatLngFromDegrees(test.lat, test.lng), test.precision)
+		if err != nil {
+			t.Errorf("%v, %v: %v", test.lat, test.lng, err)
+			continue
+		}
+
+		if hash != test.want {
+			t.Errorf("%v, %v: encoded %s, want %s", test.lat, test.lng, hash, test.want)
+		}
+	}
+
+	for _, precision := range []int{0, MaxPrecision + 1} {
+		if _, err := Encode(s2.LatLngFromDegrees(0, 0), precision); err == nil {
+			t.Errorf("precision %d: no error", precision)
+		}
+	}
+}
+
+func TestBounds(t *testing.T) {
+	rect, err := Bounds("ezs42")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lo, hi := rect.Lo(), rect.Hi()
+	got := []float64{lo.Lat.Degrees(), lo.Lng.Degrees(), hi.Lat.Degrees(), hi.Lng.Degrees()}
+	want := []float64{42.5830078125, -5.625, 42.626953125, -5.5810546875}
+	for i := range got {
+		if math.Abs(got[i]-want[i]) > 1e-12 {
+			t.Errorf("bounds %v, want %v", got, want)
+			break
+		}
+	}
+
+	center, err := Decode("u4pruydqqvj")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if math.Abs(center.Lat.Degrees()-57.64911) > 1e-5 || math.Abs(center.Lng.Degrees()-10.40744) > 1e-5 {
+		t.Errorf("decoded %v", center)
+	}
+
+	// Cells along the antimeridian contain the points they are encoded from.
+	for _, lat := range []float64{-45, 0, 45} {
+		latLng := s2.LatLngFromDegrees(lat, -180)
+		hash, err := Encode(latLng, 6)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if rect, err := Bounds(hash); err != nil || !rect.ContainsLatLng(latLng) {
+			t.Errorf("%s: bounds %v do not contain %v, %v", hash, rect, latLng, err)
+		}
+	}
+
+	for _, hash := range []string{"", "ezs4a", "ezs42ezs42ezs"} {
+		if _, err := Bounds(hash); err == nil {
+			t.Errorf("%q: no error", hash)
+		}
+	}
+}
+
+func TestNeighbors(t *testing.T) {
+	tests := map[string][]string{
+		"dqcjqc": {"dqcjqf", "dqcjr4", "dqcjr1", "dqcjr0", "dqcjqb", "dqcjq8", "dqcjq9", "dqcjqd"},
+		"9":      {"c", "f", "d", "6", "3", "2", "8", "b"},
+		"u":      {"", "", "v", "t", "s", "e", "g", ""},
+		"00":     {"01", "03", "02", "", "", "", "pb", "pc"},
+		"zzzz":   {"", "", "bpbp", "bpbn", "zzzy", "zzzw", "zzzx", ""},
+	}
+
+	for hash, want := range tests {
+		neighbors, err := Neighbors(hash)
+		if err != nil {
+			t.Errorf("%s: %v", hash, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(neighbors, want) {
+			t.Errorf("%s: neighbors %v, want %v", hash, neighbors, want)
+		}
+	}
+}
+
+func TestNeighborErrors(t *testing.T) {
+	if neighbor, err := Neighbor("u", North); err != ErrNoNeighbor {
+		t.Errorf("north of u: %q, %v", neighbor, err)
+	}
+
+	if neighbor, err := Neighbor("0", SouthWest); err != ErrNoNeighbor {
+		t.Errorf("south west of 0: %q, %v", neighbor, err)
+	}
+
+	if _, err := Neighbor("u", NorthWest+1); err == nil || err == ErrNoNeighbor {
+		t.Errorf("invalid direction: %v", err)
+	}
+
+	if _, err := Neighbors("a"); err == nil {
+		t.Errorf("invalid geohash: no error")
+	}
+}
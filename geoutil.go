@@ -62,6 +62,20 @@ func PointCoordinates(point s2.Point, precision int) ([]float64, error) {
 	return pointCoordinates(point, precisionFunc), nil
 }
 
+func PolylineCoordinates(polyline *s2.Polyline, precision int) ([][]float64, error) {
+	precisionFunc, err := selectPrecisionFunc(precision)
+	if err != nil {
+		return nil, err
+	}
+
+	lcs := make([][]float64, len(*polyline))
+	for i, point := range *polyline {
+		lcs[i] = pointCoordinates(point, precisionFunc)
+	}
+
+	return lcs, nil
+}
+
 func loopCoordinates(loop *s2.Loop, precisionFunc func(s1.Angle) float64) [][]float64 {
 	nv := loop.NumVertices()
 	if nv == 0 {
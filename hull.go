@@ -0,0 +1,465 @@
+package geoutil
+
+import (
+	"container/heap"
+	"fmt"
+
+	"github.com/golang/geo/s1"
+	"github.com/golang/geo/s2"
+)
+
+// hullQuery returns a convex hull query of the vertices of geometries.
+func hullQuery(geometries []interface{}) (*s2.ConvexHullQuery, error) {
+	query := s2.NewConvexHullQuery()
+	for _, geometry := range geometries {
+		switch geometry := geometry.(type) {
+		case nil:
+		case s2.LatLng:
+			query.AddPoint(s2.PointFromLatLng(geometry))
+		case s2.Point:
+			query.AddPoint(geometry)
+		case []s2.Point:
+			for _, point := range geometry {
+				query.AddPoint(point)
+			}
+
+		case *s2.Polyline:
+			query.AddPolyline(geometry)
+		case []*s2.Polyline:
+			for _, polyline := range geometry {
+				query.AddPolyline(polyline)
+			}
+
+		case *s2.Loop:
+			query.AddLoop(geometry)
+		case *s2.Polygon:
+			query.AddPolygon(geometry)
+		case s2.CellUnion:
+			for _, id := range geometry {
+				query.AddLoop(s2.LoopFromCell(s2.CellFromCellID(id)))
+			}
+
+		default:
+			return nil, fmt.Errorf("geoutil: unknown geometry type %T", geometry)
+		}
+	}
+
+	return query, nil
+}
+
+// convexHull returns the convex hull loop of a query, which has at least
+// three vertices unless it is empty or full.
+func convexHull(query *s2.ConvexHullQuery) *s2.Loop {
+	loop := query.ConvexHull()
+	if loop.IsEmpty() || loop.IsFull() || loop.NumVertices() >= 3 {
+		return loop
+	}
+
+	// The hull of collinear points is their two extreme points.
+	query = s2.NewConvexHullQuery()
+	for _, point := range loop.Vertices() {
+		query.AddPoint(point)
+	}
+
+	return query.ConvexHull()
+}
+
+func polygonFromHull(loop *s2.Loop) *s2.Polygon {
+	if loop.IsEmpty() {
+		return s2.PolygonFromLoops(nil)
+	}
+
+	if loop.IsFull() {
+		return s2.FullPolygon()
+	}
+
+	return s2.PolygonFromLoops([]*s2.Loop{loop})
+}
+
+// ConvexHull returns the smallest convex polygon containing geometries. The
+// hull of a single point or of collinear points is a very thin triangle, and
+// the hull of geometries not contained by a hemisphere is the full polygon.
+func ConvexHull(geometries ...interface{}) (*s2.Polygon, error) {
+	query, err := hullQuery(geometries)
+	if err != nil {
+		return nil, err
+	}
+
+	return polygonFromHull(convexHull(query)), nil
+}
+
+// hullVertex is a point of a concave hull, which once on the hull is in a
+// circular doubly linked list in counterclockwise order, with the cells
+// covering its edge to the next vertex.
+type hullVertex struct {
+	point      s2.Point
+	prev, next *hullVertex
+	cells      []s2.CellID
+}
+
+// hullIndex is an index of the vertices in cells, with the number of
+// vertices in each cell and its descendants so that searches skip empty
+// cells. Points are indexed in their leaf cells, and edges in the cells
+// covering them.
+type hullIndex struct {
+	vertices map[s2.CellID][]*hullVertex
+	counts   map[s2.CellID]int
+}
+
+func newHullIndex() *hullIndex {
+	return &hullIndex{
+		vertices: map[s2.CellID][]*hullVertex{},
+		counts:   map[s2.CellID]int{},
+	}
+}
+
+func (x *hullIndex) add(id s2.CellID, v *hullVertex) {
+	x.vertices[id] = append(x.vertices[id], v)
+	for level := id.Level(); level >= 0; level-- {
+		x.counts[id.Parent(level)]++
+	}
+}
+
+func (x *hullIndex) remove(id s2.CellID, v *hullVertex) {
+	vertices := x.vertices[id]
+	for i, u := range vertices {
+		if u == v {
+			vertices[i] = vertices[len(vertices)-1]
+			vertices = vertices[:len(vertices)-1]
+			break
+		}
+	}
+
+	if len(vertices) == 0 {
+		delete(x.vertices, id)
+	} else {
+		x.vertices[id] = vertices
+	}
+
+	for level := id.Level(); level >= 0; level-- {
+		parent := id.Parent(level)
+		if x.counts[parent]--; x.counts[parent] == 0 {
+			delete(x.counts, parent)
+		}
+	}
+}
+
+// visit calls f with the vertices in the cells intersecting a cap until f
+// returns true, and reports whether it did.
+func (x *hullIndex) visit(c s2.Cap, f func(v *hullVertex) bool) bool {
+	var visitCell func(id s2.CellID) bool
+	visitCell = func(id s2.CellID) bool {
+		if x.counts[id] == 0 || !c.IntersectsCell(s2.CellFromCellID(id)) {
+			return false
+		}
+
+		for _, v := range x.vertices[id] {
+			if f(v) {
+				return true
+			}
+		}
+
+		if id.IsLeaf() {
+			return false
+		}
+
+		for _, child := range id.Children() {
+			if visitCell(child) {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	for face := 0; face < 6; face++ {
+		if visitCell(s2.CellIDFromFace(face)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// hullEntry is a cell or a vertex searched by its distance to an edge.
+type hullEntry struct {
+	id       s2.CellID
+	vertex   *hullVertex
+	distance s1.Angle
+}
+
+type hullEntryHeap []hullEntry
+
+func (h hullEntryHeap) Len() int { return len(h) }
+func (h hullEntryHeap) Less(i, j int) bool {
+	switch {
+	case h[i].distance != h[j].distance:
+		return h[i].distance < h[j].distance
+	case h[i].vertex == nil || h[j].vertex == nil:
+		return h[i].vertex == nil && h[j].vertex != nil
+	default:
+		return h[i].vertex.point.Cmp(h[j].vertex.point.Vector) < 0
+	}
+}
+
+func (h hullEntryHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *hullEntryHeap) Push(x interface{}) { *h = append(*h, x.(hullEntry)) }
+func (h *hullEntryHeap) Pop() interface{} {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
+
+// nearest calls f with the points within a distance of an edge, from the
+// nearest, until f returns true.
+func (x *hullIndex) nearest(a, b s2.Point, max s1.Angle, f func(v *hullVertex, distance s1.Angle) bool) {
+	h := &hullEntryHeap{}
+	pushCell := func(id s2.CellID) {
+		if x.counts[id] == 0 {
+			return
+		}
+
+		if distance := s2.CellFromCellID(id).DistanceToEdge(a, b).Angle(); distance <= max {
+			heap.Push(h, hullEntry{id: id, distance: distance})
+		}
+	}
+
+	for face := 0; face < 6; face++ {
+		pushCell(s2.CellIDFromFace(face))
+	}
+
+	for h.Len() > 0 {
+		e := heap.Pop(h).(hullEntry)
+		if e.vertex != nil {
+			if f(e.vertex, e.distance) {
+				return
+			}
+
+			continue
+		}
+
+		for _, v := range x.vertices[e.id] {
+			if distance := s2.DistanceFromSegment(v.point, a, b); distance <= max {
+				heap.Push(h, hullEntry{vertex: v, distance: distance})
+			}
+		}
+
+		if !e.id.IsLeaf() {
+			for _, child := range e.id.Children() {
+				pushCell(child)
+			}
+		}
+	}
+}
+
+// boundingCap returns a cap containing points, centered on their mean.
+func boundingCap(points ...s2.Point) s2.Cap {
+	sum := s2.Point{}
+	for _, point := range points {
+		sum.Vector = sum.Add(point.Vector)
+	}
+
+	if sum.Norm() == 0 {
+		return s2.FullCap()
+	}
+
+	c := s2.CapFromPoint(s2.Point{Vector: sum.Normalize()})
+	for _, point := range points {
+		c = c.AddPoint(point)
+	}
+
+	return c
+}
+
+// ConcaveHull returns a polygon containing geometries whose boundary passes
+// through their vertices, found by digging into the edges of the convex hull
+// like the concaveman algorithm. An edge is replaced by two edges through the
+// vertex nearest to it when the shorter of the two is at most its length
+// divided by concavity. A concavity of 1 gives a detailed hull, larger values
+// give smoother hulls, and an infinite concavity gives the convex hull. The
+// concavity must be positive.
+func ConcaveHull(concavity float64, geometries ...interface{}) (*s2.Polygon, error) {
+	if !(concavity > 0) {
+		return nil, fmt.Errorf("geoutil: invalid concavity %v", concavity)
+	}
+
+	query, err := hullQuery(geometries)
+	if err != nil {
+		return nil, err
+	}
+
+	loop := convexHull(query)
+	if loop.IsEmpty() || loop.IsFull() {
+		return polygonFromHull(loop), nil
+	}
+
+	first := &hullVertex{point: loop.Vertex(0)}
+	seen := map[s2.Point]bool{first.point: true}
+	last := first
+	for i := 1; i < loop.NumVertices(); i++ {
+		v := &hullVertex{point: loop.Vertex(i), prev: last}
+		last.next = v
+		last = v
+		seen[v.point] = true
+	}
+
+	last.next = first
+	first.prev = last
+
+	// The edges of the hull are indexed in the cells of about their length
+	// covering them.
+	edges := newHullIndex()
+	addEdge := func(v *hullVertex) {
+		level := s2.MinWidthMetric.MaxLevel(v.point.Distance(v.next.point).Radians())
+		coverer := &s2.RegionCoverer{MinLevel: level, MaxLevel: level, MaxCells: 8}
+		v.cells = coverer.Covering(boundingCap(v.point, v.next.point))
+		for _, id := range v.cells {
+			edges.add(id, v)
+		}
+	}
+
+	removeEdge := func(v *hullVertex) {
+		for _, id := range v.cells {
+			edges.remove(id, v)
+		}
+	}
+
+	queue := []*hullVertex{}
+	for v := first; ; {
+		addEdge(v)
+		queue = append(queue, v)
+		if v = v.next; v == first {
+			break
+		}
+	}
+
+	// The convex hull query holds no vertices other than its own, so the
+	// vertices are collected again.
+	remaining := newHullIndex()
+	addPoint := func(point s2.Point) {
+		if !seen[point] {
+			seen[point] = true
+			remaining.add(s2.CellFromPoint(point).ID(), &hullVertex{point: point})
+		}
+	}
+
+	for _, geometry := range geometries {
+		switch geometry := geometry.(type) {
+		case s2.LatLng:
+			addPoint(s2.PointFromLatLng(geometry))
+		case s2.Point:
+			addPoint(geometry)
+		case []s2.Point:
+			for _, point := range geometry {
+				addPoint(point)
+			}
+
+		case *s2.Polyline:
+			for _, point := range *geometry {
+				addPoint(point)
+			}
+
+		case []*s2.Polyline:
+			for _, polyline := range geometry {
+				for _, point := range *polyline {
+					addPoint(point)
+				}
+			}
+
+		case *s2.Loop:
+			for _, point := range geometry.Vertices() {
+				addPoint(point)
+			}
+
+		case *s2.Polygon:
+			for _, loop := range geometry.Loops() {
+				for _, point := range loop.Vertices() {
+					addPoint(point)
+				}
+			}
+
+		case s2.CellUnion:
+			for _, id := range geometry {
+				cell := s2.CellFromCellID(id)
+				for k := 0; k < 4; k++ {
+					addPoint(cell.Vertex(k))
+				}
+			}
+		}
+	}
+
+	// crosses reports whether an edge crosses an edge of the hull.
+	crosses := func(a, b s2.Point) bool {
+		return edges.visit(boundingCap(a, b), func(v *hullVertex) bool {
+			return s2.CrossingSign(a, b, v.point, v.next.point) == s2.Cross
+		})
+	}
+
+	// inTriangle reports whether a point other than c is on or inside the
+	// triangle abc, which would be left outside of the hull.
+	inTriangle := func(a, b, c s2.Point) bool {
+		return remaining.visit(boundingCap(a, b, c), func(v *hullVertex) bool {
+			return v.point != c &&
+				s2.RobustSign(a, b, v.point) != s2.Clockwise &&
+				s2.RobustSign(b, c, v.point) != s2.Clockwise &&
+				s2.RobustSign(c, a, v.point) != s2.Clockwise
+		})
+	}
+
+	for len(queue) > 0 {
+		a := queue[0]
+		queue = queue[1:]
+		b := a.next
+		maxLength := a.point.Distance(b.point) / s1.Angle(concavity)
+
+		// The candidates are the vertices inside the hull closer to the edge
+		// than the longest edge allowed. The nearest candidate closer to the
+		// edge than to the neighboring edges is dug in, if the new edges are
+		// short enough and leave the hull valid.
+		var dug *hullVertex
+		remaining.nearest(a.point, b.point, maxLength, func(c *hullVertex, distance s1.Angle) bool {
+			if s2.RobustSign(a.point, b.point, c.point) != s2.CounterClockwise {
+				return false
+			}
+
+			if distance >= s2.DistanceFromSegment(c.point, a.prev.point, a.point) ||
+				distance >= s2.DistanceFromSegment(c.point, b.point, b.next.point) {
+				return false
+			}
+
+			if crosses(a.point, c.point) || crosses(c.point, b.point) || inTriangle(a.point, b.point, c.point) {
+				return false
+			}
+
+			if a.point.Distance(c.point) <= maxLength || c.point.Distance(b.point) <= maxLength {
+				dug = c
+			}
+
+			return true
+		})
+
+		if dug == nil {
+			continue
+		}
+
+		remaining.remove(s2.CellFromPoint(dug.point).ID(), dug)
+		removeEdge(a)
+		dug.prev, dug.next = a, b
+		a.next = dug
+		b.prev = dug
+		addEdge(a)
+		addEdge(dug)
+		queue = append(queue, a, dug)
+	}
+
+	points := []s2.Point{}
+	for v := first; ; {
+		points = append(points, v.point)
+		if v = v.next; v == first {
+			break
+		}
+	}
+
+	return s2.PolygonFromLoops([]*s2.Loop{s2.LoopFromPoints(points)}), nil
+}
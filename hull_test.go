@@ -0,0 +1,159 @@
+package geoutil
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/golang/geo/s2"
+)
+
+// checkHull checks that a hull is valid and covers points, which are either
+// its vertices or inside of it.
+func checkHull(t *testing.T, name string, hull *s2.Polygon, points []s2.Point) {
+	t.Helper()
+	if problems, err := Validate(hull); err != nil || len(problems) != 0 {
+		t.Errorf("%s: invalid hull %v, %v", name, problems, err)
+	}
+
+	vertices := map[s2.Point]bool{}
+	for _, loop := range hull.Loops() {
+		for _, vertex := range loop.Vertices() {
+			vertices[vertex] = true
+		}
+	}
+
+	for _, point := range points {
+		if !vertices[point] && !hull.ContainsPoint(point) {
+			t.Errorf("%s: point %v outside of the hull", name, s2.LatLngFromPoint(point))
+			return
+		}
+	}
+}
+
+// notchedPoints returns points scattered over a square with a notch
+// between latitudes 1 and 9 east of longitude 5, as wide as it is deep.
+func notchedPoints() []s2.Point {
+	r := rand.New(rand.NewSource(1))
+	points := []s2.Point{}
+	for len(points) < 1000 {
+		lat, lng := 10*r.Float64(), 10*r.Float64()
+		if lng <= 5 || lat <= 1 || lat >= 9 {
+			points = append(points, pointFromDegrees(lat, lng))
+		}
+	}
+
+	return points
+}
+
+func TestConvexHull(t *testing.T) {
+	points := notchedPoints()
+	hull, err := ConvexHull(points)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	checkHull(t, "convex", hull, points)
+	if !hull.ContainsPoint(pointFromDegrees(5, 8)) {
+		t.Errorf("convex hull does not cover the notch")
+	}
+
+	// The hull of collinear points is a thin triangle through its extreme
+	// points.
+	hull, err = ConvexHull(polylinesFromDegrees([]float64{0, 0, 0, 1, 0, 2}))
+	if err != nil || hull.NumLoops() != 1 || hull.Loop(0).NumVertices() != 3 {
+		t.Errorf("hull of collinear points %v, %v", hull, err)
+	}
+
+	hull, err = ConvexHull()
+	if err != nil || !hull.IsEmpty() {
+		t.Errorf("hull of nothing %v, %v", hull, err)
+	}
+
+	if _, err := ConvexHull(1); err == nil {
+		t.Errorf("unknown geometry: no error")
+	}
+}
+
+func TestConcaveHull(t *testing.T) {
+	points := notchedPoints()
+	tests := []struct {
+		concavity float64
+		notch     bool
+	}{
+		{1, false},
+		{2, true},
+		{math.Inf(1), true},
+	}
+
+	for _, test := range tests {
+		hull, err := ConcaveHull(test.concavity, points)
+		if err != nil {
+			t.Errorf("concavity %v: %v", test.concavity, err)
+			continue
+		}
+
+		checkHull(t, "concave", hull, points)
+		if notch := hull.ContainsPoint(pointFromDegrees(5, 8)); notch != test.notch {
+			t.Errorf("concavity %v: hull covers the notch %v, want %v", test.concavity, notch, test.notch)
+		}
+	}
+
+	// An infinite concavity gives the convex hull.
+	hull, err := ConcaveHull(math.Inf(1), points)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	convex, err := ConvexHull(points)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !hull.Loop(0).Equal(convex.Loop(0)) {
+		t.Errorf("hull of infinite concavity of %d vertices, want %d", hull.Loop(0).NumVertices(), convex.Loop(0).NumVertices())
+	}
+
+	for _, concavity := range []float64{0, -1, math.NaN()} {
+		if _, err := ConcaveHull(concavity, points); err == nil {
+			t.Errorf("concavity %v: no error", concavity)
+		}
+	}
+
+	hull, err = ConcaveHull(2)
+	if err != nil || !hull.IsEmpty() {
+		t.Errorf("hull of nothing %v, %v", hull, err)
+	}
+}
+
+func TestConcaveHullManyPoints(t *testing.T) {
+
+	// Points scattered over a disk, denser at its center.
+	r := rand.New(rand.NewSource(1))
+	points := make([]s2.Point, 10000)
+	for i := range points {
+		distance, azimuth := 10*r.Float64(), 2*math.Pi*r.Float64()
+		points[i] = pointFromDegrees(distance*math.Sin(azimuth), distance*math.Cos(azimuth))
+	}
+
+	start := time.Now()
+	hull, err := ConcaveHull(2, points)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("hull found in %v", elapsed)
+	}
+
+	checkHull(t, "many points", hull, points)
+	convex, err := ConvexHull(points)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n := hull.Loop(0).NumVertices(); n <= convex.Loop(0).NumVertices() {
+		t.Errorf("concave hull of %d vertices, no more than the convex hull", n)
+	}
+}
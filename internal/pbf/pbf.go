@@ -0,0 +1,362 @@
+// Package pbf implements the subset of the protocol buffers wire format used
+// by the vector encodings of geoutil.
+package pbf
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// Wire types.
+const (
+	Varint          = 0
+	Fixed64         = 1
+	LengthDelimited = 2
+	Fixed32         = 5
+)
+
+var errTruncated = errors.New("pbf: truncated message")
+
+// EncodeZigZag maps signed integers to unsigned integers so that numbers with
+// a small absolute value have a small varint encoding.
+func EncodeZigZag(v int64) uint64 {
+	return uint64(v<<1) ^ uint64(v>>63)
+}
+
+// DecodeZigZag reverses EncodeZigZag.
+func DecodeZigZag(v uint64) int64 {
+	return int64(v>>1) ^ -int64(v&1)
+}
+
+// Writer appends protocol buffer fields to a byte slice.
+type Writer struct {
+	buf []byte
+}
+
+// Data returns the encoded message.
+func (w *Writer) Data() []byte {
+	return w.buf
+}
+
+// Len returns the length of the encoded message.
+func (w *Writer) Len() int {
+	return len(w.buf)
+}
+
+// Reset discards the encoded message, keeping the underlying storage.
+func (w *Writer) Reset() {
+	w.buf = w.buf[:0]
+}
+
+func (w *Writer) appendVarint(v uint64) {
+	var scratch [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(scratch[:], v)
+	w.buf = append(w.buf, scratch[:n]...)
+}
+
+func (w *Writer) key(field, wireType int) {
+	w.appendVarint(uint64(field)<<3 | uint64(wireType))
+}
+
+func (w *Writer) Uint64(field int, v uint64) {
+	w.key(field, Varint)
+	w.appendVarint(v)
+}
+
+func (w *Writer) Int64(field int, v int64) {
+	w.Uint64(field, uint64(v))
+}
+
+func (w *Writer) Sint64(field int, v int64) {
+	w.Uint64(field, EncodeZigZag(v))
+}
+
+func (w *Writer) Bool(field int, v bool) {
+	if v {
+		w.Uint64(field, 1)
+	} else {
+		w.Uint64(field, 0)
+	}
+}
+
+func (w *Writer) Double(field int, v float64) {
+	w.key(field, Fixed64)
+	w.buf = append(w.buf, 0, 0, 0, 0, 0, 0, 0, 0)
+	binary.LittleEndian.PutUint64(w.buf[len(w.buf)-8:], math.Float64bits(v))
+}
+
+func (w *Writer) Float(field int, v float32) {
+	w.key(field, Fixed32)
+	w.buf = append(w.buf, 0, 0, 0, 0)
+	binary.LittleEndian.PutUint32(w.buf[len(w.buf)-4:], math.Float32bits(v))
+}
+
+func (w *Writer) Bytes(field int, v []byte) {
+	w.key(field, LengthDelimited)
+	w.appendVarint(uint64(len(v)))
+	w.buf = append(w.buf, v...)
+}
+
+func (w *Writer) String(field int, v string) {
+	w.key(field, LengthDelimited)
+	w.appendVarint(uint64(len(v)))
+	w.buf = append(w.buf, v...)
+}
+
+// Message writes an embedded message.
+func (w *Writer) Message(field int, m *Writer) {
+	w.Bytes(field, m.buf)
+}
+
+// PackedUint64s writes a packed repeated varint field.
+func (w *Writer) PackedUint64s(field int, v []uint64) {
+	if len(v) == 0 {
+		return
+	}
+
+	m := &Writer{}
+	for _, e := range v {
+		m.appendVarint(e)
+	}
+
+	w.Message(field, m)
+}
+
+// PackedUint32s writes a packed repeated varint field.
+func (w *Writer) PackedUint32s(field int, v []uint32) {
+	if len(v) == 0 {
+		return
+	}
+
+	m := &Writer{}
+	for _, e := range v {
+		m.appendVarint(uint64(e))
+	}
+
+	w.Message(field, m)
+}
+
+// PackedSint64s writes a packed repeated zigzag varint field.
+func (w *Writer) PackedSint64s(field int, v []int64) {
+	if len(v) == 0 {
+		return
+	}
+
+	m := &Writer{}
+	for _, e := range v {
+		m.appendVarint(EncodeZigZag(e))
+	}
+
+	w.Message(field, m)
+}
+
+// Reader iterates over the fields of a protocol buffer message. Errors are
+// sticky: once one occurs, Next returns false and Err reports it.
+type Reader struct {
+	buf      []byte
+	field    int
+	wireType int
+	err      error
+}
+
+func NewReader(buf []byte) *Reader {
+	return &Reader{
+		buf: buf,
+	}
+}
+
+// Err returns the first error encountered while reading.
+func (r *Reader) Err() error {
+	return r.err
+}
+
+func (r *Reader) fail(err error) {
+	if r.err == nil {
+		r.err = err
+	}
+
+	r.buf = nil
+}
+
+func (r *Reader) readVarint() uint64 {
+	v, n := binary.Uvarint(r.buf)
+	if n <= 0 {
+		r.fail(errTruncated)
+		return 0
+	}
+
+	r.buf = r.buf[n:]
+	return v
+}
+
+// Next advances to the next field, returning false at the end of the message
+// or on error.
+func (r *Reader) Next() bool {
+	if r.err != nil || len(r.buf) == 0 {
+		return false
+	}
+
+	key := r.readVarint()
+	if r.err != nil {
+		return false
+	}
+
+	r.field = int(key >> 3)
+	r.wireType = int(key & 7)
+	return true
+}
+
+// Field returns the number of the current field.
+func (r *Reader) Field() int {
+	return r.field
+}
+
+// WireType returns the wire type of the current field.
+func (r *Reader) WireType() int {
+	return r.wireType
+}
+
+func (r *Reader) expect(wireType int) bool {
+	if r.err != nil {
+		return false
+	}
+
+	if r.wireType != wireType {
+		r.fail(fmt.Errorf("pbf: field %d has wire type %d, expected %d", r.field, r.wireType, wireType))
+		return false
+	}
+
+	return true
+}
+
+func (r *Reader) next(n int) []byte {
+	if n < 0 || n > len(r.buf) {
+		r.fail(errTruncated)
+		return nil
+	}
+
+	v := r.buf[:n]
+	r.buf = r.buf[n:]
+	return v
+}
+
+func (r *Reader) Uint64() uint64 {
+	if !r.expect(Varint) {
+		return 0
+	}
+
+	return r.readVarint()
+}
+
+func (r *Reader) Int64() int64 {
+	return int64(r.Uint64())
+}
+
+func (r *Reader) Sint64() int64 {
+	return DecodeZigZag(r.Uint64())
+}
+
+func (r *Reader) Bool() bool {
+	return r.Uint64() != 0
+}
+
+func (r *Reader) Double() float64 {
+	if !r.expect(Fixed64) {
+		return 0
+	}
+
+	b := r.next(8)
+	if b == nil {
+		return 0
+	}
+
+	return math.Float64frombits(binary.LittleEndian.Uint64(b))
+}
+
+func (r *Reader) Float() float32 {
+	if !r.expect(Fixed32) {
+		return 0
+	}
+
+	b := r.next(4)
+	if b == nil {
+		return 0
+	}
+
+	return math.Float32frombits(binary.LittleEndian.Uint32(b))
+}
+
+// Bytes returns the contents of a length-delimited field. The returned slice
+// aliases the message.
+func (r *Reader) Bytes() []byte {
+	if !r.expect(LengthDelimited) {
+		return nil
+	}
+
+	n := r.readVarint()
+	if n > uint64(len(r.buf)) {
+		r.fail(errTruncated)
+		return nil
+	}
+
+	return r.next(int(n))
+}
+
+func (r *Reader) String() string {
+	return string(r.Bytes())
+}
+
+// Uint64s appends the values of a repeated varint field, accepting both the
+// packed and the unpacked encoding.
+func (r *Reader) Uint64s(v []uint64) []uint64 {
+	if r.wireType == Varint {
+		return append(v, r.Uint64())
+	}
+
+	p := &Reader{buf: r.Bytes(), wireType: Varint}
+	for len(p.buf) > 0 && p.err == nil {
+		v = append(v, p.readVarint())
+	}
+
+	if p.err != nil {
+		r.fail(p.err)
+	}
+
+	return v
+}
+
+// Sint64s appends the values of a repeated zigzag varint field.
+func (r *Reader) Sint64s(v []int64) []int64 {
+	for _, e := range r.Uint64s(nil) {
+		v = append(v, DecodeZigZag(e))
+	}
+
+	return v
+}
+
+// Uint32s appends the values of a repeated varint field.
+func (r *Reader) Uint32s(v []uint32) []uint32 {
+	for _, e := range r.Uint64s(nil) {
+		v = append(v, uint32(e))
+	}
+
+	return v
+}
+
+// Skip skips over the current field.
+func (r *Reader) Skip() {
+	switch r.wireType {
+	case Varint:
+		r.readVarint()
+	case Fixed64:
+		r.next(8)
+	case LengthDelimited:
+		r.Bytes()
+	case Fixed32:
+		r.next(4)
+	default:
+		r.fail(fmt.Errorf("pbf: unsupported wire type %d", r.wireType))
+	}
+}
@@ -0,0 +1,372 @@
+package wkt
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/topos-ai/geoutil"
+)
+
+// parser is a recursive descent parser over the tokens of a WKT string.
+type parser struct {
+	tokens []string
+	i      int
+}
+
+func tokenize(s string) []string {
+	tokens := []string{}
+	start := -1
+	for i, c := range s {
+		switch {
+		case c == '(' || c == ')' || c == ',':
+			if start >= 0 {
+				tokens = append(tokens, s[start:i])
+				start = -1
+			}
+
+			tokens = append(tokens, string(c))
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			if start >= 0 {
+				tokens = append(tokens, s[start:i])
+				start = -1
+			}
+		default:
+			if start < 0 {
+				start = i
+			}
+		}
+	}
+
+	if start >= 0 {
+		tokens = append(tokens, s[start:])
+	}
+
+	return tokens
+}
+
+func (p *parser) peek() string {
+	if p.i < len(p.tokens) {
+		return p.tokens[p.i]
+	}
+
+	return ""
+}
+
+func (p *parser) next() string {
+	token := p.peek()
+	if p.i < len(p.tokens) {
+		p.i++
+	}
+
+	return token
+}
+
+func (p *parser) expect(token string) error {
+	if t := p.next(); t != token {
+		if t == "" {
+			return fmt.Errorf("wkt: expected %s, found end of input", token)
+		}
+
+		return fmt.Errorf("wkt: expected %s, found %s", token, t)
+	}
+
+	return nil
+}
+
+// empty consumes the EMPTY keyword, reporting whether it was present.
+func (p *parser) empty() bool {
+	if strings.EqualFold(p.peek(), "EMPTY") {
+		p.i++
+		return true
+	}
+
+	return false
+}
+
+// position parses the numbers of a position, keeping the longitude and
+// latitude, which must be in range.
+func (p *parser) position() ([]float64, error) {
+	position := []float64{}
+	for {
+		token := p.peek()
+		if token == "" || token == "," || token == ")" {
+			break
+		}
+
+		v, err := strconv.ParseFloat(token, 64)
+		if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+			return nil, fmt.Errorf("wkt: invalid coordinate %s", token)
+		}
+
+		position = append(position, v)
+		p.i++
+	}
+
+	if len(position) < 2 || len(position) > 4 {
+		return nil, fmt.Errorf("wkt: cannot process position with dimension %d", len(position))
+	}
+
+	if math.Abs(position[0]) > 180 || math.Abs(position[1]) > 90 {
+		return nil, fmt.Errorf("wkt: position %v out of range", position[:2])
+	}
+
+	return position[:2], nil
+}
+
+// list parses a parenthesized, comma separated list, or EMPTY.
+func (p *parser) list(item func() error) error {
+	if p.empty() {
+		return nil
+	}
+
+	if err := p.expect("("); err != nil {
+		return err
+	}
+
+	for {
+		if err := item(); err != nil {
+			return err
+		}
+
+		if p.peek() != "," {
+			break
+		}
+
+		p.i++
+	}
+
+	return p.expect(")")
+}
+
+func (p *parser) line() ([][]float64, error) {
+	line := [][]float64{}
+	err := p.list(func() error {
+		position, err := p.position()
+		if err != nil {
+			return err
+		}
+
+		line = append(line, position)
+		return nil
+	})
+
+	return line, err
+}
+
+func (p *parser) lines() ([][][]float64, error) {
+	lines := [][][]float64{}
+	err := p.list(func() error {
+		line, err := p.line()
+		if err != nil {
+			return err
+		}
+
+		lines = append(lines, line)
+		return nil
+	})
+
+	return lines, err
+}
+
+// multiPoint parses the points of a MULTIPOINT, which may or may not be
+// parenthesized individually.
+func (p *parser) multiPoint() ([][]float64, error) {
+	points := [][]float64{}
+	err := p.list(func() error {
+		if p.peek() != "(" {
+			position, err := p.position()
+			if err != nil {
+				return err
+			}
+
+			points = append(points, position)
+			return nil
+		}
+
+		line, err := p.line()
+		if err != nil {
+			return err
+		}
+
+		if len(line) != 1 {
+			return fmt.Errorf("wkt: MULTIPOINT member with %d positions", len(line))
+		}
+
+		points = append(points, line[0])
+		return nil
+	})
+
+	return points, err
+}
+
+// geometryType parses the geometry tag and its optional dimension, such as
+// POINT Z or POINTZM.
+func (p *parser) geometryType() string {
+	tag := strings.ToUpper(p.next())
+	for _, suffix := range []string{"ZM", "Z", "M"} {
+		if !strings.HasSuffix(tag, suffix) {
+			continue
+		}
+
+		switch t := strings.TrimSuffix(tag, suffix); t {
+		case "POINT", "LINESTRING", "POLYGON", "MULTIPOINT", "MULTILINESTRING", "MULTIPOLYGON":
+			return t
+		}
+	}
+
+	switch strings.ToUpper(p.peek()) {
+	case "Z", "M", "ZM":
+		p.i++
+	}
+
+	return tag
+}
+
+func (p *parser) geometry() (interface{}, error) {
+	switch tag := p.geometryType(); tag {
+	case "POINT":
+		line, err := p.line()
+		if err != nil {
+			return nil, err
+		}
+
+		switch len(line) {
+		case 0:
+			return nil, nil
+		case 1:
+			return geoutil.PointFromPointCoordinates(line[0])
+		default:
+			return nil, fmt.Errorf("wkt: POINT with %d positions", len(line))
+		}
+
+	case "LINESTRING":
+		line, err := p.line()
+		if err != nil {
+			return nil, err
+		}
+
+		return geoutil.PolylineFromLineStringCoordinates(line)
+
+	case "POLYGON":
+		rings, err := p.lines()
+		if err != nil {
+			return nil, err
+		}
+
+		polygons, err := nonEmptyPolygons([][][][]float64{rings})
+		if err != nil {
+			return nil, err
+		}
+
+		return geoutil.PolygonFromMultiPolygonCoordinates(polygons)
+
+	case "MULTIPOINT":
+		points, err := p.multiPoint()
+		if err != nil {
+			return nil, err
+		}
+
+		return geoutil.PointsFromMultiPointCoordinates(points)
+
+	case "MULTILINESTRING":
+		lines, err := p.lines()
+		if err != nil {
+			return nil, err
+		}
+
+		return geoutil.PolylinesFromMultiLineStringCoordinates(lines)
+
+	case "MULTIPOLYGON":
+		polygons := [][][][]float64{}
+		err := p.list(func() error {
+			rings, err := p.lines()
+			if err != nil {
+				return err
+			}
+
+			polygons = append(polygons, rings)
+			return nil
+		})
+
+		if err != nil {
+			return nil, err
+		}
+
+		polygons, err = nonEmptyPolygons(polygons)
+		if err != nil {
+			return nil, err
+		}
+
+		return geoutil.PolygonFromMultiPolygonCoordinates(polygons)
+
+	case "":
+		return nil, fmt.Errorf("wkt: empty input")
+
+	default:
+		return nil, fmt.Errorf("wkt: unsupported geometry type %s", tag)
+	}
+}
+
+// nonEmptyPolygons drops empty polygons and rings, which cannot be
+// represented by s2 loops. Other rings must hold at least four positions and
+// end where they start.
+func nonEmptyPolygons(polygons [][][][]float64) ([][][][]float64, error) {
+	result := [][][][]float64{}
+	for _, polygon := range polygons {
+		rings := [][][]float64{}
+		for _, ring := range polygon {
+			if len(ring) == 0 {
+				continue
+			}
+
+			if len(ring) < 4 {
+				return nil, fmt.Errorf("wkt: ring with %d positions", len(ring))
+			}
+
+			if first, last := ring[0], ring[len(ring)-1]; first[0] != last[0] || first[1] != last[1] {
+				return nil, fmt.Errorf("wkt: ring is not closed")
+			}
+
+			rings = append(rings, ring)
+		}
+
+		if len(rings) > 0 {
+			result = append(result, rings)
+		}
+	}
+
+	return result, nil
+}
+
+// Unmarshal parses a WKT or EWKT geometry into one of the s2 types used by
+// geoutil: s2.Point, []s2.Point, *s2.Polyline, []*s2.Polyline or
+// *s2.Polygon. POINT EMPTY yields nil. Z and M values are dropped, and the
+// SRID of EWKT is ignored.
+func Unmarshal(data []byte) (interface{}, error) {
+	s := strings.TrimSpace(string(data))
+	if strings.HasPrefix(strings.ToUpper(s), "SRID=") {
+		i := strings.IndexByte(s, ';')
+		if i < 0 {
+			return nil, fmt.Errorf("wkt: invalid EWKT SRID prefix")
+		}
+
+		s = s[i+1:]
+	}
+
+	p := &parser{
+		tokens: tokenize(s),
+	}
+
+	geometry, err := p.geometry()
+	if err != nil {
+		return nil, err
+	}
+
+	if token := p.peek(); token != "" {
+		return nil, fmt.Errorf("wkt: unexpected %s after geometry", token)
+	}
+
+	return geometry, nil
+}
@@ -0,0 +1,159 @@
+// Package wkt reads and writes the Well-Known Text representation of
+// geometries.
+package wkt
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/golang/geo/s2"
+
+	"github.com/topos-ai/geoutil"
+)
+
+func writePosition(b *strings.Builder, position []float64) {
+	b.WriteString(strconv.FormatFloat(position[0], 'f', -1, 64))
+	b.WriteString(" ")
+	b.WriteString(strconv.FormatFloat(position[1], 'f', -1, 64))
+}
+
+func writeLine(b *strings.Builder, line [][]float64) {
+	if len(line) == 0 {
+		b.WriteString("EMPTY")
+		return
+	}
+
+	b.WriteString("(")
+	for i, position := range line {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+
+		writePosition(b, position)
+	}
+
+	b.WriteString(")")
+}
+
+func writeLines(b *strings.Builder, lines [][][]float64) {
+	if len(lines) == 0 {
+		b.WriteString("EMPTY")
+		return
+	}
+
+	b.WriteString("(")
+	for i, line := range lines {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+
+		writeLine(b, line)
+	}
+
+	b.WriteString(")")
+}
+
+func encode(b *strings.Builder, v interface{}, precision int) error {
+	switch geometry := v.(type) {
+	case s2.LatLng:
+		return encode(b, s2.PointFromLatLng(geometry), precision)
+
+	case s2.Point:
+		coords, err := geoutil.PointCoordinates(geometry, precision)
+		if err != nil {
+			return err
+		}
+
+		b.WriteString("POINT ")
+		writeLine(b, [][]float64{coords})
+
+	case []s2.Point:
+		points := make([][][]float64, len(geometry))
+		for i, point := range geometry {
+			coords, err := geoutil.PointCoordinates(point, precision)
+			if err != nil {
+				return err
+			}
+
+			points[i] = [][]float64{coords}
+		}
+
+		b.WriteString("MULTIPOINT ")
+		writeLines(b, points)
+
+	case *s2.Polyline:
+		coords, err := geoutil.PolylineCoordinates(geometry, precision)
+		if err != nil {
+			return err
+		}
+
+		b.WriteString("LINESTRING ")
+		writeLine(b, coords)
+
+	case []*s2.Polyline:
+		lines := make([][][]float64, len(geometry))
+		for i, polyline := range geometry {
+			coords, err := geoutil.PolylineCoordinates(polyline, precision)
+			if err != nil {
+				return err
+			}
+
+			lines[i] = coords
+		}
+
+		b.WriteString("MULTILINESTRING ")
+		writeLines(b, lines)
+
+	case *s2.Polygon:
+		polygonCoordinates, err := geoutil.PolygonCoordinates(geometry, precision)
+		if err != nil {
+			return err
+		}
+
+		if len(polygonCoordinates) == 1 {
+			b.WriteString("POLYGON ")
+			writeLines(b, polygonCoordinates[0])
+			return nil
+		}
+
+		b.WriteString("MULTIPOLYGON ")
+		if len(polygonCoordinates) == 0 {
+			b.WriteString("EMPTY")
+			return nil
+		}
+
+		b.WriteString("(")
+		for i, polygonCoords := range polygonCoordinates {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+
+			writeLines(b, polygonCoords)
+		}
+
+		b.WriteString(")")
+
+	default:
+		return fmt.Errorf("wkt: unknown geometry type %T", v)
+	}
+
+	return nil
+}
+
+// Marshal returns the WKT representation of a geometry with coordinates
+// rounded to a geoutil precision level. Polygons with a single shell are
+// written as POLYGON and polygons with several shells as MULTIPOLYGON. A nil
+// geometry is written as POINT EMPTY.
+func Marshal(v interface{}, precision int) ([]byte, error) {
+	if v == nil {
+		return []byte("POINT EMPTY"), nil
+	}
+
+	b := &strings.Builder{}
+	if err := encode(b, v, precision); err != nil {
+		return nil, err
+	}
+
+	return []byte(b.String()), nil
+}
@@ -0,0 +1,118 @@
+package wkt
+
+import (
+	"testing"
+
+	"github.com/golang/geo/s2"
+)
+
+func TestRoundTrip(t *testing.T) {
+	tests := []string{
+		"POINT (1 2)",
+		"MULTIPOINT ((1 2), (3 4))",
+		"LINESTRING (0 0, 1 1, 2 0)",
+		"MULTILINESTRING ((0 0, 1 1), (2 2, 3 3))",
+		"POLYGON ((0 0, 10 0, 10 10, 0 10, 0 0), (2 2, 2 4, 4 4, 4 2, 2 2))",
+		"MULTIPOLYGON (((0 0, 1 0, 1 1, 0 1, 0 0)), ((5 5, 6 5, 6 6, 5 6, 5 5)))",
+	}
+
+	for _, test := range tests {
+		geometry, err := Unmarshal([]byte(test))
+		if err != nil {
+			t.Errorf("%s: %v", test, err)
+			continue
+		}
+
+		data, err := Marshal(geometry, 2)
+		if err != nil {
+			t.Errorf("%s: %v", test, err)
+			continue
+		}
+
+		if string(data) != test {
+			t.Errorf("%s: encoded %s", test, data)
+		}
+	}
+}
+
+func TestUnmarshal(t *testing.T) {
+	tests := map[string]string{
+		"SRID=4326;POINT(1 2)":                         "POINT (1 2)",
+		"point z (1 2 3)":                              "POINT (1 2)",
+		"POINTZM (1 2 3 4)":                            "POINT (1 2)",
+		"MULTIPOINT (1 2, 3 4)":                        "MULTIPOINT ((1 2), (3 4))",
+		"POLYGON ((0 0, 1 0, 1 1, 0 1, 0 0), EMPTY)":   "POLYGON ((0 0, 1 0, 1 1, 0 1, 0 0))",
+		"MULTIPOLYGON (EMPTY, ((0 0, 1 0, 1 1, 0 0)))": "POLYGON ((0 0, 1 0, 1 1, 0 0))",
+	}
+
+	for input, want := range tests {
+		geometry, err := Unmarshal([]byte(input))
+		if err != nil {
+			t.Errorf("%s: %v", input, err)
+			continue
+		}
+
+		if data, _ := Marshal(geometry, 2); string(data) != want {
+			t.Errorf("%s: decoded %s, want %s", input, data, want)
+		}
+	}
+
+	geometry, err := Unmarshal([]byte("POINT EMPTY"))
+	if err != nil || geometry != nil {
+		t.Errorf("POINT EMPTY: decoded %v, %v", geometry, err)
+	}
+}
+
+func TestUnmarshalErrors(t *testing.T) {
+	tests := []string{
+		"",
+		"CIRCLE (0 0)",
+		"POINT (1)",
+		"POINT (1 2, 3 4)",
+		"POINT (NaN 1)",
+		"POINT (Inf 1)",
+		"POINT (1 91)",
+		"POINT (1 2) POINT (3 4)",
+		"SRID=4326 POINT (1 2)",
+		"POLYGON ((0 1), (0 1))",
+		"POLYGON ((0 0, 1 0, 0 0))",
+		"POLYGON ((0 0, 1 0, 1 1, 0 1))",
+		"MULTIPOLYGON (((0 0, 1 0, 1 1, 0 0)), ((5 5, 6 5)))",
+	}
+
+	for _, test := range tests {
+		if geometry, err := Unmarshal([]byte(test)); err == nil {
+			t.Errorf("%q: decoded %v, want an error", test, geometry)
+		}
+	}
+}
+
+func TestMarshal(t *testing.T) {
+	point := s2.PointFromLatLng(s2.LatLngFromDegrees(1, 2))
+	tests := []struct {
+		geometry  interface{}
+		precision int
+		want      string
+	}{
+		{nil, 0, "POINT EMPTY"},
+		{point, 2, "POINT (2 1)"},
+		{s2.LatLngFromDegrees(1, 2), 2, "POINT (2 1)"},
+		{&s2.Polygon{}, 2, "MULTIPOLYGON EMPTY"},
+	}
+
+	for _, test := range tests {
+		data, err := Marshal(test.geometry, test.precision)
+		if err != nil {
+			t.Errorf("%v: %v", test.geometry, err)
+			continue
+		}
+
+		if string(data) != test.want {
+			t.Errorf("%v: encoded %s, want %s", test.geometry, data, test.want)
+		}
+	}
+
+	if _, err := Marshal(1, 0); err == nil {
+		t.Errorf("encoding an unknown geometry returned no error")
+	}
+}
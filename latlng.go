@@ -0,0 +1,460 @@
+package geoutil
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/golang/geo/s2"
+)
+
+// LatLngFormat identifies a notation of coordinates.
+type LatLngFormat int
+
+const (
+	// FormatDecimal formats coordinates as signed decimal degrees, latitude
+	// first, such as "40.446195, -79.982195".
+	FormatDecimal LatLngFormat = iota
+
+	// FormatDMS formats coordinates as degrees, minutes and decimal seconds,
+	// such as 40°26'46.302"N 79°58'55.902"W.
+	FormatDMS
+
+	// FormatDDM formats coordinates as degrees and decimal minutes, such as
+	// 40°26.7717'N 79°58.9317'W.
+	FormatDDM
+)
+
+type coordinateTokenType int
+
+const (
+	coordinateNumber coordinateTokenType = iota
+	coordinateUnit
+	coordinateHemisphere
+	coordinateSeparator
+)
+
+// Units of coordinate components.
+const (
+	unitNone = iota
+	unitDegrees
+	unitMinutes
+	unitSeconds
+)
+
+type coordinateToken struct {
+	typ   coordinateTokenType
+	text  string
+	value byte
+}
+
+// lexCoordinates splits a coordinate string into numbers, unit symbols,
+// hemisphere letters and separators.
+func lexCoordinates(s string) ([]coordinateToken, error) {
+	tokens := []coordinateToken{}
+	runes := []rune(s)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == ',' || r == ';' || r == '/':
+			tokens = append(tokens, coordinateToken{typ: coordinateSeparator})
+			i++
+		case r == '°' || r == 'º' || r == '˚':
+			tokens = append(tokens, coordinateToken{typ: coordinateUnit, value: unitDegrees})
+			i++
+		case r == '\'' && i+1 < len(runes) && runes[i+1] == '\'':
+			tokens = append(tokens, coordinateToken{typ: coordinateUnit, value: unitSeconds})
+			i += 2
+		case r == '\'' || r == '′' || r == '’':
+			tokens = append(tokens, coordinateToken{typ: coordinateUnit, value: unitMinutes})
+			i++
+		case r == '"' || r == '″' || r == '”':
+			tokens = append(tokens, coordinateToken{typ: coordinateUnit, value: unitSeconds})
+			i++
+		case strings.ContainsRune("NSEWnsew", r):
+			tokens = append(tokens, coordinateToken{typ: coordinateHemisphere, value: byte(unicode.ToUpper(r))})
+			i++
+		case r == '-' || r == '+' || r == '−' || r == '.' || unicode.IsDigit(r):
+			j := i
+			text := ""
+			if r == '-' || r == '+' || r == '−' {
+				if r != '+' {
+					text = "-"
+				}
+
+				j++
+			}
+
+			start := j
+			for j < len(runes) && (runes[j] == '.' || unicode.IsDigit(runes[j])) {
+				j++
+			}
+
+			if j == start {
+				return nil, fmt.Errorf("geoutil: invalid sign in coordinates %q", s)
+			}
+
+			text += string(runes[start:j])
+			tokens = append(tokens, coordinateToken{typ: coordinateNumber, text: text})
+			i = j
+		default:
+			return nil, fmt.Errorf("geoutil: invalid character %q in coordinates %q", r, s)
+		}
+	}
+
+	return tokens, nil
+}
+
+// coordinateComponent is one coordinate as written: up to three numbers with
+// optional units and an optional hemisphere.
+type coordinateComponent struct {
+	numbers    []coordinateToken
+	hemisphere byte
+}
+
+// splitCoordinates groups tokens into the latitude and longitude components.
+// Hemisphere letters delimit components if present, then separators, then
+// degree symbols; otherwise the numbers are split in half.
+func splitCoordinates(tokens []coordinateToken) ([]*coordinateComponent, error) {
+	hemispheres, separators, degrees, numbers := 0, 0, 0, 0
+	for _, token := range tokens {
+		switch token.typ {
+		case coordinateHemisphere:
+			hemispheres++
+		case coordinateSeparator:
+			separators++
+		case coordinateUnit:
+			if token.value == unitDegrees {
+				degrees++
+			}
+		case coordinateNumber:
+			numbers++
+		}
+	}
+
+	components := []*coordinateComponent{}
+	current := &coordinateComponent{}
+	flush := func() {
+		if len(current.numbers) > 0 || current.hemisphere != 0 {
+			components = append(components, current)
+		}
+
+		current = &coordinateComponent{}
+	}
+
+	leading := len(tokens) > 0 && tokens[0].typ == coordinateHemisphere
+	for i, token := range tokens {
+		switch token.typ {
+		case coordinateHemisphere:
+			if leading {
+				flush()
+				current.hemisphere = token.value
+			} else {
+				current.hemisphere = token.value
+				flush()
+			}
+
+		case coordinateSeparator:
+			if hemispheres == 0 {
+				flush()
+			}
+
+		case coordinateUnit:
+			if len(current.numbers) == 0 {
+				return nil, fmt.Errorf("geoutil: unit without a number in coordinates")
+			}
+
+			last := &current.numbers[len(current.numbers)-1]
+			if last.value != unitNone {
+				return nil, fmt.Errorf("geoutil: repeated unit in coordinates")
+			}
+
+			last.value = token.value
+
+		case coordinateNumber:
+
+			// A number followed by a degree symbol starts a new component
+			// when degree symbols delimit components.
+			if hemispheres == 0 && separators == 0 && degrees == 2 && i+1 < len(tokens) &&
+				tokens[i+1].typ == coordinateUnit && tokens[i+1].value == unitDegrees {
+				flush()
+			}
+
+			current.numbers = append(current.numbers, token)
+		}
+	}
+
+	flush()
+
+	// Without any delimiter, the numbers are split in half.
+	if hemispheres == 0 && separators == 0 && degrees != 2 && len(components) == 1 {
+		if numbers%2 == 1 {
+			return nil, fmt.Errorf("geoutil: cannot split an odd number of values into coordinates")
+		}
+
+		all := components[0].numbers
+		components = []*coordinateComponent{
+			{numbers: all[:numbers/2]},
+			{numbers: all[numbers/2:]},
+		}
+	}
+
+	if len(components) != 2 {
+		return nil, fmt.Errorf("geoutil: found %d coordinates instead of 2", len(components))
+	}
+
+	return components, nil
+}
+
+// degrees returns the signed value in degrees of a component.
+func (c *coordinateComponent) degrees() (float64, error) {
+	if n := len(c.numbers); n == 0 || n > 3 {
+		return 0, fmt.Errorf("geoutil: invalid coordinate with %d numbers", n)
+	}
+
+	value, negative := 0.0, false
+	for i, number := range c.numbers {
+		unit := int(number.value)
+		if unit == unitNone {
+			unit = unitDegrees + i
+		}
+
+		if unit != unitDegrees+i {
+			return 0, fmt.Errorf("geoutil: unexpected unit in coordinate %s", number.text)
+		}
+
+		text := number.text
+		if strings.HasPrefix(text, "-") {
+			if i > 0 {
+				return 0, fmt.Errorf("geoutil: negative minutes or seconds %s", text)
+			}
+
+			negative = true
+			text = text[1:]
+		}
+
+		v, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return 0, fmt.Errorf("geoutil: invalid number %s in coordinate", number.text)
+		}
+
+		if i < len(c.numbers)-1 && v != math.Trunc(v) {
+			return 0, fmt.Errorf("geoutil: fractional value %s followed by minutes or seconds", number.text)
+		}
+
+		if i > 0 && v >= 60 {
+			return 0, fmt.Errorf("geoutil: minutes or seconds %s exceed 60", number.text)
+		}
+
+		value += v / math.Pow(60, float64(i))
+	}
+
+	if c.hemisphere == 'S' || c.hemisphere == 'W' {
+		if negative {
+			return 0, fmt.Errorf("geoutil: negative coordinate with hemisphere %c", c.hemisphere)
+		}
+
+		negative = true
+	}
+
+	if negative {
+		value = -value
+	}
+
+	return value, nil
+}
+
+// isLatitude reports whether the hemisphere of a component denotes a
+// latitude.
+func (c *coordinateComponent) isLatitude() bool {
+	return c.hemisphere == 'N' || c.hemisphere == 'S'
+}
+
+// ParseLatLng parses coordinates in decimal degrees, degrees and decimal
+// minutes, or degrees, minutes and seconds, such as 40°26'46"N 79°58'56"W,
+// "N 40 26.767 W 79 58.933" or "40.446, -79.982". Hemisphere letters determine
+// the order of the coordinates; otherwise latitude is expected first unless
+// only the first coordinate exceeds 90 degrees.
+func ParseLatLng(s string) (s2.LatLng, error) {
+	tokens, err := lexCoordinates(s)
+	if err != nil {
+		return s2.LatLng{}, err
+	}
+
+	components, err := splitCoordinates(tokens)
+	if err != nil {
+		return s2.LatLng{}, err
+	}
+
+	first, err := components[0].degrees()
+	if err != nil {
+		return s2.LatLng{}, err
+	}
+
+	second, err := components[1].degrees()
+	if err != nil {
+		return s2.LatLng{}, err
+	}
+
+	lat, lng := first, second
+	switch a, b := components[0], components[1]; {
+	case a.hemisphere != 0 && b.hemisphere != 0:
+		if a.isLatitude() == b.isLatitude() {
+			return s2.LatLng{}, fmt.Errorf("geoutil: coordinates %q have two latitudes or two longitudes", s)
+		}
+
+		if !a.isLatitude() {
+			lat, lng = second, first
+		}
+
+	case a.hemisphere != 0 || b.hemisphere != 0:
+		return s2.LatLng{}, fmt.Errorf("geoutil: coordinates %q have a single hemisphere", s)
+	case math.Abs(first) > 90 && math.Abs(second) <= 90:
+		lat, lng = second, first
+	}
+
+	if math.Abs(lat) > 90 {
+		return s2.LatLng{}, fmt.Errorf("geoutil: latitude %g is out of range", lat)
+	}
+
+	if math.Abs(lng) > 180 {
+		return s2.LatLng{}, fmt.Errorf("geoutil: longitude %g is out of range", lng)
+	}
+
+	return s2.LatLngFromDegrees(lat, lng), nil
+}
+
+// decimalPlaces returns the number of decimal places in degrees of a
+// precision level, or -1 for the shortest representation.
+func decimalPlaces(precision int) (int, error) {
+	switch precision {
+	case PrecisionMax:
+		return -1, nil
+	case PrecisionE5:
+		return 5, nil
+	case PrecisionE6:
+		return 6, nil
+	case PrecisionE7:
+		return 7, nil
+	default:
+		return 0, fmt.Errorf("geoutil: invalid precision level %d", precision)
+	}
+}
+
+// Decimal places of seconds and minutes at PrecisionMax, which are enough to
+// represent any coordinate to within a micrometer without exposing the noise
+// of the conversion from degrees.
+const (
+	maxSecondsPlaces = 6
+	maxMinutesPlaces = 8
+)
+
+// formatDecimal formats a coordinate in decimal degrees rounded to the given
+// number of decimal places, or to the shortest representation if places is
+// negative. Values rounded to zero are formatted without a sign.
+func formatDecimal(degrees float64, places int) string {
+	if places >= 0 {
+		scale := math.Pow(10, float64(places))
+		degrees = math.Round(degrees*scale) / scale
+	}
+
+	if degrees == 0 {
+		degrees = 0
+	}
+
+	return strconv.FormatFloat(degrees, 'f', places, 64)
+}
+
+// formatSexagesimal formats the absolute value of a coordinate in degrees
+// and minutes, and in seconds if seconds is true, rounded to the given
+// number of decimal places of the last component. If places is negative,
+// the last component is rounded to a fixed number of decimal places and
+// trailing zeros are trimmed. It also reports whether the value rounds to
+// zero.
+func formatSexagesimal(degrees float64, seconds bool, places int) (string, bool) {
+	divisions := 60.0
+	trim := places < 0
+	switch {
+	case trim && seconds:
+		places = maxSecondsPlaces
+	case trim:
+		places = maxMinutesPlaces
+	}
+
+	if seconds {
+		divisions = 3600
+	}
+
+	// Round in integer units of the last decimal place, so that values such
+	// as 59.9999 seconds carry into minutes.
+	scale := int64(math.Pow(10, float64(places)))
+	units := int64(math.Round(degrees * divisions * float64(scale)))
+	zero := units == 0
+	last := units % (60 * scale)
+	units /= 60 * scale
+	lastText := fmt.Sprintf("%0*d", 2, last/scale)
+	if fraction := fmt.Sprintf("%0*d", places, last%scale); trim {
+		if fraction = strings.TrimRight(fraction, "0"); fraction != "" {
+			lastText += "." + fraction
+		}
+	} else if places > 0 {
+		lastText += "." + fraction
+	}
+
+	if !seconds {
+		return fmt.Sprintf("%d°%s'", units, lastText), zero
+	}
+
+	return fmt.Sprintf("%d°%02d'%s\"", units/60, units%60, lastText), zero
+}
+
+// FormatLatLng formats coordinates in a notation, rounded to a precision
+// level. The precision level applies to degrees: minutes and seconds have
+// one and three fewer decimal places, respectively. At PrecisionMax, decimal
+// degrees have the shortest representation, and minutes and seconds are
+// rounded to 8 and 6 decimal places, respectively, with trailing zeros
+// trimmed. Coordinates rounded to zero are north and east.
+func FormatLatLng(latLng s2.LatLng, format LatLngFormat, precision int) (string, error) {
+	places, err := decimalPlaces(precision)
+	if err != nil {
+		return "", err
+	}
+
+	latLng = latLng.Normalized()
+	lat, lng := latLng.Lat.Degrees(), latLng.Lng.Degrees()
+	if format == FormatDecimal {
+		return formatDecimal(lat, places) + ", " + formatDecimal(lng, places), nil
+	}
+
+	seconds := false
+	switch format {
+	case FormatDMS:
+		seconds = true
+		if places >= 0 {
+			places -= 3
+		}
+	case FormatDDM:
+		if places >= 0 {
+			places--
+		}
+	default:
+		return "", fmt.Errorf("geoutil: invalid coordinate format %d", format)
+	}
+
+	latText, latZero := formatSexagesimal(math.Abs(lat), seconds, places)
+	lngText, lngZero := formatSexagesimal(math.Abs(lng), seconds, places)
+	latHemisphere, lngHemisphere := 'N', 'E'
+	if lat < 0 && !latZero {
+		latHemisphere = 'S'
+	}
+
+	if lng < 0 && !lngZero {
+		lngHemisphere = 'W'
+	}
+
+	return fmt.Sprintf("%s%c %s%c", latText, latHemisphere, lngText, lngHemisphere), nil
+}
@@ -0,0 +1,146 @@
+package geoutil
+
+import (
+	"math"
+	"testing"
+
+	"github.com/golang/geo/s2"
+)
+
+func TestParseLatLng(t *testing.T) {
+	tests := []struct {
+		s        string
+		lat, lng float64
+	}{
+		{"40.446195, -79.982195", 40.446195, -79.982195},
+		{"40.446195 -79.982195", 40.446195, -79.982195},
+		{"-179.982195, 40.446195", 40.446195, -179.982195},
+		{`40°26'46.302"N 79°58'55.902"W`, 40.446195, -79.982195},
+		{`79°58'55.902"W 40°26'46.302"N`, 40.446195, -79.982195},
+		{"40°26.7717'N 79°58.9317'W", 40.446195, -79.982195},
+		{"N 40 26.7717 W 79 58.9317", 40.446195, -79.982195},
+		{"40 26 46.302 -79 58 55.902", 40.446195, -79.982195},
+		{"40° 26′ 46.302″ N, 79° 58′ 55.902″ W", 40.446195, -79.982195},
+		{"40°26'46.302''s 79°58'55.902''e", -40.446195, 79.982195},
+		{"40° -79°", 40, -79},
+		{"0; 180", 0, 180},
+	}
+
+	for _, test := range tests {
+		latLng, err := ParseLatLng(test.s)
+		if err != nil {
+			t.Errorf("%s: %v", test.s, err)
+			continue
+		}
+
+		if math.Abs(latLng.Lat.Degrees()-test.lat) > 1e-9 || math.Abs(latLng.Lng.Degrees()-test.lng) > 1e-9 {
+			t.Errorf("%s: parsed %v, want %v, %v", test.s, latLng, test.lat, test.lng)
+		}
+	}
+
+	invalid := []string{
+		"",
+		"40.4",
+		"40.4, -79.9, 1",
+		"1 2 3",
+		"40.4N 79.9N",
+		"40.4N -79.9",
+		"-40.4S 79.9W",
+		"91, 95",
+		"0, 181",
+		"95, 181",
+		"40.5°26' 79°58'",
+		"40°61' 79°58'",
+		"40°-26' 79°58'",
+		"40' 79'",
+		"40°° 79°",
+		"40.4x, 79.9",
+		"40.4.4, 79.9",
+	}
+
+	for _, s := range invalid {
+		if _, err := ParseLatLng(s); err == nil {
+			t.Errorf("%q: no error", s)
+		}
+	}
+}
+
+func TestFormatLatLng(t *testing.T) {
+	pittsburgh := s2.LatLngFromDegrees(40.446195, -79.982195)
+	lyon := s2.LatLngFromDegrees(45.76, 4.76)
+	tests := []struct {
+		latLng    s2.LatLng
+		format    LatLngFormat
+		precision int
+		want      string
+	}{
+		{pittsburgh, FormatDecimal, PrecisionMax, "40.446195, -79.982195"},
+		{pittsburgh, FormatDecimal, PrecisionE5, "40.44620, -79.98220"},
+		{pittsburgh, FormatDecimal, PrecisionE7, "40.4461950, -79.9821950"},
+		{pittsburgh, FormatDMS, PrecisionMax, `40°26'46.302"N 79°58'55.902"W`},
+		{pittsburgh, FormatDMS, PrecisionE5, `40°26'46.30"N 79°58'55.90"W`},
+		{pittsburgh, FormatDMS, PrecisionE7, `40°26'46.3020"N 79°58'55.9020"W`},
+		{pittsburgh, FormatDDM, PrecisionMax, "40°26.7717'N 79°58.9317'W"},
+		{pittsburgh, FormatDDM, PrecisionE6, "40°26.77170'N 79°58.93170'W"},
+
+		// Seconds and minutes at PrecisionMax are free of the noise of the
+		// conversion from degrees.
+		{lyon, FormatDMS, PrecisionMax, `45°45'36"N 4°45'36"E`},
+		{lyon, FormatDDM, PrecisionMax, "45°45.6'N 4°45.6'E"},
+		{s2.LatLngFromDegrees(-33.8568, 151.2153), FormatDMS, PrecisionMax, `33°51'24.48"S 151°12'55.08"E`},
+
+		// Rounding carries into minutes and degrees.
+		{s2.LatLngFromDegrees(10.9999999, -20.99999999), FormatDMS, PrecisionE5, `11°00'00.00"N 21°00'00.00"W`},
+		{s2.LatLngFromDegrees(10.9999999, -20.99999999), FormatDDM, PrecisionE5, "11°00.0000'N 21°00.0000'W"},
+
+		// Coordinates rounded to zero have no sign and are north and east.
+		{s2.LatLngFromDegrees(0, math.Copysign(0, -1)), FormatDecimal, PrecisionMax, "0, 0"},
+		{s2.LatLngFromDegrees(0, math.Copysign(0, -1)), FormatDMS, PrecisionMax, `0°00'00"N 0°00'00"E`},
+		{s2.LatLngFromDegrees(-1e-7, -1e-10), FormatDecimal, PrecisionE5, "0.00000, 0.00000"},
+		{s2.LatLngFromDegrees(-1e-7, -1e-10), FormatDMS, PrecisionE5, `0°00'00.00"N 0°00'00.00"E`},
+		{s2.LatLngFromDegrees(-1e-7, -1e-11), FormatDDM, PrecisionMax, "0°00.000006'S 0°00'E"},
+	}
+
+	for _, test := range tests {
+		s, err := FormatLatLng(test.latLng, test.format, test.precision)
+		if err != nil {
+			t.Errorf("%v: %v", test.latLng, err)
+			continue
+		}
+
+		if s != test.want {
+			t.Errorf("%v, format %d, precision %d: formatted %s, want %s", test.latLng, test.format, test.precision, s, test.want)
+		}
+	}
+
+	if _, err := FormatLatLng(pittsburgh, FormatDMS, PrecisionE7+1); err == nil {
+		t.Errorf("invalid precision: no error")
+	}
+
+	if _, err := FormatLatLng(pittsburgh, FormatDDM+1, PrecisionMax); err == nil {
+		t.Errorf("invalid format: no error")
+	}
+}
+
+func TestFormatLatLngRoundTrip(t *testing.T) {
+	for lat := -90.0; lat <= 90; lat += 7.3 {
+		for lng := -180.0; lng <= 180; lng += 11.9 {
+			latLng := s2.LatLngFromDegrees(lat, lng)
+			for format := FormatDecimal; format <= FormatDDM; format++ {
+				s, err := FormatLatLng(latLng, format, PrecisionE7)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				parsed, err := ParseLatLng(s)
+				if err != nil {
+					t.Fatalf("%s: %v", s, err)
+				}
+
+				if d := parsed.Distance(latLng).Degrees(); d > 1e-7 {
+					t.Errorf("%v: parsed %s as %v", latLng, s, parsed)
+				}
+			}
+		}
+	}
+}
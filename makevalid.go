@@ -0,0 +1,258 @@
+package geoutil
+
+import (
+	"fmt"
+
+	"github.com/golang/geo/s2"
+)
+
+// RepairKind identifies a kind of change made to repair a geometry.
+type RepairKind int
+
+const (
+	// RepairDroppedCoordinate is an out-of-range coordinate that was
+	// removed.
+	RepairDroppedCoordinate RepairKind = iota
+
+	// RepairRemovedDuplicate is a vertex equal to the previous vertex that
+	// was removed.
+	RepairRemovedDuplicate
+
+	// RepairRemovedSpike is the tip of a spike, a vertex whose previous and
+	// next vertices are equal, that was removed with its edges.
+	RepairRemovedSpike
+
+	// RepairDroppedDegenerate is a ring of fewer than three distinct
+	// vertices or of no area, or an empty polygon, that was removed.
+	RepairDroppedDegenerate
+
+	// RepairSplitSelfIntersection is a ring crossing itself or visiting a
+	// vertex twice that was split into rings at its crossings, keeping the
+	// points inside an odd number of its turns.
+	RepairSplitSelfIntersection
+
+	// RepairResolvedCrossing is a ring crossing another ring, whose areas
+	// were combined at their crossings.
+	RepairResolvedCrossing
+
+	// RepairFixedNesting is a hole outside of its shell, whose area was
+	// ignored, or a hole inside another hole, whose areas were combined.
+	RepairFixedNesting
+
+	// RepairMergedParts is a polygon inside another polygon of a
+	// multipolygon, whose areas were combined.
+	RepairMergedParts
+
+	// RepairClosedRing is a ring whose last vertex was not equal to its first
+	// vertex, that was closed by an edge between them.
+	RepairClosedRing
+)
+
+var repairKindNames = [...]string{
+	RepairDroppedCoordinate:     "dropped coordinate",
+	RepairRemovedDuplicate:      "removed duplicate vertex",
+	RepairRemovedSpike:          "removed spike",
+	RepairDroppedDegenerate:     "dropped degenerate geometry",
+	RepairSplitSelfIntersection: "split self-intersection",
+	RepairResolvedCrossing:      "resolved crossing rings",
+	RepairFixedNesting:          "fixed nesting",
+	RepairMergedParts:           "merged parts",
+	RepairClosedRing:            "closed ring",
+}
+
+func (k RepairKind) String() string {
+	if k < 0 || int(k) >= len(repairKindNames) {
+		return fmt.Sprintf("RepairKind(%d)", int(k))
+	}
+
+	return repairKindNames[k]
+}
+
+// Repair locates a change made to repair a geometry, in the same way as a
+// Problem locates a problem of the original geometry.
+type Repair struct {
+	Kind      RepairKind
+	Part      int
+	Ring      int
+	Vertex    int
+	OtherPart int
+	OtherRing int
+}
+
+func (r Repair) String() string {
+	s := fmt.Sprintf("%s at part %d, ring %d", r.Kind, r.Part, r.Ring)
+	if r.Vertex >= 0 {
+		s += fmt.Sprintf(", vertex %d", r.Vertex)
+	}
+
+	if r.Kind == RepairResolvedCrossing {
+		s += fmt.Sprintf(" with part %d, ring %d", r.OtherPart, r.OtherRing)
+	}
+
+	return s
+}
+
+// repairRing returns the distinct in-range vertices of a ring, without its
+// closing vertex, and the spikes of the ring by the index of the vertex
+// returning from the spike to the index of its tip.
+func repairRing(coords [][]float64) ([]s2.Point, map[int]int) {
+	points := []s2.Point{}
+	indexes := []int{}
+	spikes := map[int]int{}
+	for i, pointCoords := range coords {
+		point, ok := validPoint(pointCoords)
+		if !ok {
+			continue
+		}
+
+		n := len(points)
+		switch {
+		case n > 0 && points[n-1] == point:
+			continue
+
+		// Returning to the vertex before the previous one removes the
+		// previous one, which is the tip of a spike.
+		case n > 1 && points[n-2] == point:
+			spikes[i] = indexes[n-1]
+			points = points[:n-1]
+			indexes = indexes[:n-1]
+			continue
+		}
+
+		points = append(points, point)
+		indexes = append(indexes, i)
+	}
+
+	if n := len(points) - 1; n > 0 && points[0] == points[n] {
+		points = points[:n]
+	}
+
+	return points, spikes
+}
+
+// repairsFromProblems returns the changes made to repair the problems of the
+// coordinates of a multipolygon.
+func repairsFromProblems(coords [][][][]float64, spikes map[[2]int]map[int]int) []Repair {
+	repairs := []Repair{}
+	for _, problem := range ValidateMultiPolygonCoordinates(coords) {
+		repair := Repair{
+			Part:      problem.Part,
+			Ring:      problem.Ring,
+			Vertex:    problem.Vertex,
+			OtherPart: problem.OtherPart,
+			OtherRing: problem.OtherRing,
+		}
+
+		switch problem.Kind {
+		case ProblemOutOfRange:
+			repair.Kind = RepairDroppedCoordinate
+		case ProblemDuplicateVertex:
+			repair.Kind = RepairRemovedDuplicate
+		case ProblemDegenerate:
+			repair.Kind = RepairDroppedDegenerate
+		case ProblemSelfIntersection:
+			repair.Kind = RepairSplitSelfIntersection
+			if tip, ok := spikes[[2]int{problem.Part, problem.Ring}][problem.Vertex]; ok {
+				repair.Kind = RepairRemovedSpike
+				repair.Vertex = tip
+			}
+
+		case ProblemCrossingRings:
+			repair.Kind = RepairResolvedCrossing
+		case ProblemImproperNesting:
+			repair.Kind = RepairFixedNesting
+			if problem.Ring == 0 {
+				repair.Kind = RepairMergedParts
+			}
+
+		case ProblemUnclosedRing:
+			repair.Kind = RepairClosedRing
+		}
+
+		repairs = append(repairs, repair)
+	}
+
+	return repairs
+}
+
+// MakeValidMultiPolygonCoordinates returns a valid polygon covering the area
+// of the coordinates of a possibly invalid multipolygon as closely as
+// possible, and the changes made to repair it. Out-of-range coordinates,
+// duplicate vertices and spikes are removed, rings crossing themselves cover
+// the points inside an odd number of their turns, holes are subtracted from
+// their shell only, and overlapping polygons are merged.
+func MakeValidMultiPolygonCoordinates(coords [][][][]float64) (*s2.Polygon, []Repair) {
+	inputs := []overlayInput{}
+	parts := [][]int{}
+	spikes := map[[2]int]map[int]int{}
+	for i, polygonCoords := range coords {
+		part := []int{}
+		for j, linearRingCoords := range polygonCoords {
+			points, ringSpikes := repairRing(linearRingCoords)
+			if len(ringSpikes) > 0 {
+				spikes[[2]int{i, j}] = ringSpikes
+			}
+
+			if len(points) < 3 {
+
+				// A polygon without a shell is dropped.
+				if j == 0 {
+					break
+				}
+
+				continue
+			}
+
+			part = append(part, len(inputs))
+			inputs = append(inputs, ringInput(points))
+		}
+
+		if len(part) > 0 {
+			parts = append(parts, part)
+		}
+	}
+
+	polygon := overlay(inputs, defaultSnapRadius, func(inside []bool) bool {
+		for _, part := range parts {
+			if !inside[part[0]] {
+				continue
+			}
+
+			inHole := false
+			for _, hole := range part[1:] {
+				if inside[hole] {
+					inHole = true
+					break
+				}
+			}
+
+			if !inHole {
+				return true
+			}
+		}
+
+		return false
+	})
+
+	return polygon, repairsFromProblems(coords, spikes)
+}
+
+// MakeValidPolygonCoordinates returns a valid polygon covering the area of
+// the coordinates of a possibly invalid polygon as closely as possible, and
+// the changes made to repair it.
+func MakeValidPolygonCoordinates(coords [][][]float64) (*s2.Polygon, []Repair) {
+	return MakeValidMultiPolygonCoordinates([][][][]float64{coords})
+}
+
+// MakeValid returns a valid polygon covering the area of a possibly invalid
+// polygon as closely as possible, and the changes made to repair it, located
+// in the coordinates returned by PolygonCoordinates.
+func MakeValid(polygon *s2.Polygon) (*s2.Polygon, []Repair, error) {
+	coords, err := PolygonCoordinates(polygon, PrecisionMax)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	valid, repairs := MakeValidMultiPolygonCoordinates(coords)
+	return valid, repairs, nil
+}
@@ -0,0 +1,197 @@
+package geoutil
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/golang/geo/s2"
+)
+
+// checkValid checks that a polygon is valid and has the area of the
+// coordinates of a valid multipolygon.
+func checkValid(t *testing.T, name string, polygon *s2.Polygon, want [][][][]float64) {
+	t.Helper()
+	problems, err := Validate(polygon)
+	if err != nil || len(problems) != 0 {
+		t.Errorf("%s: invalid result %v, %v", name, problems, err)
+	}
+
+	area := 0.0
+	for _, polygonCoords := range want {
+		wantPolygon, err := PolygonFromPolygonCoordinates(polygonCoords)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		area += wantPolygon.Area()
+	}
+
+	if math.Abs(polygon.Area()-area) > 1e-9 {
+		t.Errorf("%s: area %v, want %v", name, polygon.Area(), area)
+	}
+}
+
+func TestMakeValidRepairs(t *testing.T) {
+	tests := []struct {
+		name   string
+		coords [][][][]float64
+		want   []Repair
+	}{
+		{
+			name:   "unclosed ring",
+			coords: [][][][]float64{{square[:4]}},
+			want:   []Repair{{Kind: RepairClosedRing, Vertex: -1}},
+		},
+		{
+			name:   "hole crossing its shell",
+			coords: [][][][]float64{{square, {{5, 5}, {15, 5}, {15, 6}, {5, 6}, {5, 5}}}},
+			want:   []Repair{{Kind: RepairResolvedCrossing, Vertex: 1, OtherRing: 1}},
+		},
+		{
+			name:   "spike",
+			coords: [][][][]float64{{{{0, 0}, {10, 0}, {15, 0}, {10, 0}, {10, 10}, {0, 10}, {0, 0}}}},
+			want:   []Repair{{Kind: RepairRemovedSpike, Vertex: 2}},
+		},
+	}
+
+	for _, test := range tests {
+		_, repairs := MakeValidMultiPolygonCoordinates(test.coords)
+		if !reflect.DeepEqual(repairs, test.want) {
+			t.Errorf("%s: repaired %v, want %v", test.name, repairs, test.want)
+		}
+	}
+
+	want := "resolved crossing rings at part 0, ring 0, vertex 1 with part 0, ring 1"
+	if s := (Repair{Kind: RepairResolvedCrossing, Vertex: 1, OtherRing: 1}).String(); s != want {
+		t.Errorf("formatted %s, want %s", s, want)
+	}
+}
+
+func TestMakeValid(t *testing.T) {
+	tests := []struct {
+		name   string
+		coords [][][][]float64
+		want   [][][][]float64
+	}{
+		{
+			name:   "valid",
+			coords: [][][][]float64{{square, squareHole}},
+			want:   [][][][]float64{{square, squareHole}},
+		},
+		{
+			name:   "unclosed ring",
+			coords: [][][][]float64{{square[:4]}},
+			want:   [][][][]float64{{square}},
+		},
+		{
+			name:   "out of range and duplicate vertices",
+			coords: [][][][]float64{{{{0, 0}, {10, 0}, {10, 0}, {200, 5}, {10, 10}, {0, 10}, {0, 0}}}},
+			want:   [][][][]float64{{square}},
+		},
+		{
+			name:   "spike",
+			coords: [][][][]float64{{{{0, 0}, {10, 0}, {15, 0}, {10, 0}, {10, 10}, {0, 10}, {0, 0}}}},
+			want:   [][][][]float64{{square}},
+		},
+		{
+			// The edges are geodesics, which cross north of 5 degrees.
+			name:   "self-intersection",
+			coords: [][][][]float64{{{{0, 0}, {10, 10}, {10, 0}, {0, 10}, {0, 0}}}},
+			want: [][][][]float64{
+				{{{0, 0}, {5, 5.057514896828208}, {0, 10}, {0, 0}}},
+				{{{10, 0}, {10, 10}, {5, 5.057514896828208}, {10, 0}}},
+			},
+		},
+		{
+			name:   "hole crossing its shell",
+			coords: [][][][]float64{{square, {{5, 5}, {15, 5}, {15, 6}, {5, 6}, {5, 5}}}},
+			want: [][][][]float64{{{
+				{0, 0}, {10, 0}, {10, 5.019001817489643}, {5, 5}, {5, 6}, {10, 6.022750884811923}, {10, 10}, {0, 10}, {0, 0},
+			}}},
+		},
+		{
+			name:   "hole outside of its shell",
+			coords: [][][][]float64{{square, {{20, 0}, {20, 1}, {21, 1}, {20, 0}}}},
+			want:   [][][][]float64{{square}},
+		},
+		{
+			name:   "overlapping polygons",
+			coords: [][][][]float64{{square}, {{{0, 0}, {10, 0}, {10, 10}, {0, 10}, {0, 0}}}, {squareHole}},
+			want:   [][][][]float64{{square}},
+		},
+		{
+			// The vertex of the second square is within the snap radius of
+			// the edge of the first, which is split there.
+			name:   "nearly adjacent polygons",
+			coords: [][][][]float64{{square}, {{{10, 5 - 1e-9}, {10 + 1e-12, 5}, {20, 5}, {20, 10}, {10, 10}, {10, 5 - 1e-9}}}},
+			want:   [][][][]float64{{square}, {{{10, 5 - 1e-9}, {10, 5}, {20, 5}, {20, 10}, {10, 10}, {10, 5 - 1e-9}}}},
+		},
+		{
+			name:   "empty",
+			coords: [][][][]float64{{{{0, 0}, {1, 1}, {0, 0}}}, {}},
+			want:   [][][][]float64{},
+		},
+	}
+
+	for _, test := range tests {
+		polygon, _ := MakeValidMultiPolygonCoordinates(test.coords)
+		checkValid(t, test.name, polygon, test.want)
+	}
+
+	polygon, err := PolygonFromPolygonCoordinates([][][]float64{square, squareHole})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	valid, repairs, err := MakeValid(polygon)
+	if err != nil || len(repairs) != 0 {
+		t.Fatalf("repaired a valid polygon with %v, %v", repairs, err)
+	}
+
+	checkValid(t, "MakeValid", valid, [][][][]float64{{square, squareHole}})
+}
+
+func TestMakeValidLongitudeBand(t *testing.T) {
+
+	// Two strips with interleaved sawtooth sides, whose many edges cross
+	// each other and span the same narrow band of longitudes.
+	const teeth = 500
+	a := [][]float64{{90, -20}}
+	b := [][]float64{{90.05, 20}}
+	for i := 0; i <= 2*teeth; i++ {
+		lat := -20 + 20*float64(i)/teeth
+		a = append(a, []float64{90.01 + 0.02*float64(i%2), lat})
+		b = append(b, []float64{90.02 + 0.02*float64((i+1)%2), -lat})
+	}
+
+	a = append(a, []float64{90, 20}, []float64{90, -20})
+	b = append(b, []float64{90.05, -20}, []float64{90.05, 20})
+
+	start := time.Now()
+	polygon, repairs := MakeValidMultiPolygonCoordinates([][][][]float64{{a}, {b}})
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("repaired in %v", elapsed)
+	}
+
+	want := []Repair{{Kind: RepairResolvedCrossing, Vertex: 1, OtherPart: 1}}
+	if !reflect.DeepEqual(repairs, want) {
+		t.Errorf("repaired %v, want %v", repairs, want)
+	}
+
+	if problems, err := Validate(polygon); err != nil || len(problems) != 0 {
+		t.Errorf("invalid result %v, %v", problems, err)
+	}
+
+	// The union covers both strips, which overlap at their teeth.
+	polygonA, _ := PolygonFromPolygonCoordinates([][][]float64{a})
+	polygonB, _ := PolygonFromPolygonCoordinates([][][]float64{b})
+	if area := polygon.Area(); area <= math.Max(polygonA.Area(), polygonB.Area()) || area >= polygonA.Area()+polygonB.Area() {
+		t.Errorf("union of area %v, strips of areas %v and %v", area, polygonA.Area(), polygonB.Area())
+	}
+
+	if !polygon.ContainsPoint(pointFromDegrees(0, 90.005)) || !polygon.ContainsPoint(pointFromDegrees(0, 90.045)) {
+		t.Errorf("union does not cover the strips")
+	}
+}
@@ -0,0 +1,194 @@
+package geoutil
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/golang/geo/s2"
+)
+
+// Model is a model of the shape of the Earth measuring distances and areas.
+// Edges are measured as the shortest paths between their vertices on the
+// model, which are the geodesics of an ellipsoid.
+type Model interface {
+
+	// Distance returns the length in meters of the shortest path between
+	// two points.
+	Distance(a, b s2.Point) float64
+
+	// LoopArea returns the area in square meters of the region of a loop.
+	LoopArea(loop *s2.Loop) float64
+}
+
+// Sphere is a spherical model of the Earth.
+type Sphere struct {
+	Radius float64
+}
+
+// MeanSphere is the sphere of the mean radius of the Earth.
+var MeanSphere = Sphere{Radius: EarthRadius}
+
+func (s Sphere) Distance(a, b s2.Point) float64 {
+	return a.Distance(b).Radians() * s.Radius
+}
+
+func (s Sphere) LoopArea(loop *s2.Loop) float64 {
+	return loop.Area() * s.Radius * s.Radius
+}
+
+// Ellipsoid is an ellipsoidal model of the Earth, measured with the geodesic
+// algorithms of C. F. F. Karney, which are accurate to a few nanometers.
+// Ellipsoids are made with NewEllipsoid.
+type Ellipsoid struct {
+	geodesic *geodesic
+}
+
+// NewEllipsoid returns the ellipsoid of a semi-major axis in meters and a
+// flattening.
+func NewEllipsoid(semiMajorAxis, flattening float64) *Ellipsoid {
+	return &Ellipsoid{
+		geodesic: newGeodesic(semiMajorAxis, flattening),
+	}
+}
+
+// SemiMajorAxis returns the equatorial radius of the ellipsoid in meters.
+func (e *Ellipsoid) SemiMajorAxis() float64 {
+	return e.geodesic.a
+}
+
+// Flattening returns the flattening of the ellipsoid.
+func (e *Ellipsoid) Flattening() float64 {
+	return e.geodesic.f
+}
+
+// WGS84 is the ellipsoid of the World Geodetic System, as used by GPS and by
+// PostGIS geography.
+var WGS84 = NewEllipsoid(6378137, 1/298.257223563)
+
+func (e *Ellipsoid) Distance(a, b s2.Point) float64 {
+	p, q := s2.LatLngFromPoint(a), s2.LatLngFromPoint(b)
+	distance, _ := e.geodesic.inverse(p.Lat.Degrees(), p.Lng.Degrees(), q.Lat.Degrees(), q.Lng.Degrees())
+	return distance
+}
+
+func (e *Ellipsoid) LoopArea(loop *s2.Loop) float64 {
+	switch {
+	case loop.IsEmpty():
+		return 0
+	case loop.IsFull():
+		return 4 * math.Pi * e.geodesic.c2
+	}
+
+	points := make([][2]float64, loop.NumVertices())
+	for i := range points {
+		latLng := s2.LatLngFromPoint(loop.Vertex(i))
+		points[i] = [2]float64{latLng.Lat.Degrees(), latLng.Lng.Degrees()}
+	}
+
+	_, area := e.geodesic.polygonArea(points)
+	return area
+}
+
+// pathLength returns the length in meters of a path of points.
+func pathLength(points []s2.Point, model Model) float64 {
+	length := 0.0
+	for i := 1; i < len(points); i++ {
+		length += model.Distance(points[i-1], points[i])
+	}
+
+	return length
+}
+
+// loopPerimeter returns the length in meters of the boundary of a loop.
+func loopPerimeter(loop *s2.Loop, model Model) float64 {
+	if loop.IsEmpty() || loop.IsFull() {
+		return 0
+	}
+
+	vertices := loop.Vertices()
+	return pathLength(append(vertices, vertices[0]), model)
+}
+
+// Area returns the area in square meters of a geometry, which is zero for
+// points and polylines.
+func Area(geometry interface{}, model Model) (float64, error) {
+	switch geometry := geometry.(type) {
+	case s2.LatLng, s2.Point, []s2.Point, *s2.Polyline, []*s2.Polyline:
+		return 0, nil
+	case *s2.Loop:
+		return model.LoopArea(geometry), nil
+	case *s2.Polygon:
+		area := 0.0
+		for _, loop := range geometry.Loops() {
+			if loop.IsHole() {
+				area -= model.LoopArea(loop)
+			} else {
+				area += model.LoopArea(loop)
+			}
+		}
+
+		return area, nil
+	case s2.CellUnion:
+		area := 0.0
+		for _, id := range geometry {
+			area += model.LoopArea(s2.LoopFromCell(s2.CellFromCellID(id)))
+		}
+
+		return area, nil
+	default:
+		return 0, fmt.Errorf("geoutil: unknown geometry type %T", geometry)
+	}
+}
+
+// Length returns the length in meters of a polyline or the sum of the
+// lengths of polylines, which is zero for points and polygons.
+func Length(geometry interface{}, model Model) (float64, error) {
+	switch geometry := geometry.(type) {
+	case s2.LatLng, s2.Point, []s2.Point, *s2.Loop, *s2.Polygon, s2.CellUnion:
+		return 0, nil
+	case *s2.Polyline:
+		return pathLength(*geometry, model), nil
+	case []*s2.Polyline:
+		length := 0.0
+		for _, polyline := range geometry {
+			length += pathLength(*polyline, model)
+		}
+
+		return length, nil
+	default:
+		return 0, fmt.Errorf("geoutil: unknown geometry type %T", geometry)
+	}
+}
+
+// Perimeter returns the length in meters of the boundary of a polygon,
+// including its holes, which is zero for points and polylines.
+func Perimeter(geometry interface{}, model Model) (float64, error) {
+	switch geometry := geometry.(type) {
+	case s2.LatLng, s2.Point, []s2.Point, *s2.Polyline, []*s2.Polyline:
+		return 0, nil
+	case *s2.Loop:
+		return loopPerimeter(geometry, model), nil
+	case *s2.Polygon:
+		perimeter := 0.0
+		for _, loop := range geometry.Loops() {
+			perimeter += loopPerimeter(loop, model)
+		}
+
+		return perimeter, nil
+	case s2.CellUnion:
+		perimeter := 0.0
+		for _, id := range geometry {
+			perimeter += loopPerimeter(s2.LoopFromCell(s2.CellFromCellID(id)), model)
+		}
+
+		return perimeter, nil
+	default:
+		return 0, fmt.Errorf("geoutil: unknown geometry type %T", geometry)
+	}
+}
+
+// Distance returns the length in meters of the shortest path between two
+// points on a model.
+func Distance(a, b s2.LatLng, model Model) float64 {
+	return model.Distance(s2.PointFromLatLng(a), s2.PointFromLatLng(b))
+}
@@ -0,0 +1,173 @@
+package geoutil
+
+import (
+	"math"
+	"testing"
+
+	"github.com/golang/geo/s2"
+)
+
+// The lengths of the WGS84 meridian quadrant and equatorial degree, from
+// GeographicLib.
+const (
+	wgs84Quadrant       = 10001965.7293127
+	wgs84EquatorDegree  = 111319.49079327357
+	wgs84MeridianTo45   = 4984944.378
+	wgs84EllipsoidArea  = 510065621724088.4
+	wgs84EquatorQuarter = 10018754.171394622
+)
+
+func TestDistance(t *testing.T) {
+	tests := []struct {
+		name                   string
+		lat1, lng1, lat2, lng2 float64
+		model                  Model
+		want, tolerance        float64
+	}{
+		{"equatorial degree", 0, 0, 0, 1, WGS84, wgs84EquatorDegree, 1e-6},
+		{"meridian quadrant", 0, 0, 90, 0, WGS84, wgs84Quadrant, 1e-3},
+		{"meridian arc to 45 degrees", 0, 0, 45, 0, WGS84, wgs84MeridianTo45, 1e-3},
+		{"meridian arc from 45 degrees south", -45, 10, 0, 10, WGS84, wgs84MeridianTo45, 1e-3},
+
+		// The shortest paths between antipodal points of the equator pass
+		// through the poles.
+		{"antipodal", 0, 0, 0, 180, WGS84, 2 * wgs84Quadrant, 1e-3},
+		{"same point", 12, 34, 12, 34, WGS84, 0, 0},
+		{"sphere quadrant", 0, 0, 90, 0, MeanSphere, EarthRadius * math.Pi / 2, 1e-6},
+		{"unit sphere", 0, 0, 0, 60, Sphere{Radius: 1}, math.Pi / 3, 1e-12},
+		{"unit ellipsoid without flattening", 0, 0, 0, 60, NewEllipsoid(1, 0), math.Pi / 3, 1e-12},
+	}
+
+	for _, test := range tests {
+		a, b := s2.LatLngFromDegrees(test.lat1, test.lng1), s2.LatLngFromDegrees(test.lat2, test.lng2)
+		if d := Distance(a, b, test.model); math.Abs(d-test.want) > test.tolerance {
+			t.Errorf("%s: distance %v, want %v", test.name, d, test.want)
+		}
+
+		if d := Distance(b, a, test.model); math.Abs(d-test.want) > test.tolerance {
+			t.Errorf("%s: reverse distance %v, want %v", test.name, d, test.want)
+		}
+	}
+
+	if a, f := WGS84.SemiMajorAxis(), WGS84.Flattening(); a != 6378137 || f != 1/298.257223563 {
+		t.Errorf("WGS84 of semi-major axis %v and flattening %v", a, f)
+	}
+}
+
+func TestArea(t *testing.T) {
+
+	// The octant between the equator, the prime meridian and the 90th
+	// meridian east is an eighth of the surface of either model.
+	octant := s2.LoopFromPoints([]s2.Point{pointFromDegrees(0, 0), pointFromDegrees(0, 90), pointFromDegrees(90, 0)})
+	polygon, err := PolygonFromPolygonCoordinates([][][]float64{square, squareHole})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	shell, err := PolygonFromPolygonCoordinates([][][]float64{square})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hole, err := PolygonFromPolygonCoordinates([][][]float64{squareHole})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The area of a polygon with a hole is that of its shell minus that of
+	// the hole.
+	shellArea, _ := Area(shell, WGS84)
+	holeArea, _ := Area(hole, WGS84)
+	tests := []struct {
+		name            string
+		geometry        interface{}
+		model           Model
+		want, tolerance float64
+	}{
+		{"octant", octant, WGS84, wgs84EllipsoidArea / 8, 1},
+		{"sphere octant", octant, MeanSphere, math.Pi * EarthRadius * EarthRadius / 2, 1},
+		{"full", s2.FullLoop(), WGS84, wgs84EllipsoidArea, 1},
+		{"empty", s2.EmptyLoop(), WGS84, 0, 0},
+		{"polyline", &s2.Polyline{pointFromDegrees(0, 0), pointFromDegrees(1, 1)}, WGS84, 0, 0},
+		{"point", pointFromDegrees(1, 1), WGS84, 0, 0},
+		{"cells", s2.CellUnion{s2.CellIDFromFace(0), s2.CellIDFromFace(1)}, MeanSphere, 4 * math.Pi * EarthRadius * EarthRadius / 3, 1},
+		{"polygon with a hole", polygon, WGS84, shellArea - holeArea, 1e-3},
+	}
+
+	for _, test := range tests {
+		area, err := Area(test.geometry, test.model)
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+
+		if math.Abs(area-test.want) > test.tolerance {
+			t.Errorf("%s: area %v, want %v", test.name, area, test.want)
+		}
+	}
+
+	// The areas on the ellipsoid and the mean sphere differ by less than
+	// their flattening.
+	ellipsoidArea, _ := Area(shell, WGS84)
+	sphereArea, _ := Area(shell, MeanSphere)
+	if math.Abs(ellipsoidArea-sphereArea) > 0.01*sphereArea {
+		t.Errorf("square of area %v on the ellipsoid and %v on the sphere", ellipsoidArea, sphereArea)
+	}
+
+	if _, err := Area(1, WGS84); err == nil {
+		t.Errorf("measuring an unknown geometry returned no error")
+	}
+}
+
+func TestLengthAndPerimeter(t *testing.T) {
+	polyline := s2.Polyline{pointFromDegrees(0, 0), pointFromDegrees(0, 1), pointFromDegrees(0, 2)}
+	length, err := Length(&polyline, WGS84)
+	if err != nil || math.Abs(length-2*wgs84EquatorDegree) > 1e-6 {
+		t.Errorf("polyline of length %v, %v, want %v", length, err, 2*wgs84EquatorDegree)
+	}
+
+	length, err = Length([]*s2.Polyline{&polyline, &polyline}, WGS84)
+	if err != nil || math.Abs(length-4*wgs84EquatorDegree) > 1e-6 {
+		t.Errorf("polylines of length %v, %v, want %v", length, err, 4*wgs84EquatorDegree)
+	}
+
+	octant := s2.LoopFromPoints([]s2.Point{pointFromDegrees(0, 0), pointFromDegrees(0, 90), pointFromDegrees(90, 0)})
+	want := 2*wgs84Quadrant + wgs84EquatorQuarter
+	if perimeter, err := Perimeter(octant, WGS84); err != nil || math.Abs(perimeter-want) > 1e-3 {
+		t.Errorf("octant of perimeter %v, %v, want %v", perimeter, err, want)
+	}
+
+	// The perimeter of a polygon includes that of its hole.
+	polygon, err := PolygonFromPolygonCoordinates([][][]float64{square, squareHole})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hole, err := PolygonFromPolygonCoordinates([][][]float64{squareHole})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	shellPerimeter, _ := Perimeter(polygon.Loop(0), MeanSphere)
+	holePerimeter, _ := Perimeter(hole, MeanSphere)
+	want = shellPerimeter + holePerimeter
+	if perimeter, err := Perimeter(polygon, MeanSphere); err != nil || math.Abs(perimeter-want) > 1e-6 {
+		t.Errorf("polygon of perimeter %v, %v, want %v", perimeter, err, want)
+	}
+
+	if length, err := Length(polygon, WGS84); err != nil || length != 0 {
+		t.Errorf("polygon of length %v, %v", length, err)
+	}
+
+	if perimeter, err := Perimeter(s2.FullLoop(), WGS84); err != nil || perimeter != 0 {
+		t.Errorf("full loop of perimeter %v, %v", perimeter, err)
+	}
+
+	if _, err := Length(1, WGS84); err == nil {
+		t.Errorf("measuring an unknown geometry returned no error")
+	}
+
+	if _, err := Perimeter(1, WGS84); err == nil {
+		t.Errorf("measuring an unknown geometry returned no error")
+	}
+}
@@ -0,0 +1,184 @@
+// Package mgrs converts between s2 types and Military Grid Reference System
+// references on the WGS84 ellipsoid.
+package mgrs
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/golang/geo/s2"
+
+	"github.com/topos-ai/geoutil/utm"
+)
+
+// Precisions are the number of digits of the easting and northing of a
+// reference, from 100km squares to 1m squares.
+const (
+	Precision100km = iota
+	Precision10km
+	Precision1km
+	Precision100m
+	Precision10m
+	Precision1m
+)
+
+// columnLetters are the letters of the 100km square columns, which repeat
+// every three zones.
+var columnLetters = [...]string{"ABCDEFGH", "JKLMNPQR", "STUVWXYZ"}
+
+// rowLetters are the letters of the 100km square rows, which repeat every
+// 2000km. Rows of even zones are offset by five letters.
+const rowLetters = "ABCDEFGHJKLMNPQRSTUV"
+
+const squareSize = 100000
+
+func validatePrecision(precision int) error {
+	if precision < Precision100km || precision > Precision1m {
+		return fmt.Errorf("mgrs: invalid precision %d", precision)
+	}
+
+	return nil
+}
+
+// squareResolution returns the size in meters of the squares of a precision.
+func squareResolution(precision int) float64 {
+	return math.Pow(10, float64(Precision1m-precision))
+}
+
+// Encode returns the reference of the square of the given precision
+// containing a location, such as "31UDQ4825111932" for 1m precision. Digits
+// are truncated rather than rounded, as the reference identifies the square.
+func Encode(latLng s2.LatLng, precision int) (string, error) {
+	if err := validatePrecision(precision); err != nil {
+		return "", err
+	}
+
+	c, err := utm.FromLatLng(latLng)
+	if err != nil {
+		return "", err
+	}
+
+	band, err := utm.LatitudeBand(latLng)
+	if err != nil {
+		return "", err
+	}
+
+	column := int(math.Floor(c.Easting/squareSize)) - 1
+	columns := columnLetters[(c.Zone-1)%len(columnLetters)]
+	if column < 0 || column >= len(columns) {
+		return "", fmt.Errorf("mgrs: easting %g is outside of zone %d", c.Easting, c.Zone)
+	}
+
+	row := int(math.Floor(c.Northing/squareSize)) % len(rowLetters)
+	if c.Zone%2 == 0 {
+		row = (row + 5) % len(rowLetters)
+	}
+
+	resolution := squareResolution(precision)
+	easting := int(math.Floor(math.Mod(c.Easting, squareSize) / resolution))
+	northing := int(math.Floor(math.Mod(c.Northing, squareSize) / resolution))
+	reference := fmt.Sprintf("%d%c%c%c", c.Zone, band, columns[column], rowLetters[row])
+	if precision > Precision100km {
+		reference += fmt.Sprintf("%0*d%0*d", precision, easting, precision, northing)
+	}
+
+	return reference, nil
+}
+
+// Parse returns the UTM coordinate of the southwest corner of the square of a
+// reference, and the precision of the reference. Spaces are ignored.
+func Parse(reference string) (utm.Coordinate, int, error) {
+	s := strings.ToUpper(strings.Join(strings.Fields(reference), ""))
+
+	// Zone.
+	i := 0
+	for i < len(s) && i < 2 && s[i] >= '0' && s[i] <= '9' {
+		i++
+	}
+
+	zone, err := strconv.Atoi(s[:i])
+	if err != nil || zone < 1 || zone > 60 {
+		return utm.Coordinate{}, 0, fmt.Errorf("mgrs: invalid zone in %q", reference)
+	}
+
+	if len(s) < i+3 {
+		return utm.Coordinate{}, 0, fmt.Errorf("mgrs: invalid reference %q", reference)
+	}
+
+	// Latitude band and 100km square.
+	band := strings.IndexByte(utm.LatitudeBands, s[i])
+	if band < 0 {
+		return utm.Coordinate{}, 0, fmt.Errorf("mgrs: invalid latitude band in %q", reference)
+	}
+
+	column := strings.IndexByte(columnLetters[(zone-1)%len(columnLetters)], s[i+1])
+	row := strings.IndexByte(rowLetters, s[i+2])
+	if column < 0 || row < 0 {
+		return utm.Coordinate{}, 0, fmt.Errorf("mgrs: invalid 100km square in %q", reference)
+	}
+
+	if zone%2 == 0 {
+		row = (row + len(rowLetters) - 5) % len(rowLetters)
+	}
+
+	// Easting and northing digits.
+	digits := s[i+3:]
+	if len(digits)%2 == 1 || len(digits) > 2*Precision1m {
+		return utm.Coordinate{}, 0, fmt.Errorf("mgrs: invalid easting and northing in %q", reference)
+	}
+
+	precision := len(digits) / 2
+	easting, northing := 0, 0
+	if precision > 0 {
+		if easting, err = strconv.Atoi(digits[:precision]); err != nil {
+			return utm.Coordinate{}, 0, fmt.Errorf("mgrs: invalid easting in %q", reference)
+		}
+
+		if northing, err = strconv.Atoi(digits[precision:]); err != nil {
+			return utm.Coordinate{}, 0, fmt.Errorf("mgrs: invalid northing in %q", reference)
+		}
+	}
+
+	resolution := squareResolution(precision)
+	c := utm.Coordinate{
+		Zone:       zone,
+		Hemisphere: utm.North,
+		Easting:    float64(column+1)*squareSize + float64(easting)*resolution,
+		Northing:   float64(row)*squareSize + float64(northing)*resolution,
+	}
+
+	// The row letters repeat every 2000km, so the northing is the first
+	// repetition north of the bottom of the latitude band, rounded down to
+	// the 100km squares that straddle it.
+	bandLat := float64(utm.MinLatitude + 8*band)
+	if bandLat < 0 {
+		c.Hemisphere = utm.South
+	}
+
+	bottom, err := utm.FromLatLngInZone(s2.LatLngFromDegrees(bandLat, float64(zone*6-183)), zone)
+	if err != nil {
+		return utm.Coordinate{}, 0, err
+	}
+
+	bottomNorthing := math.Floor(bottom.Northing/squareSize) * squareSize
+	for c.Northing < bottomNorthing {
+		c.Northing += 20 * squareSize
+	}
+
+	return c, precision, nil
+}
+
+// Decode returns the location of the center of the square of a reference.
+func Decode(reference string) (s2.LatLng, error) {
+	c, precision, err := Parse(reference)
+	if err != nil {
+		return s2.LatLng{}, err
+	}
+
+	resolution := squareResolution(precision)
+	c.Easting += resolution / 2
+	c.Northing += resolution / 2
+	return c.LatLng()
+}
@@ -0,0 +1,124 @@
+package mgrs
+
+import (
+	"math"
+	"testing"
+
+	"github.com/golang/geo/s2"
+
+	"github.com/topos-ai/geoutil/utm"
+)
+
+func TestEncode(t *testing.T) {
+	tests := []struct {
+		lat, lng  float64
+		precision int
+		want      string
+	}{
+		{48.8583, 2.2945, Precision1m, "31UDQ4825111943"},
+		{48.8583, 2.2945, Precision100m, "31UDQ482119"},
+		{48.8583, 2.2945, Precision100km, "31UDQ"},
+		{0, 0, Precision1m, "31NAA6602100000"},
+		{-33.8568, 151.2153, Precision1m, "56HLH3490052288"},
+		{40.6892, -74.0445, Precision10m, "18TWL80730469"},
+		{-23.5, -46.6, Precision1km, "23KLQ3600"},
+	}
+
+	for _, test := range tests {
+		reference, err := Encode(s2.LatLngFromDegrees(test.lat, test.lng), test.precision)
+		if err != nil {
+			t.Errorf("%v, %v: %v", test.lat, test.lng, err)
+			continue
+		}
+
+		if reference != test.want {
+			t.Errorf("%v, %v, %d: encoded %s, want %s", test.lat, test.lng, test.precision, reference, test.want)
+		}
+	}
+
+	for _, precision := range []int{-1, Precision1m + 1} {
+		if _, err := Encode(s2.LatLngFromDegrees(0, 0), precision); err == nil {
+			t.Errorf("precision %d: no error", precision)
+		}
+	}
+
+	if _, err := Encode(s2.LatLngFromDegrees(85, 0), Precision1m); err == nil {
+		t.Errorf("polar location: no error")
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		reference string
+		want      utm.Coordinate
+		precision int
+	}{
+		{"31UDQ4825111943", utm.Coordinate{Zone: 31, Hemisphere: utm.North, Easting: 448251, Northing: 5411943}, Precision1m},
+		{"31U DQ 48251 11943", utm.Coordinate{Zone: 31, Hemisphere: utm.North, Easting: 448251, Northing: 5411943}, Precision1m},
+		{"31udq", utm.Coordinate{Zone: 31, Hemisphere: utm.North, Easting: 400000, Northing: 5400000}, Precision100km},
+		{"56HLH3490052288", utm.Coordinate{Zone: 56, Hemisphere: utm.South, Easting: 334900, Northing: 6252288}, Precision1m},
+		{"23KLQ3600", utm.Coordinate{Zone: 23, Hemisphere: utm.South, Easting: 336000, Northing: 7400000}, Precision1km},
+		{"31NAA6602100000", utm.Coordinate{Zone: 31, Hemisphere: utm.North, Easting: 166021, Northing: 0}, Precision1m},
+	}
+
+	for _, test := range tests {
+		c, precision, err := Parse(test.reference)
+		if err != nil {
+			t.Errorf("%s: %v", test.reference, err)
+			continue
+		}
+
+		if c != test.want || precision != test.precision {
+			t.Errorf("%s: parsed %+v at precision %d, want %+v at %d", test.reference, c, precision, test.want, test.precision)
+		}
+	}
+
+	invalid := []string{
+		"",
+		"UDQ",
+		"0UDQ",
+		"61UDQ",
+		"31",
+		"31ADQ",
+		"31IDQ",
+		"31UJQ",
+		"31UDW",
+		"31UDQ1",
+		"31UDQ123",
+		"31UDQ123456789012",
+		"31UDQ12A4",
+	}
+
+	for _, reference := range invalid {
+		if _, _, err := Parse(reference); err == nil {
+			t.Errorf("%q: no error", reference)
+		}
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	for lat := -79.9; lat < 84; lat += 3.7 {
+		for lng := -179.9; lng < 180; lng += 9.1 {
+			latLng := s2.LatLngFromDegrees(lat, lng)
+			for precision := Precision100km; precision <= Precision1m; precision++ {
+				reference, err := Encode(latLng, precision)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				decoded, err := Decode(reference)
+				if err != nil {
+					t.Fatalf("%s: %v", reference, err)
+				}
+
+				// The decoded center of the square is within half its
+				// diagonal of the location, with some slack for the scale
+				// factor of the projection.
+				resolution := math.Pow(10, float64(Precision1m-precision))
+				if d := decoded.Distance(latLng).Radians() * 6371000; d > resolution*0.75 {
+					t.Errorf("%v, %d: decoded %s %v meters away", latLng, precision, reference, d)
+				}
+			}
+		}
+	}
+}
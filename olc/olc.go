@@ -0,0 +1,286 @@
+// Package olc converts between s2 types and Open Location Codes, also known
+// as Plus Codes.
+package olc
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/golang/geo/r1"
+	"github.com/golang/geo/s1"
+	"github.com/golang/geo/s2"
+)
+
+const (
+	// Separator separates the first eight digits of a code from the rest.
+	Separator = '+'
+
+	// Padding replaces the missing digits of codes shorter than eight digits.
+	Padding = '0'
+
+	// PairCodeLength is the number of digits of the codes encoded as pairs of
+	// latitude and longitude digits, and the default code length.
+	PairCodeLength = 10
+
+	// MinCodeLength and MaxCodeLength bound the number of digits of a code.
+	MinCodeLength = 2
+	MaxCodeLength = 15
+)
+
+const (
+	alphabet          = "23456789CFGHJMPQRVWX"
+	encodingBase      = len(alphabet)
+	separatorPosition = 8
+	gridColumns       = 4
+	gridRows          = 5
+	latMax            = 90
+	lngMax            = 180
+
+	// pairFirstPlaceValue is the place value of the first pair of digits, and
+	// pairPrecision the inverse of the size in degrees of the cells of the
+	// last pair.
+	pairFirstPlaceValue = 160000
+	pairPrecision       = 8000
+
+	// gridLatFirstPlaceValue and gridLngFirstPlaceValue are the place values
+	// of the first grid digit, and finalLatPrecision and finalLngPrecision the
+	// inverse of the size in degrees of the cells of the last grid digit.
+	gridLatFirstPlaceValue = 625
+	gridLngFirstPlaceValue = 256
+	finalLatPrecision      = pairPrecision * 3125
+	finalLngPrecision      = pairPrecision * 1024
+
+	// minTrimmableCodeLength is the minimum length of a code that can be
+	// shortened.
+	minTrimmableCodeLength = 6
+)
+
+var alphabetIndexes = func() [256]int {
+	indexes := [256]int{}
+	for i := range indexes {
+		indexes[i] = -1
+	}
+
+	for i := 0; i < len(alphabet); i++ {
+		indexes[alphabet[i]] = i
+		indexes[alphabet[i]|0x20] = i
+	}
+
+	return indexes
+}()
+
+// CheckValid returns an error if code is neither a valid full code nor a
+// valid short code.
+func CheckValid(code string) error {
+	separator := strings.IndexByte(code, Separator)
+	switch {
+	case separator < 0:
+		return fmt.Errorf("olc: code %q has no separator", code)
+	case separator != strings.LastIndexByte(code, Separator):
+		return fmt.Errorf("olc: code %q has more than one separator", code)
+	case separator > separatorPosition || separator%2 == 1:
+		return fmt.Errorf("olc: code %q has a misplaced separator", code)
+	case len(code)-separator-1 == 1:
+		return fmt.Errorf("olc: code %q has a single digit after the separator", code)
+	}
+
+	digits := code[:separator]
+	if padding := strings.IndexByte(digits, Padding); padding >= 0 {
+		switch {
+		case separator < separatorPosition:
+			return fmt.Errorf("olc: short code %q is padded", code)
+		case padding < MinCodeLength || padding%2 == 1:
+			return fmt.Errorf("olc: code %q has misplaced padding", code)
+		case strings.Trim(digits[padding:], string(Padding)) != "":
+			return fmt.Errorf("olc: code %q has digits after padding", code)
+		case len(code) > separator+1:
+			return fmt.Errorf("olc: padded code %q has digits after the separator", code)
+		}
+
+		digits = digits[:padding]
+	}
+
+	digits += code[separator+1:]
+	if len(digits) == 0 {
+		return fmt.Errorf("olc: code %q has no digits", code)
+	}
+
+	for i := 0; i < len(digits); i++ {
+		if alphabetIndexes[digits[i]] < 0 {
+			return fmt.Errorf("olc: code %q has invalid character %q", code, digits[i])
+		}
+	}
+
+	return nil
+}
+
+// IsValid reports whether code is a valid full or short code.
+func IsValid(code string) bool {
+	return CheckValid(code) == nil
+}
+
+// IsShort reports whether code is a valid short code, which has fewer than
+// eight digits before the separator and must be recovered relative to a
+// reference location.
+func IsShort(code string) bool {
+	return IsValid(code) && strings.IndexByte(code, Separator) < separatorPosition
+}
+
+// IsFull reports whether code is a valid full code, which identifies an area
+// on its own.
+func IsFull(code string) bool {
+	if !IsValid(code) || strings.IndexByte(code, Separator) < separatorPosition {
+		return false
+	}
+
+	// The first latitude digit may not exceed 90 degrees and the first
+	// longitude digit may not exceed 180 degrees.
+	if alphabetIndexes[code[0]]*encodingBase >= 2*latMax {
+		return false
+	}
+
+	return alphabetIndexes[code[1]]*encodingBase < 2*lngMax
+}
+
+// latitudeValue returns the latitude as an integer number of final grid cells
+// from the south pole.
+func latitudeValue(degrees float64) int64 {
+
+	// Values are rounded to six decimals first, so that the loss of precision
+	// of angles converted from radians does not change the last digit.
+	value := int64(math.Floor(math.Round(degrees*finalLatPrecision*1e6) / 1e6))
+	value += latMax * finalLatPrecision
+	switch {
+	case value < 0:
+		value = 0
+	case value >= 2*latMax*finalLatPrecision:
+		value = 2*latMax*finalLatPrecision - 1
+	}
+
+	return value
+}
+
+// longitudeValue returns the longitude as an integer number of final grid
+// cells from the antimeridian.
+func longitudeValue(degrees float64) int64 {
+	value := int64(math.Floor(math.Round(degrees*finalLngPrecision*1e6) / 1e6))
+	value += lngMax * finalLngPrecision
+	value %= 2 * lngMax * finalLngPrecision
+	if value < 0 {
+		value += 2 * lngMax * finalLngPrecision
+	}
+
+	return value
+}
+
+func encode(lat, lng float64, length int) (string, error) {
+	if length < MinCodeLength || (length < PairCodeLength && length%2 == 1) {
+		return "", fmt.Errorf("olc: invalid code length %d", length)
+	}
+
+	if length > MaxCodeLength {
+		length = MaxCodeLength
+	}
+
+	latValue, lngValue := latitudeValue(lat), longitudeValue(lng)
+
+	// Digits are computed from the last to the first.
+	digits := make([]byte, MaxCodeLength)
+	for i := MaxCodeLength - 1; i >= PairCodeLength; i-- {
+		digits[i] = alphabet[latValue%gridRows*gridColumns+lngValue%gridColumns]
+		latValue /= gridRows
+		lngValue /= gridColumns
+	}
+
+	for i := PairCodeLength - 2; i >= 0; i -= 2 {
+		digits[i] = alphabet[latValue%int64(encodingBase)]
+		digits[i+1] = alphabet[lngValue%int64(encodingBase)]
+		latValue /= int64(encodingBase)
+		lngValue /= int64(encodingBase)
+	}
+
+	if length < separatorPosition {
+		return string(digits[:length]) + strings.Repeat(string(Padding), separatorPosition-length) + string(Separator), nil
+	}
+
+	return string(digits[:separatorPosition]) + string(Separator) + string(digits[separatorPosition:length]), nil
+}
+
+// Encode returns the full code of the given number of digits containing a
+// location. Codes of fewer than ten digits must have an even number of
+// digits, and codes longer than fifteen digits are truncated.
+func Encode(latLng s2.LatLng, length int) (string, error) {
+	return encode(latLng.Lat.Degrees(), latLng.Lng.Degrees(), length)
+}
+
+// digitReplacer removes the separator and padding from a code.
+var digitReplacer = strings.NewReplacer(string(Separator), "", string(Padding), "")
+
+// decode returns the bounds in degrees of the area of a full code.
+func decode(code string) (r1.Interval, r1.Interval, error) {
+	if !IsFull(code) {
+		return r1.EmptyInterval(), r1.EmptyInterval(), fmt.Errorf("olc: %q is not a valid full code", code)
+	}
+
+	digits := digitReplacer.Replace(code)
+	if len(digits) > MaxCodeLength {
+		digits = digits[:MaxCodeLength]
+	}
+
+	// Pair digits.
+	latValue, lngValue := int64(-latMax*pairPrecision), int64(-lngMax*pairPrecision)
+	placeValue := int64(pairFirstPlaceValue)
+	n := len(digits)
+	if n > PairCodeLength {
+		n = PairCodeLength
+	}
+
+	for i := 0; i < n; i += 2 {
+		latValue += int64(alphabetIndexes[digits[i]]) * placeValue
+		lngValue += int64(alphabetIndexes[digits[i+1]]) * placeValue
+		if i < n-2 {
+			placeValue /= int64(encodingBase)
+		}
+	}
+
+	lat := float64(latValue) / pairPrecision
+	lng := float64(lngValue) / pairPrecision
+	latSize := float64(placeValue) / pairPrecision
+	lngSize := float64(placeValue) / pairPrecision
+
+	// Grid digits.
+	if len(digits) > PairCodeLength {
+		latValue, lngValue = 0, 0
+		rowPlaceValue, columnPlaceValue := int64(gridLatFirstPlaceValue), int64(gridLngFirstPlaceValue)
+		for i := PairCodeLength; i < len(digits); i++ {
+			index := int64(alphabetIndexes[digits[i]])
+			latValue += index / gridColumns * rowPlaceValue
+			lngValue += index % gridColumns * columnPlaceValue
+			if i < len(digits)-1 {
+				rowPlaceValue /= gridRows
+				columnPlaceValue /= gridColumns
+			}
+		}
+
+		lat += float64(latValue) / finalLatPrecision
+		lng += float64(lngValue) / finalLngPrecision
+		latSize = float64(rowPlaceValue) / finalLatPrecision
+		lngSize = float64(columnPlaceValue) / finalLngPrecision
+	}
+
+	return r1.Interval{Lo: lat, Hi: lat + latSize}, r1.Interval{Lo: lng, Hi: lng + lngSize}, nil
+}
+
+// Decode returns the area of a full code.
+func Decode(code string) (s2.Rect, error) {
+	lat, lng, err := decode(code)
+	if err != nil {
+		return s2.EmptyRect(), err
+	}
+
+	return s2.Rect{
+		Lat: r1.Interval{Lo: lat.Lo * math.Pi / 180, Hi: math.Min(lat.Hi, latMax) * math.Pi / 180},
+		Lng: s1.IntervalFromEndpoints(lng.Lo*math.Pi/180, lng.Hi*math.Pi/180),
+	}, nil
+}
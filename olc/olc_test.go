@@ -0,0 +1,194 @@
+package olc
+
+import (
+	"math"
+	"testing"
+
+	"github.com/golang/geo/s2"
+)
+
+// The test vectors are those of the encoding, decoding and validity tests of
+// the reference implementation.
+
+func TestValidity(t *testing.T) {
+	tests := []struct {
+		code                     string
+		isValid, isShort, isFull bool
+	}{
+		{"8FWC2345+G6", true, false, true},
+		{"8FWC2345+G6G", true, false, true},
+		{"8fwc2345+", true, false, true},
+		{"8FWCX400+", true, false, true},
+		{"WC2345+G6g", true, true, false},
+		{"2345+G6", true, true, false},
+		{"45+G6", true, true, false},
+		{"+G6", true, true, false},
+		{"G+", false, false, false},
+		{"+", false, false, false},
+		{"8FWC2345+G", false, false, false},
+		{"8FWC2_45+G6", false, false, false},
+		{"8FWC2η45+G6", false, false, false},
+		{"8FWC2345+G6+", false, false, false},
+		{"8FWC2345G6+", false, false, false},
+		{"8FWC2300+G6", false, false, false},
+		{"WC2300+G6g", false, false, false},
+		{"WC2345+G", false, false, false},
+		{"WC2300+", false, false, false},
+
+		// Codes beyond the poles or the antimeridian are valid but not full.
+		{"X2222222+22", true, false, false},
+		{"FX222222+22", true, false, false},
+		{"2X222222+22", true, false, false},
+	}
+
+	for _, test := range tests {
+		if IsValid(test.code) != test.isValid || IsShort(test.code) != test.isShort || IsFull(test.code) != test.isFull {
+			t.Errorf("%s: valid %v, short %v, full %v, want %v, %v, %v", test.code,
+				IsValid(test.code), IsShort(test.code), IsFull(test.code), test.isValid, test.isShort, test.isFull)
+		}
+	}
+}
+
+func TestEncode(t *testing.T) {
+	tests := []struct {
+		lat, lng float64
+		length   int
+		code     string
+	}{
+		{20.375, 2.775, 6, "7FG49Q00+"},
+		{20.3700625, 2.7821875, 10, "7FG49QCJ+2V"},
+		{20.3701125, 2.782234375, 11, "7FG49QCJ+2VX"},
+		{20.3701135, 2.78223535156, 13, "7FG49QCJ+2VXGJ"},
+		{47.0000625, 8.0000625, 10, "8FVC2222+22"},
+		{-41.2730625, 174.7859375, 10, "4VCPPQGP+Q9"},
+		{0.5, -179.5, 4, "62G20000+"},
+		{-89.5, -179.5, 4, "22220000+"},
+		{20.5, 2.5, 4, "7FG40000+"},
+		{-89.9999375, -179.9999375, 10, "22222222+22"},
+		{0.5, 179.5, 4, "6VGX0000+"},
+		{1, 1, 11, "6FH32222+222"},
+		{90, 1, 4, "CFX30000+"},
+		{92, 1, 4, "CFX30000+"},
+		{1, 180, 4, "62H20000+"},
+		{1, 181, 4, "62H30000+"},
+		{90, 1, 10, "CFX3X2X2+X2"},
+	}
+
+	for _, test := range tests {
+		code, err := encode(test.lat, test.lng, test.length)
+		if err != nil {
+			t.Errorf("%v, %v: %v", test.lat, test.lng, err)
+			continue
+		}
+
+		if code != test.code {
+			t.Errorf("%v, %v, %d: encoded %s, want %s", test.lat, test.lng, test.length, code, test.code)
+		}
+	}
+
+	code, err := Encode(s2.LatLngFromDegrees(47.0000625, 8.0000625), PairCodeLength)
+	if err != nil || code != "8FVC2222+22" {
+		t.Errorf("encoded %s, %v", code, err)
+	}
+
+	for _, length := range []int{-1, 0, 1, 3, 7, 9} {
+		if _, err := encode(0, 0, length); err == nil {
+			t.Errorf("length %d: no error", length)
+		}
+	}
+
+	// Lengths beyond the maximum are truncated.
+	if code, err := encode(1, 1, 20); err != nil || code != "6FH32222+2222222" {
+		t.Errorf("encoded %s, %v", code, err)
+	}
+}
+
+func TestDecode(t *testing.T) {
+
+	// Areas along the antimeridian start at longitude 180, as s1 intervals
+	// do.
+	tests := []struct {
+		code                       string
+		latLo, lngLo, latHi, lngHi float64
+	}{
+		{"7FG49Q00+", 20.35, 2.75, 20.4, 2.8},
+		{"7FG49QCJ+2V", 20.37, 2.782125, 20.370125, 2.78225},
+		{"7FG49QCJ+2VX", 20.3701, 2.78221875, 20.370125, 2.78225},
+		{"7FG49QCJ+2VXGJ", 20.370113, 2.782234375, 20.370114, 2.78223632812},
+		{"8FVC2222+22", 47.0, 8.0, 47.000125, 8.000125},
+		{"4VCPPQGP+Q9", -41.273125, 174.785875, -41.273, 174.786},
+		{"62G20000+", 0.0, 180.0, 1, -179},
+		{"22220000+", -90, 180, -89, -179},
+		{"7FG40000+", 20.0, 2.0, 21.0, 3.0},
+		{"22222222+22", -90.0, 180.0, -89.999875, -179.999875},
+		{"6VGX0000+", 0, 179, 1, 180},
+		{"6FH32222+222", 1.0, 1.0, 1.000025, 1.00003125},
+		{"CFX30000+", 89, 1, 90, 2},
+		{"62H20000+", 1, 180, 2, -179},
+		{"62H30000+", 1, -179, 2, -178},
+		{"CFX3X2X2+X2", 89.999875, 1, 90, 1.000125},
+		{"7fg49qcj+2vx", 20.3701, 2.78221875, 20.370125, 2.78225},
+		{"72222222+22", 10, 180, 10.000125, -179.999875},
+	}
+
+	for _, test := range tests {
+		rect, err := Decode(test.code)
+		if err != nil {
+			t.Errorf("%s: %v", test.code, err)
+			continue
+		}
+
+		lo, hi := rect.Lo(), rect.Hi()
+		got := []float64{lo.Lat.Degrees(), lo.Lng.Degrees(), hi.Lat.Degrees(), hi.Lng.Degrees()}
+		want := []float64{test.latLo, test.lngLo, test.latHi, test.lngHi}
+		for i := range got {
+			if math.Abs(got[i]-want[i]) > 1e-10 {
+				t.Errorf("%s: decoded %v, want %v", test.code, got, want)
+				break
+			}
+		}
+	}
+
+	rect, err := Decode("72222222+22")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if inside := s2.LatLngFromDegrees(10.0001, -180); !rect.ContainsLatLng(inside) {
+		t.Errorf("area %v does not contain %v", rect, inside)
+	}
+
+	if outside := s2.LatLngFromDegrees(10.0001, 179.9999); rect.ContainsLatLng(outside) {
+		t.Errorf("area %v contains %v", rect, outside)
+	}
+
+	for _, code := range []string{"2345+G6", "8FWC2345+G", "X2222222+22", ""} {
+		if _, err := Decode(code); err == nil {
+			t.Errorf("%q: no error", code)
+		}
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	for lat := -89.5; lat < 90; lat += 7.3 {
+		for lng := -179.5; lng < 180; lng += 11.7 {
+			for _, length := range []int{2, 4, 6, 8, 10, 11, 12, 13, 14, 15} {
+				code, err := encode(lat, lng, length)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				latInterval, lngInterval, err := decode(code)
+				if err != nil {
+					t.Fatalf("%s: %v", code, err)
+				}
+
+				// Locations on the edge of an area may be encoded in either
+				// of the areas sharing the edge.
+				if !latInterval.Expanded(1e-9).Contains(lat) || !lngInterval.Expanded(1e-9).Contains(lng) {
+					t.Errorf("%v, %v, %d: area of %s does not contain the location", lat, lng, length, code)
+				}
+			}
+		}
+	}
+}
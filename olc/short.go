@@ -0,0 +1,104 @@
+package olc
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/golang/geo/s2"
+)
+
+// pairResolutions are the sizes in degrees of the cells of each pair of
+// digits.
+var pairResolutions = [...]float64{20, 1, 0.05, 0.0025, 0.000125}
+
+// referenceDegrees returns the latitude and longitude in degrees of a
+// reference point.
+func referenceDegrees(reference s2.Point) (float64, float64) {
+	latLng := s2.LatLngFromPoint(reference).Normalized()
+	return latLng.Lat.Degrees(), latLng.Lng.Degrees()
+}
+
+// Shorten removes as many leading digits as possible from a full code such
+// that the code can be recovered relative to a reference point close to it.
+// Padded codes and codes of fewer than six digits cannot be shortened.
+func Shorten(code string, reference s2.Point) (string, error) {
+	if !IsFull(code) {
+		return "", fmt.Errorf("olc: %q is not a valid full code", code)
+	}
+
+	if strings.IndexByte(code, Padding) >= 0 {
+		return "", fmt.Errorf("olc: padded code %q cannot be shortened", code)
+	}
+
+	code = strings.ToUpper(code)
+	lat, lng, err := decode(code)
+	if err != nil {
+		return "", err
+	}
+
+	if len(digitReplacer.Replace(code)) < minTrimmableCodeLength {
+		return "", fmt.Errorf("olc: code %q is too short to be shortened", code)
+	}
+
+	refLat, refLng := referenceDegrees(reference)
+	distance := math.Max(math.Abs(lat.Center()-refLat), math.Abs(lng.Center()-refLng))
+	for i := len(pairResolutions) - 2; i >= 1; i-- {
+
+		// Leave a margin so that the code is recovered even if the reference
+		// point used differs slightly.
+		if distance < pairResolutions[i]*0.3 {
+			return code[(i+1)*2:], nil
+		}
+	}
+
+	return code, nil
+}
+
+// RecoverNearest returns the full code of the area nearest to a reference
+// point matching a short code. Full codes are returned as they are.
+func RecoverNearest(code string, reference s2.Point) (string, error) {
+	if IsFull(code) {
+		return strings.ToUpper(code), nil
+	}
+
+	if !IsShort(code) {
+		return "", fmt.Errorf("olc: %q is not a valid short code", code)
+	}
+
+	code = strings.ToUpper(code)
+	refLat, refLng := referenceDegrees(reference)
+
+	// Prefix the short code with the missing digits of the code of the
+	// reference point.
+	missing := separatorPosition - strings.IndexByte(code, Separator)
+	refCode, err := encode(refLat, refLng, PairCodeLength)
+	if err != nil {
+		return "", err
+	}
+
+	lat, lng, err := decode(refCode[:missing] + code)
+	if err != nil {
+		return "", err
+	}
+
+	// The area is within half the resolution of the missing digits of the
+	// reference point, otherwise the nearest matching area is adjacent.
+	resolution := math.Pow(float64(encodingBase), float64(2-missing/2))
+	centerLat, centerLng := lat.Center(), lng.Center()
+	switch {
+	case refLat+resolution/2 < centerLat && centerLat-resolution >= -latMax:
+		centerLat -= resolution
+	case refLat-resolution/2 > centerLat && centerLat+resolution <= latMax:
+		centerLat += resolution
+	}
+
+	switch {
+	case refLng+resolution/2 < centerLng:
+		centerLng -= resolution
+	case refLng-resolution/2 > centerLng:
+		centerLng += resolution
+	}
+
+	return encode(centerLat, centerLng, len(digitReplacer.Replace(refCode[:missing]+code)))
+}
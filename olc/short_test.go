@@ -0,0 +1,102 @@
+package olc
+
+import (
+	"testing"
+
+	"github.com/golang/geo/s2"
+)
+
+// The test vectors are those of the short code tests of the reference
+// implementation: codes are shortened and recovered (B), only shortened (S)
+// or only recovered (R).
+var shortCodeTests = []struct {
+	code      string
+	lat, lng  float64
+	shortCode string
+	testType  string
+}{
+	{"9C3W9QCJ+2VX", 51.3701125, -1.217765625, "+2VX", "B"},
+	{"9C3W9QCJ+2VX", 51.3708675, -1.217765625, "CJ+2VX", "B"},
+	{"9C3W9QCJ+2VX", 51.3693575, -1.217765625, "CJ+2VX", "B"},
+	{"9C3W9QCJ+2VX", 51.3701125, -1.218520625, "CJ+2VX", "B"},
+	{"9C3W9QCJ+2VX", 51.3701125, -1.217010625, "CJ+2VX", "B"},
+	{"9C3W9QCJ+2VX", 51.3852125, -1.217765625, "9QCJ+2VX", "B"},
+	{"9C3W9QCJ+2VX", 51.3550125, -1.217765625, "9QCJ+2VX", "B"},
+	{"9C3W9QCJ+2VX", 51.3701125, -1.202665625, "9QCJ+2VX", "B"},
+	{"9C3W9QCJ+2VX", 51.3701125, -1.232865625, "9QCJ+2VX", "B"},
+	{"9C3W9QCJ+2VX", 51.4181125, -1.217765625, "9QCJ+2VX", "B"},
+	{"9C3W9QCJ+2VX", 51.3221125, -1.217765625, "9QCJ+2VX", "B"},
+	{"9C3W9QCJ+2VX", 51.3701125, -1.265765625, "9QCJ+2VX", "B"},
+	{"9C3W9QCJ+2VX", 51.3701125, -1.169765625, "9QCJ+2VX", "B"},
+	{"8FJFW222+", 42.899, 9.012, "22+", "B"},
+	{"796RXG22+", 14.95125, -23.5001, "22+", "B"},
+	{"8FVC2GGG+GG", 46.976, 8.526, "2GGG+GG", "B"},
+	{"8FRCXGGG+GG", 47.026, 8.526, "XGGG+GG", "B"},
+	{"8FR9GXGG+GG", 46.526, 8.026, "GXGG+GG", "B"},
+	{"8FRCG2GG+GG", 46.526, 7.976, "G2GG+GG", "B"},
+	{"CFX22222+22", 89.6, 0.0, "2222+22", "R"},
+	{"2CXXXXXX+XX", -81.0, 0.0, "XXXXXX+XX", "R"},
+	{"8FRCG2GG+GG", 46.526, 7.976, "8FRCG2GG+GG", "R"},
+	{"8FRCG2GG+GG", 46.526, 7.976, "8frCG2GG+gG", "R"},
+}
+
+func TestShorten(t *testing.T) {
+	for _, test := range shortCodeTests {
+		if test.testType == "R" {
+			continue
+		}
+
+		reference := s2.PointFromLatLng(s2.LatLngFromDegrees(test.lat, test.lng))
+		shortCode, err := Shorten(test.code, reference)
+		if err != nil {
+			t.Errorf("%s, %v, %v: %v", test.code, test.lat, test.lng, err)
+			continue
+		}
+
+		if shortCode != test.shortCode {
+			t.Errorf("%s, %v, %v: shortened to %s, want %s", test.code, test.lat, test.lng, shortCode, test.shortCode)
+		}
+	}
+
+	reference := s2.PointFromLatLng(s2.LatLngFromDegrees(51.3701125, -1.217765625))
+	for _, code := range []string{"9C3W9Q00+", "9C3W+", "CJ+2VX", "9C3W"} {
+		if _, err := Shorten(code, reference); err == nil {
+			t.Errorf("%s: no error", code)
+		}
+	}
+
+	// Codes far from the reference are kept whole.
+	if code, err := Shorten("9C3W9QCJ+2VX", s2.PointFromLatLng(s2.LatLngFromDegrees(0, 0))); err != nil || code != "9C3W9QCJ+2VX" {
+		t.Errorf("shortened to %s, %v", code, err)
+	}
+}
+
+func TestRecoverNearest(t *testing.T) {
+	for _, test := range shortCodeTests {
+		if test.testType == "S" {
+			continue
+		}
+
+		reference := s2.PointFromLatLng(s2.LatLngFromDegrees(test.lat, test.lng))
+		code, err := RecoverNearest(test.shortCode, reference)
+		if err != nil {
+			t.Errorf("%s, %v, %v: %v", test.shortCode, test.lat, test.lng, err)
+			continue
+		}
+
+		if code != test.code {
+			t.Errorf("%s, %v, %v: recovered %s, want %s", test.shortCode, test.lat, test.lng, code, test.code)
+		}
+	}
+
+	reference := s2.PointFromLatLng(s2.LatLngFromDegrees(0, 0))
+	if code, err := RecoverNearest("9c3w9qcj+2vx", reference); err != nil || code != "9C3W9QCJ+2VX" {
+		t.Errorf("recovered full code as %s, %v", code, err)
+	}
+
+	for _, code := range []string{"CJ+2", "9C3W9Q00+2VX", ""} {
+		if _, err := RecoverNearest(code, reference); err == nil {
+			t.Errorf("%q: no error", code)
+		}
+	}
+}
@@ -0,0 +1,302 @@
+package osm
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/golang/geo/s2"
+
+	"github.com/topos-ai/geoutil"
+	"github.com/topos-ai/geoutil/encoding/geojson"
+)
+
+var (
+	errUnclosedRing = errors.New("osm: member ways do not form closed rings")
+	errMissingNode  = errors.New("osm: way references a missing node")
+)
+
+// linearTags are the keys of closed ways that are lines unless tagged
+// area=yes.
+var linearTags = []string{"highway", "barrier"}
+
+// isArea reports whether a closed way represents an area.
+func isArea(tags map[string]string) bool {
+	switch tags["area"] {
+	case "yes":
+		return true
+	case "no":
+		return false
+	}
+
+	for _, key := range linearTags {
+		if _, ok := tags[key]; ok {
+			return false
+		}
+	}
+
+	return true
+}
+
+// index resolves the references of relations and ways.
+type index struct {
+	nodes map[int64]*Node
+	ways  map[int64]*Way
+}
+
+// coordinates returns the positions of a sequence of node IDs.
+func (x *index) coordinates(nodeIDs []int64) ([][]float64, error) {
+	coords := make([][]float64, len(nodeIDs))
+	for i, id := range nodeIDs {
+		node, ok := x.nodes[id]
+		if !ok {
+			return nil, errMissingNode
+		}
+
+		latLng := s2.LatLngFromPoint(node.Point)
+		coords[i] = []float64{latLng.Lng.Degrees(), latLng.Lat.Degrees()}
+	}
+
+	return coords, nil
+}
+
+func reversed(ids []int64) []int64 {
+	r := make([]int64, len(ids))
+	for i, id := range ids {
+		r[len(ids)-1-i] = id
+	}
+
+	return r
+}
+
+// joinRings joins ways into closed rings by matching their end nodes,
+// reversing ways as needed.
+func joinRings(ways [][]int64) ([][]int64, error) {
+	remaining := [][]int64{}
+	for _, way := range ways {
+		if len(way) >= 2 {
+			remaining = append(remaining, way)
+		}
+	}
+
+	rings := [][]int64{}
+	for len(remaining) > 0 {
+		ring := append([]int64{}, remaining[0]...)
+		remaining = remaining[1:]
+		for ring[0] != ring[len(ring)-1] {
+			found := false
+			for i, way := range remaining {
+				first, last := way[0], way[len(way)-1]
+				switch {
+				case first == ring[len(ring)-1]:
+					ring = append(ring, way[1:]...)
+				case last == ring[len(ring)-1]:
+					ring = append(ring, reversed(way[:len(way)-1])...)
+				case last == ring[0]:
+					ring = append(append([]int64{}, way[:len(way)-1]...), ring...)
+				case first == ring[0]:
+					ring = append(reversed(way[1:]), ring...)
+				default:
+					continue
+				}
+
+				remaining = append(remaining[:i], remaining[i+1:]...)
+				found = true
+				break
+			}
+
+			if !found {
+				return nil, errUnclosedRing
+			}
+		}
+
+		if len(ring) < 4 {
+			return nil, fmt.Errorf("osm: ring with %d nodes", len(ring))
+		}
+
+		rings = append(rings, ring)
+	}
+
+	return rings, nil
+}
+
+// multipolygon assembles the outer and inner member ways of a multipolygon
+// relation. Each inner ring becomes a hole of the smallest outer ring
+// containing it; the orientation of rings is left to geoutil, which
+// normalizes shells and inverts holes as needed.
+func (x *index) multipolygon(relation *Relation) (*s2.Polygon, error) {
+	outerWays, innerWays := [][]int64{}, [][]int64{}
+	for _, member := range relation.Members {
+		if member.Type != MemberWay {
+			continue
+		}
+
+		way, ok := x.ways[member.Ref]
+		if !ok {
+			return nil, fmt.Errorf("osm: relation %d references missing way %d", relation.ID, member.Ref)
+		}
+
+		switch member.Role {
+		case "outer", "":
+			outerWays = append(outerWays, way.NodeIDs)
+		case "inner":
+			innerWays = append(innerWays, way.NodeIDs)
+		}
+	}
+
+	outerRings, err := joinRings(outerWays)
+	if err != nil {
+		return nil, err
+	}
+
+	innerRings, err := joinRings(innerWays)
+	if err != nil {
+		return nil, err
+	}
+
+	polygons := make([][][][]float64, len(outerRings))
+	outerLoops := make([]*s2.Loop, len(outerRings))
+	for i, ring := range outerRings {
+		coords, err := x.coordinates(ring)
+		if err != nil {
+			return nil, err
+		}
+
+		polygon, err := geoutil.PolygonFromPolygonCoordinates([][][]float64{coords})
+		if err != nil {
+			return nil, err
+		}
+
+		polygons[i] = [][][]float64{coords}
+		outerLoops[i] = polygon.Loop(0)
+	}
+
+	for _, ring := range innerRings {
+		coords, err := x.coordinates(ring)
+		if err != nil {
+			return nil, err
+		}
+
+		polygon, err := geoutil.PolygonFromPolygonCoordinates([][][]float64{coords})
+		if err != nil {
+			return nil, err
+		}
+
+		inner := polygon.Loop(0)
+		shell := -1
+		for i, outer := range outerLoops {
+			if outer.Contains(inner) && (shell < 0 || outer.Area() < outerLoops[shell].Area()) {
+				shell = i
+			}
+		}
+
+		// Inner rings outside every outer ring are dropped.
+		if shell >= 0 {
+			polygons[shell] = append(polygons[shell], coords)
+		}
+	}
+
+	return geoutil.PolygonFromMultiPolygonCoordinates(polygons)
+}
+
+func properties(tags map[string]string) map[string]interface{} {
+	p := make(map[string]interface{}, len(tags))
+	for k, v := range tags {
+		p[k] = v
+	}
+
+	return p
+}
+
+// FeatureCollection converts the tagged elements of the data into features:
+// nodes become s2.Point geometries, open ways *s2.Polyline geometries, and
+// closed ways and multipolygon or boundary relations *s2.Polygon
+// geometries. Closed highways and barriers are kept as polylines unless
+// tagged area=yes. Tags become properties and IDs take the form "way/123".
+//
+// Untagged elements, other relations, and ways or relations that reference
+// elements missing from the extract or that do not form closed rings are
+// skipped.
+func (data *Data) FeatureCollection() (*geojson.FeatureCollection, error) {
+	x := &index{
+		nodes: make(map[int64]*Node, len(data.Nodes)),
+		ways:  make(map[int64]*Way, len(data.Ways)),
+	}
+
+	for _, node := range data.Nodes {
+		x.nodes[node.ID] = node
+	}
+
+	for _, way := range data.Ways {
+		x.ways[way.ID] = way
+	}
+
+	fc := &geojson.FeatureCollection{
+		Features: []*geojson.Feature{},
+	}
+
+	for _, node := range data.Nodes {
+		if len(node.Tags) == 0 {
+			continue
+		}
+
+		fc.Features = append(fc.Features, &geojson.Feature{
+			ID:         fmt.Sprintf("node/%d", node.ID),
+			Properties: properties(node.Tags),
+			Geometry:   node.Point,
+		})
+	}
+
+	for _, way := range data.Ways {
+		if len(way.Tags) == 0 || len(way.NodeIDs) < 2 {
+			continue
+		}
+
+		coords, err := x.coordinates(way.NodeIDs)
+		if err == errMissingNode {
+			continue
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		var geometry interface{}
+		n := len(way.NodeIDs)
+		if n >= 4 && way.NodeIDs[0] == way.NodeIDs[n-1] && isArea(way.Tags) {
+			geometry, err = geoutil.PolygonFromPolygonCoordinates([][][]float64{coords})
+		} else {
+			geometry, err = geoutil.PolylineFromLineStringCoordinates(coords)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		fc.Features = append(fc.Features, &geojson.Feature{
+			ID:         fmt.Sprintf("way/%d", way.ID),
+			Properties: properties(way.Tags),
+			Geometry:   geometry,
+		})
+	}
+
+	for _, relation := range data.Relations {
+		switch relation.Tags["type"] {
+		case "multipolygon", "boundary":
+		default:
+			continue
+		}
+
+		polygon, err := x.multipolygon(relation)
+		if err != nil {
+			continue
+		}
+
+		fc.Features = append(fc.Features, &geojson.Feature{
+			ID:         fmt.Sprintf("relation/%d", relation.ID),
+			Properties: properties(relation.Tags),
+			Geometry:   polygon,
+		})
+	}
+
+	return fc, nil
+}
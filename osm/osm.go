@@ -0,0 +1,78 @@
+// Package osm reads OpenStreetMap data and assembles its ways and
+// multipolygon relations into s2 geometries.
+package osm
+
+import (
+	"github.com/golang/geo/s2"
+)
+
+// Member types.
+const (
+	MemberNode     = "node"
+	MemberWay      = "way"
+	MemberRelation = "relation"
+)
+
+// Node represents an OSM node.
+type Node struct {
+	ID    int64
+	Point s2.Point
+	Tags  map[string]string
+}
+
+// Way represents an OSM way as the IDs of its nodes. Polyline holds the
+// geometry of the way when the locations of its nodes are known to the reader.
+type Way struct {
+	ID       int64
+	NodeIDs  []int64
+	Polyline *s2.Polyline
+	Tags     map[string]string
+}
+
+// Member represents a member of an OSM relation.
+type Member struct {
+	Type string
+	Ref  int64
+	Role string
+}
+
+// Relation represents an OSM relation.
+type Relation struct {
+	ID      int64
+	Members []Member
+	Tags    map[string]string
+}
+
+// Data holds the elements of an OSM extract in file order.
+type Data struct {
+	Nodes     []*Node
+	Ways      []*Way
+	Relations []*Relation
+}
+
+// resolveWays sets the polylines of ways whose nodes all have known
+// locations.
+func resolveWays(ways []*Way, locations map[int64]s2.Point) {
+	for _, way := range ways {
+		if way.Polyline != nil {
+			continue
+		}
+
+		points := make([]s2.Point, len(way.NodeIDs))
+		resolved := true
+		for i, id := range way.NodeIDs {
+			point, ok := locations[id]
+			if !ok {
+				resolved = false
+				break
+			}
+
+			points[i] = point
+		}
+
+		if resolved {
+			polyline := s2.Polyline(points)
+			way.Polyline = &polyline
+		}
+	}
+}
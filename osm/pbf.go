@@ -0,0 +1,277 @@
+package osm
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+	"sync"
+
+	"github.com/golang/geo/s2"
+
+	"github.com/topos-ai/geoutil/internal/pbf"
+)
+
+var errPBFReaderClosed = errors.New("osm: read from a closed PBFReader")
+
+// pbfBlock is the decoded contents of a blob: either the header block or a
+// primitive block.
+type pbfBlock struct {
+	header *PBFHeader
+	data   *Data
+	err    error
+}
+
+type pbfJob struct {
+	blobType string
+	blob     []byte
+	result   chan<- *pbfBlock
+}
+
+// PBFReader reads the blocks of an .osm.pbf file. Blobs are read sequentially
+// but decompressed and decoded in parallel, and blocks are returned in file
+// order.
+//
+// Ways are returned with a Polyline when the file has the LocationsOnWays
+// feature, which carries the geometry of ways directly, or when their nodes
+// are in the same block.
+type PBFReader struct {
+
+	// CacheLocations makes the reader remember the location of every node it
+	// returns so that ways following their nodes, as in sorted files, are
+	// returned with a Polyline. The cache grows with the number of nodes of
+	// the file, to tens of gigabytes for the planet, so large files are best
+	// read with LocationsOnWays instead. It must be set before the first call
+	// to Read.
+	CacheLocations bool
+
+	results   chan chan *pbfBlock
+	done      chan struct{}
+	closeOnce sync.Once
+	header    *PBFHeader
+	locations map[int64]s2.Point
+	err       error
+}
+
+// NewPBFReader returns a reader decoding blobs with the given number of
+// goroutines, or with GOMAXPROCS goroutines if workers is not positive.
+func NewPBFReader(r io.Reader, workers int) *PBFReader {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+
+	pr := &PBFReader{
+		results: make(chan chan *pbfBlock, workers),
+		done:    make(chan struct{}),
+	}
+
+	jobs := make(chan *pbfJob)
+	for i := 0; i < workers; i++ {
+		go decodeBlobs(jobs)
+	}
+
+	go pr.readBlobs(r, jobs)
+	return pr
+}
+
+func decodeBlobs(jobs <-chan *pbfJob) {
+	for job := range jobs {
+		block := &pbfBlock{}
+		data, err := blobData(job.blob)
+		switch {
+		case err != nil:
+			block.err = err
+		case job.blobType == blobTypeHeader:
+			block.header, block.err = decodeHeaderBlock(data)
+		default:
+			block.data, block.err = decodePrimitiveBlock(data)
+		}
+
+		job.result <- block
+	}
+}
+
+// readBlob reads the next BlobHeader and Blob of the file.
+func readBlob(r io.Reader) (string, []byte, error) {
+	var size uint32
+	if err := binary.Read(r, binary.BigEndian, &size); err != nil {
+		return "", nil, err
+	}
+
+	if size > maxBlobHeaderSize {
+		return "", nil, fmt.Errorf("osm: blob header size %d exceeds the maximum", size)
+	}
+
+	headerData := make([]byte, size)
+	if _, err := io.ReadFull(r, headerData); err != nil {
+		return "", nil, io.ErrUnexpectedEOF
+	}
+
+	blobType, dataSize := "", uint64(0)
+	hr := pbf.NewReader(headerData)
+	for hr.Next() {
+		switch hr.Field() {
+		case blobHeaderType:
+			blobType = hr.String()
+		case blobHeaderDataSize:
+			dataSize = hr.Uint64()
+		default:
+			hr.Skip()
+		}
+	}
+
+	if err := hr.Err(); err != nil {
+		return "", nil, err
+	}
+
+	if dataSize > maxBlobSize {
+		return "", nil, fmt.Errorf("osm: blob size %d exceeds the maximum", dataSize)
+	}
+
+	blob := make([]byte, dataSize)
+	if _, err := io.ReadFull(r, blob); err != nil {
+		return "", nil, io.ErrUnexpectedEOF
+	}
+
+	return blobType, blob, nil
+}
+
+func (pr *PBFReader) readBlobs(r io.Reader, jobs chan<- *pbfJob) {
+	defer close(pr.results)
+	defer close(jobs)
+
+	for {
+		blobType, blob, err := readBlob(r)
+		if err == io.EOF {
+			return
+		}
+
+		result := make(chan *pbfBlock, 1)
+		select {
+		case pr.results <- result:
+		case <-pr.done:
+			return
+		}
+
+		if err != nil {
+			result <- &pbfBlock{err: err}
+			return
+		}
+
+		switch blobType {
+		case blobTypeHeader, blobTypeData:
+		default:
+
+			// Unknown blob types are skipped.
+			result <- &pbfBlock{}
+			continue
+		}
+
+		// The result is queued, so it is filled even when the reader is closed
+		// before the blob is decoded.
+		select {
+		case jobs <- &pbfJob{blobType: blobType, blob: blob, result: result}:
+		case <-pr.done:
+			result <- &pbfBlock{err: errPBFReaderClosed}
+			return
+		}
+	}
+}
+
+// resolve sets the polylines of the ways of a block from the locations of
+// the nodes of the block and, with CacheLocations, of previous blocks.
+func (pr *PBFReader) resolve(data *Data) {
+	if !pr.CacheLocations && (len(data.Nodes) == 0 || len(data.Ways) == 0) {
+		return
+	}
+
+	locations := pr.locations
+	switch {
+	case !pr.CacheLocations:
+		locations = make(map[int64]s2.Point, len(data.Nodes))
+	case locations == nil:
+		locations = map[int64]s2.Point{}
+		pr.locations = locations
+	}
+
+	for _, node := range data.Nodes {
+		locations[node.ID] = node.Point
+	}
+
+	resolveWays(data.Ways, locations)
+}
+
+// Read returns the elements of the next primitive block, or io.EOF at the end
+// of the file.
+func (pr *PBFReader) Read() (*Data, error) {
+	if pr.err != nil {
+		return nil, pr.err
+	}
+
+	select {
+	case <-pr.done:
+		pr.err = errPBFReaderClosed
+		return nil, pr.err
+	default:
+	}
+
+	for result := range pr.results {
+		block := <-result
+		switch {
+		case block.err != nil:
+			pr.err = block.err
+			pr.Close()
+			return nil, block.err
+		case block.header != nil:
+			pr.header = block.header
+		case block.data != nil:
+			if pr.header == nil {
+				pr.err = fmt.Errorf("osm: data block before header block")
+				pr.Close()
+				return nil, pr.err
+			}
+
+			pr.resolve(block.data)
+			return block.data, nil
+		}
+	}
+
+	pr.err = io.EOF
+	return nil, io.EOF
+}
+
+// Header returns the header block of the file, which is known once Read has
+// returned a block.
+func (pr *PBFReader) Header() *PBFHeader {
+	return pr.header
+}
+
+// ReadAll reads the remaining blocks into a single Data.
+func (pr *PBFReader) ReadAll() (*Data, error) {
+	all := &Data{}
+	for {
+		data, err := pr.Read()
+		if err == io.EOF {
+			return all, nil
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		all.Nodes = append(all.Nodes, data.Nodes...)
+		all.Ways = append(all.Ways, data.Ways...)
+		all.Relations = append(all.Relations, data.Relations...)
+	}
+}
+
+// Close stops the goroutines of the reader. It must be called if the file is
+// not read to the end.
+func (pr *PBFReader) Close() error {
+	pr.closeOnce.Do(func() {
+		close(pr.done)
+	})
+
+	return nil
+}
@@ -0,0 +1,246 @@
+package osm
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/topos-ai/geoutil/internal/pbf"
+)
+
+func readPBFFixture(t *testing.T, name string) []byte {
+	t.Helper()
+	data, err := ioutil.ReadFile("testdata/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return data
+}
+
+func TestPBFReader(t *testing.T) {
+	pr := NewPBFReader(bytes.NewReader(readPBFFixture(t, "sample.osm.pbf")), 2)
+	defer pr.Close()
+
+	nodes, err := pr.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	header := pr.Header()
+	if header == nil || header.WritingProgram != "geoutil-test" || header.Source != "hand" ||
+		!reflect.DeepEqual(header.RequiredFeatures, []string{"OsmSchema-V0.6", "DenseNodes"}) ||
+		!reflect.DeepEqual(header.OptionalFeatures, []string{"Sort.Type_then_ID"}) {
+		t.Errorf("read header %+v", header)
+	}
+
+	if header != nil {
+		lo, hi := header.Bounds.Lo(), header.Bounds.Hi()
+		if lo.Lat.Degrees() != 0 || lo.Lng.Degrees() != 0 || math.Abs(hi.Lat.Degrees()-14) > 1e-9 || math.Abs(hi.Lng.Degrees()-14) > 1e-9 {
+			t.Errorf("read bounds %v", header.Bounds)
+		}
+	}
+
+	ids := []int64{}
+	for _, node := range nodes.Nodes {
+		ids = append(ids, node.ID)
+	}
+
+	if !reflect.DeepEqual(ids, []int64{1, 2, 3, 4, 9, 5, 6}) {
+		t.Errorf("read nodes %v", ids)
+	}
+
+	cafe := nodes.Nodes[4]
+	if !cafe.Point.ApproxEqual(at(0.5, 0.5)) || !reflect.DeepEqual(cafe.Tags, map[string]string{"amenity": "cafe", "name": "Corner Café"}) {
+		t.Errorf("read node %+v", cafe)
+	}
+
+	if !nodes.Nodes[6].Point.ApproxEqual(at(0, 3)) || len(nodes.Nodes[6].Tags) != 0 {
+		t.Errorf("read node %+v", nodes.Nodes[6])
+	}
+
+	ways, err := pr.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(ways.Ways) != 3 || len(ways.Relations) != 1 {
+		t.Fatalf("read %d ways and %d relations", len(ways.Ways), len(ways.Relations))
+	}
+
+	if way := ways.Ways[0]; way.ID != 10 || !reflect.DeepEqual(way.NodeIDs, []int64{1, 2, 3, 4, 1}) || way.Tags["building"] != "yes" {
+		t.Errorf("read way %+v", way)
+	}
+
+	// Without the location cache, only the way carrying its own locations
+	// has a polyline. Its coordinates use the granularity and offset of its
+	// block.
+	if ways.Ways[0].Polyline != nil || ways.Ways[1].Polyline != nil {
+		t.Errorf("resolved ways from the nodes of another block")
+	}
+
+	if polyline := ways.Ways[2].Polyline; polyline == nil || len(*polyline) != 2 ||
+		!(*polyline)[0].ApproxEqual(at(5, 5)) || !(*polyline)[1].ApproxEqual(at(6, 6)) {
+		t.Errorf("read way locations %v", ways.Ways[2].Polyline)
+	}
+
+	want := []Member{
+		{Type: MemberWay, Ref: 11, Role: ""},
+		{Type: MemberNode, Ref: 9, Role: "stop"},
+	}
+
+	if relation := ways.Relations[0]; relation.ID != 102 || relation.Tags["type"] != "route" || !reflect.DeepEqual(relation.Members, want) {
+		t.Errorf("read relation %+v", relation)
+	}
+
+	if _, err := pr.Read(); err != io.EOF {
+		t.Errorf("read past the last block: %v", err)
+	}
+}
+
+func TestPBFReaderCacheLocations(t *testing.T) {
+	pr := NewPBFReader(bytes.NewReader(readPBFFixture(t, "sample.osm.pbf")), 0)
+	pr.CacheLocations = true
+	data, err := pr.ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(data.Nodes) != 7 || len(data.Ways) != 3 || len(data.Relations) != 1 {
+		t.Fatalf("read %d nodes, %d ways and %d relations", len(data.Nodes), len(data.Ways), len(data.Relations))
+	}
+
+	building := data.Ways[0].Polyline
+	if building == nil || len(*building) != 5 || !(*building)[2].ApproxEqual(at(1, 1)) {
+		t.Errorf("resolved way 10 as %v", building)
+	}
+
+	if data.Ways[1].Polyline == nil || !(*data.Ways[1].Polyline)[2].ApproxEqual(at(0, 3)) {
+		t.Errorf("resolved way 11 as %v", data.Ways[1].Polyline)
+	}
+
+	fc, err := data.FeatureCollection()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ids := []interface{}{}
+	for _, f := range fc.Features {
+		ids = append(ids, f.ID)
+	}
+
+	if want := []interface{}{"node/9", "way/10", "way/11"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("converted features %v, want %v", ids, want)
+	}
+}
+
+func TestPBFReaderResolve(t *testing.T) {
+
+	// Ways are resolved from the nodes of their own block without the cache.
+	pr := &PBFReader{}
+	data := &Data{
+		Nodes: []*Node{{ID: 1, Point: at(0, 0)}, {ID: 2, Point: at(0, 1)}},
+		Ways:  []*Way{{ID: 1, NodeIDs: []int64{1, 2}}, {ID: 2, NodeIDs: []int64{2, 3}}},
+	}
+
+	pr.resolve(data)
+	if data.Ways[0].Polyline == nil || data.Ways[1].Polyline != nil || pr.locations != nil {
+		t.Errorf("resolved %v and %v, cached %v", data.Ways[0].Polyline, data.Ways[1].Polyline, pr.locations)
+	}
+}
+
+// blob returns a BlobHeader and Blob holding raw data.
+func blob(blobType string, data []byte) []byte {
+	body := &pbf.Writer{}
+	body.Bytes(blobRaw, data)
+	header := &pbf.Writer{}
+	header.String(blobHeaderType, blobType)
+	header.Uint64(blobHeaderDataSize, uint64(len(body.Data())))
+
+	b := &bytes.Buffer{}
+	binary.Write(b, binary.BigEndian, uint32(len(header.Data())))
+	b.Write(header.Data())
+	b.Write(body.Data())
+	return b.Bytes()
+}
+
+func TestPBFReaderErrors(t *testing.T) {
+	fixture := readPBFFixture(t, "sample.osm.pbf")
+	headerSize := 4 + int(binary.BigEndian.Uint32(fixture))
+
+	unsupported := &pbf.Writer{}
+	unsupported.String(headerBlockRequiredFeatures, "HistoricalInformationV2")
+
+	zstd := &pbf.Writer{}
+	zstd.Bytes(blobZstdData, []byte{0})
+	zstdBlob := &pbf.Writer{}
+	zstdBlob.String(blobHeaderType, blobTypeData)
+	zstdBlob.Uint64(blobHeaderDataSize, uint64(len(zstd.Data())))
+
+	tests := map[string][]byte{
+		"truncated":            fixture[:len(fixture)-10],
+		"truncated header":     fixture[:headerSize-3],
+		"data before header":   blob(blobTypeData, nil),
+		"unsupported feature":  blob(blobTypeHeader, unsupported.Data()),
+		"header size":          {0xff, 0xff, 0xff, 0xff},
+		"invalid string index": append(blob(blobTypeHeader, nil), blob(blobTypeData, []byte{0x12, 0x04, 0x1a, 0x02, 0x10, 0x05})...),
+	}
+
+	b := &bytes.Buffer{}
+	binary.Write(b, binary.BigEndian, uint32(len(zstdBlob.Data())))
+	b.Write(zstdBlob.Data())
+	b.Write(zstd.Data())
+	tests["compression"] = append(blob(blobTypeHeader, nil), b.Bytes()...)
+
+	for name, data := range tests {
+		pr := NewPBFReader(bytes.NewReader(data), 1)
+		if _, err := pr.ReadAll(); err == nil || err == io.EOF {
+			t.Errorf("%s: read with error %v", name, err)
+		}
+
+		pr.Close()
+	}
+}
+
+func TestPBFReaderClose(t *testing.T) {
+	data := blob(blobTypeHeader, nil)
+	for i := 0; i < 1000; i++ {
+		data = append(data, blob(blobTypeData, nil)...)
+	}
+
+	// Reads racing with Close return an error instead of waiting for blobs
+	// that are no longer decoded.
+	for i := 0; i < 20; i++ {
+		pr := NewPBFReader(bytes.NewReader(data), 2)
+		if _, err := pr.Read(); err != nil {
+			t.Fatal(err)
+		}
+
+		errs := make(chan error)
+		go func() {
+			_, err := pr.ReadAll()
+			errs <- err
+		}()
+
+		pr.Close()
+		select {
+		case err := <-errs:
+			if err != nil && err != errPBFReaderClosed {
+				t.Errorf("read with error %v", err)
+			}
+		case <-time.After(10 * time.Second):
+			t.Fatal("read blocked after Close")
+		}
+	}
+
+	pr := NewPBFReader(bytes.NewReader(data), 2)
+	pr.Close()
+	if _, err := pr.Read(); err != errPBFReaderClosed {
+		t.Errorf("read after Close with error %v", err)
+	}
+}
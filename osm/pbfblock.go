@@ -0,0 +1,568 @@
+package osm
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/golang/geo/s2"
+
+	"github.com/topos-ai/geoutil/internal/pbf"
+)
+
+// Fields of the fileformat.proto and osmformat.proto messages.
+const (
+	blobHeaderType     = 1
+	blobHeaderDataSize = 3
+
+	blobRaw      = 1
+	blobRawSize  = 2
+	blobZlibData = 3
+	blobLZMAData = 4
+	blobLZ4Data  = 6
+	blobZstdData = 7
+
+	headerBlockBBox             = 1
+	headerBlockRequiredFeatures = 4
+	headerBlockOptionalFeatures = 5
+	headerBlockWritingProgram   = 16
+	headerBlockSource           = 17
+
+	headerBBoxLeft   = 1
+	headerBBoxRight  = 2
+	headerBBoxTop    = 3
+	headerBBoxBottom = 4
+
+	primitiveBlockStringTable    = 1
+	primitiveBlockPrimitiveGroup = 2
+	primitiveBlockGranularity    = 17
+	primitiveBlockLatOffset      = 19
+	primitiveBlockLonOffset      = 20
+
+	stringTableS = 1
+
+	primitiveGroupNodes     = 1
+	primitiveGroupDense     = 2
+	primitiveGroupWays      = 3
+	primitiveGroupRelations = 4
+
+	nodeID   = 1
+	nodeKeys = 2
+	nodeVals = 3
+	nodeLat  = 8
+	nodeLon  = 9
+
+	denseNodesID       = 1
+	denseNodesLat      = 8
+	denseNodesLon      = 9
+	denseNodesKeysVals = 10
+
+	wayID   = 1
+	wayKeys = 2
+	wayVals = 3
+	wayRefs = 8
+	wayLat  = 9
+	wayLon  = 10
+
+	relationID       = 1
+	relationKeys     = 2
+	relationVals     = 3
+	relationRolesSID = 8
+	relationMemIDs   = 9
+	relationTypes    = 10
+)
+
+const (
+	blobTypeHeader = "OSMHeader"
+	blobTypeData   = "OSMData"
+
+	maxBlobHeaderSize = 64 * 1024
+	maxBlobSize       = 32 * 1024 * 1024
+)
+
+// supportedFeatures are the required features of a file that can be read.
+var supportedFeatures = map[string]bool{
+	"OsmSchema-V0.6":        true,
+	"DenseNodes":            true,
+	"HistoricalInformation": true,
+	"LocationsOnWays":       true,
+}
+
+// PBFHeader holds the header block of an .osm.pbf file.
+type PBFHeader struct {
+	Bounds           s2.Rect
+	RequiredFeatures []string
+	OptionalFeatures []string
+	WritingProgram   string
+	Source           string
+}
+
+// blobData returns the uncompressed contents of a Blob message.
+func blobData(data []byte) ([]byte, error) {
+	rawSize := 0
+	r := pbf.NewReader(data)
+	for r.Next() {
+		switch r.Field() {
+		case blobRaw:
+			return r.Bytes(), r.Err()
+		case blobRawSize:
+			rawSize = int(r.Uint64())
+		case blobZlibData:
+			if rawSize > maxBlobSize {
+				return nil, fmt.Errorf("osm: blob size %d exceeds the maximum", rawSize)
+			}
+
+			zr, err := zlib.NewReader(bytes.NewReader(r.Bytes()))
+			if err != nil {
+				return nil, err
+			}
+
+			defer zr.Close()
+			return ioutil.ReadAll(io.LimitReader(zr, maxBlobSize))
+		case blobLZMAData, blobLZ4Data, blobZstdData:
+			return nil, fmt.Errorf("osm: unsupported blob compression %d", r.Field())
+		default:
+			r.Skip()
+		}
+	}
+
+	if err := r.Err(); err != nil {
+		return nil, err
+	}
+
+	return nil, fmt.Errorf("osm: blob without data")
+}
+
+func decodeHeaderBlock(data []byte) (*PBFHeader, error) {
+	header := &PBFHeader{
+		Bounds: s2.FullRect(),
+	}
+
+	r := pbf.NewReader(data)
+	for r.Next() {
+		switch r.Field() {
+		case headerBlockBBox:
+			var left, right, top, bottom int64
+			br := pbf.NewReader(r.Bytes())
+			for br.Next() {
+				switch br.Field() {
+				case headerBBoxLeft:
+					left = br.Sint64()
+				case headerBBoxRight:
+					right = br.Sint64()
+				case headerBBoxTop:
+					top = br.Sint64()
+				case headerBBoxBottom:
+					bottom = br.Sint64()
+				default:
+					br.Skip()
+				}
+			}
+
+			if err := br.Err(); err != nil {
+				return nil, err
+			}
+
+			header.Bounds = s2.RectFromLatLng(s2.LatLngFromDegrees(float64(bottom)*1e-9, float64(left)*1e-9)).
+				AddPoint(s2.LatLngFromDegrees(float64(top)*1e-9, float64(right)*1e-9))
+		case headerBlockRequiredFeatures:
+			header.RequiredFeatures = append(header.RequiredFeatures, r.String())
+		case headerBlockOptionalFeatures:
+			header.OptionalFeatures = append(header.OptionalFeatures, r.String())
+		case headerBlockWritingProgram:
+			header.WritingProgram = r.String()
+		case headerBlockSource:
+			header.Source = r.String()
+		default:
+			r.Skip()
+		}
+	}
+
+	if err := r.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, feature := range header.RequiredFeatures {
+		if !supportedFeatures[feature] {
+			return nil, fmt.Errorf("osm: unsupported required feature %s", feature)
+		}
+	}
+
+	return header, nil
+}
+
+// primitiveBlock holds the string table and coordinate encoding of a
+// PrimitiveBlock message.
+type primitiveBlock struct {
+	strings     []string
+	granularity int64
+	latOffset   int64
+	lonOffset   int64
+}
+
+func (b *primitiveBlock) string(i uint64) (string, error) {
+	if i >= uint64(len(b.strings)) {
+		return "", fmt.Errorf("osm: string index %d out of range", i)
+	}
+
+	return b.strings[i], nil
+}
+
+func (b *primitiveBlock) tags(keys, vals []uint64) (map[string]string, error) {
+	if len(keys) != len(vals) {
+		return nil, fmt.Errorf("osm: %d tag keys and %d values", len(keys), len(vals))
+	}
+
+	tags := make(map[string]string, len(keys))
+	for i := range keys {
+		k, err := b.string(keys[i])
+		if err != nil {
+			return nil, err
+		}
+
+		v, err := b.string(vals[i])
+		if err != nil {
+			return nil, err
+		}
+
+		tags[k] = v
+	}
+
+	return tags, nil
+}
+
+func (b *primitiveBlock) point(lat, lon int64) s2.Point {
+	return s2.PointFromLatLng(s2.LatLngFromDegrees(
+		1e-9*float64(b.latOffset+b.granularity*lat),
+		1e-9*float64(b.lonOffset+b.granularity*lon),
+	))
+}
+
+func (b *primitiveBlock) decodeNode(data []byte) (*Node, error) {
+	var lat, lon int64
+	keys, vals := []uint64{}, []uint64{}
+	node := &Node{}
+	r := pbf.NewReader(data)
+	for r.Next() {
+		switch r.Field() {
+		case nodeID:
+			node.ID = r.Sint64()
+		case nodeKeys:
+			keys = r.Uint64s(keys)
+		case nodeVals:
+			vals = r.Uint64s(vals)
+		case nodeLat:
+			lat = r.Sint64()
+		case nodeLon:
+			lon = r.Sint64()
+		default:
+			r.Skip()
+		}
+	}
+
+	if err := r.Err(); err != nil {
+		return nil, err
+	}
+
+	tags, err := b.tags(keys, vals)
+	if err != nil {
+		return nil, err
+	}
+
+	node.Point = b.point(lat, lon)
+	node.Tags = tags
+	return node, nil
+}
+
+func (b *primitiveBlock) decodeDenseNodes(data []byte) ([]*Node, error) {
+	ids, lats, lons := []int64{}, []int64{}, []int64{}
+	keysVals := []uint64{}
+	r := pbf.NewReader(data)
+	for r.Next() {
+		switch r.Field() {
+		case denseNodesID:
+			ids = r.Sint64s(ids)
+		case denseNodesLat:
+			lats = r.Sint64s(lats)
+		case denseNodesLon:
+			lons = r.Sint64s(lons)
+		case denseNodesKeysVals:
+			keysVals = r.Uint64s(keysVals)
+		default:
+			r.Skip()
+		}
+	}
+
+	if err := r.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(lats) != len(ids) || len(lons) != len(ids) {
+		return nil, fmt.Errorf("osm: dense nodes with %d IDs, %d latitudes and %d longitudes", len(ids), len(lats), len(lons))
+	}
+
+	nodes := make([]*Node, len(ids))
+	var id, lat, lon int64
+	for i := range ids {
+		id += ids[i]
+		lat += lats[i]
+		lon += lons[i]
+
+		// Tags are stored as key, value index pairs, each node's list ending
+		// with a zero.
+		tags := map[string]string{}
+		for len(keysVals) > 0 {
+			k := keysVals[0]
+			keysVals = keysVals[1:]
+			if k == 0 {
+				break
+			}
+
+			if len(keysVals) == 0 {
+				return nil, fmt.Errorf("osm: dense node tag key without value")
+			}
+
+			key, err := b.string(k)
+			if err != nil {
+				return nil, err
+			}
+
+			value, err := b.string(keysVals[0])
+			if err != nil {
+				return nil, err
+			}
+
+			tags[key] = value
+			keysVals = keysVals[1:]
+		}
+
+		nodes[i] = &Node{
+			ID:    id,
+			Point: b.point(lat, lon),
+			Tags:  tags,
+		}
+	}
+
+	return nodes, nil
+}
+
+func (b *primitiveBlock) decodeWay(data []byte) (*Way, error) {
+	keys, vals := []uint64{}, []uint64{}
+	refs, lats, lons := []int64{}, []int64{}, []int64{}
+	way := &Way{}
+	r := pbf.NewReader(data)
+	for r.Next() {
+		switch r.Field() {
+		case wayID:
+			way.ID = r.Int64()
+		case wayKeys:
+			keys = r.Uint64s(keys)
+		case wayVals:
+			vals = r.Uint64s(vals)
+		case wayRefs:
+			refs = r.Sint64s(refs)
+		case wayLat:
+			lats = r.Sint64s(lats)
+		case wayLon:
+			lons = r.Sint64s(lons)
+		default:
+			r.Skip()
+		}
+	}
+
+	if err := r.Err(); err != nil {
+		return nil, err
+	}
+
+	tags, err := b.tags(keys, vals)
+	if err != nil {
+		return nil, err
+	}
+
+	way.Tags = tags
+	way.NodeIDs = make([]int64, len(refs))
+	var ref int64
+	for i := range refs {
+		ref += refs[i]
+		way.NodeIDs[i] = ref
+	}
+
+	// Files with the LocationsOnWays feature carry the geometry of ways.
+	if len(lats) == len(refs) && len(lons) == len(refs) && len(refs) > 0 {
+		points := make([]s2.Point, len(refs))
+		var lat, lon int64
+		for i := range refs {
+			lat += lats[i]
+			lon += lons[i]
+			points[i] = b.point(lat, lon)
+		}
+
+		polyline := s2.Polyline(points)
+		way.Polyline = &polyline
+	}
+
+	return way, nil
+}
+
+func (b *primitiveBlock) decodeRelation(data []byte) (*Relation, error) {
+	keys, vals := []uint64{}, []uint64{}
+	roles, types := []uint64{}, []uint64{}
+	memIDs := []int64{}
+	relation := &Relation{}
+	r := pbf.NewReader(data)
+	for r.Next() {
+		switch r.Field() {
+		case relationID:
+			relation.ID = r.Int64()
+		case relationKeys:
+			keys = r.Uint64s(keys)
+		case relationVals:
+			vals = r.Uint64s(vals)
+		case relationRolesSID:
+			roles = r.Uint64s(roles)
+		case relationMemIDs:
+			memIDs = r.Sint64s(memIDs)
+		case relationTypes:
+			types = r.Uint64s(types)
+		default:
+			r.Skip()
+		}
+	}
+
+	if err := r.Err(); err != nil {
+		return nil, err
+	}
+
+	tags, err := b.tags(keys, vals)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(roles) != len(memIDs) || len(types) != len(memIDs) {
+		return nil, fmt.Errorf("osm: relation %d with mismatched member fields", relation.ID)
+	}
+
+	relation.Tags = tags
+	relation.Members = make([]Member, len(memIDs))
+	var ref int64
+	for i := range memIDs {
+		ref += memIDs[i]
+		role, err := b.string(roles[i])
+		if err != nil {
+			return nil, err
+		}
+
+		member := Member{
+			Ref:  ref,
+			Role: role,
+		}
+
+		switch types[i] {
+		case 0:
+			member.Type = MemberNode
+		case 1:
+			member.Type = MemberWay
+		case 2:
+			member.Type = MemberRelation
+		default:
+			return nil, fmt.Errorf("osm: invalid member type %d", types[i])
+		}
+
+		relation.Members[i] = member
+	}
+
+	return relation, nil
+}
+
+func (b *primitiveBlock) decodeGroup(data []byte, block *Data) error {
+	r := pbf.NewReader(data)
+	for r.Next() {
+		switch r.Field() {
+		case primitiveGroupNodes:
+			node, err := b.decodeNode(r.Bytes())
+			if err != nil {
+				return err
+			}
+
+			block.Nodes = append(block.Nodes, node)
+		case primitiveGroupDense:
+			nodes, err := b.decodeDenseNodes(r.Bytes())
+			if err != nil {
+				return err
+			}
+
+			block.Nodes = append(block.Nodes, nodes...)
+		case primitiveGroupWays:
+			way, err := b.decodeWay(r.Bytes())
+			if err != nil {
+				return err
+			}
+
+			block.Ways = append(block.Ways, way)
+		case primitiveGroupRelations:
+			relation, err := b.decodeRelation(r.Bytes())
+			if err != nil {
+				return err
+			}
+
+			block.Relations = append(block.Relations, relation)
+		default:
+			r.Skip()
+		}
+	}
+
+	return r.Err()
+}
+
+func decodePrimitiveBlock(data []byte) (*Data, error) {
+	b := &primitiveBlock{
+		granularity: 100,
+	}
+
+	groups := [][]byte{}
+	r := pbf.NewReader(data)
+	for r.Next() {
+		switch r.Field() {
+		case primitiveBlockStringTable:
+			sr := pbf.NewReader(r.Bytes())
+			for sr.Next() {
+				if sr.Field() == stringTableS {
+					b.strings = append(b.strings, sr.String())
+				} else {
+					sr.Skip()
+				}
+			}
+
+			if err := sr.Err(); err != nil {
+				return nil, err
+			}
+		case primitiveBlockPrimitiveGroup:
+			groups = append(groups, r.Bytes())
+		case primitiveBlockGranularity:
+			b.granularity = r.Int64()
+		case primitiveBlockLatOffset:
+			b.latOffset = r.Int64()
+		case primitiveBlockLonOffset:
+			b.lonOffset = r.Int64()
+		default:
+			r.Skip()
+		}
+	}
+
+	if err := r.Err(); err != nil {
+		return nil, err
+	}
+
+	// Groups are decoded once the string table and the coordinate encoding,
+	// which may follow them, are known.
+	block := &Data{}
+	for _, group := range groups {
+		if err := b.decodeGroup(group, block); err != nil {
+			return nil, err
+		}
+	}
+
+	return block, nil
+}
@@ -0,0 +1,164 @@
+package osm
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+
+	"github.com/golang/geo/s2"
+)
+
+type xmlTag struct {
+	K string `xml:"k,attr"`
+	V string `xml:"v,attr"`
+}
+
+type xmlNode struct {
+	ID      int64    `xml:"id,attr"`
+	Lat     float64  `xml:"lat,attr"`
+	Lon     float64  `xml:"lon,attr"`
+	Visible string   `xml:"visible,attr"`
+	Tags    []xmlTag `xml:"tag"`
+}
+
+type xmlNd struct {
+	Ref int64 `xml:"ref,attr"`
+}
+
+type xmlWay struct {
+	ID      int64    `xml:"id,attr"`
+	Visible string   `xml:"visible,attr"`
+	Nds     []xmlNd  `xml:"nd"`
+	Tags    []xmlTag `xml:"tag"`
+}
+
+type xmlMember struct {
+	Type string `xml:"type,attr"`
+	Ref  int64  `xml:"ref,attr"`
+	Role string `xml:"role,attr"`
+}
+
+type xmlRelation struct {
+	ID      int64       `xml:"id,attr"`
+	Visible string      `xml:"visible,attr"`
+	Members []xmlMember `xml:"member"`
+	Tags    []xmlTag    `xml:"tag"`
+}
+
+func xmlTags(tags []xmlTag) map[string]string {
+	m := make(map[string]string, len(tags))
+	for _, tag := range tags {
+		m[tag.K] = tag.V
+	}
+
+	return m
+}
+
+// ReadXML reads an .osm XML document. Elements marked as not visible, as
+// found in history extracts, are skipped.
+func ReadXML(r io.Reader) (*Data, error) {
+	data := &Data{}
+	d := xml.NewDecoder(r)
+	root := true
+	for {
+		token, err := d.Token()
+		if err == io.EOF {
+			locations := make(map[int64]s2.Point, len(data.Nodes))
+			for _, node := range data.Nodes {
+				locations[node.ID] = node.Point
+			}
+
+			resolveWays(data.Ways, locations)
+			return data, nil
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+
+		if root {
+			if start.Name.Local != "osm" {
+				return nil, fmt.Errorf("osm: invalid root element %s", start.Name.Local)
+			}
+
+			root = false
+			continue
+		}
+
+		switch start.Name.Local {
+		case "node":
+			n := &xmlNode{}
+			if err := d.DecodeElement(n, &start); err != nil {
+				return nil, err
+			}
+
+			if n.Visible == "false" {
+				continue
+			}
+
+			data.Nodes = append(data.Nodes, &Node{
+				ID:    n.ID,
+				Point: s2.PointFromLatLng(s2.LatLngFromDegrees(n.Lat, n.Lon)),
+				Tags:  xmlTags(n.Tags),
+			})
+
+		case "way":
+			w := &xmlWay{}
+			if err := d.DecodeElement(w, &start); err != nil {
+				return nil, err
+			}
+
+			if w.Visible == "false" {
+				continue
+			}
+
+			way := &Way{
+				ID:      w.ID,
+				NodeIDs: make([]int64, len(w.Nds)),
+				Tags:    xmlTags(w.Tags),
+			}
+
+			for i, nd := range w.Nds {
+				way.NodeIDs[i] = nd.Ref
+			}
+
+			data.Ways = append(data.Ways, way)
+
+		case "relation":
+			rel := &xmlRelation{}
+			if err := d.DecodeElement(rel, &start); err != nil {
+				return nil, err
+			}
+
+			if rel.Visible == "false" {
+				continue
+			}
+
+			relation := &Relation{
+				ID:      rel.ID,
+				Members: make([]Member, len(rel.Members)),
+				Tags:    xmlTags(rel.Tags),
+			}
+
+			for i, member := range rel.Members {
+				relation.Members[i] = Member{
+					Type: member.Type,
+					Ref:  member.Ref,
+					Role: member.Role,
+				}
+			}
+
+			data.Relations = append(data.Relations, relation)
+
+		default:
+			if err := d.Skip(); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
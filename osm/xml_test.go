@@ -0,0 +1,177 @@
+package osm
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/golang/geo/s2"
+
+	"github.com/topos-ai/geoutil"
+)
+
+func readXMLFixture(t *testing.T, name string) *Data {
+	t.Helper()
+	f, err := os.Open("testdata/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer f.Close()
+	data, err := ReadXML(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return data
+}
+
+func at(lat, lng float64) s2.Point {
+	return s2.PointFromLatLng(s2.LatLngFromDegrees(lat, lng))
+}
+
+func TestReadXML(t *testing.T) {
+	data := readXMLFixture(t, "sample.osm")
+	if len(data.Nodes) != 16 || len(data.Ways) != 7 || len(data.Relations) != 3 {
+		t.Fatalf("read %d nodes, %d ways and %d relations", len(data.Nodes), len(data.Ways), len(data.Relations))
+	}
+
+	// The invisible node 8 and way 14 are skipped.
+	for _, node := range data.Nodes {
+		if node.ID == 8 {
+			t.Errorf("read invisible node")
+		}
+	}
+
+	cafe := data.Nodes[7]
+	if cafe.ID != 9 || !cafe.Point.ApproxEqual(at(0.5, 0.5)) || cafe.Tags["name"] != "Corner Café" {
+		t.Errorf("read node %+v", cafe)
+	}
+
+	building := data.Ways[0]
+	if building.ID != 10 || !reflect.DeepEqual(building.NodeIDs, []int64{1, 2, 3, 4, 1}) || building.Tags["building"] != "yes" {
+		t.Errorf("read way %+v", building)
+	}
+
+	if building.Polyline == nil || len(*building.Polyline) != 5 || !(*building.Polyline)[2].ApproxEqual(at(1, 1)) {
+		t.Errorf("resolved way 10 as %v", building.Polyline)
+	}
+
+	// Way 13 references a node missing from the extract.
+	if data.Ways[3].ID != 13 || data.Ways[3].Polyline != nil {
+		t.Errorf("resolved way %d as %v", data.Ways[3].ID, data.Ways[3].Polyline)
+	}
+
+	want := []Member{
+		{Type: MemberWay, Ref: 20, Role: "outer"},
+		{Type: MemberWay, Ref: 21, Role: "outer"},
+		{Type: MemberWay, Ref: 22, Role: "inner"},
+		{Type: MemberNode, Ref: 9, Role: "label"},
+	}
+
+	if relation := data.Relations[0]; relation.ID != 100 || !reflect.DeepEqual(relation.Members, want) {
+		t.Errorf("read relation %+v", relation)
+	}
+}
+
+func TestReadXMLErrors(t *testing.T) {
+	tests := map[string]string{
+		"root":      `<gpx><node id="1" lat="0" lon="0"/></gpx>`,
+		"malformed": `<osm><node id="1" lat="0" lon="0"></osm>`,
+		"attribute": `<osm><node id="one" lat="0" lon="0"/></osm>`,
+	}
+
+	for name, test := range tests {
+		if _, err := ReadXML(strings.NewReader(test)); err == nil {
+			t.Errorf("%s: no error", name)
+		}
+	}
+}
+
+func TestFeatureCollection(t *testing.T) {
+	fc, err := readXMLFixture(t, "sample.osm").FeatureCollection()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ids := []interface{}{}
+	for _, f := range fc.Features {
+		ids = append(ids, f.ID)
+	}
+
+	want := []interface{}{"node/9", "way/10", "way/11", "way/12", "relation/100"}
+	if !reflect.DeepEqual(ids, want) {
+		t.Fatalf("converted features %v, want %v", ids, want)
+	}
+
+	if _, ok := fc.Features[0].Geometry.(s2.Point); !ok || fc.Features[0].Properties["amenity"] != "cafe" {
+		t.Errorf("converted node to %v with %v", fc.Features[0].Geometry, fc.Features[0].Properties)
+	}
+
+	building, ok := fc.Features[1].Geometry.(*s2.Polygon)
+	if !ok || !building.ContainsPoint(at(0.5, 0.5)) || building.ContainsPoint(at(2, 2)) {
+		t.Errorf("converted building to %v", fc.Features[1].Geometry)
+	}
+
+	// The closed roundabout is a highway, and so a line.
+	for _, f := range fc.Features[2:4] {
+		if _, ok := f.Geometry.(*s2.Polyline); !ok {
+			t.Errorf("converted %v to %T, want a polyline", f.ID, f.Geometry)
+		}
+	}
+
+	forest, ok := fc.Features[4].Geometry.(*s2.Polygon)
+	if !ok {
+		t.Fatalf("converted relation to %T", fc.Features[4].Geometry)
+	}
+
+	coords, err := geoutil.PolygonCoordinates(forest, geoutil.PrecisionE6)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(coords) != 1 || len(coords[0]) != 2 || len(coords[0][0]) != 5 || len(coords[0][1]) != 5 {
+		t.Errorf("converted relation to %v", coords)
+	}
+
+	if !forest.ContainsPoint(at(13, 13)) || forest.ContainsPoint(at(11.5, 11.5)) || forest.ContainsPoint(at(9, 9)) {
+		t.Errorf("relation contains the wrong points")
+	}
+
+	if forest.Area() >= forest.Loop(0).Area() {
+		t.Errorf("relation area %v includes its hole", forest.Area())
+	}
+}
+
+func TestJoinRings(t *testing.T) {
+	tests := []struct {
+		ways [][]int64
+		want [][]int64
+	}{
+		{[][]int64{{1, 2, 3, 1}}, [][]int64{{1, 2, 3, 1}}},
+		{[][]int64{{1, 2}, {2, 3}, {3, 1}}, [][]int64{{1, 2, 3, 1}}},
+		{[][]int64{{1, 2}, {3, 2}, {1, 3}}, [][]int64{{1, 2, 3, 1}}},
+		{[][]int64{{2, 3}, {1, 2}, {3, 1}}, [][]int64{{1, 2, 3, 1}}},
+		{[][]int64{{1, 2, 3, 1}, {4, 5}, {5, 6, 4}}, [][]int64{{1, 2, 3, 1}, {4, 5, 6, 4}}},
+		{[][]int64{{7}}, [][]int64{}},
+	}
+
+	for _, test := range tests {
+		rings, err := joinRings(test.ways)
+		if err != nil {
+			t.Errorf("%v: %v", test.ways, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(rings, test.want) {
+			t.Errorf("%v: joined %v, want %v", test.ways, rings, test.want)
+		}
+	}
+
+	for _, ways := range [][][]int64{{{1, 2}, {2, 3}}, {{1, 2, 1}}} {
+		if _, err := joinRings(ways); err == nil {
+			t.Errorf("%v: no error", ways)
+		}
+	}
+}
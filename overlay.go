@@ -0,0 +1,840 @@
+package geoutil
+
+import (
+	"math"
+	"sort"
+
+	"github.com/golang/geo/s1"
+	"github.com/golang/geo/s2"
+)
+
+// defaultSnapRadius is the distance within which vertices are merged and
+// edges are split at nearby vertices when overlaying geometries, about 0.6mm
+// on the Earth.
+const defaultSnapRadius = s1.Angle(1e-10)
+
+// maxNodingRounds bounds the number of rounds of splitting edges, as snapping
+// a split point may create new crossings.
+const maxNodingRounds = 32
+
+// maxUnionInputs is the largest number of inputs united in a single overlay,
+// as the time to find which inputs contain each face grows with both.
+const maxUnionInputs = 16
+
+// overlayInput is a region bounded by rings, such as a polygon or a single
+// ring of a multipolygon. Crossing an edge traversed an odd number of times
+// by the rings of an input enters or leaves the region, and contains reports
+// whether the region contains a point away from its boundary. The rings of a
+// simple input, such as the result of an overlay, neither cross each other
+// nor have vertices within the snap radius of their other edges.
+type overlayInput struct {
+	rings    [][]s2.Point
+	contains func(s2.Point) bool
+	simple   bool
+}
+
+// polygonInput returns the input of the region of a polygon.
+func polygonInput(polygon *s2.Polygon) overlayInput {
+	input := overlayInput{contains: polygon.ContainsPoint}
+	for _, loop := range polygon.Loops() {
+		if !loop.IsEmpty() && !loop.IsFull() {
+			input.rings = append(input.rings, loop.Vertices())
+		}
+	}
+
+	return input
+}
+
+// ringInput returns the input of the region of a ring by the even-odd rule,
+// the region of a ring that crosses itself being the points inside an odd
+// number of its turns. Of the two complementary regions, the one that does
+// not contain the point opposite the vertices of the ring is chosen.
+func ringInput(points []s2.Point) overlayInput {
+	loop := s2.LoopFromPoints(points)
+	sum := s2.Point{}
+	for _, point := range points {
+		sum.Vector = sum.Add(point.Vector)
+	}
+
+	outside := s2.Point{Vector: sum.Mul(-1).Normalize()}
+	if sum.Norm() == 0 {
+		outside = s2.OriginPoint()
+	}
+
+	invert := loop.ContainsPoint(outside)
+	return overlayInput{
+		rings: [][]s2.Point{points},
+		contains: func(point s2.Point) bool {
+			return loop.ContainsPoint(point) != invert
+		},
+	}
+}
+
+// overlayGraph is the planar graph of the noded edges of a set of inputs.
+type overlayGraph struct {
+	snap     s1.Angle
+	level    int
+	vertices []s2.Point
+	cells    map[s2.CellID][]int
+
+	// rings are the rings of the inputs as sequences of vertices, and
+	// ringInputs the index of the input of each ring. simple tells which
+	// inputs are simple.
+	rings      [][]int
+	ringInputs []int
+	simple     []bool
+
+	// edges are the pairs of vertices of the edges traversed an odd number
+	// of times by an input, the first vertex being the smallest, and
+	// edgeInputs the inputs traversing them an odd number of times. The
+	// half-edges 2*i and 2*i+1 run along edge i in the order of its vertices
+	// and in the opposite order.
+	edges      [][2]int
+	edgeInputs [][]int
+
+	// out are the outgoing half-edges of each vertex in counterclockwise
+	// order, and positions the position of each half-edge in out.
+	out       [][]int
+	positions []int
+
+	// faces are the faces on the left of each half-edge.
+	faces    []int
+	numFaces int
+}
+
+// vertex returns the vertex within the snap radius of a point, adding the
+// point as a new vertex if there is none.
+func (g *overlayGraph) vertex(point s2.Point) int {
+	id := s2.CellFromPoint(point).ID().Parent(g.level)
+	best, bestDistance := -1, g.snap
+	for _, cell := range append(id.AllNeighbors(g.level), id) {
+		for _, v := range g.cells[cell] {
+			if d := g.vertices[v].Distance(point); d <= bestDistance {
+				best, bestDistance = v, d
+			}
+		}
+	}
+
+	if best >= 0 {
+		return best
+	}
+
+	g.vertices = append(g.vertices, point)
+	g.cells[id] = append(g.cells[id], len(g.vertices)-1)
+	return len(g.vertices) - 1
+}
+
+// addRing adds the ring of an input, snapping its vertices and removing
+// repeated vertices.
+func (g *overlayGraph) addRing(points []s2.Point, input int) {
+	ring := make([]int, 0, len(points))
+	for _, point := range points {
+		ring = append(ring, g.vertex(point))
+	}
+
+	g.rings = append(g.rings, compactRing(ring))
+	g.ringInputs = append(g.ringInputs, input)
+}
+
+// compactRing removes consecutive repeated vertices of a ring, including the
+// closing vertex.
+func compactRing(ring []int) []int {
+	compacted := ring[:0]
+	for _, v := range ring {
+		if n := len(compacted); n > 0 && compacted[n-1] == v {
+			continue
+		}
+
+		compacted = append(compacted, v)
+	}
+
+	for n := len(compacted); n > 1 && compacted[0] == compacted[n-1]; n-- {
+		compacted = compacted[:n-1]
+	}
+
+	return compacted
+}
+
+// ringEdge returns the endpoints of an edge of a ring.
+func (g *overlayGraph) ringEdge(ring, edge int) (int, int) {
+	r := g.rings[ring]
+	return r[edge], r[(edge+1)%len(r)]
+}
+
+// node splits the edges of the rings at their crossings and at the vertices
+// within the snap radius of them, until no edge needs splitting.
+func (g *overlayGraph) node() {
+	for round := 0; round < maxNodingRounds; round++ {
+		splits := g.splits()
+		if len(splits) == 0 {
+			return
+		}
+
+		for i, ring := range g.rings {
+			noded := make([]int, 0, len(ring))
+			for j, v := range ring {
+				noded = append(noded, v)
+				vs := splits[[2]int{i, j}]
+				if len(vs) == 0 {
+					continue
+				}
+
+				// Split points are ordered by their distance from the start
+				// of the edge.
+				start := g.vertices[v]
+				sort.Slice(vs, func(a, b int) bool {
+					return start.Distance(g.vertices[vs[a]]) < start.Distance(g.vertices[vs[b]])
+				})
+
+				noded = append(noded, vs...)
+			}
+
+			g.rings[i] = compactRing(noded)
+		}
+	}
+}
+
+// edgeIndex indexes the edges of chains of points, rings being closed chains,
+// to find the edges crossing an edge and the edges near a point. Edges are
+// identified by the index of their chain and of their first point.
+type edgeIndex struct {
+	snap   s1.Angle
+	level  int
+	index  *s2.ShapeIndex
+	chains map[s2.Shape][2]int
+
+	// query is created once all the chains are added, as it does not see
+	// the chains added after it.
+	query *s2.CrossingEdgeQuery
+
+	// cells are the edges having an endpoint in each cell of the level,
+	// whose cells are at least twice as wide as the snap radius.
+	cells map[s2.CellID][][2]int
+}
+
+func newEdgeIndex(snap s1.Angle) *edgeIndex {
+	return &edgeIndex{
+		snap:   snap,
+		level:  s2.MinWidthMetric.MaxLevel(2 * snap.Radians()),
+		index:  s2.NewShapeIndex(),
+		chains: map[s2.Shape][2]int{},
+		cells:  map[s2.CellID][][2]int{},
+	}
+}
+
+// add adds a chain of points, closed by an edge from its last point to its
+// first point if it is a ring.
+func (x *edgeIndex) add(chain int, points []s2.Point, ring bool) {
+	polyline := append(s2.Polyline(nil), points...)
+	if ring {
+		polyline = append(polyline, points[0])
+	}
+
+	x.addEdges(chain, 0, polyline)
+}
+
+// addEdges adds the edges between consecutive points of a part of a chain,
+// whose first point is the point of index first in the chain.
+func (x *edgeIndex) addEdges(chain, first int, points s2.Polyline) {
+	x.index.Add(&points)
+	x.chains[&points] = [2]int{chain, first}
+	x.query = nil
+	for i := 1; i < len(points); i++ {
+		for _, point := range points[i-1 : i+1] {
+			id := s2.CellFromPoint(point).ID().Parent(x.level)
+			x.cells[id] = append(x.cells[id], [2]int{chain, first + i - 1})
+		}
+	}
+}
+
+// crossings returns the edges crossing the edge from a to b, in order.
+func (x *edgeIndex) crossings(a, b s2.Point, crossType s2.CrossingType) [][2]int {
+	if x.query == nil {
+		x.query = s2.NewCrossingEdgeQuery(x.index)
+	}
+
+	edges := [][2]int{}
+	for shape, ids := range x.query.CrossingsEdgeMap(a, b, crossType) {
+		chain := x.chains[shape]
+		for _, id := range ids {
+			edges = append(edges, [2]int{chain[0], chain[1] + id})
+		}
+	}
+
+	return sortedEdges(edges)
+}
+
+// near returns, in order, the edges within the snap radius of a point and
+// possibly other edges nearby. Such an edge either has an endpoint within
+// twice the snap radius of the point, in the cells around it, or crosses the
+// boundary of a square around the point slightly larger than the snap
+// radius.
+func (x *edgeIndex) near(point s2.Point) [][2]int {
+	edges := [][2]int{}
+	id := s2.CellFromPoint(point).ID().Parent(x.level)
+	for _, cell := range append(id.AllNeighbors(x.level), id) {
+		edges = append(edges, x.cells[cell]...)
+	}
+
+	side := 1.1 * x.snap.Radians()
+	u := point.Ortho()
+	w := point.Vector.Cross(u)
+	corners := make([]s2.Point, 4)
+	for i, sign := range [][2]float64{{1, 1}, {-1, 1}, {-1, -1}, {1, -1}} {
+		corners[i] = s2.Point{Vector: point.Add(u.Mul(sign[0] * side)).Add(w.Mul(sign[1] * side)).Normalize()}
+	}
+
+	for i, corner := range corners {
+		edges = append(edges, x.crossings(corner, corners[(i+1)%4], s2.CrossingTypeAll)...)
+	}
+
+	return sortedEdges(edges)
+}
+
+// sortedEdges sorts edges by chain and index, and removes duplicates.
+func sortedEdges(edges [][2]int) [][2]int {
+	sort.Slice(edges, func(i, j int) bool {
+		a, b := edges[i], edges[j]
+		return a[0] < b[0] || a[0] == b[0] && a[1] < b[1]
+	})
+
+	unique := edges[:0]
+	for i, edge := range edges {
+		if i == 0 || edge != edges[i-1] {
+			unique = append(unique, edge)
+		}
+	}
+
+	return unique
+}
+
+// expandedRect returns a rectangle containing the points within an angle of
+// a rectangle.
+func expandedRect(rect s2.Rect, angle s1.Angle) s2.Rect {
+	if rect.IsEmpty() {
+		return rect
+	}
+
+	lat := rect.Lat.Expanded(angle.Radians()).Intersection(s2.FullRect().Lat)
+	maxLat := math.Max(-lat.Lo, lat.Hi)
+	if maxLat >= math.Pi/2 {
+		return s2.Rect{Lat: lat, Lng: s1.FullInterval()}
+	}
+
+	// Away from the poles, the longitudes within the angle of a point are
+	// within twice the angle divided by the cosine of the latitude.
+	return s2.Rect{Lat: lat, Lng: rect.Lng.Expanded(2 * angle.Radians() / math.Cos(maxLat))}
+}
+
+// otherBounds returns, for each input, a rectangle containing the points
+// within the snap radius of the rings of the other inputs.
+func (g *overlayGraph) otherBounds() []s2.Rect {
+	bounds := make([]s2.Rect, len(g.simple))
+	for i := range bounds {
+		bounds[i] = s2.EmptyRect()
+	}
+
+	for i, ring := range g.rings {
+		if len(ring) == 0 {
+			continue
+		}
+
+		bounder := s2.NewRectBounder()
+		for j := 0; j <= len(ring); j++ {
+			bounder.AddPoint(g.vertices[ring[j%len(ring)]])
+		}
+
+		input := g.ringInputs[i]
+		bounds[input] = bounds[input].Union(bounder.RectBound())
+	}
+
+	// The bounds of the other inputs are the union of the bounds of the
+	// inputs before and after each input.
+	others := make([]s2.Rect, len(bounds))
+	before := s2.EmptyRect()
+	for i, bound := range bounds {
+		others[i] = before
+		before = before.Union(bound)
+	}
+
+	after := s2.EmptyRect()
+	for i := len(bounds) - 1; i >= 0; i-- {
+		others[i] = expandedRect(others[i].Union(after), g.snap)
+		after = after.Union(bounds[i])
+	}
+
+	return others
+}
+
+// splits returns the vertices at which the edges of the rings must be split,
+// by ring and edge.
+func (g *overlayGraph) splits() map[[2]int][]int {
+
+	// The edges and vertices of simple inputs are only checked near the
+	// other inputs, which they may cross or come close to. The other edges
+	// of simple inputs neither cross nor come close to any checked edge or
+	// vertex, and are not indexed.
+	others := g.otherBounds()
+	checked := func(ring int, points ...s2.Point) bool {
+		input := g.ringInputs[ring]
+		if !g.simple[input] {
+			return true
+		}
+
+		bounder := s2.NewRectBounder()
+		for _, point := range points {
+			bounder.AddPoint(point)
+		}
+
+		return bounder.RectBound().Intersects(others[input])
+	}
+
+	x := newEdgeIndex(g.snap)
+	checkedEdges := make([][]bool, len(g.rings))
+	for i, ring := range g.rings {
+		if len(ring) < 2 {
+			continue
+		}
+
+		checkedEdges[i] = make([]bool, len(ring))
+		points := s2.Polyline{}
+		for j := range ring {
+			a, b := g.ringEdge(i, j)
+			checkedEdges[i][j] = checked(i, g.vertices[a], g.vertices[b])
+			if checkedEdges[i][j] {
+				if len(points) == 0 {
+					points = append(points, g.vertices[a])
+				}
+
+				points = append(points, g.vertices[b])
+			}
+
+			// Runs of checked edges are added as parts of the ring.
+			if len(points) > 0 && (!checkedEdges[i][j] || j == len(ring)-1) {
+				last := j
+				if checkedEdges[i][j] {
+					last++
+				}
+
+				x.addEdges(i, last-len(points)+1, points)
+				points = s2.Polyline{}
+			}
+		}
+	}
+
+	// Pairs of edges and pairs of a vertex and an edge are ordered so that
+	// the vertices are created and the splits found in a deterministic
+	// order. A pair of crossing edges of different inputs is near both
+	// inputs, and both of its edges are checked.
+	crossings := [][4]int{}
+	nearby := [][3]int{}
+	visited := map[int]bool{}
+	for i, ring := range g.rings {
+		if len(ring) < 2 {
+			continue
+		}
+
+		for j, v := range ring {
+			a, b := g.ringEdge(i, j)
+			if checkedEdges[i][j] {
+				for _, edge := range x.crossings(g.vertices[a], g.vertices[b], s2.CrossingTypeInterior) {
+
+					// Each pair of crossing edges is found once.
+					if edge[0] < i || edge[0] == i && edge[1] <= j {
+						continue
+					}
+
+					crossings = append(crossings, [4]int{i, j, edge[0], edge[1]})
+				}
+			}
+
+			if visited[v] || !checked(i, g.vertices[v]) {
+				continue
+			}
+
+			visited[v] = true
+			for _, edge := range x.near(g.vertices[v]) {
+				nearby = append(nearby, [3]int{v, edge[0], edge[1]})
+			}
+		}
+	}
+
+	sort.Slice(crossings, func(i, j int) bool {
+		a, b := crossings[i], crossings[j]
+		for k := range a {
+			if a[k] != b[k] {
+				return a[k] < b[k]
+			}
+		}
+
+		return false
+	})
+
+	sort.Slice(nearby, func(i, j int) bool {
+		a, b := nearby[i], nearby[j]
+		for k := range a {
+			if a[k] != b[k] {
+				return a[k] < b[k]
+			}
+		}
+
+		return false
+	})
+
+	splits := map[[2]int][]int{}
+	split := func(ring, edge, v int) {
+		a, b := g.ringEdge(ring, edge)
+		if v == a || v == b {
+			return
+		}
+
+		key := [2]int{ring, edge}
+		for _, w := range splits[key] {
+			if w == v {
+				return
+			}
+		}
+
+		splits[key] = append(splits[key], v)
+	}
+
+	// Edges are split at their crossings.
+	for _, crossing := range crossings {
+		a, b := g.ringEdge(crossing[0], crossing[1])
+		c, d := g.ringEdge(crossing[2], crossing[3])
+		v := g.vertex(s2.Intersection(g.vertices[a], g.vertices[b], g.vertices[c], g.vertices[d]))
+		split(crossing[0], crossing[1], v)
+		split(crossing[2], crossing[3], v)
+	}
+
+	// Edges are split at the vertices near them.
+	for _, near := range nearby {
+		c, d := g.ringEdge(near[1], near[2])
+		if s2.DistanceFromSegment(g.vertices[near[0]], g.vertices[c], g.vertices[d]) < g.snap {
+			split(near[1], near[2], near[0])
+		}
+	}
+
+	return splits
+}
+
+// build builds the edges, the order of the half-edges around each vertex,
+// and the faces of the graph.
+func (g *overlayGraph) build() {
+	parity := map[[2]int]map[int]bool{}
+	for i, ring := range g.rings {
+		if len(ring) < 2 {
+			continue
+		}
+
+		for j := range ring {
+			a, b := g.ringEdge(i, j)
+			if a > b {
+				a, b = b, a
+			}
+
+			key := [2]int{a, b}
+			if parity[key] == nil {
+				parity[key] = map[int]bool{}
+			}
+
+			input := g.ringInputs[i]
+			if parity[key][input] {
+				delete(parity[key], input)
+			} else {
+				parity[key][input] = true
+			}
+		}
+	}
+
+	for key, inputs := range parity {
+		if len(inputs) == 0 {
+			continue
+		}
+
+		g.edges = append(g.edges, key)
+	}
+
+	sort.Slice(g.edges, func(i, j int) bool {
+		a, b := g.edges[i], g.edges[j]
+		return a[0] < b[0] || a[0] == b[0] && a[1] < b[1]
+	})
+
+	g.edgeInputs = make([][]int, len(g.edges))
+	for i, edge := range g.edges {
+		for input := range parity[edge] {
+			g.edgeInputs[i] = append(g.edgeInputs[i], input)
+		}
+
+		sort.Ints(g.edgeInputs[i])
+	}
+
+	// Half-edges are ordered counterclockwise around their origin.
+	g.out = make([][]int, len(g.vertices))
+	for h := 0; h < 2*len(g.edges); h++ {
+		g.out[g.origin(h)] = append(g.out[g.origin(h)], h)
+	}
+
+	g.positions = make([]int, 2*len(g.edges))
+	for v, hs := range g.out {
+		point := g.vertices[v]
+		u := point.Ortho()
+		w := point.Cross(u)
+		angles := make(map[int]float64, len(hs))
+		for _, h := range hs {
+			normal := point.Cross(g.vertices[g.destination(h)].Vector)
+			tangent := normal.Cross(point.Vector)
+			angles[h] = math.Atan2(tangent.Dot(w), tangent.Dot(u))
+		}
+
+		sort.Slice(hs, func(i, j int) bool {
+			return angles[hs[i]] < angles[hs[j]]
+		})
+
+		for i, h := range hs {
+			g.positions[h] = i
+		}
+	}
+
+	g.faces = make([]int, 2*len(g.edges))
+	for h := range g.faces {
+		g.faces[h] = -1
+	}
+
+	for h := range g.faces {
+		if g.faces[h] >= 0 {
+			continue
+		}
+
+		for e := h; g.faces[e] < 0; e = g.next(e) {
+			g.faces[e] = g.numFaces
+		}
+
+		g.numFaces++
+	}
+}
+
+func (g *overlayGraph) origin(h int) int {
+	return g.edges[h/2][h%2]
+}
+
+func (g *overlayGraph) destination(h int) int {
+	return g.edges[h/2][1-h%2]
+}
+
+// next returns the half-edge following a half-edge around the face on its
+// left, which is the first half-edge clockwise from its twin around its
+// destination.
+func (g *overlayGraph) next(h int) int {
+	twin := h ^ 1
+	hs := g.out[g.origin(twin)]
+	return hs[(g.positions[twin]+len(hs)-1)%len(hs)]
+}
+
+// inside returns which inputs contain each face. The containment of one face
+// of each connected component of the graph is tested, and the other faces
+// are reached by crossing edges, which enters or leaves their inputs.
+func (g *overlayGraph) inside(inputs []overlayInput) [][]bool {
+	components := make([]int, len(g.vertices))
+	for v := range components {
+		components[v] = v
+	}
+
+	var find func(v int) int
+	find = func(v int) int {
+		if components[v] != v {
+			components[v] = find(components[v])
+		}
+
+		return components[v]
+	}
+
+	for _, edge := range g.edges {
+		components[find(edge[0])] = find(edge[1])
+	}
+
+	faceEdges := make([][]int, g.numFaces)
+	for h, f := range g.faces {
+		faceEdges[f] = append(faceEdges[f], h)
+	}
+
+	inside := make([][]bool, g.numFaces)
+	tested := map[int]bool{}
+	for h := range g.faces {
+		component := find(g.origin(h))
+		if tested[component] {
+			continue
+		}
+
+		tested[component] = true
+
+		// The tested point is just left of the middle of the half-edge, and
+		// closer to it than to any other edge.
+		a, b := g.vertices[g.origin(h)], g.vertices[g.destination(h)]
+		middle := a.Add(b.Vector).Normalize()
+		left := a.Cross(b.Vector).Normalize()
+		point := s2.Point{Vector: middle.Add(left.Mul(g.snap.Radians() / 100)).Normalize()}
+
+		f := g.faces[h]
+		inside[f] = make([]bool, len(inputs))
+		for i, input := range inputs {
+			inside[f][i] = input.contains(point)
+		}
+
+		queue := []int{f}
+		for len(queue) > 0 {
+			f := queue[0]
+			queue = queue[1:]
+			for _, e := range faceEdges[f] {
+				other := g.faces[e^1]
+				if inside[other] != nil {
+					continue
+				}
+
+				inside[other] = append([]bool(nil), inside[f]...)
+				for _, input := range g.edgeInputs[e/2] {
+					inside[other][input] = !inside[other][input]
+				}
+
+				queue = append(queue, other)
+			}
+		}
+	}
+
+	return inside
+}
+
+// overlay returns the polygon of the points for which selected returns true
+// given which inputs contain them, with vertices closer than snap merged.
+func overlay(inputs []overlayInput, snap s1.Angle, selected func(inside []bool) bool) *s2.Polygon {
+	g := &overlayGraph{
+		snap:   snap,
+		level:  s2.MinWidthMetric.MaxLevel(snap.Radians()),
+		cells:  map[s2.CellID][]int{},
+		simple: make([]bool, len(inputs)),
+	}
+
+	for i, input := range inputs {
+		g.simple[i] = input.simple
+		for _, ring := range input.rings {
+			g.addRing(ring, i)
+		}
+	}
+
+	g.node()
+	g.build()
+
+	// Without edges, every input is either empty or full.
+	if len(g.edges) == 0 {
+		inside := make([]bool, len(inputs))
+		for i, input := range inputs {
+			inside[i] = input.contains(s2.OriginPoint())
+		}
+
+		if selected(inside) {
+			return s2.FullPolygon()
+		}
+
+		return s2.PolygonFromLoops(nil)
+	}
+
+	inside := g.inside(inputs)
+	faces := make([]bool, g.numFaces)
+	for f := range faces {
+		faces[f] = selected(inside[f])
+	}
+
+	// The boundary of the result is made of the half-edges with a selected
+	// face on their left and an unselected face on their right.
+	boundary := func(h int) bool {
+		return faces[g.faces[h]] && !faces[g.faces[h^1]]
+	}
+
+	visited := make([]bool, 2*len(g.edges))
+	loops := []*s2.Loop{}
+	for h := range visited {
+		if visited[h] || !boundary(h) {
+			continue
+		}
+
+		// The next boundary half-edge is found by turning clockwise around
+		// the destination through selected faces.
+		chain := []int{}
+		for e := h; !visited[e]; {
+			visited[e] = true
+			chain = append(chain, g.origin(e))
+			e = g.next(e)
+			for !boundary(e) {
+				e = g.next(e ^ 1)
+			}
+		}
+
+		for _, ring := range splitRing(chain) {
+			points := make([]s2.Point, len(ring))
+			for i, v := range ring {
+				points[i] = g.vertices[v]
+			}
+
+			loops = append(loops, s2.LoopFromPoints(points))
+		}
+	}
+
+	return s2.PolygonFromOrientedLoops(loops)
+}
+
+// anyInside reports whether any input contains a point.
+func anyInside(inside []bool) bool {
+	for _, in := range inside {
+		if in {
+			return true
+		}
+	}
+
+	return false
+}
+
+// unionInputs returns the union of inputs. The halves of many inputs are
+// united separately and then with each other, so that the inputs near each
+// other in the order of the inputs are merged first, and the larger unions
+// are only noded where the halves meet.
+func unionInputs(inputs []overlayInput, snap s1.Angle) *s2.Polygon {
+	if len(inputs) <= maxUnionInputs {
+		return overlay(inputs, snap, anyInside)
+	}
+
+	middle := len(inputs) / 2
+	halves := []overlayInput{
+		polygonInput(unionInputs(inputs[:middle], snap)),
+		polygonInput(unionInputs(inputs[middle:], snap)),
+	}
+
+	for i := range halves {
+		halves[i].simple = true
+	}
+
+	return overlay(halves, snap, anyInside)
+}
+
+// splitRing splits a ring visiting a vertex more than once into rings
+// visiting each vertex once.
+func splitRing(ring []int) [][]int {
+	rings := [][]int{}
+	stack := []int{}
+	positions := map[int]int{}
+	for _, v := range ring {
+		if i, ok := positions[v]; ok {
+			rings = append(rings, append([]int(nil), stack[i:]...))
+			for _, w := range stack[i+1:] {
+				delete(positions, w)
+			}
+
+			stack = stack[:i+1]
+			continue
+		}
+
+		positions[v] = len(stack)
+		stack = append(stack, v)
+	}
+
+	return append(rings, stack)
+}
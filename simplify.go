@@ -0,0 +1,663 @@
+package geoutil
+
+import (
+	"container/heap"
+	"encoding/binary"
+	"math"
+	"sort"
+
+	"github.com/golang/geo/s1"
+	"github.com/golang/geo/s2"
+)
+
+// SimplifyMethod identifies a line simplification algorithm.
+type SimplifyMethod int
+
+const (
+	// DouglasPeucker keeps the vertices farther than the tolerance from the
+	// simplified line, splitting the line at the farthest vertex first.
+	DouglasPeucker SimplifyMethod = iota
+
+	// VisvalingamWhyatt removes the vertices forming with their neighbors a
+	// triangle of area smaller than the square of the tolerance, smallest
+	// first.
+	VisvalingamWhyatt
+)
+
+// SimplifyOptions are the options of simplification. Use AngleFromMeters for
+// a tolerance in meters.
+type SimplifyOptions struct {
+	Method    SimplifyMethod
+	Tolerance s1.Angle
+
+	// PreserveTopology restores removed vertices until no simplified edge
+	// crosses or touches another edge, no simplified edge moves across a
+	// vertex of another edge, and no ring has fewer than three vertices.
+	// Otherwise rings may cross, and collapsed rings are dropped.
+	PreserveTopology bool
+}
+
+// simplifyArc is a part of a line or ring between junctions, simplified once
+// for all the lines and rings sharing it.
+type simplifyArc struct {
+	points []s2.Point
+	keep   []bool
+}
+
+// kept returns the indexes of the kept points of an arc.
+func (a *simplifyArc) kept() []int {
+	kept := []int{}
+	for i, keep := range a.keep {
+		if keep {
+			kept = append(kept, i)
+		}
+	}
+
+	return kept
+}
+
+// farthest returns the index of the point between two points of an arc
+// farthest from the edge between them, or -1 if they are adjacent.
+func (a *simplifyArc) farthest(i, j int) (int, s1.Angle) {
+	k, distance := -1, s1.Angle(-1)
+	for l := i + 1; l < j; l++ {
+		d := a.points[l].Distance(a.points[i])
+		if a.points[i] != a.points[j] {
+			d = s2.DistanceFromSegment(a.points[l], a.points[i], a.points[j])
+		}
+
+		if d > distance {
+			k, distance = l, d
+		}
+	}
+
+	return k, distance
+}
+
+func (a *simplifyArc) douglasPeucker(tolerance s1.Angle) {
+	n := len(a.points)
+	stack := [][2]int{{0, n - 1}}
+	for len(stack) > 0 {
+		span := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		k, distance := a.farthest(span[0], span[1])
+		if k >= 0 && distance > tolerance {
+			a.keep[k] = true
+			stack = append(stack, [2]int{span[0], k}, [2]int{k, span[1]})
+		}
+	}
+}
+
+// vertexArea is the effective area of a vertex in Visvalingam-Whyatt
+// simplification.
+type vertexArea struct {
+	index int
+	area  float64
+}
+
+type vertexAreaHeap []vertexArea
+
+func (h vertexAreaHeap) Len() int            { return len(h) }
+func (h vertexAreaHeap) Less(i, j int) bool  { return h[i].area < h[j].area }
+func (h vertexAreaHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *vertexAreaHeap) Push(x interface{}) { *h = append(*h, x.(vertexArea)) }
+func (h *vertexAreaHeap) Pop() interface{} {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
+
+func (a *simplifyArc) visvalingamWhyatt(tolerance s1.Angle) {
+	n := len(a.points)
+	threshold := tolerance.Radians() * tolerance.Radians()
+	prev := make([]int, n)
+	next := make([]int, n)
+	areas := make([]float64, n)
+	h := &vertexAreaHeap{}
+	for i := 1; i < n-1; i++ {
+		prev[i], next[i] = i-1, i+1
+		areas[i] = s2.PointArea(a.points[i-1], a.points[i], a.points[i+1])
+		*h = append(*h, vertexArea{index: i, area: areas[i]})
+	}
+
+	heap.Init(h)
+	for i := 1; i < n-1; i++ {
+		a.keep[i] = true
+	}
+
+	for h.Len() > 0 {
+		v := heap.Pop(h).(vertexArea)
+
+		// Entries of removed vertices and outdated areas are skipped.
+		if !a.keep[v.index] || v.area != areas[v.index] {
+			continue
+		}
+
+		if v.area >= threshold {
+			break
+		}
+
+		a.keep[v.index] = false
+		p, q := prev[v.index], next[v.index]
+		next[p], prev[q] = q, p
+
+		// The area of a neighbor is at least the area of the removed vertex,
+		// so that vertices are removed in order of significance.
+		for _, i := range []int{p, q} {
+			if i == 0 || i == n-1 {
+				continue
+			}
+
+			areas[i] = math.Max(s2.PointArea(a.points[prev[i]], a.points[i], a.points[next[i]]), v.area)
+			heap.Push(h, vertexArea{index: i, area: areas[i]})
+		}
+	}
+}
+
+// simplifier simplifies the lines and rings of a set of geometries, cut into
+// arcs at their junctions so that shared borders are simplified the same way.
+type simplifier struct {
+	options   SimplifyOptions
+	sequences [][]s2.Point
+	rings     []bool
+	arcs      []*simplifyArc
+	arcKeys   map[string]int
+
+	// paths are the arcs of each sequence, reversed arcs being indexed by the
+	// complement of their index.
+	paths [][]int
+}
+
+// add adds a line or a ring without its closing point, and returns its
+// index.
+func (s *simplifier) add(points []s2.Point, ring bool) int {
+	sequence := make([]s2.Point, 0, len(points))
+	for _, point := range points {
+		if n := len(sequence); n == 0 || sequence[n-1] != point {
+			sequence = append(sequence, point)
+		}
+	}
+
+	if n := len(sequence) - 1; ring && n > 0 && sequence[0] == sequence[n] {
+		sequence = sequence[:n]
+	}
+
+	s.sequences = append(s.sequences, sequence)
+	s.rings = append(s.rings, ring)
+	return len(s.sequences) - 1
+}
+
+// junctions returns the points where sequences meet or diverge: the end
+// points of lines, and every point reached from different neighbors.
+func (s *simplifier) junctions() map[s2.Point]bool {
+	junctions := map[s2.Point]bool{}
+	seen := map[s2.Point][2]s2.Point{}
+	visit := func(point, prev, next s2.Point) {
+		if pointLess(next, prev) {
+			prev, next = next, prev
+		}
+
+		neighbors := [2]s2.Point{prev, next}
+		if previous, ok := seen[point]; !ok {
+			seen[point] = neighbors
+		} else if previous != neighbors {
+			junctions[point] = true
+		}
+	}
+
+	for i, sequence := range s.sequences {
+		n := len(sequence)
+		if n == 0 {
+			continue
+		}
+
+		if !s.rings[i] {
+			junctions[sequence[0]] = true
+			junctions[sequence[n-1]] = true
+			for j := 1; j < n-1; j++ {
+				visit(sequence[j], sequence[j-1], sequence[j+1])
+			}
+
+			continue
+		}
+
+		for j := range sequence {
+			visit(sequence[j], sequence[(j+n-1)%n], sequence[(j+1)%n])
+		}
+	}
+
+	return junctions
+}
+
+func pointLess(a, b s2.Point) bool {
+	switch {
+	case a.X != b.X:
+		return a.X < b.X
+	case a.Y != b.Y:
+		return a.Y < b.Y
+	default:
+		return a.Z < b.Z
+	}
+}
+
+func pointsKey(points []s2.Point) string {
+	buf := make([]byte, 24*len(points))
+	for i, point := range points {
+		binary.LittleEndian.PutUint64(buf[24*i:], math.Float64bits(point.X))
+		binary.LittleEndian.PutUint64(buf[24*i+8:], math.Float64bits(point.Y))
+		binary.LittleEndian.PutUint64(buf[24*i+16:], math.Float64bits(point.Z))
+	}
+
+	return string(buf)
+}
+
+func reversedPoints(points []s2.Point) []s2.Point {
+	r := make([]s2.Point, len(points))
+	for i, point := range points {
+		r[len(points)-1-i] = point
+	}
+
+	return r
+}
+
+// arcIndex returns the index of an arc, reusing an existing arc when it has
+// the same points in either direction.
+func (s *simplifier) arcIndex(points []s2.Point) int {
+	if i, ok := s.arcKeys[pointsKey(points)]; ok {
+		return i
+	}
+
+	if i, ok := s.arcKeys[pointsKey(reversedPoints(points))]; ok {
+		return ^i
+	}
+
+	arc := &simplifyArc{
+		points: points,
+		keep:   make([]bool, len(points)),
+	}
+
+	arc.keep[0], arc.keep[len(points)-1] = true, true
+	s.arcs = append(s.arcs, arc)
+	s.arcKeys[pointsKey(points)] = len(s.arcs) - 1
+	return len(s.arcs) - 1
+}
+
+// cut splits every sequence into arcs at the junctions.
+func (s *simplifier) cut() {
+	junctions := s.junctions()
+	s.arcKeys = map[string]int{}
+	s.paths = make([][]int, len(s.sequences))
+	for i, sequence := range s.sequences {
+		points := sequence
+		if len(points) == 0 {
+			continue
+		}
+
+		if s.rings[i] {
+			start := -1
+			for j, point := range points {
+				if junctions[point] {
+					start = j
+					break
+				}
+			}
+
+			// A ring without junctions is a single closed arc, starting at
+			// its smallest point so that it matches identical rings.
+			if start < 0 {
+				start = 0
+				for j, point := range points {
+					if pointLess(point, points[start]) {
+						start = j
+					}
+				}
+			}
+
+			points = append(append([]s2.Point{}, points[start:]...), points[:start]...)
+			points = append(points, points[0])
+		}
+
+		if len(points) == 1 {
+			s.paths[i] = []int{s.arcIndex(points)}
+			continue
+		}
+
+		start := 0
+		for j := 1; j < len(points); j++ {
+			if j == len(points)-1 || junctions[points[j]] {
+				s.paths[i] = append(s.paths[i], s.arcIndex(points[start:j+1]))
+				start = j
+			}
+		}
+	}
+}
+
+// arcEdge identifies an edge between two kept points of an arc.
+type arcEdge struct {
+	arc, start, end int
+}
+
+// restore keeps the point of an edge farthest from it, and reports whether
+// the edge had removed points.
+func (s *simplifier) restore(edge arcEdge) bool {
+	arc := s.arcs[edge.arc]
+	k, _ := arc.farthest(edge.start, edge.end)
+	if k < 0 {
+		return false
+	}
+
+	arc.keep[k] = true
+	return true
+}
+
+// numVertices returns the number of vertices of a simplified sequence.
+func (s *simplifier) numVertices(i int) int {
+	n := 0
+	for _, a := range s.paths[i] {
+		if a < 0 {
+			a = ^a
+		}
+
+		n += len(s.arcs[a].kept()) - 1
+	}
+
+	if !s.rings[i] {
+		n++
+	}
+
+	return n
+}
+
+// collapsed returns an edge to restore for each ring with fewer than three
+// vertices, the one spanning the most points.
+func (s *simplifier) collapsed() []arcEdge {
+	edges := []arcEdge{}
+	for i, path := range s.paths {
+		if !s.rings[i] || len(s.sequences[i]) < 3 || s.numVertices(i) >= 3 {
+			continue
+		}
+
+		longest := arcEdge{arc: -1}
+		for _, a := range path {
+			if a < 0 {
+				a = ^a
+			}
+
+			kept := s.arcs[a].kept()
+			for j := 1; j < len(kept); j++ {
+				if longest.arc < 0 || kept[j]-kept[j-1] > longest.end-longest.start {
+					longest = arcEdge{arc: a, start: kept[j-1], end: kept[j]}
+				}
+			}
+		}
+
+		if longest.arc >= 0 {
+			edges = append(edges, longest)
+		}
+	}
+
+	return edges
+}
+
+// crossing returns the simplified edges crossing or touching other edges
+// away from their shared end points.
+func (s *simplifier) crossing() []arcEdge {
+	index := s2.NewShapeIndex()
+	shapeArcs := map[s2.Shape]int{}
+	kept := make([][]int, len(s.arcs))
+	for i, arc := range s.arcs {
+		kept[i] = arc.kept()
+		if len(kept[i]) < 2 {
+			continue
+		}
+
+		polyline := make(s2.Polyline, len(kept[i]))
+		for j, k := range kept[i] {
+			polyline[j] = arc.points[k]
+		}
+
+		index.Add(&polyline)
+		shapeArcs[&polyline] = i
+	}
+
+	edges := []arcEdge{}
+	query := s2.NewCrossingEdgeQuery(index)
+	for i, arc := range s.arcs {
+		for j := 1; j < len(kept[i]); j++ {
+			a, b := arc.points[kept[i][j-1]], arc.points[kept[i][j]]
+			for shape, crossings := range query.CrossingsEdgeMap(a, b, s2.CrossingTypeAll) {
+				other := shapeArcs[shape]
+				for _, e := range crossings {
+					c, d := s.arcs[other].points[kept[other][e]], s.arcs[other].points[kept[other][e+1]]
+					if a == c || a == d || b == c || b == d {
+						continue
+					}
+
+					edges = append(edges, arcEdge{arc: i, start: kept[i][j-1], end: kept[i][j]})
+				}
+			}
+		}
+	}
+
+	return edges
+}
+
+// enclosing returns the simplified edges that moved across a kept point of
+// another edge, which lies in the region between the edge and the points it
+// replaces.
+func (s *simplifier) enclosing() []arcEdge {
+	points := []s2.Point{}
+	for _, arc := range s.arcs {
+		for _, k := range arc.kept() {
+			points = append(points, arc.points[k])
+		}
+	}
+
+	// Points are sorted by their z coordinate, which increases with their
+	// latitude.
+	sort.Slice(points, func(i, j int) bool {
+		return points[i].Z < points[j].Z
+	})
+
+	zs := make([]float64, len(points))
+	for i, point := range points {
+		zs[i] = point.Z
+	}
+
+	edges := []arcEdge{}
+	for i, arc := range s.arcs {
+		kept := arc.kept()
+		for j := 1; j < len(kept); j++ {
+			start, end := kept[j-1], kept[j]
+			a, b := arc.points[start], arc.points[end]
+			if end-start < 2 || a == b {
+				continue
+			}
+
+			replaced := arc.points[start : end+1]
+			region := ringInput(replaced)
+			bounder := s2.NewRectBounder()
+			for _, point := range replaced {
+				bounder.AddPoint(point)
+			}
+
+			bounder.AddPoint(a)
+			bound := bounder.RectBound()
+			if region.contains(s2.PointFromCoords(0, 0, 1)) || region.contains(s2.PointFromCoords(0, 0, -1)) {
+				bound = s2.FullRect()
+			}
+
+			lo, hi := math.Sin(bound.Lat.Lo), math.Sin(bound.Lat.Hi)
+			for k := sort.SearchFloat64s(zs, lo); k < len(points) && zs[k] <= hi; k++ {
+				point := points[k]
+				if point != a && point != b && bound.ContainsPoint(point) && region.contains(point) {
+					edges = append(edges, arcEdge{arc: i, start: start, end: end})
+					break
+				}
+			}
+		}
+	}
+
+	return edges
+}
+
+// simplify simplifies the arcs, restoring points until the topology is
+// preserved if requested.
+func (s *simplifier) simplify() {
+	s.cut()
+	for _, arc := range s.arcs {
+		if len(arc.points) < 3 {
+			continue
+		}
+
+		switch s.options.Method {
+		case VisvalingamWhyatt:
+			arc.visvalingamWhyatt(s.options.Tolerance)
+		default:
+			arc.douglasPeucker(s.options.Tolerance)
+		}
+	}
+
+	if !s.options.PreserveTopology {
+		return
+	}
+
+	// Each round restores a point of every edge causing a problem, which
+	// ends at the latest with the original points.
+	for {
+		restored := false
+		edges := append(s.collapsed(), s.crossing()...)
+		for _, edge := range append(edges, s.enclosing()...) {
+			if s.restore(edge) {
+				restored = true
+			}
+		}
+
+		if !restored {
+			return
+		}
+	}
+}
+
+// points returns the simplified points of a sequence, without the closing
+// point of a ring.
+func (s *simplifier) points(i int) []s2.Point {
+	points := []s2.Point{}
+	for _, a := range s.paths[i] {
+		reversed := a < 0
+		if reversed {
+			a = ^a
+		}
+
+		arc := s.arcs[a]
+		arcPoints := make([]s2.Point, 0, len(arc.points))
+		for _, k := range arc.kept() {
+			arcPoints = append(arcPoints, arc.points[k])
+		}
+
+		if reversed {
+			arcPoints = reversedPoints(arcPoints)
+		}
+
+		if len(points) > 0 {
+			arcPoints = arcPoints[1:]
+		}
+
+		points = append(points, arcPoints...)
+	}
+
+	if n := len(points) - 1; s.rings[i] && n > 0 && points[0] == points[n] {
+		points = points[:n]
+	}
+
+	return points
+}
+
+// SimplifyPolylines simplifies polylines together, so that their shared
+// parts are simplified the same way.
+func SimplifyPolylines(polylines []*s2.Polyline, options SimplifyOptions) []*s2.Polyline {
+	s := &simplifier{options: options}
+	for _, polyline := range polylines {
+		s.add(*polyline, false)
+	}
+
+	s.simplify()
+	simplified := make([]*s2.Polyline, len(polylines))
+	for i := range polylines {
+		polyline := s2.Polyline(s.points(i))
+		simplified[i] = &polyline
+	}
+
+	return simplified
+}
+
+// SimplifyPolyline simplifies a polyline.
+func SimplifyPolyline(polyline *s2.Polyline, options SimplifyOptions) *s2.Polyline {
+	return SimplifyPolylines([]*s2.Polyline{polyline}, options)[0]
+}
+
+// SimplifyPolygons simplifies polygons together, so that the borders shared
+// by neighboring polygons are simplified the same way and, if the topology
+// is preserved, the polygons do not overlap after simplification.
+func SimplifyPolygons(polygons []*s2.Polygon, options SimplifyOptions) []*s2.Polygon {
+	s := &simplifier{options: options}
+	sequences := make([][]int, len(polygons))
+	for i, polygon := range polygons {
+		for _, loop := range polygon.Loops() {
+			if loop.IsEmpty() || loop.IsFull() {
+				sequences[i] = append(sequences[i], -1)
+				continue
+			}
+
+			// Rings are oriented with the interior of the polygon on their
+			// left.
+			points := make([]s2.Point, loop.NumVertices())
+			for j := range points {
+				points[j] = loop.OrientedVertex(j)
+			}
+
+			sequences[i] = append(sequences[i], s.add(points, true))
+		}
+	}
+
+	s.simplify()
+	simplified := make([]*s2.Polygon, len(polygons))
+	for i, polygon := range polygons {
+		if polygon.IsFull() {
+			simplified[i] = s2.FullPolygon()
+			continue
+		}
+
+		// The holes of a collapsed shell, which follow it in the polygon, are
+		// dropped with it.
+		shellDropped := false
+		loops := []*s2.Loop{}
+		for k, j := range sequences[i] {
+			hole := polygon.Loop(k).IsHole()
+			if j < 0 || hole && shellDropped {
+				continue
+			}
+
+			points := s.points(j)
+			if !hole {
+				shellDropped = len(points) < 3
+			}
+
+			if len(points) < 3 {
+				continue
+			}
+
+			loops = append(loops, s2.LoopFromPoints(points))
+		}
+
+		simplified[i] = s2.PolygonFromOrientedLoops(loops)
+	}
+
+	return simplified
+}
+
+// SimplifyPolygon simplifies a polygon.
+func SimplifyPolygon(polygon *s2.Polygon, options SimplifyOptions) *s2.Polygon {
+	return SimplifyPolygons([]*s2.Polygon{polygon}, options)[0]
+}
@@ -0,0 +1,96 @@
+package geoutil
+
+import (
+	"math"
+	"testing"
+
+	"github.com/golang/geo/s1"
+	"github.com/golang/geo/s2"
+)
+
+func TestSimplifyPolyline(t *testing.T) {
+	polyline := polylinesFromDegrees([]float64{0, 0, 0.001, 1, 0, 2, 1, 3, 0, 4})[0]
+	tests := []struct {
+		method    SimplifyMethod
+		tolerance s1.Angle
+		want      int
+	}{
+		{DouglasPeucker, 0.01 * s1.Degree, 4},
+		{DouglasPeucker, 2 * s1.Degree, 2},
+		{VisvalingamWhyatt, 0.1 * s1.Degree, 4},
+		{VisvalingamWhyatt, 2 * s1.Degree, 2},
+		{DouglasPeucker, 0, 5},
+	}
+
+	for _, test := range tests {
+		simplified := SimplifyPolyline(polyline, SimplifyOptions{Method: test.method, Tolerance: test.tolerance})
+		if len(*simplified) != test.want {
+			t.Errorf("method %d, tolerance %v: simplified to %d vertices, want %d", test.method, test.tolerance, len(*simplified), test.want)
+			continue
+		}
+
+		if (*simplified)[0] != (*polyline)[0] || (*simplified)[test.want-1] != (*polyline)[4] {
+			t.Errorf("method %d, tolerance %v: moved the end points", test.method, test.tolerance)
+		}
+	}
+}
+
+func TestSimplifyPolygonsSharedBorder(t *testing.T) {
+	west, _ := PolygonFromPolygonCoordinates([][][]float64{{{0, 0}, {5, 0}, {5.01, 5}, {5, 10}, {0, 10}, {0, 0}}})
+	east, _ := PolygonFromPolygonCoordinates([][][]float64{{{5, 0}, {10, 0}, {10, 10}, {5, 10}, {5.01, 5}, {5, 0}}})
+	simplified := SimplifyPolygons([]*s2.Polygon{west, east}, SimplifyOptions{Tolerance: 0.1 * s1.Degree})
+	for i, polygon := range simplified {
+		if polygon.NumLoops() != 1 || polygon.Loop(0).NumVertices() != 4 {
+			t.Errorf("polygon %d: simplified to %d loops", i, polygon.NumLoops())
+		}
+	}
+
+	// The shared border is simplified the same way, leaving no gap or
+	// overlap, so the polygons still tile the square.
+	square, _ := PolygonFromPolygonCoordinates([][][]float64{{{0, 0}, {5, 0}, {10, 0}, {10, 10}, {5, 10}, {0, 10}, {0, 0}}})
+	if area := simplified[0].Area() + simplified[1].Area(); math.Abs(area-square.Area()) > 1e-12 {
+		t.Errorf("simplified polygons of area %v, want %v", area, square.Area())
+	}
+}
+
+func TestSimplifyPreserveTopology(t *testing.T) {
+	polygon, err := PolygonFromPolygonCoordinates([][][]float64{
+		{{0, 0}, {10, 0}, {10, 10}, {5, 12}, {0, 10}, {0, 0}},
+		{{4.9, 10.9}, {5.1, 10.9}, {5.1, 11.1}, {4.9, 11.1}, {4.9, 10.9}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Without preserving the topology, the shell moves south of the hole.
+	options := SimplifyOptions{Tolerance: 3 * s1.Degree}
+	if simplified := SimplifyPolygon(polygon, options); simplified.NumLoops() == 2 && simplified.Loop(1).IsHole() {
+		t.Errorf("simplified the shell past the hole to %d loops", simplified.NumLoops())
+	}
+
+	options.PreserveTopology = true
+	simplified := SimplifyPolygon(polygon, options)
+	if simplified.NumLoops() != 2 || !simplified.Loop(1).IsHole() || simplified.Loop(0).NumVertices() != 5 {
+		t.Fatalf("simplified to %d loops, the first of %d vertices", simplified.NumLoops(), simplified.Loop(0).NumVertices())
+	}
+
+	// The hole may be simplified to a triangle, but stays inside the shell.
+	holeArea := polygon.Loop(1).Area()
+	if area := simplified.Area(); area < polygon.Area()-1e-12 || area > polygon.Area()+holeArea {
+		t.Errorf("simplified to area %v, want %v", area, polygon.Area())
+	}
+
+	// Crossing rings are also prevented.
+	polygon, err = PolygonFromPolygonCoordinates([][][]float64{
+		{{0, 0}, {10, 0}, {10, 10}, {5, 12}, {0, 10}, {0, 0}},
+		{{4, 10.5}, {6, 10.5}, {5, 11.5}, {4, 10.5}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	simplified = SimplifyPolygon(polygon, options)
+	if problems, err := Validate(simplified); err != nil || len(problems) != 0 || simplified.NumLoops() != 2 {
+		t.Errorf("simplified to %d loops with problems %v, %v", simplified.NumLoops(), problems, err)
+	}
+}
@@ -0,0 +1,324 @@
+// Package utm converts between s2 types and Universal Transverse Mercator
+// coordinates on the WGS84 ellipsoid.
+package utm
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/golang/geo/s1"
+	"github.com/golang/geo/s2"
+)
+
+// Hemisphere identifies the hemisphere of a UTM coordinate.
+type Hemisphere byte
+
+const (
+	North Hemisphere = 'N'
+	South Hemisphere = 'S'
+)
+
+const (
+	// MinLatitude and MaxLatitude bound the latitudes in degrees covered by
+	// UTM zones. The polar regions are covered by UPS instead.
+	MinLatitude = -80
+	MaxLatitude = 84
+
+	// falseEasting and falseNorthing are added to the coordinates of the
+	// projection, the latter in the southern hemisphere only.
+	falseEasting  = 500000
+	falseNorthing = 10000000
+
+	// k0 is the scale factor on the central meridian.
+	k0 = 0.9996
+
+	// WGS84 ellipsoid.
+	semiMajorAxis = 6378137
+	flattening    = 1 / 298.257223563
+)
+
+// Coordinate represents a UTM coordinate in meters.
+type Coordinate struct {
+	Zone       int
+	Hemisphere Hemisphere
+	Easting    float64
+	Northing   float64
+}
+
+var (
+
+	// n is the third flattening and e the eccentricity of the ellipsoid.
+	n = flattening / (2 - flattening)
+	e = math.Sqrt(flattening * (2 - flattening))
+
+	// rectifyingRadius is the radius of the sphere of the same meridian
+	// length as the ellipsoid.
+	rectifyingRadius = semiMajorAxis / (1 + n) * (1 + n*n/4 + math.Pow(n, 4)/64 + math.Pow(n, 6)/256)
+
+	// alpha and beta are the coefficients of the 6th order Krüger series of
+	// the forward and inverse projections.
+	alpha = [...]float64{
+		n/2 - 2*n*n/3 + 5*math.Pow(n, 3)/16 + 41*math.Pow(n, 4)/180 - 127*math.Pow(n, 5)/288 + 7891*math.Pow(n, 6)/37800,
+		13*n*n/48 - 3*math.Pow(n, 3)/5 + 557*math.Pow(n, 4)/1440 + 281*math.Pow(n, 5)/630 - 1983433*math.Pow(n, 6)/1935360,
+		61*math.Pow(n, 3)/240 - 103*math.Pow(n, 4)/140 + 15061*math.Pow(n, 5)/26880 + 167603*math.Pow(n, 6)/181440,
+		49561*math.Pow(n, 4)/161280 - 179*math.Pow(n, 5)/168 + 6601661*math.Pow(n, 6)/7257600,
+		34729*math.Pow(n, 5)/80640 - 3418889*math.Pow(n, 6)/1995840,
+		212378941 * math.Pow(n, 6) / 319334400,
+	}
+
+	beta = [...]float64{
+		n/2 - 2*n*n/3 + 37*math.Pow(n, 3)/96 - math.Pow(n, 4)/360 - 81*math.Pow(n, 5)/512 + 96199*math.Pow(n, 6)/604800,
+		n*n/48 + math.Pow(n, 3)/15 - 437*math.Pow(n, 4)/1440 + 46*math.Pow(n, 5)/105 - 1118711*math.Pow(n, 6)/3870720,
+		17*math.Pow(n, 3)/480 - 37*math.Pow(n, 4)/840 - 209*math.Pow(n, 5)/4480 + 5569*math.Pow(n, 6)/90720,
+		4397*math.Pow(n, 4)/161280 - 11*math.Pow(n, 5)/504 - 830251*math.Pow(n, 6)/7257600,
+		4583*math.Pow(n, 5)/161280 - 108847*math.Pow(n, 6)/3991680,
+		20648693 * math.Pow(n, 6) / 638668800,
+	}
+)
+
+// LatitudeBands are the letters of the 8 degree latitude bands from 80S; the
+// last band, X, extends to 84N.
+const LatitudeBands = "CDEFGHJKLMNPQRSTUVWX"
+
+func validateLatitude(latLng s2.LatLng) error {
+	if lat := latLng.Lat.Degrees(); lat < MinLatitude || lat > MaxLatitude {
+		return fmt.Errorf("utm: latitude %g is outside of the UTM zones", lat)
+	}
+
+	return nil
+}
+
+func validateZone(zone int) error {
+	if zone < 1 || zone > 60 {
+		return fmt.Errorf("utm: invalid zone %d", zone)
+	}
+
+	return nil
+}
+
+// LatitudeBand returns the letter of the latitude band of a location.
+func LatitudeBand(latLng s2.LatLng) (byte, error) {
+	if err := validateLatitude(latLng); err != nil {
+		return 0, err
+	}
+
+	band := int(math.Floor((latLng.Lat.Degrees() - MinLatitude) / 8))
+	if band >= len(LatitudeBands) {
+		band = len(LatitudeBands) - 1
+	}
+
+	return LatitudeBands[band], nil
+}
+
+// Zone returns the zone of a location, including the exceptions of the zones
+// of southwest Norway and Svalbard.
+func Zone(latLng s2.LatLng) (int, error) {
+	if err := validateLatitude(latLng); err != nil {
+		return 0, err
+	}
+
+	latLng = latLng.Normalized()
+	lat, lng := latLng.Lat.Degrees(), latLng.Lng.Degrees()
+	zone := int(math.Floor((lng+180)/6)) + 1
+	if zone > 60 {
+		zone = 60
+	}
+
+	switch {
+
+	// Zone 32 is widened to cover southwest Norway.
+	case lat >= 56 && lat < 64 && lng >= 3 && lng < 12:
+		return 32, nil
+
+	// Zones 32, 34 and 36 are absent around Svalbard.
+	case lat >= 72 && lng >= 0 && lng < 42:
+		switch {
+		case lng < 9:
+			return 31, nil
+		case lng < 21:
+			return 33, nil
+		case lng < 33:
+			return 35, nil
+		default:
+			return 37, nil
+		}
+	}
+
+	return zone, nil
+}
+
+// centralMeridian returns the longitude of the central meridian of a zone.
+func centralMeridian(zone int) s1.Angle {
+	return s1.Angle(zone*6-183) * s1.Degree
+}
+
+// FromLatLng returns the UTM coordinate of a location in its zone.
+func FromLatLng(latLng s2.LatLng) (Coordinate, error) {
+	zone, err := Zone(latLng)
+	if err != nil {
+		return Coordinate{}, err
+	}
+
+	return FromLatLngInZone(latLng, zone)
+}
+
+// FromLatLngInZone returns the UTM coordinate of a location in the given
+// zone, which need not be the zone of the location, as when the coordinates
+// of a feature spanning several zones are expressed in a single zone.
+func FromLatLngInZone(latLng s2.LatLng, zone int) (Coordinate, error) {
+	if err := validateLatitude(latLng); err != nil {
+		return Coordinate{}, err
+	}
+
+	if err := validateZone(zone); err != nil {
+		return Coordinate{}, err
+	}
+
+	phi := latLng.Lat.Radians()
+	lambda := (latLng.Lng - centralMeridian(zone)).Radians()
+	lambda = math.Remainder(lambda, 2*math.Pi)
+
+	// Conformal latitude.
+	tau := math.Tan(phi)
+	sigma := math.Sinh(e * math.Atanh(e*tau/math.Sqrt(1+tau*tau)))
+	tauPrime := tau*math.Sqrt(1+sigma*sigma) - sigma*math.Sqrt(1+tau*tau)
+
+	// Spherical transverse Mercator coordinates, then Krüger series.
+	cosLambda := math.Cos(lambda)
+	xiPrime := math.Atan2(tauPrime, cosLambda)
+	etaPrime := math.Asinh(math.Sin(lambda) / math.Sqrt(tauPrime*tauPrime+cosLambda*cosLambda))
+
+	xi, eta := xiPrime, etaPrime
+	for j, a := range alpha {
+		k := 2 * float64(j+1)
+		xi += a * math.Sin(k*xiPrime) * math.Cosh(k*etaPrime)
+		eta += a * math.Cos(k*xiPrime) * math.Sinh(k*etaPrime)
+	}
+
+	c := Coordinate{
+		Zone:       zone,
+		Hemisphere: North,
+		Easting:    k0*rectifyingRadius*eta + falseEasting,
+		Northing:   k0 * rectifyingRadius * xi,
+	}
+
+	if phi < 0 {
+		c.Hemisphere = South
+		c.Northing += falseNorthing
+	}
+
+	return c, nil
+}
+
+// LatLng returns the location of a UTM coordinate.
+func (c Coordinate) LatLng() (s2.LatLng, error) {
+	if err := validateZone(c.Zone); err != nil {
+		return s2.LatLng{}, err
+	}
+
+	northing := c.Northing
+	switch c.Hemisphere {
+	case North:
+	case South:
+		northing -= falseNorthing
+	default:
+		return s2.LatLng{}, fmt.Errorf("utm: invalid hemisphere %q", byte(c.Hemisphere))
+	}
+
+	eta := (c.Easting - falseEasting) / (k0 * rectifyingRadius)
+	xi := northing / (k0 * rectifyingRadius)
+
+	xiPrime, etaPrime := xi, eta
+	for j, b := range beta {
+		k := 2 * float64(j+1)
+		xiPrime -= b * math.Sin(k*xi) * math.Cosh(k*eta)
+		etaPrime -= b * math.Cos(k*xi) * math.Sinh(k*eta)
+	}
+
+	sinhEtaPrime := math.Sinh(etaPrime)
+	sinXiPrime, cosXiPrime := math.Sin(xiPrime), math.Cos(xiPrime)
+	tauPrime := sinXiPrime / math.Sqrt(sinhEtaPrime*sinhEtaPrime+cosXiPrime*cosXiPrime)
+
+	// Solve for the latitude whose conformal latitude is tauPrime by Newton's
+	// method.
+	tau := tauPrime
+	for i := 0; i < 10; i++ {
+		sigma := math.Sinh(e * math.Atanh(e*tau/math.Sqrt(1+tau*tau)))
+		tauI := tau*math.Sqrt(1+sigma*sigma) - sigma*math.Sqrt(1+tau*tau)
+		delta := (tauPrime - tauI) / math.Sqrt(1+tauI*tauI) *
+			(1 + (1-e*e)*tau*tau) / ((1 - e*e) * math.Sqrt(1+tau*tau))
+		tau += delta
+		if math.Abs(delta) < 1e-12 {
+			break
+		}
+	}
+
+	lambda := math.Atan2(sinhEtaPrime, cosXiPrime)
+	return s2.LatLng{
+		Lat: s1.Angle(math.Atan(tau)),
+		Lng: s1.Angle(lambda) + centralMeridian(c.Zone),
+	}.Normalized(), nil
+}
+
+// Point returns the location of a UTM coordinate as a point.
+func (c Coordinate) Point() (s2.Point, error) {
+	latLng, err := c.LatLng()
+	if err != nil {
+		return s2.Point{}, err
+	}
+
+	return s2.PointFromLatLng(latLng), nil
+}
+
+// String returns the coordinate in the form "31N 448251 5411932", rounded to
+// the meter.
+func (c Coordinate) String() string {
+	return fmt.Sprintf("%d%c %.0f %.0f", c.Zone, c.Hemisphere, c.Easting, c.Northing)
+}
+
+// Parse parses a coordinate in the form returned by String. The hemisphere
+// may be separated from the zone by spaces.
+func Parse(s string) (Coordinate, error) {
+	fields := strings.Fields(strings.ToUpper(s))
+	if len(fields) == 4 {
+		fields = []string{fields[0] + fields[1], fields[2], fields[3]}
+	}
+
+	if len(fields) != 3 || len(fields[0]) < 2 {
+		return Coordinate{}, fmt.Errorf("utm: invalid coordinate %q", s)
+	}
+
+	zoneField := fields[0]
+	hemisphere := Hemisphere(zoneField[len(zoneField)-1])
+	if hemisphere != North && hemisphere != South {
+		return Coordinate{}, fmt.Errorf("utm: invalid hemisphere in %q", s)
+	}
+
+	zone, err := strconv.Atoi(zoneField[:len(zoneField)-1])
+	if err != nil {
+		return Coordinate{}, fmt.Errorf("utm: invalid zone in %q", s)
+	}
+
+	if err := validateZone(zone); err != nil {
+		return Coordinate{}, err
+	}
+
+	easting, err := strconv.ParseFloat(fields[1], 64)
+	if err != nil {
+		return Coordinate{}, fmt.Errorf("utm: invalid easting in %q", s)
+	}
+
+	northing, err := strconv.ParseFloat(fields[2], 64)
+	if err != nil {
+		return Coordinate{}, fmt.Errorf("utm: invalid northing in %q", s)
+	}
+
+	return Coordinate{
+		Zone:       zone,
+		Hemisphere: hemisphere,
+		Easting:    easting,
+		Northing:   northing,
+	}, nil
+}
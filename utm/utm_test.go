@@ -0,0 +1,180 @@
+package utm
+
+import (
+	"math"
+	"testing"
+
+	"github.com/golang/geo/s2"
+)
+
+func TestFromLatLng(t *testing.T) {
+
+	// The expected coordinates are computed with the series of Snyder's Map
+	// Projections: A Working Manual, accurate to the millimeter within a few
+	// degrees of the central meridian.
+	tests := []struct {
+		lat, lng float64
+		want     Coordinate
+	}{
+		{48.8583, 2.2945, Coordinate{31, North, 448251.898, 5411943.794}},
+		{0, 0, Coordinate{31, North, 166021.443, 0}},
+		{45, 3, Coordinate{31, North, 500000, 4982950.400}},
+		{-45, 3, Coordinate{31, South, 500000, 5017049.600}},
+		{60, 5, Coordinate{32, North, 276979.926, 6658157.203}},
+		{-33.8568, 151.2153, Coordinate{56, South, 334900.570, 6252288.753}},
+		{40.6892, -74.0445, Coordinate{18, North, 580735.871, 4504695.165}},
+		{-23.5, -46.6, Coordinate{23, South, 336625.132, 7400218.860}},
+	}
+
+	for _, test := range tests {
+		c, err := FromLatLng(s2.LatLngFromDegrees(test.lat, test.lng))
+		if err != nil {
+			t.Errorf("%v, %v: %v", test.lat, test.lng, err)
+			continue
+		}
+
+		if c.Zone != test.want.Zone || c.Hemisphere != test.want.Hemisphere ||
+			math.Abs(c.Easting-test.want.Easting) > 0.01 || math.Abs(c.Northing-test.want.Northing) > 0.01 {
+			t.Errorf("%v, %v: converted to %+v, want %+v", test.lat, test.lng, c, test.want)
+		}
+	}
+
+	for _, lat := range []float64{84.1, -80.1} {
+		if _, err := FromLatLng(s2.LatLngFromDegrees(lat, 0)); err == nil {
+			t.Errorf("latitude %v: no error", lat)
+		}
+	}
+
+	for _, zone := range []int{0, 61} {
+		if _, err := FromLatLngInZone(s2.LatLngFromDegrees(0, 0), zone); err == nil {
+			t.Errorf("zone %d: no error", zone)
+		}
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	for lat := -80.0; lat <= 84; lat += 4.1 {
+		for lng := -180.0; lng < 180; lng += 7.7 {
+			latLng := s2.LatLngFromDegrees(lat, lng)
+			zone, err := Zone(latLng)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// Locations are also converted in the next zone, far from its
+			// central meridian.
+			for _, z := range []int{zone, zone%60 + 1} {
+				c, err := FromLatLngInZone(latLng, z)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				parsed, err := Parse(c.String())
+				if err != nil {
+					t.Fatalf("%s: %v", c, err)
+				}
+
+				if parsed.Zone != c.Zone || parsed.Hemisphere != c.Hemisphere ||
+					math.Abs(parsed.Easting-c.Easting) > 0.5 || math.Abs(parsed.Northing-c.Northing) > 0.5 {
+					t.Errorf("parsed %s as %+v", c, parsed)
+				}
+
+				decoded, err := c.LatLng()
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if d := decoded.Distance(latLng).Degrees(); d > 1e-9 {
+					t.Errorf("%v in zone %d: decoded %v", latLng, z, decoded)
+				}
+			}
+		}
+	}
+}
+
+func TestZone(t *testing.T) {
+	tests := []struct {
+		lat, lng float64
+		zone     int
+		band     byte
+	}{
+		{0, 0, 31, 'N'},
+		{-0.1, -0.1, 30, 'M'},
+		{0, -180, 1, 'N'},
+		{0, 180, 60, 'N'},
+		{0, 179.9, 60, 'N'},
+		{-80, 0, 31, 'C'},
+		{84, 0, 31, 'X'},
+		{72, 0, 31, 'X'},
+		{71.9, 0, 31, 'W'},
+
+		// Southwest Norway.
+		{60, 5, 32, 'V'},
+		{56, 3, 32, 'V'},
+		{64, 5, 31, 'W'},
+		{63.9, 2.9, 31, 'V'},
+
+		// Svalbard.
+		{75, 8.9, 31, 'X'},
+		{75, 10, 33, 'X'},
+		{75, 21, 35, 'X'},
+		{75, 33, 37, 'X'},
+		{75, 42, 38, 'X'},
+	}
+
+	for _, test := range tests {
+		latLng := s2.LatLngFromDegrees(test.lat, test.lng)
+		zone, err := Zone(latLng)
+		if err != nil {
+			t.Errorf("%v, %v: %v", test.lat, test.lng, err)
+			continue
+		}
+
+		band, err := LatitudeBand(latLng)
+		if err != nil {
+			t.Errorf("%v, %v: %v", test.lat, test.lng, err)
+			continue
+		}
+
+		if zone != test.zone || band != test.band {
+			t.Errorf("%v, %v: zone %d%c, want %d%c", test.lat, test.lng, zone, band, test.zone, test.band)
+		}
+	}
+
+	if _, err := LatitudeBand(s2.LatLngFromDegrees(-81, 0)); err == nil {
+		t.Errorf("latitude band beyond 80S: no error")
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := map[string]Coordinate{
+		"31N 448252 5411944":      {31, North, 448252, 5411944},
+		"31 n 448252 5411944":     {31, North, 448252, 5411944},
+		"56S 334900.57 6252288.8": {56, South, 334900.57, 6252288.8},
+		"1s 500000 0":             {1, South, 500000, 0},
+	}
+
+	for s, want := range tests {
+		c, err := Parse(s)
+		if err != nil {
+			t.Errorf("%s: %v", s, err)
+			continue
+		}
+
+		if c != want {
+			t.Errorf("%s: parsed %+v, want %+v", s, c, want)
+		}
+	}
+
+	for _, s := range []string{"", "N 1 2", "0N 1 2", "61N 1 2", "31X 1 2", "31N one 2", "31N 1 two", "31N 1", "31 N 1 2 3"} {
+		if _, err := Parse(s); err == nil {
+			t.Errorf("%q: no error", s)
+		}
+	}
+
+	for _, c := range []Coordinate{{0, North, 500000, 0}, {31, 'X', 500000, 0}} {
+		if _, err := c.Point(); err == nil {
+			t.Errorf("%+v: no error", c)
+		}
+	}
+}
@@ -0,0 +1,511 @@
+package geoutil
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/golang/geo/s2"
+)
+
+// ProblemKind identifies a kind of geometry problem.
+type ProblemKind int
+
+const (
+	// ProblemOutOfRange is a coordinate that is not a number, beyond 90
+	// degrees of latitude or 180 degrees of longitude, or of the wrong
+	// dimension.
+	ProblemOutOfRange ProblemKind = iota
+
+	// ProblemDuplicateVertex is a vertex equal to the previous vertex.
+	ProblemDuplicateVertex
+
+	// ProblemDegenerate is a ring of fewer than three distinct vertices or
+	// of no area, or a line string of fewer than two distinct vertices.
+	ProblemDegenerate
+
+	// ProblemSelfIntersection is an edge of a ring crossing another edge of
+	// the same ring, or a vertex visited twice by a ring.
+	ProblemSelfIntersection
+
+	// ProblemCrossingRings is an edge of a ring crossing an edge of another
+	// ring.
+	ProblemCrossingRings
+
+	// ProblemImproperNesting is a hole outside of its shell, a hole inside
+	// another hole, or a shell inside another polygon of a multipolygon.
+	ProblemImproperNesting
+
+	// ProblemUnclosedRing is a ring whose last vertex is not equal to its
+	// first vertex.
+	ProblemUnclosedRing
+)
+
+var problemKindNames = [...]string{
+	ProblemOutOfRange:       "out-of-range coordinate",
+	ProblemDuplicateVertex:  "duplicate vertex",
+	ProblemDegenerate:       "degenerate geometry",
+	ProblemSelfIntersection: "self-intersection",
+	ProblemCrossingRings:    "crossing rings",
+	ProblemImproperNesting:  "improper nesting",
+	ProblemUnclosedRing:     "unclosed ring",
+}
+
+func (k ProblemKind) String() string {
+	if k < 0 || int(k) >= len(problemKindNames) {
+		return fmt.Sprintf("ProblemKind(%d)", int(k))
+	}
+
+	return problemKindNames[k]
+}
+
+// Problem locates a problem of a geometry. Part is the index of the polygon,
+// line string or point of a multi-geometry, and 0 otherwise. Ring is the index
+// of the ring of a polygon, the shell being ring 0, and 0 for other
+// geometries. Vertex is the index of the vertex in the coordinates of the
+// ring or line string, or -1 if the problem concerns the whole ring. Crossing
+// rings are reported once per pair of rings, at the first vertex of the ring
+// starting an edge that crosses the other ring, which is located by OtherPart
+// and OtherRing; those are 0 for other problems.
+type Problem struct {
+	Kind      ProblemKind
+	Part      int
+	Ring      int
+	Vertex    int
+	OtherPart int
+	OtherRing int
+}
+
+func (p Problem) String() string {
+	s := fmt.Sprintf("%s at part %d, ring %d", p.Kind, p.Part, p.Ring)
+	if p.Vertex >= 0 {
+		s += fmt.Sprintf(", vertex %d", p.Vertex)
+	}
+
+	if p.Kind == ProblemCrossingRings {
+		s += fmt.Sprintf(" with part %d, ring %d", p.OtherPart, p.OtherRing)
+	}
+
+	return s
+}
+
+// ValidationError reports the problems of an invalid geometry.
+type ValidationError struct {
+	Problems []Problem
+}
+
+func (e *ValidationError) Error() string {
+	if len(e.Problems) == 1 {
+		return fmt.Sprintf("geoutil: invalid geometry: %s", e.Problems[0])
+	}
+
+	return fmt.Sprintf("geoutil: invalid geometry: %s and %d more problems", e.Problems[0], len(e.Problems)-1)
+}
+
+// ErrorFromProblems returns a *ValidationError reporting problems, or nil if
+// there are no problems.
+func ErrorFromProblems(problems []Problem) error {
+	if len(problems) == 0 {
+		return nil
+	}
+
+	return &ValidationError{Problems: problems}
+}
+
+// validPoint returns the point of coordinates, and whether they are in range.
+func validPoint(coords []float64) (s2.Point, bool) {
+	if len(coords) != 2 {
+		return s2.Point{}, false
+	}
+
+	lng, lat := coords[0], coords[1]
+	if math.IsNaN(lng) || math.IsNaN(lat) || math.Abs(lng) > 180 || math.Abs(lat) > 90 {
+		return s2.Point{}, false
+	}
+
+	return s2.PointFromLatLng(s2.LatLngFromDegrees(lat, lng)), true
+}
+
+// validRing is a ring or line string with its duplicate vertices and closing
+// vertex removed. indexes holds the index of each vertex in the coordinates.
+// The edges of loop follow the vertices, while region is the normalized loop
+// used to test containment.
+type validRing struct {
+	part, ring int
+	points     []s2.Point
+	indexes    []int
+	loop       *s2.Loop
+	region     *s2.Loop
+}
+
+// validateVertices checks the coordinates of a ring or line string and
+// returns its distinct vertices, or nil if a coordinate is out of range.
+func validateVertices(problems *[]Problem, part, ring int, coords [][]float64, closed bool) *validRing {
+	vr := &validRing{
+		part: part,
+		ring: ring,
+	}
+
+	inRange := true
+	for i, pointCoords := range coords {
+		point, ok := validPoint(pointCoords)
+		if !ok {
+			*problems = append(*problems, Problem{Kind: ProblemOutOfRange, Part: part, Ring: ring, Vertex: i})
+			inRange = false
+			continue
+		}
+
+		if n := len(vr.points); n > 0 && vr.points[n-1] == point {
+
+			// The closing vertex of a ring is not a duplicate.
+			if !closed || i != len(coords)-1 {
+				*problems = append(*problems, Problem{Kind: ProblemDuplicateVertex, Part: part, Ring: ring, Vertex: i})
+			}
+
+			continue
+		}
+
+		vr.points = append(vr.points, point)
+		vr.indexes = append(vr.indexes, i)
+	}
+
+	if !inRange {
+		return nil
+	}
+
+	if n := len(vr.points) - 1; closed && n > 0 {
+		if vr.points[0] != vr.points[n] {
+			*problems = append(*problems, Problem{Kind: ProblemUnclosedRing, Part: part, Ring: ring, Vertex: -1})
+		} else {
+			vr.points = vr.points[:n]
+			vr.indexes = vr.indexes[:n]
+		}
+	}
+
+	return vr
+}
+
+// validateRing checks the coordinates of a ring and returns its loop, or nil
+// if the ring is too degenerate to be checked further.
+func validateRing(problems *[]Problem, part, ring int, coords [][]float64) *validRing {
+	vr := validateVertices(problems, part, ring, coords, true)
+	if vr == nil {
+		return nil
+	}
+
+	if len(vr.points) < 3 {
+		*problems = append(*problems, Problem{Kind: ProblemDegenerate, Part: part, Ring: ring, Vertex: -1})
+		return nil
+	}
+
+	// A ring may not visit a vertex twice.
+	seen := make(map[s2.Point]bool, len(vr.points))
+	for i, point := range vr.points {
+		if seen[point] {
+			*problems = append(*problems, Problem{Kind: ProblemSelfIntersection, Part: part, Ring: ring, Vertex: vr.indexes[i]})
+		}
+
+		seen[point] = true
+	}
+
+	vr.loop = s2.LoopFromPoints(vr.points)
+	return vr
+}
+
+// validateRegion sets the region of a ring that does not cross itself, or
+// reports it as degenerate if it has no area.
+func validateRegion(problems *[]Problem, vr *validRing) {
+	region := s2.LoopFromPoints(vr.points)
+	region.Normalize()
+	if region.Area() == 0 {
+		*problems = append(*problems, Problem{Kind: ProblemDegenerate, Part: vr.part, Ring: vr.ring, Vertex: -1})
+		return
+	}
+
+	vr.region = region
+}
+
+// regionContains reports whether the region of a ring contains the region of
+// another ring not crossing it, which is when it contains a vertex of the
+// other ring off its boundary. s2.Loop.Contains is only used when the rings
+// share all their vertices, as it misses the containment of loops of many
+// edges.
+func regionContains(vr, other *validRing) bool {
+	vertices := make(map[s2.Point]bool, len(vr.points))
+	for _, point := range vr.points {
+		vertices[point] = true
+	}
+
+	for _, point := range other.points {
+		if !vertices[point] {
+			return vr.region.ContainsPoint(point)
+		}
+	}
+
+	return vr.region.Contains(other.region)
+}
+
+// validateCrossings reports the edges of rings crossing other edges of the
+// same ring, and each pair of crossing rings once, and returns the set of
+// pairs of crossing rings, including rings crossing themselves.
+func validateCrossings(problems *[]Problem, rings []*validRing) map[[2]*validRing]bool {
+	index := s2.NewShapeIndex()
+	loopRings := make(map[s2.Shape]*validRing, len(rings))
+	order := make(map[*validRing]int, len(rings))
+	for i, vr := range rings {
+		index.Add(vr.loop)
+		loopRings[vr.loop] = vr
+		order[vr] = i
+	}
+
+	crossing := map[[2]*validRing]bool{}
+	query := s2.NewCrossingEdgeQuery(index)
+	for i, vr := range rings {
+		for j := 0; j < vr.loop.NumEdges(); j++ {
+			edge := vr.loop.Edge(j)
+			for shape, edges := range query.CrossingsEdgeMap(edge.V0, edge.V1, s2.CrossingTypeInterior) {
+				other := loopRings[shape]
+				k := order[other]
+				for _, e := range edges {
+
+					// Report each pair of crossing edges once.
+					if k < i || k == i && e <= j {
+						continue
+					}
+
+					if other == vr {
+						crossing[[2]*validRing{vr, vr}] = true
+						*problems = append(*problems, Problem{Kind: ProblemSelfIntersection, Part: vr.part, Ring: vr.ring, Vertex: vr.indexes[j]})
+						continue
+					}
+
+					// The edges of a ring are visited in order, so the
+					// first crossing found locates the pair.
+					if crossing[[2]*validRing{vr, other}] {
+						continue
+					}
+
+					crossing[[2]*validRing{vr, other}] = true
+					crossing[[2]*validRing{other, vr}] = true
+
+					*problems = append(*problems, Problem{
+						Kind:      ProblemCrossingRings,
+						Part:      vr.part,
+						Ring:      vr.ring,
+						Vertex:    vr.indexes[j],
+						OtherPart: other.part,
+						OtherRing: other.ring,
+					})
+				}
+			}
+		}
+	}
+
+	return crossing
+}
+
+// sortProblems sorts problems by location.
+func sortProblems(problems []Problem) {
+	sort.SliceStable(problems, func(i, j int) bool {
+		a, b := problems[i], problems[j]
+		switch {
+		case a.Part != b.Part:
+			return a.Part < b.Part
+		case a.Ring != b.Ring:
+			return a.Ring < b.Ring
+		case a.Vertex != b.Vertex:
+			return a.Vertex < b.Vertex
+		case a.Kind != b.Kind:
+			return a.Kind < b.Kind
+		case a.OtherPart != b.OtherPart:
+			return a.OtherPart < b.OtherPart
+		default:
+			return a.OtherRing < b.OtherRing
+		}
+	})
+}
+
+// ValidateMultiPolygonCoordinates checks the coordinates of a multipolygon.
+func ValidateMultiPolygonCoordinates(coords [][][][]float64) []Problem {
+	problems := []Problem{}
+	polygons := make([][]*validRing, len(coords))
+	rings := []*validRing{}
+	for i, polygonCoords := range coords {
+		if len(polygonCoords) == 0 {
+			problems = append(problems, Problem{Kind: ProblemDegenerate, Part: i, Ring: 0, Vertex: -1})
+			continue
+		}
+
+		polygons[i] = make([]*validRing, len(polygonCoords))
+		for j, linearRingCoords := range polygonCoords {
+			vr := validateRing(&problems, i, j, linearRingCoords)
+			if vr != nil {
+				rings = append(rings, vr)
+			}
+
+			polygons[i][j] = vr
+		}
+	}
+
+	crossing := validateCrossings(&problems, rings)
+	for _, vr := range rings {
+		if !crossing[[2]*validRing{vr, vr}] {
+			validateRegion(&problems, vr)
+		}
+	}
+
+	// Holes must be inside their shell and outside of the other holes.
+	for i, polygon := range polygons {
+		if len(polygon) == 0 || polygon[0] == nil || polygon[0].region == nil {
+			continue
+		}
+
+		shell := polygon[0]
+		for j, hole := range polygon[1:] {
+			if hole == nil || hole.region == nil || crossing[[2]*validRing{shell, hole}] {
+				continue
+			}
+
+			if !regionContains(shell, hole) {
+				problems = append(problems, Problem{Kind: ProblemImproperNesting, Part: i, Ring: j + 1, Vertex: -1})
+				continue
+			}
+
+			for _, other := range polygon[1:] {
+				if other == nil || other == hole || other.region == nil || crossing[[2]*validRing{hole, other}] {
+					continue
+				}
+
+				if regionContains(other, hole) {
+					problems = append(problems, Problem{Kind: ProblemImproperNesting, Part: i, Ring: j + 1, Vertex: -1})
+					break
+				}
+			}
+		}
+	}
+
+	// Shells must be outside of the other polygons, or inside their holes.
+	for i, polygon := range polygons {
+		if len(polygon) == 0 || polygon[0] == nil || polygon[0].region == nil {
+			continue
+		}
+
+		shell := polygon[0]
+		for k, other := range polygons {
+			if k == i || len(other) == 0 || other[0] == nil || other[0].region == nil || crossing[[2]*validRing{shell, other[0]}] {
+				continue
+			}
+
+			if !regionContains(other[0], shell) {
+				continue
+			}
+
+			inHole := false
+			for _, hole := range other[1:] {
+				if hole != nil && hole.region != nil && regionContains(hole, shell) {
+					inHole = true
+					break
+				}
+			}
+
+			if !inHole {
+				problems = append(problems, Problem{Kind: ProblemImproperNesting, Part: i, Ring: 0, Vertex: -1})
+				break
+			}
+		}
+	}
+
+	sortProblems(problems)
+	return problems
+}
+
+// ValidatePolygonCoordinates checks the coordinates of a polygon.
+func ValidatePolygonCoordinates(coords [][][]float64) []Problem {
+	return ValidateMultiPolygonCoordinates([][][][]float64{coords})
+}
+
+// ValidateMultiLineStringCoordinates checks the coordinates of a multi line
+// string.
+func ValidateMultiLineStringCoordinates(coords [][][]float64) []Problem {
+	problems := []Problem{}
+	for i, lineStringCoords := range coords {
+		vr := validateVertices(&problems, i, 0, lineStringCoords, false)
+		if vr != nil && len(vr.points) < 2 {
+			problems = append(problems, Problem{Kind: ProblemDegenerate, Part: i, Ring: 0, Vertex: -1})
+		}
+	}
+
+	return problems
+}
+
+// ValidateLineStringCoordinates checks the coordinates of a line string.
+func ValidateLineStringCoordinates(coords [][]float64) []Problem {
+	return ValidateMultiLineStringCoordinates([][][]float64{coords})
+}
+
+// ValidateMultiPointCoordinates checks the coordinates of a multipoint.
+func ValidateMultiPointCoordinates(coords [][]float64) []Problem {
+	problems := []Problem{}
+	for i, pointCoords := range coords {
+		if _, ok := validPoint(pointCoords); !ok {
+			problems = append(problems, Problem{Kind: ProblemOutOfRange, Part: i, Ring: 0, Vertex: 0})
+		}
+	}
+
+	return problems
+}
+
+// ValidatePointCoordinates checks the coordinates of a point.
+func ValidatePointCoordinates(coords []float64) []Problem {
+	return ValidateMultiPointCoordinates([][]float64{coords})
+}
+
+// Validate checks a decoded geometry. Polygons are checked as the
+// multipolygon of their shells and holes in the order of PolygonCoordinates.
+// Decoding does not preserve holes outside of their shell, which become
+// shells, so problems of nesting are only found by checking coordinates
+// before decoding.
+func Validate(geometry interface{}) ([]Problem, error) {
+	switch geometry := geometry.(type) {
+	case s2.LatLng:
+		if !geometry.IsValid() {
+			return []Problem{{Kind: ProblemOutOfRange}}, nil
+		}
+
+		return []Problem{}, nil
+	case s2.Point:
+		return Validate([]s2.Point{geometry})
+	case []s2.Point:
+		problems := []Problem{}
+		for i, point := range geometry {
+			if !point.IsUnit() || !s2.LatLngFromPoint(point).IsValid() {
+				problems = append(problems, Problem{Kind: ProblemOutOfRange, Part: i})
+			}
+		}
+
+		return problems, nil
+	case *s2.Polyline:
+		return Validate([]*s2.Polyline{geometry})
+	case []*s2.Polyline:
+		coords := make([][][]float64, len(geometry))
+		for i, polyline := range geometry {
+			lineStringCoords, err := PolylineCoordinates(polyline, PrecisionMax)
+			if err != nil {
+				return nil, err
+			}
+
+			coords[i] = lineStringCoords
+		}
+
+		return ValidateMultiLineStringCoordinates(coords), nil
+	case *s2.Polygon:
+		coords, err := PolygonCoordinates(geometry, PrecisionMax)
+		if err != nil {
+			return nil, err
+		}
+
+		return ValidateMultiPolygonCoordinates(coords), nil
+	default:
+		return nil, fmt.Errorf("geoutil: unknown geometry type %T", geometry)
+	}
+}
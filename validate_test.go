@@ -0,0 +1,210 @@
+package geoutil
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/golang/geo/s2"
+)
+
+var (
+	square     = [][]float64{{0, 0}, {10, 0}, {10, 10}, {0, 10}, {0, 0}}
+	squareHole = [][]float64{{4, 4}, {4, 6}, {6, 6}, {6, 4}, {4, 4}}
+)
+
+// circleCoords returns the coordinates of a ring of vertices around the
+// origin at a radius in degrees.
+func circleCoords(n int, radius float64) [][]float64 {
+	coords := make([][]float64, n+1)
+	for i := range coords {
+		angle := 2 * math.Pi * float64(i%n) / float64(n)
+		coords[i] = []float64{radius * math.Cos(angle), radius * math.Sin(angle)}
+	}
+
+	return coords
+}
+
+func TestValidateMultiPolygonCoordinates(t *testing.T) {
+	tests := []struct {
+		name   string
+		coords [][][][]float64
+		want   []Problem
+	}{
+		{
+			name:   "valid",
+			coords: [][][][]float64{{square, squareHole}, {{{20, 0}, {30, 0}, {30, 10}, {20, 0}}}},
+			want:   []Problem{},
+		},
+		{
+			name:   "out of range",
+			coords: [][][][]float64{{{{0, 0}, {10, 0}, {10, 91}, {0, 10}, {math.NaN(), 0}, {0, 0}}}},
+			want: []Problem{
+				{Kind: ProblemOutOfRange, Vertex: 2},
+				{Kind: ProblemOutOfRange, Vertex: 4},
+			},
+		},
+		{
+			name:   "duplicate vertex",
+			coords: [][][][]float64{{{{0, 0}, {10, 0}, {10, 0}, {10, 10}, {0, 10}, {0, 0}}}},
+			want:   []Problem{{Kind: ProblemDuplicateVertex, Vertex: 2}},
+		},
+		{
+			name:   "degenerate",
+			coords: [][][][]float64{{square, {{1, 1}, {2, 2}, {1, 1}}}, {}},
+			want: []Problem{
+				{Kind: ProblemDegenerate, Ring: 1, Vertex: -1},
+				{Kind: ProblemDegenerate, Part: 1, Vertex: -1},
+			},
+		},
+		{
+			name:   "unclosed ring",
+			coords: [][][][]float64{{square[:4], squareHole}},
+			want:   []Problem{{Kind: ProblemUnclosedRing, Vertex: -1}},
+		},
+		{
+			name:   "self-intersection",
+			coords: [][][][]float64{{{{0, 0}, {10, 10}, {10, 0}, {0, 10}, {0, 0}}}},
+			want:   []Problem{{Kind: ProblemSelfIntersection, Vertex: 0}},
+		},
+		{
+			name:   "vertex visited twice",
+			coords: [][][][]float64{{{{0, 0}, {5, 5}, {10, 0}, {10, 10}, {5, 5}, {0, 10}, {0, 0}}}},
+			want:   []Problem{{Kind: ProblemSelfIntersection, Vertex: 4}},
+		},
+		{
+			// The hole crosses the shell with two of its edges, and is
+			// reported once.
+			name:   "hole crossing its shell",
+			coords: [][][][]float64{{square, {{5, 5}, {15, 5}, {15, 6}, {5, 6}, {5, 5}}}},
+			want:   []Problem{{Kind: ProblemCrossingRings, Vertex: 1, OtherRing: 1}},
+		},
+		{
+			name:   "crossing polygons",
+			coords: [][][][]float64{{square}, {{{5, 5}, {15, 5}, {15, 15}, {5, 15}, {5, 5}}}},
+			want:   []Problem{{Kind: ProblemCrossingRings, Vertex: 1, OtherPart: 1}},
+		},
+		{
+			name:   "hole outside of its shell",
+			coords: [][][][]float64{{square, {{20, 0}, {20, 1}, {21, 1}, {20, 0}}}},
+			want:   []Problem{{Kind: ProblemImproperNesting, Ring: 1, Vertex: -1}},
+		},
+		{
+			name:   "hole inside another hole",
+			coords: [][][][]float64{{square, {{2, 2}, {2, 8}, {8, 8}, {8, 2}, {2, 2}}, squareHole}},
+			want:   []Problem{{Kind: ProblemImproperNesting, Ring: 2, Vertex: -1}},
+		},
+		{
+			name:   "shell inside another polygon",
+			coords: [][][][]float64{{square}, {{{1, 1}, {2, 1}, {2, 2}, {1, 1}}}, {squareHole}},
+			want: []Problem{
+				{Kind: ProblemImproperNesting, Part: 1, Vertex: -1},
+				{Kind: ProblemImproperNesting, Part: 2, Vertex: -1},
+			},
+		},
+		{
+			name:   "rings of many vertices",
+			coords: [][][][]float64{{circleCoords(100, 3), circleCoords(50, 1)}},
+			want:   []Problem{},
+		},
+		{
+			name:   "shell of many vertices inside another polygon",
+			coords: [][][][]float64{{circleCoords(100, 3)}, {circleCoords(50, 1)}},
+			want:   []Problem{{Kind: ProblemImproperNesting, Part: 1, Vertex: -1}},
+		},
+		{
+			name:   "shell inside a hole",
+			coords: [][][][]float64{{square, {{2, 2}, {2, 8}, {8, 8}, {8, 2}, {2, 2}}}, {squareHole}},
+			want:   []Problem{},
+		},
+	}
+
+	for _, test := range tests {
+		problems := ValidateMultiPolygonCoordinates(test.coords)
+		if !reflect.DeepEqual(problems, test.want) {
+			t.Errorf("%s: found %v, want %v", test.name, problems, test.want)
+		}
+	}
+}
+
+func TestValidateLineStringCoordinates(t *testing.T) {
+	tests := []struct {
+		coords [][][]float64
+		want   []Problem
+	}{
+		{[][][]float64{{{0, 0}, {1, 1}}, {{0, 0}, {0, 0}, {1, 1}}}, []Problem{{Kind: ProblemDuplicateVertex, Part: 1, Vertex: 1}}},
+		{[][][]float64{{{0, 0}}, {{1, 1}, {1, 1}}}, []Problem{
+			{Kind: ProblemDegenerate, Vertex: -1},
+			{Kind: ProblemDuplicateVertex, Part: 1, Vertex: 1},
+			{Kind: ProblemDegenerate, Part: 1, Vertex: -1},
+		}},
+		{[][][]float64{{{0, 0}, {181, 1}}}, []Problem{{Kind: ProblemOutOfRange, Vertex: 1}}},
+	}
+
+	for _, test := range tests {
+		if problems := ValidateMultiLineStringCoordinates(test.coords); !reflect.DeepEqual(problems, test.want) {
+			t.Errorf("%v: found %v, want %v", test.coords, problems, test.want)
+		}
+	}
+
+	problems := ValidateMultiPointCoordinates([][]float64{{0, 0}, {0}, {0, -91}})
+	want := []Problem{{Kind: ProblemOutOfRange, Part: 1}, {Kind: ProblemOutOfRange, Part: 2}}
+	if !reflect.DeepEqual(problems, want) {
+		t.Errorf("found %v, want %v", problems, want)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	polygon, err := PolygonFromPolygonCoordinates([][][]float64{square, squareHole})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	polyline := s2.Polyline{pointFromDegrees(0, 0), pointFromDegrees(1, 1)}
+	geometries := []interface{}{
+		s2.LatLngFromDegrees(1, 1),
+		pointFromDegrees(1, 1),
+		&polyline,
+		polygon,
+	}
+
+	for _, geometry := range geometries {
+		problems, err := Validate(geometry)
+		if err != nil || len(problems) != 0 {
+			t.Errorf("%T: found %v, %v", geometry, problems, err)
+		}
+	}
+
+	problems, err := Validate(s2.LatLngFromDegrees(100, 0))
+	if err != nil || len(problems) != 1 || problems[0].Kind != ProblemOutOfRange {
+		t.Errorf("found %v, %v", problems, err)
+	}
+
+	if _, err := Validate(1); err == nil {
+		t.Errorf("validating an unknown geometry returned no error")
+	}
+}
+
+func TestProblemString(t *testing.T) {
+	tests := map[Problem]string{
+		{Kind: ProblemDuplicateVertex, Part: 1, Ring: 2, Vertex: 3}:                  "duplicate vertex at part 1, ring 2, vertex 3",
+		{Kind: ProblemUnclosedRing, Ring: 1, Vertex: -1}:                             "unclosed ring at part 0, ring 1",
+		{Kind: ProblemCrossingRings, Part: 1, Vertex: 4, OtherPart: 2, OtherRing: 1}: "crossing rings at part 1, ring 0, vertex 4 with part 2, ring 1",
+		{Kind: ProblemKind(-1), Vertex: -1}:                                          "ProblemKind(-1) at part 0, ring 0",
+	}
+
+	for problem, want := range tests {
+		if s := problem.String(); s != want {
+			t.Errorf("formatted %s, want %s", s, want)
+		}
+	}
+
+	err := ErrorFromProblems([]Problem{{Kind: ProblemDegenerate, Vertex: -1}, {Kind: ProblemUnclosedRing, Vertex: -1}})
+	if want := "geoutil: invalid geometry: degenerate geometry at part 0, ring 0 and 1 more problems"; err == nil || err.Error() != want {
+		t.Errorf("error %v, want %s", err, want)
+	}
+
+	if err := ErrorFromProblems(nil); err != nil {
+		t.Errorf("error %v without problems", err)
+	}
+}